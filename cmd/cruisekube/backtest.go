@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/adapters/kube"
+	"github.com/truefoundry/cruisekube/pkg/adapters/metricsProvider/prometheus"
+	"github.com/truefoundry/cruisekube/pkg/backtest"
+	"github.com/truefoundry/cruisekube/pkg/config"
+	"github.com/truefoundry/cruisekube/pkg/logging"
+	"github.com/truefoundry/cruisekube/pkg/task"
+
+	"github.com/spf13/cobra"
+)
+
+func newBacktestCommand() *cobra.Command {
+	var (
+		fixturePath   string
+		strategy      string
+		forecaster    string
+		statsInterval time.Duration
+		warmUp        time.Duration
+		outputPath    string
+	)
+	backtestCmd := &cobra.Command{
+		Use:   "backtest",
+		Short: "Replay recorded usage through the stats and a strategy and score the recommendations",
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			cfg, err := config.LoadWithViperInstance(ctx, v, configFilePath)
+			if err != nil {
+				logging.Fatalf(ctx, "Failed to load config: %v", err)
+			}
+			fixture, err := backtest.LoadFixture(fixturePath)
+			if err != nil {
+				logging.Fatalf(ctx, "Failed to load fixture: %v", err)
+			}
+
+			backtestConfig := backtestConfigFromTasks(ctx, cfg)
+			if strategy != "" {
+				backtestConfig.Strategy = strategy
+			}
+			if forecaster != "" {
+				backtestConfig.Forecaster.Name = forecaster
+			}
+			backtestConfig.StatsInterval = statsInterval
+			backtestConfig.WarmUp = warmUp
+
+			// the stats and strategies log every workload at info level
+			logging.SetLevel(slog.LevelWarn)
+			report, err := backtest.Run(ctx, fixture, backtestConfig)
+			if err != nil {
+				logging.Fatalf(ctx, "Backtest failed: %v", err)
+			}
+			writeJSONOutput(ctx, outputPath, report)
+		},
+	}
+	backtestCmd.Flags().StringVar(&fixturePath, "fixture", "", "Path to the recorded fixture")
+	backtestCmd.Flags().StringVar(&strategy, "strategy", "", "Optimization strategy, defaults to the strategy of the applyRecommendation task")
+	backtestCmd.Flags().StringVar(&forecaster, "forecaster", "", "Forecaster, defaults to the forecaster of the createStats task")
+	backtestCmd.Flags().DurationVar(&statsInterval, "stats-interval", backtest.DefaultStatsInterval, "How often stats are rebuilt and recommendations applied")
+	backtestCmd.Flags().DurationVar(&warmUp, "warm-up", backtest.DefaultWarmUp, "Recorded time used as history before the first recommendation")
+	backtestCmd.Flags().StringVar(&outputPath, "output", "", "Path of the JSON report, defaults to stdout")
+	if err := backtestCmd.MarkFlagRequired("fixture"); err != nil {
+		logging.Fatalf(context.Background(), "Failed to mark flag required: %v", err)
+	}
+
+	backtestCmd.AddCommand(newBacktestRecordCommand())
+	return backtestCmd
+}
+
+func newBacktestRecordCommand() *cobra.Command {
+	var (
+		namespaces []string
+		lookback   time.Duration
+		step       time.Duration
+		outputPath string
+	)
+	recordCmd := &cobra.Command{
+		Use:   "record",
+		Short: "Record the usage stored in prometheus into a backtest fixture",
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			cfg, err := config.LoadWithViperInstance(ctx, v, configFilePath)
+			if err != nil {
+				logging.Fatalf(ctx, "Failed to load config: %v", err)
+			}
+
+			kubeconfigPath, promURL := "", cfg.Dependencies.InCluster.PrometheusURL
+			if cfg.ControllerMode == config.ClusterModeLocal {
+				kubeconfigPath, promURL = cfg.Dependencies.Local.KubeconfigPath, cfg.Dependencies.Local.PrometheusURL
+				if kubeconfigPath == "" {
+					if home := homeDir(); home != "" {
+						kubeconfigPath = filepath.Join(home, ".kube", "config")
+					}
+				}
+			}
+			kubeClient, err := kube.NewKubeClient(ctx, kubeconfigPath)
+			if err != nil {
+				logging.Fatalf(ctx, "Failed to create kube client: %v", err)
+			}
+			promClient, err := prometheus.NewPrometheusProvider(ctx, prometheus.GetPrometheusClientConfig(promURL))
+			if err != nil {
+				logging.Fatalf(ctx, "Failed to create prometheus client: %v", err)
+			}
+
+			end := time.Now()
+			fixture, err := backtest.Record(ctx, kubeClient, promClient.GetClient(), backtest.RecordOptions{
+				Namespaces: namespaces,
+				Start:      end.Add(-lookback),
+				End:        end,
+				Step:       step,
+			})
+			if err != nil {
+				logging.Fatalf(ctx, "Failed to record fixture: %v", err)
+			}
+			if err := fixture.Save(outputPath); err != nil {
+				logging.Fatalf(ctx, "Failed to save fixture: %v", err)
+			}
+			logging.Infof(ctx, "Recorded %d workloads into %s", len(fixture.Workloads), outputPath)
+		},
+	}
+	recordCmd.Flags().StringSliceVar(&namespaces, "namespace", nil, "Namespaces to record, repeat or comma separate")
+	recordCmd.Flags().DurationVar(&lookback, "lookback", 8*24*time.Hour, "How far back to record")
+	recordCmd.Flags().DurationVar(&step, "step", time.Minute, "Time between two samples")
+	recordCmd.Flags().StringVar(&outputPath, "output", "fixture.json", "Path of the fixture")
+	if err := recordCmd.MarkFlagRequired("namespace"); err != nil {
+		logging.Fatalf(context.Background(), "Failed to mark flag required: %v", err)
+	}
+	return recordCmd
+}
+
+// backtestConfigFromTasks uses the recommendation settings, strategy, stabilization, forecaster and calendar
+// the controller runs with.
+func backtestConfigFromTasks(ctx context.Context, cfg *config.Config) backtest.Config {
+	backtestConfig := backtest.Config{Settings: cfg.RecommendationSettings}
+	if taskConfig := cfg.GetTaskConfig(config.ApplyRecommendationKey); taskConfig != nil {
+		var metadata task.ApplyRecommendationMetadata
+		if err := taskConfig.ConvertMetadataToStruct(&metadata); err != nil {
+			logging.Warnf(ctx, "Error converting applyRecommendation metadata to struct: %v", err)
+		}
+		backtestConfig.Strategy = metadata.Strategy
+		backtestConfig.Stabilization = metadata.Stabilization
+	}
+	if taskConfig := cfg.GetTaskConfig(config.CreateStatsKey); taskConfig != nil {
+		var metadata task.CreateStatsMetadata
+		if err := taskConfig.ConvertMetadataToStruct(&metadata); err != nil {
+			logging.Warnf(ctx, "Error converting createStats metadata to struct: %v", err)
+		}
+		backtestConfig.Forecaster = metadata.Forecaster
+		backtestConfig.Calendar = metadata.Calendar
+	}
+	return backtestConfig
+}
+
+func writeJSONOutput(ctx context.Context, path string, value any) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		logging.Fatalf(ctx, "Failed to marshal output: %v", err)
+	}
+	if path == "" {
+		if _, err := os.Stdout.Write(append(data, '\n')); err != nil {
+			logging.Fatalf(ctx, "Failed to write output: %v", err)
+		}
+		return
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		logging.Fatalf(ctx, "Failed to write output to %s: %v", path, err)
+	}
+}
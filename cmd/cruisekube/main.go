@@ -8,19 +8,25 @@ import (
 
 	"github.com/truefoundry/cruisekube/pkg/adapters/database"
 	"github.com/truefoundry/cruisekube/pkg/adapters/kube"
+	metricsprovider "github.com/truefoundry/cruisekube/pkg/adapters/metricsProvider"
+	"github.com/truefoundry/cruisekube/pkg/adapters/metricsProvider/kubelet"
 	"github.com/truefoundry/cruisekube/pkg/adapters/metricsProvider/prometheus"
+	"github.com/truefoundry/cruisekube/pkg/client"
 	"github.com/truefoundry/cruisekube/pkg/cluster"
 	"github.com/truefoundry/cruisekube/pkg/config"
 	"github.com/truefoundry/cruisekube/pkg/contextutils"
+	"github.com/truefoundry/cruisekube/pkg/handlers"
 	"github.com/truefoundry/cruisekube/pkg/middleware"
 	"github.com/truefoundry/cruisekube/pkg/oom"
 	"github.com/truefoundry/cruisekube/pkg/repository/storage"
 	"github.com/truefoundry/cruisekube/pkg/server"
 	"github.com/truefoundry/cruisekube/pkg/task"
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
 	"github.com/truefoundry/cruisekube/pkg/telemetry"
 
 	"github.com/truefoundry/cruisekube/pkg/logging"
 
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	_ "go.uber.org/automaxprocs"
@@ -99,6 +105,9 @@ func main() {
 		logging.Fatalf(ctx, "Failed to bind flag: %v", err)
 	}
 
+	rootCmd.AddCommand(newBacktestCommand())
+	rootCmd.AddCommand(newRecordingRulesCommand())
+
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
@@ -117,6 +126,10 @@ func runcruisekube(cmd *cobra.Command, args []string) {
 	}
 	logging.Infof(ctx, "Configuration loaded: controllerMode=%s executionMode=%s", cfg.ControllerMode, cfg.ExecutionMode)
 
+	if err := utils.RegisterCustomWorkloads(cfg.CustomWorkloads); err != nil {
+		logging.Fatalf(ctx, "Invalid custom workloads: %v", err)
+	}
+
 	if cfg.Telemetry.Enabled {
 		shutdown, err := telemetry.Init(ctx, cfg.Telemetry)
 		if err != nil {
@@ -205,14 +218,8 @@ func setupControllerMode(ctx context.Context, cfg *config.Config) {
 			logging.Fatalf(ctx, "Failed to create dynamic client: %v", err)
 		}
 
-		promURL := cfg.Dependencies.Local.PrometheusURL
-		defaultPromConfig := prometheus.GetPrometheusClientConfig(promURL)
-		promClient, err = prometheus.NewPrometheusProvider(contextutils.WithCluster(ctx, "local"), defaultPromConfig)
-		if err != nil {
-			logging.Fatalf(ctx, "Failed to create prometheus client: %v", err)
-		}
-
-		clusterManager = cluster.NewSingleClusterManager(ctx, kubeClient, dynamicClient, promClient.GetClient())
+		promClient = newPrometheusProvider(contextutils.WithCluster(ctx, "local"), cfg, storageRepo, cfg.Dependencies.Local.PrometheusURL)
+		clusterManager = cluster.NewSingleClusterManager(ctx, kubeClient, dynamicClient, prometheusAPI(promClient))
 	case config.ClusterModeInCluster:
 		logging.Infof(ctx, "In-cluster mode")
 		ctx = contextutils.WithCluster(ctx, "in-cluster")
@@ -226,13 +233,8 @@ func setupControllerMode(ctx context.Context, cfg *config.Config) {
 			logging.Fatalf(ctx, "Failed to create dynamic client: %v", err)
 		}
 
-		defaultPromConfig := prometheus.GetPrometheusClientConfig(cfg.Dependencies.InCluster.PrometheusURL)
-		promClient, err = prometheus.NewPrometheusProvider(contextutils.WithCluster(ctx, "in-cluster"), defaultPromConfig)
-		if err != nil {
-			logging.Fatalf(ctx, "Failed to create prometheus client: %v", err)
-		}
-
-		clusterManager = cluster.NewSingleClusterManager(ctx, kubeClient, dynamicClient, promClient.GetClient())
+		promClient = newPrometheusProvider(contextutils.WithCluster(ctx, "in-cluster"), cfg, storageRepo, cfg.Dependencies.InCluster.PrometheusURL)
+		clusterManager = cluster.NewSingleClusterManager(ctx, kubeClient, dynamicClient, prometheusAPI(promClient))
 	default:
 		logging.Fatalf(ctx, "Invalid controller mode: %s", cfg.ControllerMode)
 	}
@@ -281,12 +283,37 @@ func setupControllerMode(ctx context.Context, cfg *config.Config) {
 	// Add tasks to cluster manager
 	////////
 	for ID, cluster := range clusterManager.GetAllClusters() {
+		var metricsProvider metricsprovider.MetricsProvider = promClient
+		if !cfg.Dependencies.UsesPrometheusMetricsProvider() {
+			kubeletProvider, err := kubelet.NewKubeletProvider(cluster.KubeClient, storageRepo, cfg.Dependencies.MetricsProvider.KubeletSource)
+			if err != nil {
+				logging.Fatalf(ctx, "Failed to create kubelet metrics provider: %v", err)
+			}
+			metricsProvider = kubeletProvider
+
+			sampleMetricsTaskConfig := cfg.GetTaskConfig(config.SampleMetricsKey)
+			if sampleMetricsTaskConfig == nil {
+				logging.Fatalf(ctx, "The sampleMetrics task is required by the kubelet metrics provider")
+			}
+			clusterManager.AddTask(task.NewSampleMetricsTask(
+				ctx,
+				kubeletProvider,
+				&task.SampleMetricsTaskConfig{
+					Name:            ID + "_" + config.SampleMetricsKey,
+					Enabled:         sampleMetricsTaskConfig.Enabled,
+					Schedule:        sampleMetricsTaskConfig.Schedule,
+					ClusterID:       ID,
+					TargetNamespace: cfg.Controller.TargetNamespace,
+				},
+			))
+		}
+
 		createStatsTaskConfig := cfg.GetTaskConfig(config.CreateStatsKey)
 		clusterManager.AddTask(task.NewCreateStatsTask(
 			ctx,
 			cluster.KubeClient,
 			cluster.DynamicClient,
-			promClient,
+			metricsProvider,
 			storageRepo,
 			&task.CreateStatsTaskConfig{
 				Name:                       ID + "_" + config.CreateStatsKey,
@@ -323,6 +350,7 @@ func setupControllerMode(ctx context.Context, cfg *config.Config) {
 			cluster.KubeClient,
 			cluster.DynamicClient,
 			promClient,
+			storageRepo,
 			&task.ApplyRecommendationTaskConfig{
 				Name:                     ID + "_" + config.ApplyRecommendationKey,
 				Enabled:                  applyRecommendationTaskConfig.Enabled,
@@ -337,35 +365,40 @@ func setupControllerMode(ctx context.Context, cfg *config.Config) {
 			applyRecommendationTaskConfig,
 		))
 
-		fetchMetricsTaskConfig := cfg.GetTaskConfig(config.FetchMetricsKey)
-		clusterManager.AddTask(task.NewFetchMetricsTask(
-			ctx,
-			cluster.KubeClient,
-			cluster.DynamicClient,
-			promClient,
-			storageRepo,
-			&task.FetchMetricsTaskConfig{
-				Name:      ID + "_" + config.FetchMetricsKey,
-				Enabled:   fetchMetricsTaskConfig.Enabled,
-				Schedule:  fetchMetricsTaskConfig.Schedule,
-				ClusterID: ID,
-			},
-		))
-
-		nodeLoadMonitoringTaskConfig := cfg.GetTaskConfig(config.NodeLoadMonitoringKey)
-		clusterManager.AddTask(task.NewNodeLoadMonitoringTask(
-			ctx,
-			cluster.KubeClient,
-			cluster.DynamicClient,
-			promClient,
-			&task.NodeLoadMonitoringTaskConfig{
-				Name:                     ID + "_" + config.NodeLoadMonitoringKey,
-				Enabled:                  nodeLoadMonitoringTaskConfig.Enabled,
-				Schedule:                 nodeLoadMonitoringTaskConfig.Schedule,
-				ClusterID:                ID,
-				IsClusterWriteAuthorized: cfg.IsClusterWriteAuthorized(ID),
-			},
-		))
+		// fetchMetrics and nodeLoadMonitoring query prometheus directly
+		if promClient != nil {
+			fetchMetricsTaskConfig := cfg.GetTaskConfig(config.FetchMetricsKey)
+			clusterManager.AddTask(task.NewFetchMetricsTask(
+				ctx,
+				cluster.KubeClient,
+				cluster.DynamicClient,
+				promClient,
+				storageRepo,
+				&task.FetchMetricsTaskConfig{
+					Name:      ID + "_" + config.FetchMetricsKey,
+					Enabled:   fetchMetricsTaskConfig.Enabled,
+					Schedule:  fetchMetricsTaskConfig.Schedule,
+					ClusterID: ID,
+				},
+			))
+
+			nodeLoadMonitoringTaskConfig := cfg.GetTaskConfig(config.NodeLoadMonitoringKey)
+			clusterManager.AddTask(task.NewNodeLoadMonitoringTask(
+				ctx,
+				cluster.KubeClient,
+				cluster.DynamicClient,
+				promClient,
+				&task.NodeLoadMonitoringTaskConfig{
+					Name:                     ID + "_" + config.NodeLoadMonitoringKey,
+					Enabled:                  nodeLoadMonitoringTaskConfig.Enabled,
+					Schedule:                 nodeLoadMonitoringTaskConfig.Schedule,
+					ClusterID:                ID,
+					IsClusterWriteAuthorized: cfg.IsClusterWriteAuthorized(ID),
+				},
+			))
+		} else {
+			logging.Infof(ctx, "Skipping the fetchMetrics and nodeLoadMonitoring tasks of cluster %s without prometheus", ID)
+		}
 
 		cleanupOOMEventsTaskConfig := cfg.GetTaskConfig(config.CleanupOOMEventsKey)
 		clusterManager.AddTask(task.NewCleanupOOMEventsTask(
@@ -389,6 +422,13 @@ func setupControllerMode(ctx context.Context, cfg *config.Config) {
 func setupWebhookMode(ctx context.Context, cfg *config.Config) {
 	webhookPort := cfg.Webhook.Port
 	certDir := cfg.Webhook.CertsDir
+	statsCache := client.NewWebhookStatsCache(
+		client.NewRecommenderServiceClientWithClusterToken(cfg.Webhook.StatsURL.Host, cfg.Webhook.StatsURL.TfyClusterToken),
+		time.Duration(cfg.Webhook.Cache.RefreshIntervalSeconds)*time.Second,
+		time.Duration(cfg.Webhook.Cache.MaxStalenessSeconds)*time.Second,
+	)
+	statsCache.Start(ctx)
+	handlers.SetWebhookStatsCache(statsCache)
 	webhookEngine := server.SetupWebhookServerEngine(middleware.Common(nil, cfg)...)
 	go func() {
 		if err := webhookEngine.RunTLS(":"+webhookPort, certDir+"/tls.crt", certDir+"/tls.key"); err != nil {
@@ -397,6 +437,30 @@ func setupWebhookMode(ctx context.Context, cfg *config.Config) {
 	}()
 }
 
+// newPrometheusProvider returns nil when the kubelet metrics provider runs without a prometheus url.
+func newPrometheusProvider(ctx context.Context, cfg *config.Config, storageRepo *storage.Storage, promURL string) *prometheus.PrometheusProvider {
+	if promURL == "" && !cfg.Dependencies.UsesPrometheusMetricsProvider() {
+		logging.Infof(ctx, "No prometheus url, only the kubelet metrics provider is used")
+		return nil
+	}
+	promClient, err := prometheus.NewPrometheusProvider(ctx, prometheus.GetPrometheusClientConfig(promURL))
+	if err != nil {
+		logging.Fatalf(ctx, "Failed to create prometheus client: %v", err)
+	}
+	if cfg.Dependencies.MetricsProvider.IncrementalStats {
+		logging.Infof(ctx, "Computing the 7 day stats incrementally from stored sketches")
+		promClient.EnableIncrementalStats(storageRepo)
+	}
+	return promClient
+}
+
+func prometheusAPI(promClient *prometheus.PrometheusProvider) v1.API {
+	if promClient == nil {
+		return nil
+	}
+	return promClient.GetClient()
+}
+
 func blockForever() {
 	select {}
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/truefoundry/cruisekube/pkg/adapters/metricsProvider/prometheus"
+	"github.com/truefoundry/cruisekube/pkg/logging"
+
+	"github.com/spf13/cobra"
+)
+
+func newRecordingRulesCommand() *cobra.Command {
+	var (
+		format     string
+		name       string
+		namespace  string
+		labels     map[string]string
+		outputPath string
+	)
+	recordingRulesCmd := &cobra.Command{
+		Use:   "recording-rules",
+		Short: "Print the prometheus recording rules of the usage expressions behind the stats",
+		Long: "Print the prometheus recording rules of the usage expressions behind the stats. Once the recorded " +
+			"series cover the lookback windows, the stats query them instead of the raw metrics.",
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			data, err := prometheus.RecordingRulesYAML(prometheus.RecordingRulesFormat(format), name, namespace, labels)
+			if err != nil {
+				logging.Fatalf(ctx, "Failed to build recording rules: %v", err)
+			}
+			if outputPath == "" {
+				if _, err := os.Stdout.Write(data); err != nil {
+					logging.Fatalf(ctx, "Failed to write recording rules: %v", err)
+				}
+				return
+			}
+			if err := os.WriteFile(outputPath, data, 0600); err != nil {
+				logging.Fatalf(ctx, "Failed to write recording rules to %s: %v", outputPath, err)
+			}
+		},
+	}
+	recordingRulesCmd.Flags().StringVar(&format, "format", string(prometheus.RecordingRulesFormatPrometheusRule), "Output format, prometheusRule for the prometheus operator or ruleFile for the rule_files of prometheus")
+	recordingRulesCmd.Flags().StringVar(&name, "name", "cruisekube-recording-rules", "Name of the PrometheusRule")
+	recordingRulesCmd.Flags().StringVar(&namespace, "namespace", "", "Namespace of the PrometheusRule")
+	recordingRulesCmd.Flags().StringToStringVar(&labels, "label", nil, "Labels of the PrometheusRule, like the ruleSelector of the prometheus operator expects")
+	recordingRulesCmd.Flags().StringVar(&outputPath, "output", "", "Path of the YAML file, defaults to stdout")
+	return recordingRulesCmd
+}
@@ -73,9 +73,27 @@ func (s *GormDB) createTables() error {
 	if err := s.db.AutoMigrate(&Stats{}); err != nil {
 		return fmt.Errorf("failed to auto-migrate RowStats: %w", err)
 	}
+	if err := s.db.AutoMigrate(&StatDeletion{}); err != nil {
+		return fmt.Errorf("failed to auto-migrate StatDeletion: %w", err)
+	}
+	if err := s.db.AutoMigrate(&NamespaceOverrides{}); err != nil {
+		return fmt.Errorf("failed to migrate namespace overrides table: %w", err)
+	}
 	if err := s.db.AutoMigrate(&OOMEvent{}); err != nil {
 		return fmt.Errorf("failed to auto-migrate OOMEvent: %w", err)
 	}
+	if err := s.db.AutoMigrate(&ApplyRun{}, &ApplyRunEntry{}); err != nil {
+		return fmt.Errorf("failed to auto-migrate ApplyRun: %w", err)
+	}
+	if err := s.db.AutoMigrate(&StatHistory{}); err != nil {
+		return fmt.Errorf("failed to auto-migrate StatHistory: %w", err)
+	}
+	if err := s.db.AutoMigrate(&RecommendationState{}); err != nil {
+		return fmt.Errorf("failed to auto-migrate RecommendationState: %w", err)
+	}
+	if err := s.db.AutoMigrate(&UsageHistogram{}); err != nil {
+		return fmt.Errorf("failed to auto-migrate UsageHistogram: %w", err)
+	}
 	return nil
 }
 
@@ -91,29 +109,41 @@ func (s *GormDB) Close() error {
 	return nil
 }
 
+// UpsertStat writes the stat of a workload. A stat equal to the stored one only moves generated_at, so
+// updated_at keeps telling when the stat last changed.
 func (s *GormDB) UpsertStat(clusterID, workloadID string, stat types.WorkloadStat, generatedAt time.Time) error {
-	statsJSON, err := json.Marshal(stat)
+	statsJSON, err := json.Marshal(stat.Content())
 	if err != nil {
 		return fmt.Errorf("failed to marshal stats: %w", err)
 	}
 
-	rowStat := Stats{
-		ClusterID:   clusterID,
-		WorkloadID:  workloadID,
-		Stats:       string(statsJSON),
-		GeneratedAt: generatedAt,
+	var rowStat Stats
+	err = s.db.Where(&Stats{ClusterID: clusterID, WorkloadID: workloadID}).First(&rowStat).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&Stats{
+				ClusterID:   clusterID,
+				WorkloadID:  workloadID,
+				Stats:       string(statsJSON),
+				GeneratedAt: generatedAt,
+			}).Error; err != nil {
+				return fmt.Errorf("failed to insert stats: %w", err)
+			}
+			// the workload is back, it is no longer reported as removed
+			if err := tx.Where(&StatDeletion{ClusterID: clusterID, WorkloadID: workloadID}).Delete(&StatDeletion{}).Error; err != nil {
+				return fmt.Errorf("failed to delete stat deletions: %w", err)
+			}
+			return nil
+		})
+	} else if err == nil {
+		if rowStat.Stats == string(statsJSON) {
+			err = s.db.Model(&rowStat).UpdateColumn("generated_at", generatedAt).Error
+		} else {
+			err = s.db.Model(&rowStat).Updates(Stats{Stats: string(statsJSON), GeneratedAt: generatedAt}).Error
+		}
 	}
-
-	// Use GORM's Clauses for upsert functionality
-	result := s.db.Where(&Stats{ClusterID: clusterID, WorkloadID: workloadID}).
-		Assign(Stats{
-			Stats:       string(statsJSON),
-			GeneratedAt: generatedAt,
-		}).
-		FirstOrCreate(&rowStat)
-
-	if result.Error != nil {
-		return fmt.Errorf("failed to upsert stats: %w", result.Error)
+	if err != nil {
+		return fmt.Errorf("failed to upsert stats: %w", err)
 	}
 
 	return nil
@@ -163,12 +193,42 @@ func (s *GormDB) GetStatsForCluster(clusterID string) ([]types.WorkloadStat, err
 		}
 
 		stat.UpdatedAt = row.UpdatedAt
+		stat.GeneratedAt = row.GeneratedAt
 		stats = append(stats, stat)
 	}
 
 	return stats, nil
 }
 
+// GetFilteredStatsForCluster applies the since of the filter in the query, the namespace and kind are
+// only known once the stats are unmarshalled.
+func (s *GormDB) GetFilteredStatsForCluster(clusterID string, filter types.StatsFilter) ([]types.WorkloadStat, error) {
+	query := s.db.Where(&Stats{ClusterID: clusterID})
+	if !filter.Since.IsZero() {
+		query = query.Where("updated_at > ?", filter.Since)
+	}
+	var rowStats []Stats
+	if err := query.Order("updated_at DESC").Find(&rowStats).Error; err != nil {
+		return nil, fmt.Errorf("failed to query cluster stats: %w", err)
+	}
+
+	stats := make([]types.WorkloadStat, 0, len(rowStats))
+	for _, row := range rowStats {
+		var stat types.WorkloadStat
+		if err := json.Unmarshal([]byte(row.Stats), &stat); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal stats: %w", err)
+		}
+
+		stat.UpdatedAt = row.UpdatedAt
+		stat.GeneratedAt = row.GeneratedAt
+		if filter.Matches(stat) {
+			stats = append(stats, stat)
+		}
+	}
+
+	return stats, nil
+}
+
 func (s *GormDB) GetStatForWorkload(clusterID, workloadID string) (*types.WorkloadStat, error) {
 	var rowStat Stats
 	err := s.db.Where(&Stats{ClusterID: clusterID, WorkloadID: workloadID}).
@@ -187,6 +247,7 @@ func (s *GormDB) GetStatForWorkload(clusterID, workloadID string) (*types.Worklo
 	}
 
 	stat.UpdatedAt = rowStat.UpdatedAt
+	stat.GeneratedAt = rowStat.GeneratedAt
 	return &stat, nil
 }
 
@@ -237,8 +298,41 @@ func (s *GormDB) GetStatOverridesForWorkload(clusterID, workloadID string) (*typ
 	return &overrides, nil
 }
 
+// GetStatOverridesForCluster returns the overrides of every workload of the cluster keyed by workload id.
+func (s *GormDB) GetStatOverridesForCluster(clusterID string) (map[string]*types.Overrides, error) {
+	var rowStats []Stats
+	err := s.db.Select("workload_id", "overrides").
+		Where(&Stats{ClusterID: clusterID}).
+		Find(&rowStats).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cluster overrides: %w", err)
+	}
+
+	overrides := make(map[string]*types.Overrides, len(rowStats))
+	for _, row := range rowStats {
+		var workloadOverrides types.Overrides
+		if err := json.Unmarshal([]byte(row.Overrides), &workloadOverrides); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal overrides of workload %s: %w", row.WorkloadID, err)
+		}
+		overrides[row.WorkloadID] = &workloadOverrides
+	}
+	return overrides, nil
+}
+
 func (s *GormDB) DeleteStatsForCluster(clusterID string) error {
-	err := s.db.Where(&Stats{ClusterID: clusterID}).Delete(&Stats{}).Error
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var workloadIDs []string
+		if err := tx.Model(&Stats{}).Where(&Stats{ClusterID: clusterID}).Pluck("workload_id", &workloadIDs).Error; err != nil {
+			return fmt.Errorf("failed to query workload ids: %w", err)
+		}
+		if err := recordStatDeletions(tx, clusterID, workloadIDs); err != nil {
+			return err
+		}
+		if err := tx.Where(&Stats{ClusterID: clusterID}).Delete(&Stats{}).Error; err != nil {
+			return fmt.Errorf("failed to delete stats: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to delete cluster stats: %w", err)
 	}
@@ -247,18 +341,69 @@ func (s *GormDB) DeleteStatsForCluster(clusterID string) error {
 }
 
 func (s *GormDB) DeleteStatForWorkload(clusterID, workloadID string) error {
-	result := s.db.Where(&Stats{ClusterID: clusterID, WorkloadID: workloadID}).Delete(&Stats{})
-	if result.Error != nil {
-		return fmt.Errorf("failed to delete workload stat: %w", result.Error)
+	var rowsAffected int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where(&Stats{ClusterID: clusterID, WorkloadID: workloadID}).Delete(&Stats{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete stat: %w", result.Error)
+		}
+		rowsAffected = result.RowsAffected
+		if rowsAffected == 0 {
+			return nil
+		}
+		return recordStatDeletions(tx, clusterID, []string{workloadID})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete workload stat: %w", err)
 	}
 
-	if result.RowsAffected == 0 {
+	if rowsAffected == 0 {
 		return fmt.Errorf("workload stat not found")
 	}
 
 	return nil
 }
 
+func recordStatDeletions(tx *gorm.DB, clusterID string, workloadIDs []string) error {
+	if len(workloadIDs) == 0 {
+		return nil
+	}
+	removedAt := time.Now()
+	deletions := make([]StatDeletion, 0, len(workloadIDs))
+	for _, workloadID := range workloadIDs {
+		deletions = append(deletions, StatDeletion{ClusterID: clusterID, WorkloadID: workloadID, RemovedAt: removedAt})
+	}
+	if err := tx.Create(&deletions).Error; err != nil {
+		return fmt.Errorf("failed to record stat deletions: %w", err)
+	}
+	return nil
+}
+
+// GetStatDeletionsForCluster returns the ids of the workloads whose stats were deleted after since.
+func (s *GormDB) GetStatDeletionsForCluster(clusterID string, since time.Time) ([]string, error) {
+	var workloadIDs []string
+	err := s.db.Model(&StatDeletion{}).
+		Where(&StatDeletion{ClusterID: clusterID}).
+		Where("removed_at > ?", since).
+		Distinct().
+		Order("workload_id").
+		Pluck("workload_id", &workloadIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stat deletions: %w", err)
+	}
+	return workloadIDs, nil
+}
+
+func (s *GormDB) DeleteOldStatDeletions(clusterID string, olderThan time.Time) (int64, error) {
+	result := s.db.Where(&StatDeletion{ClusterID: clusterID}).
+		Where("removed_at < ?", olderThan).
+		Delete(&StatDeletion{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete old stat deletions: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
 func (s *GormDB) UpdateStatOverridesForWorkload(clusterID, workloadID string, overrides *types.Overrides) error {
 	overridesJSON, err := json.Marshal(overrides)
 	if err != nil {
@@ -280,6 +425,44 @@ func (s *GormDB) UpdateStatOverridesForWorkload(clusterID, workloadID string, ov
 	return nil
 }
 
+// GetNamespaceOverridesForCluster returns the overrides of the namespaces of the cluster keyed by namespace.
+func (s *GormDB) GetNamespaceOverridesForCluster(clusterID string) (map[string]*types.Overrides, error) {
+	var rows []NamespaceOverrides
+	if err := s.db.Where(&NamespaceOverrides{ClusterID: clusterID}).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to query namespace overrides: %w", err)
+	}
+
+	overrides := make(map[string]*types.Overrides, len(rows))
+	for _, row := range rows {
+		var namespaceOverrides types.Overrides
+		if err := json.Unmarshal([]byte(row.Overrides), &namespaceOverrides); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal overrides of namespace %s: %w", row.Namespace, err)
+		}
+		overrides[row.Namespace] = &namespaceOverrides
+	}
+	return overrides, nil
+}
+
+func (s *GormDB) UpsertNamespaceOverrides(clusterID, namespace string, overrides *types.Overrides) error {
+	overridesJSON, err := json.Marshal(overrides)
+	if err != nil {
+		return fmt.Errorf("failed to marshal overrides: %w", err)
+	}
+
+	result := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "cluster_id"}, {Name: "namespace"}},
+		DoUpdates: clause.AssignmentColumns([]string{"overrides", "updated_at"}),
+	}).Create(&NamespaceOverrides{
+		ClusterID: clusterID,
+		Namespace: namespace,
+		Overrides: string(overridesJSON),
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to upsert namespace overrides: %w", result.Error)
+	}
+	return nil
+}
+
 func (s *GormDB) InsertOOMEvent(event *types.OOMEvent) error {
 	dbEvent := OOMEvent{
 		ClusterID:          event.ClusterID,
@@ -370,3 +553,417 @@ func (s *GormDB) DeleteOldOOMEvents(clusterID string, olderThan time.Time) (int6
 
 	return result.RowsAffected, nil
 }
+
+func (s *GormDB) InsertApplyRun(run *types.ApplyRun) error {
+	dbRun := ApplyRun{
+		ID:             run.ID,
+		ClusterID:      run.ClusterID,
+		Strategy:       run.Strategy,
+		DryRun:         run.DryRun,
+		StartedAt:      run.StartedAt,
+		FinishedAt:     run.FinishedAt,
+		UnchangedCount: run.UnchangedCount,
+	}
+	dbEntries := make([]ApplyRunEntry, 0, len(run.Entries))
+	for _, entry := range run.Entries {
+		dbEntries = append(dbEntries, ApplyRunEntry{
+			RunID:         run.ID,
+			ClusterID:     run.ClusterID,
+			Namespace:     entry.Namespace,
+			PodName:       entry.PodName,
+			NodeName:      entry.NodeName,
+			WorkloadID:    entry.WorkloadID,
+			ContainerName: entry.ContainerName,
+			Resource:      string(entry.Resource),
+			Action:        string(entry.Action),
+			OldRequest:    entry.OldRequest,
+			NewRequest:    entry.NewRequest,
+			OldLimit:      entry.OldLimit,
+			NewLimit:      entry.NewLimit,
+			Reason:        entry.Reason,
+		})
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&dbRun).Error; err != nil {
+			return fmt.Errorf("failed to insert apply run: %w", err)
+		}
+		if len(dbEntries) == 0 {
+			return nil
+		}
+		if err := tx.CreateInBatches(&dbEntries, 500).Error; err != nil {
+			return fmt.Errorf("failed to insert apply run entries: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to persist apply run %s: %w", run.ID, err)
+	}
+
+	return nil
+}
+
+func (s *GormDB) GetApplyRuns(clusterID string, filter types.ApplyRunFilter) ([]types.ApplyRun, error) {
+	query := s.db.Where("cluster_id = ? AND started_at >= ?", clusterID, filter.Since)
+	if filter.HasEntryFilter() {
+		query = query.Where("id IN (?)", s.applyRunEntriesQuery(clusterID, filter).Select("run_id"))
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var dbRuns []ApplyRun
+	if err := query.Order("started_at DESC").Find(&dbRuns).Error; err != nil {
+		return nil, fmt.Errorf("failed to query apply runs: %w", err)
+	}
+
+	runs := make([]types.ApplyRun, 0, len(dbRuns))
+	runIndex := make(map[string]int, len(dbRuns))
+	runIDs := make([]string, 0, len(dbRuns))
+	for _, dbRun := range dbRuns {
+		runIndex[dbRun.ID] = len(runs)
+		runIDs = append(runIDs, dbRun.ID)
+		runs = append(runs, toApplyRun(dbRun))
+	}
+
+	if (!filter.IncludeEntries && !filter.HasEntryFilter()) || len(runIDs) == 0 {
+		return runs, nil
+	}
+
+	var dbEntries []ApplyRunEntry
+	err := s.applyRunEntriesQuery(clusterID, filter).
+		Where("run_id IN ?", runIDs).
+		Order("id ASC").
+		Find(&dbEntries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query apply run entries: %w", err)
+	}
+	for _, dbEntry := range dbEntries {
+		i := runIndex[dbEntry.RunID]
+		runs[i].Entries = append(runs[i].Entries, toApplyRunEntry(dbEntry))
+	}
+
+	return runs, nil
+}
+
+func (s *GormDB) GetApplyRun(clusterID, runID string) (*types.ApplyRun, error) {
+	var dbRun ApplyRun
+	err := s.db.Where(&ApplyRun{ClusterID: clusterID, ID: runID}).First(&dbRun).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("apply run %s not found for cluster %s: %w", runID, clusterID, err)
+		}
+		return nil, fmt.Errorf("failed to query apply run: %w", err)
+	}
+
+	var dbEntries []ApplyRunEntry
+	if err := s.db.Where(&ApplyRunEntry{RunID: runID}).Order("id ASC").Find(&dbEntries).Error; err != nil {
+		return nil, fmt.Errorf("failed to query apply run entries: %w", err)
+	}
+
+	run := toApplyRun(dbRun)
+	run.Entries = make([]types.ApplyRunEntry, 0, len(dbEntries))
+	for _, dbEntry := range dbEntries {
+		run.Entries = append(run.Entries, toApplyRunEntry(dbEntry))
+	}
+	return &run, nil
+}
+
+func (s *GormDB) DeleteOldApplyRuns(clusterID string, olderThan time.Time) (int64, error) {
+	var rowsAffected int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		oldRuns := tx.Model(&ApplyRun{}).Select("id").Where("cluster_id = ? AND started_at < ?", clusterID, olderThan)
+		if err := tx.Where("run_id IN (?)", oldRuns).Delete(&ApplyRunEntry{}).Error; err != nil {
+			return fmt.Errorf("failed to delete apply run entries: %w", err)
+		}
+		result := tx.Where("cluster_id = ? AND started_at < ?", clusterID, olderThan).Delete(&ApplyRun{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete apply runs: %w", result.Error)
+		}
+		rowsAffected = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old apply runs: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+func (s *GormDB) InsertStatHistory(clusterID string, entries []types.StatHistoryEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	dbEntries := make([]StatHistory, 0, len(entries))
+	for _, entry := range entries {
+		statsJSON, err := json.Marshal(entry.Stat)
+		if err != nil {
+			return fmt.Errorf("failed to marshal stats of workload %s: %w", entry.WorkloadID, err)
+		}
+		dbEntries = append(dbEntries, StatHistory{
+			ClusterID:   clusterID,
+			WorkloadID:  entry.WorkloadID,
+			GeneratedAt: entry.GeneratedAt,
+			Stats:       string(statsJSON),
+		})
+	}
+
+	if err := s.db.CreateInBatches(&dbEntries, 500).Error; err != nil {
+		return fmt.Errorf("failed to insert stats history: %w", err)
+	}
+	return nil
+}
+
+func (s *GormDB) GetStatHistoryForWorkload(clusterID, workloadID string, since, until time.Time) ([]types.StatHistoryEntry, error) {
+	var dbEntries []StatHistory
+	err := s.db.Where("cluster_id = ? AND workload_id = ? AND generated_at >= ? AND generated_at < ?", clusterID, workloadID, since, until).
+		Order("generated_at ASC").
+		Find(&dbEntries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stats history: %w", err)
+	}
+
+	entries := make([]types.StatHistoryEntry, 0, len(dbEntries))
+	for _, dbEntry := range dbEntries {
+		var stat types.WorkloadStat
+		if err := json.Unmarshal([]byte(dbEntry.Stats), &stat); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal stats history entry %d: %w", dbEntry.ID, err)
+		}
+		entries = append(entries, types.StatHistoryEntry{
+			WorkloadID:  dbEntry.WorkloadID,
+			GeneratedAt: dbEntry.GeneratedAt,
+			Stat:        stat,
+		})
+	}
+	return entries, nil
+}
+
+// DownsampleStatHistory keeps only the latest stat of every workload per interval for the stats
+// generated between from and to. Both are truncated to the interval so only whole intervals are
+// downsampled.
+func (s *GormDB) DownsampleStatHistory(clusterID string, from, to time.Time, interval time.Duration) (int64, error) {
+	if interval <= 0 {
+		return 0, fmt.Errorf("invalid downsampling interval %s", interval)
+	}
+
+	var dbEntries []StatHistory
+	err := s.db.Select("id", "workload_id", "generated_at").
+		Where("cluster_id = ? AND generated_at >= ? AND generated_at < ?", clusterID, from.Truncate(interval), to.Truncate(interval)).
+		Order("workload_id ASC, generated_at DESC").
+		Find(&dbEntries).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to query stats history: %w", err)
+	}
+
+	type bucketKey struct {
+		workloadID string
+		bucket     time.Time
+	}
+	kept := make(map[bucketKey]bool)
+	var staleIDs []uint
+	for _, dbEntry := range dbEntries {
+		key := bucketKey{workloadID: dbEntry.WorkloadID, bucket: dbEntry.GeneratedAt.Truncate(interval)}
+		if kept[key] {
+			staleIDs = append(staleIDs, dbEntry.ID)
+			continue
+		}
+		kept[key] = true
+	}
+
+	var rowsAffected int64
+	for start := 0; start < len(staleIDs); start += 500 {
+		batch := staleIDs[start:min(start+500, len(staleIDs))]
+		result := s.db.Where("id IN ?", batch).Delete(&StatHistory{})
+		if result.Error != nil {
+			return rowsAffected, fmt.Errorf("failed to delete downsampled stats history: %w", result.Error)
+		}
+		rowsAffected += result.RowsAffected
+	}
+	return rowsAffected, nil
+}
+
+func (s *GormDB) DeleteOldStatHistory(clusterID string, olderThan time.Time) (int64, error) {
+	result := s.db.Where("cluster_id = ? AND generated_at < ?", clusterID, olderThan).Delete(&StatHistory{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete old stats history: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
+
+func (s *GormDB) GetRecommendationStates(clusterID string) ([]types.RecommendationState, error) {
+	var dbStates []RecommendationState
+	if err := s.db.Where(&RecommendationState{ClusterID: clusterID}).Find(&dbStates).Error; err != nil {
+		return nil, fmt.Errorf("failed to query recommendation states: %w", err)
+	}
+
+	states := make([]types.RecommendationState, 0, len(dbStates))
+	for _, dbState := range dbStates {
+		states = append(states, types.RecommendationState{
+			WorkloadID:    dbState.WorkloadID,
+			ContainerName: dbState.ContainerName,
+			Resource:      types.ApplyRunResource(dbState.Resource),
+			SmoothedValue: dbState.SmoothedValue,
+			LastResizedAt: dbState.LastResizedAt,
+			UpdatedAt:     dbState.UpdatedAt,
+		})
+	}
+	return states, nil
+}
+
+func (s *GormDB) UpsertRecommendationStates(clusterID string, states []types.RecommendationState) error {
+	if len(states) == 0 {
+		return nil
+	}
+
+	dbStates := make([]RecommendationState, 0, len(states))
+	for _, state := range states {
+		dbStates = append(dbStates, RecommendationState{
+			ClusterID:     clusterID,
+			WorkloadID:    state.WorkloadID,
+			ContainerName: state.ContainerName,
+			Resource:      string(state.Resource),
+			SmoothedValue: state.SmoothedValue,
+			LastResizedAt: state.LastResizedAt,
+			UpdatedAt:     state.UpdatedAt,
+		})
+	}
+
+	result := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "cluster_id"}, {Name: "workload_id"}, {Name: "container_name"}, {Name: "resource"}},
+		DoUpdates: clause.AssignmentColumns([]string{"smoothed_value", "last_resized_at", "updated_at"}),
+	}).CreateInBatches(&dbStates, 500)
+	if result.Error != nil {
+		return fmt.Errorf("failed to upsert recommendation states: %w", result.Error)
+	}
+	return nil
+}
+
+func (s *GormDB) DeleteOldRecommendationStates(clusterID string, olderThan time.Time) (int64, error) {
+	result := s.db.Where("cluster_id = ? AND updated_at < ?", clusterID, olderThan).Delete(&RecommendationState{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete old recommendation states: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
+
+func (s *GormDB) UpsertUsageHistograms(clusterID string, histograms []types.UsageHistogram) error {
+	if len(histograms) == 0 {
+		return nil
+	}
+
+	dbHistograms := make([]UsageHistogram, 0, len(histograms))
+	for _, histogram := range histograms {
+		bucketsJSON, err := json.Marshal(histogram.Buckets)
+		if err != nil {
+			return fmt.Errorf("failed to marshal usage histogram buckets: %w", err)
+		}
+		dbHistograms = append(dbHistograms, UsageHistogram{
+			ClusterID:         clusterID,
+			Key:               histogram.Key,
+			Namespace:         histogram.Namespace,
+			Resource:          string(histogram.Resource),
+			ResolutionSeconds: int64(histogram.Resolution.Seconds()),
+			Start:             histogram.Start,
+			Buckets:           string(bucketsJSON),
+			Count:             histogram.Count,
+			Max:               histogram.Max,
+			LastSampleAt:      histogram.LastSampleAt,
+		})
+	}
+
+	result := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "cluster_id"}, {Name: "key"}, {Name: "resource"}, {Name: "resolution_seconds"}, {Name: "start"}},
+		DoUpdates: clause.AssignmentColumns([]string{"namespace", "buckets", "count", "max", "last_sample_at", "updated_at"}),
+	}).CreateInBatches(&dbHistograms, 500)
+	if result.Error != nil {
+		return fmt.Errorf("failed to upsert usage histograms: %w", result.Error)
+	}
+	return nil
+}
+
+func (s *GormDB) GetUsageHistograms(clusterID, namespace string, resolution time.Duration, since time.Time) ([]types.UsageHistogram, error) {
+	var dbHistograms []UsageHistogram
+	err := s.db.Where("cluster_id = ? AND namespace = ? AND resolution_seconds = ? AND start >= ?",
+		clusterID, namespace, int64(resolution.Seconds()), since).
+		Order("start ASC").
+		Find(&dbHistograms).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage histograms: %w", err)
+	}
+
+	histograms := make([]types.UsageHistogram, 0, len(dbHistograms))
+	for _, dbHistogram := range dbHistograms {
+		var buckets map[int]float64
+		if err := json.Unmarshal([]byte(dbHistogram.Buckets), &buckets); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal usage histogram buckets: %w", err)
+		}
+		histograms = append(histograms, types.UsageHistogram{
+			Key:          dbHistogram.Key,
+			Namespace:    dbHistogram.Namespace,
+			Resource:     types.UsageResource(dbHistogram.Resource),
+			Resolution:   time.Duration(dbHistogram.ResolutionSeconds) * time.Second,
+			Start:        dbHistogram.Start,
+			Buckets:      buckets,
+			Count:        dbHistogram.Count,
+			Max:          dbHistogram.Max,
+			LastSampleAt: dbHistogram.LastSampleAt,
+		})
+	}
+	return histograms, nil
+}
+
+func (s *GormDB) DeleteOldUsageHistograms(clusterID string, resolution time.Duration, olderThan time.Time) (int64, error) {
+	result := s.db.Where("cluster_id = ? AND resolution_seconds = ? AND start < ?", clusterID, int64(resolution.Seconds()), olderThan).
+		Delete(&UsageHistogram{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete old usage histograms: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
+
+func (s *GormDB) applyRunEntriesQuery(clusterID string, filter types.ApplyRunFilter) *gorm.DB {
+	query := s.db.Model(&ApplyRunEntry{}).Where("cluster_id = ?", clusterID)
+	if filter.Namespace != "" {
+		query = query.Where("namespace = ?", filter.Namespace)
+	}
+	if filter.PodName != "" {
+		query = query.Where("pod_name = ?", filter.PodName)
+	}
+	if filter.WorkloadID != "" {
+		query = query.Where("workload_id = ?", filter.WorkloadID)
+	}
+	return query
+}
+
+func toApplyRun(dbRun ApplyRun) types.ApplyRun {
+	return types.ApplyRun{
+		ID:             dbRun.ID,
+		ClusterID:      dbRun.ClusterID,
+		Strategy:       dbRun.Strategy,
+		DryRun:         dbRun.DryRun,
+		StartedAt:      dbRun.StartedAt,
+		FinishedAt:     dbRun.FinishedAt,
+		UnchangedCount: dbRun.UnchangedCount,
+	}
+}
+
+func toApplyRunEntry(dbEntry ApplyRunEntry) types.ApplyRunEntry {
+	return types.ApplyRunEntry{
+		Namespace:     dbEntry.Namespace,
+		PodName:       dbEntry.PodName,
+		NodeName:      dbEntry.NodeName,
+		WorkloadID:    dbEntry.WorkloadID,
+		ContainerName: dbEntry.ContainerName,
+		Resource:      types.ApplyRunResource(dbEntry.Resource),
+		Action:        types.ApplyRunAction(dbEntry.Action),
+		OldRequest:    dbEntry.OldRequest,
+		NewRequest:    dbEntry.NewRequest,
+		OldLimit:      dbEntry.OldLimit,
+		NewLimit:      dbEntry.NewLimit,
+		Reason:        dbEntry.Reason,
+	}
+}
@@ -81,6 +81,85 @@ func testStorage(t *testing.T, storage ports.Database) {
 		t.Errorf("Expected kind Deployment, got %s", retrievedStat.Kind)
 	}
 
+	// Test UpsertStat with an unchanged stat
+	regenerated := stat
+	regenerated.UpdatedAt = time.Now().Add(time.Minute)
+	regeneratedAt := time.Now().Add(time.Minute)
+	if err := storage.UpsertStat(clusterID, workloadID, regenerated, regeneratedAt); err != nil {
+		t.Fatalf("Failed to upsert unchanged stat: %v", err)
+	}
+	unchangedStat, err := storage.GetStatForWorkload(clusterID, workloadID)
+	if err != nil {
+		t.Fatalf("Failed to get stat: %v", err)
+	}
+	if !unchangedStat.UpdatedAt.Equal(retrievedStat.UpdatedAt) {
+		t.Errorf("Expected an unchanged stat to keep updated_at %v, got %v", retrievedStat.UpdatedAt, unchangedStat.UpdatedAt)
+	}
+	if !unchangedStat.GeneratedAt.Equal(regeneratedAt) {
+		t.Errorf("Expected generated_at %v, got %v", regeneratedAt, unchangedStat.GeneratedAt)
+	}
+
+	// Test UpsertStat with a changed stat
+	time.Sleep(10 * time.Millisecond)
+	changed := stat
+	changed.Replicas = 2
+	if err := storage.UpsertStat(clusterID, workloadID, changed, time.Now()); err != nil {
+		t.Fatalf("Failed to upsert changed stat: %v", err)
+	}
+	changedStat, err := storage.GetStatForWorkload(clusterID, workloadID)
+	if err != nil {
+		t.Fatalf("Failed to get stat: %v", err)
+	}
+	if changedStat.Replicas != 2 || !changedStat.UpdatedAt.After(retrievedStat.UpdatedAt) {
+		t.Errorf("Expected a changed stat with a later updated_at than %v, got %+v", retrievedStat.UpdatedAt, changedStat)
+	}
+
+	// Test GetFilteredStatsForCluster
+	filtered, err := storage.GetFilteredStatsForCluster(clusterID, types.StatsFilter{Namespace: "default", Since: time.Now().Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("Failed to get filtered stats: %v", err)
+	}
+	if len(filtered) != 1 {
+		t.Errorf("Expected 1 stat in the default namespace, got %d", len(filtered))
+	}
+	filtered, err = storage.GetFilteredStatsForCluster(clusterID, types.StatsFilter{Since: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("Failed to get filtered stats: %v", err)
+	}
+	if len(filtered) != 0 {
+		t.Errorf("Expected no stat updated in the future, got %d", len(filtered))
+	}
+
+	// Test GetStatOverridesForCluster
+	disabled := false
+	if err := storage.UpdateStatOverridesForWorkload(clusterID, workloadID, &types.Overrides{Enabled: &disabled}); err != nil {
+		t.Fatalf("Failed to update overrides: %v", err)
+	}
+	clusterOverrides, err := storage.GetStatOverridesForCluster(clusterID)
+	if err != nil {
+		t.Fatalf("Failed to get cluster overrides: %v", err)
+	}
+	if len(clusterOverrides) != 1 || clusterOverrides[workloadID] == nil || clusterOverrides[workloadID].Enabled == nil || *clusterOverrides[workloadID].Enabled {
+		t.Errorf("Expected the disabled overrides of %s, got %+v", workloadID, clusterOverrides)
+	}
+
+	// Test UpsertNamespaceOverrides
+	strategy := "ClusterBinPacking"
+	if err := storage.UpsertNamespaceOverrides(clusterID, "default", &types.Overrides{Strategy: &strategy}); err != nil {
+		t.Fatalf("Failed to upsert namespace overrides: %v", err)
+	}
+	strategy = "AdjustAmongstPodsDistributed"
+	if err := storage.UpsertNamespaceOverrides(clusterID, "default", &types.Overrides{Strategy: &strategy}); err != nil {
+		t.Fatalf("Failed to update namespace overrides: %v", err)
+	}
+	namespaceOverrides, err := storage.GetNamespaceOverridesForCluster(clusterID)
+	if err != nil {
+		t.Fatalf("Failed to get namespace overrides: %v", err)
+	}
+	if len(namespaceOverrides) != 1 || namespaceOverrides["default"] == nil || namespaceOverrides["default"].Strategy == nil || *namespaceOverrides["default"].Strategy != strategy {
+		t.Errorf("Expected the %s strategy for the default namespace, got %+v", strategy, namespaceOverrides)
+	}
+
 	// Test DeleteStatForWorkload
 	err = storage.DeleteStatForWorkload(clusterID, workloadID)
 	if err != nil {
@@ -95,4 +174,191 @@ func testStorage(t *testing.T, storage ports.Database) {
 	if exists {
 		t.Error("Expected stat to not exist after deletion")
 	}
+
+	// Test GetStatDeletionsForCluster
+	removed, err := storage.GetStatDeletionsForCluster(clusterID, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to get stat deletions: %v", err)
+	}
+	if len(removed) != 1 || removed[0] != workloadID {
+		t.Errorf("Expected %s to be removed, got %v", workloadID, removed)
+	}
+	if err := storage.UpsertStat(clusterID, workloadID, stat, time.Now()); err != nil {
+		t.Fatalf("Failed to upsert stat again: %v", err)
+	}
+	removed, err = storage.GetStatDeletionsForCluster(clusterID, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to get stat deletions: %v", err)
+	}
+	if len(removed) != 0 {
+		t.Errorf("Expected a recreated stat to not be removed, got %v", removed)
+	}
+	if err := storage.DeleteStatsForCluster(clusterID); err != nil {
+		t.Fatalf("Failed to delete cluster stats: %v", err)
+	}
+	deletedCount, err := storage.DeleteOldStatDeletions(clusterID, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to delete old stat deletions: %v", err)
+	}
+	if deletedCount != 1 {
+		t.Errorf("Expected 1 deleted stat deletion, got %d", deletedCount)
+	}
+
+	// Test InsertApplyRun
+	run := &types.ApplyRun{
+		ID:             "test-run",
+		ClusterID:      clusterID,
+		Strategy:       "AdjustAmongstPodsDistributed",
+		DryRun:         true,
+		StartedAt:      time.Now(),
+		FinishedAt:     time.Now(),
+		UnchangedCount: 3,
+		Entries: []types.ApplyRunEntry{
+			{Namespace: "default", PodName: "test-app-1", ContainerName: "app", Resource: types.ApplyRunResourceCPU, Action: types.ApplyRunActionResized, OldRequest: 1, NewRequest: 0.5},
+			{Namespace: "default", PodName: "test-app-2", Resource: types.ApplyRunResourcePod, Action: types.ApplyRunActionEvicted},
+		},
+	}
+	if err := storage.InsertApplyRun(run); err != nil {
+		t.Fatalf("Failed to insert apply run: %v", err)
+	}
+
+	// Test GetApplyRuns with a pod filter
+	runs, err := storage.GetApplyRuns(clusterID, types.ApplyRunFilter{Since: time.Now().Add(-time.Hour), PodName: "test-app-2"})
+	if err != nil {
+		t.Fatalf("Failed to get apply runs: %v", err)
+	}
+	if len(runs) != 1 || len(runs[0].Entries) != 1 || runs[0].Entries[0].Action != types.ApplyRunActionEvicted {
+		t.Errorf("Expected one run with the eviction entry, got %+v", runs)
+	}
+	if len(runs) == 1 && runs[0].UnchangedCount != 3 {
+		t.Errorf("Expected 3 unchanged, got %d", runs[0].UnchangedCount)
+	}
+
+	// Test GetApplyRun
+	retrievedRun, err := storage.GetApplyRun(clusterID, run.ID)
+	if err != nil {
+		t.Fatalf("Failed to get apply run: %v", err)
+	}
+	if len(retrievedRun.Entries) != 2 {
+		t.Errorf("Expected 2 entries, got %d", len(retrievedRun.Entries))
+	}
+
+	// Test DeleteOldApplyRuns
+	deleted, err := storage.DeleteOldApplyRuns(clusterID, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to delete old apply runs: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected 1 deleted apply run, got %d", deleted)
+	}
+
+	// Test InsertStatHistory
+	start := time.Now().Add(-3 * time.Hour).Truncate(time.Hour)
+	history := []types.StatHistoryEntry{
+		{WorkloadID: workloadID, GeneratedAt: start, Stat: stat},
+		{WorkloadID: workloadID, GeneratedAt: start.Add(15 * time.Minute), Stat: stat},
+		{WorkloadID: workloadID, GeneratedAt: start.Add(30 * time.Minute), Stat: stat},
+		{WorkloadID: workloadID, GeneratedAt: start.Add(90 * time.Minute), Stat: stat},
+	}
+	if err := storage.InsertStatHistory(clusterID, history); err != nil {
+		t.Fatalf("Failed to insert stats history: %v", err)
+	}
+
+	// Test DownsampleStatHistory
+	downsampled, err := storage.DownsampleStatHistory(clusterID, start, start.Add(2*time.Hour), time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to downsample stats history: %v", err)
+	}
+	if downsampled != 2 {
+		t.Errorf("Expected 2 downsampled entries, got %d", downsampled)
+	}
+
+	// Test GetStatHistoryForWorkload
+	retrievedHistory, err := storage.GetStatHistoryForWorkload(clusterID, workloadID, start, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to get stats history: %v", err)
+	}
+	if len(retrievedHistory) != 2 || !retrievedHistory[0].GeneratedAt.Equal(start.Add(30*time.Minute)) {
+		t.Errorf("Expected the latest entry of every hour, got %+v", retrievedHistory)
+	}
+
+	// Test DeleteOldStatHistory
+	deleted, err = storage.DeleteOldStatHistory(clusterID, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to delete old stats history: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("Expected 2 deleted stats history entries, got %d", deleted)
+	}
+
+	// Test UpsertRecommendationStates
+	state := types.RecommendationState{WorkloadID: workloadID, ContainerName: "app", Resource: types.ApplyRunResourceCPU, SmoothedValue: 0.5, UpdatedAt: time.Now()}
+	if err := storage.UpsertRecommendationStates(clusterID, []types.RecommendationState{state}); err != nil {
+		t.Fatalf("Failed to insert recommendation state: %v", err)
+	}
+	state.SmoothedValue = 0.75
+	state.LastResizedAt = time.Now()
+	if err := storage.UpsertRecommendationStates(clusterID, []types.RecommendationState{state}); err != nil {
+		t.Fatalf("Failed to update recommendation state: %v", err)
+	}
+
+	// Test GetRecommendationStates
+	states, err := storage.GetRecommendationStates(clusterID)
+	if err != nil {
+		t.Fatalf("Failed to get recommendation states: %v", err)
+	}
+	if len(states) != 1 || states[0].SmoothedValue != 0.75 || states[0].LastResizedAt.IsZero() {
+		t.Errorf("Expected the updated recommendation state, got %+v", states)
+	}
+
+	// Test DeleteOldRecommendationStates
+	deleted, err = storage.DeleteOldRecommendationStates(clusterID, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to delete old recommendation states: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected 1 deleted recommendation state, got %d", deleted)
+	}
+
+	// Test UpsertUsageHistograms
+	slotStart := time.Now().Truncate(time.Hour)
+	histogram := types.UsageHistogram{
+		Key:        "Deployment:default:app:app",
+		Namespace:  "default",
+		Resource:   types.UsageResourceCPU,
+		Resolution: time.Hour,
+		Start:      slotStart,
+		Buckets:    map[int]float64{10: 1},
+		Count:      1,
+		Max:        0.01,
+	}
+	if err := storage.UpsertUsageHistograms(clusterID, []types.UsageHistogram{histogram}); err != nil {
+		t.Fatalf("Failed to insert usage histogram: %v", err)
+	}
+	histogram.Buckets = map[int]float64{10: 1, 20: 1}
+	histogram.Count = 2
+	histogram.Max = 0.02
+	histogram.LastSampleAt = slotStart.Add(30 * time.Minute)
+	if err := storage.UpsertUsageHistograms(clusterID, []types.UsageHistogram{histogram}); err != nil {
+		t.Fatalf("Failed to update usage histogram: %v", err)
+	}
+
+	// Test GetUsageHistograms
+	histograms, err := storage.GetUsageHistograms(clusterID, "default", time.Hour, slotStart)
+	if err != nil {
+		t.Fatalf("Failed to get usage histograms: %v", err)
+	}
+	if len(histograms) != 1 || histograms[0].Count != 2 || histograms[0].Buckets[20] != 1 || histograms[0].Resolution != time.Hour ||
+		!histograms[0].LastSampleAt.Equal(histogram.LastSampleAt) {
+		t.Errorf("Expected the updated usage histogram, got %+v", histograms)
+	}
+
+	// Test DeleteOldUsageHistograms
+	deleted, err = storage.DeleteOldUsageHistograms(clusterID, time.Hour, slotStart.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to delete old usage histograms: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected 1 deleted usage histogram, got %d", deleted)
+	}
 }
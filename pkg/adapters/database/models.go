@@ -19,6 +19,32 @@ func (Stats) TableName() string {
 	return "stats"
 }
 
+// StatDeletion records a deleted stat, so clients fetching the stats changed since a time drop it too.
+type StatDeletion struct {
+	ID         uint      `gorm:"column:id;primaryKey;autoIncrement"`
+	ClusterID  string    `gorm:"column:cluster_id;index"`
+	WorkloadID string    `gorm:"column:workload_id;index"`
+	RemovedAt  time.Time `gorm:"column:removed_at;index"`
+}
+
+func (StatDeletion) TableName() string {
+	return "stat_deletions"
+}
+
+// NamespaceOverrides are the overrides applying to every workload of a namespace.
+type NamespaceOverrides struct {
+	ID        uint      `gorm:"column:id;primaryKey;autoIncrement"`
+	ClusterID string    `gorm:"column:cluster_id;uniqueIndex:idx_namespace_overrides_unique"`
+	Namespace string    `gorm:"column:namespace;uniqueIndex:idx_namespace_overrides_unique"`
+	Overrides string    `gorm:"column:overrides;default:'{}'"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (NamespaceOverrides) TableName() string {
+	return "namespace_overrides"
+}
+
 type OOMEvent struct {
 	ID                 uint      `gorm:"column:id;primaryKey;autoIncrement"`
 	ClusterID          string    `gorm:"column:cluster_id;index;uniqueIndex:idx_oom_unique"`
@@ -37,3 +63,89 @@ type OOMEvent struct {
 func (OOMEvent) TableName() string {
 	return "oom_events"
 }
+
+type ApplyRun struct {
+	ID             string    `gorm:"column:id;primaryKey"`
+	ClusterID      string    `gorm:"column:cluster_id;index"`
+	Strategy       string    `gorm:"column:strategy"`
+	DryRun         bool      `gorm:"column:dry_run"`
+	StartedAt      time.Time `gorm:"column:started_at;index"`
+	FinishedAt     time.Time `gorm:"column:finished_at"`
+	UnchangedCount int       `gorm:"column:unchanged_count"`
+	CreatedAt      time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+func (ApplyRun) TableName() string {
+	return "apply_runs"
+}
+
+type ApplyRunEntry struct {
+	ID            uint      `gorm:"column:id;primaryKey;autoIncrement"`
+	RunID         string    `gorm:"column:run_id;index"`
+	ClusterID     string    `gorm:"column:cluster_id;index"`
+	Namespace     string    `gorm:"column:namespace;index"`
+	PodName       string    `gorm:"column:pod_name;index"`
+	NodeName      string    `gorm:"column:node_name"`
+	WorkloadID    string    `gorm:"column:workload_id;index"`
+	ContainerName string    `gorm:"column:container_name"`
+	Resource      string    `gorm:"column:resource"`
+	Action        string    `gorm:"column:action"`
+	OldRequest    float64   `gorm:"column:old_request"`
+	NewRequest    float64   `gorm:"column:new_request"`
+	OldLimit      float64   `gorm:"column:old_limit"`
+	NewLimit      float64   `gorm:"column:new_limit"`
+	Reason        string    `gorm:"column:reason"`
+	CreatedAt     time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+func (ApplyRunEntry) TableName() string {
+	return "apply_run_entries"
+}
+
+// StatHistory keeps every stat written by the stats runs, unlike Stats which only keeps the latest.
+type StatHistory struct {
+	ID          uint      `gorm:"column:id;primaryKey;autoIncrement"`
+	ClusterID   string    `gorm:"column:cluster_id;index:idx_stats_history_workload"`
+	WorkloadID  string    `gorm:"column:workload_id;index:idx_stats_history_workload"`
+	GeneratedAt time.Time `gorm:"column:generated_at;index:idx_stats_history_workload;index"`
+	Stats       string    `gorm:"column:stats"`
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+func (StatHistory) TableName() string {
+	return "stats_history"
+}
+
+type RecommendationState struct {
+	ID            uint      `gorm:"column:id;primaryKey;autoIncrement"`
+	ClusterID     string    `gorm:"column:cluster_id;uniqueIndex:idx_recommendation_state_unique"`
+	WorkloadID    string    `gorm:"column:workload_id;uniqueIndex:idx_recommendation_state_unique"`
+	ContainerName string    `gorm:"column:container_name;uniqueIndex:idx_recommendation_state_unique"`
+	Resource      string    `gorm:"column:resource;uniqueIndex:idx_recommendation_state_unique"`
+	SmoothedValue float64   `gorm:"column:smoothed_value"`
+	LastResizedAt time.Time `gorm:"column:last_resized_at"`
+	UpdatedAt     time.Time `gorm:"column:updated_at;index"`
+}
+
+func (RecommendationState) TableName() string {
+	return "recommendation_states"
+}
+
+type UsageHistogram struct {
+	ID                uint      `gorm:"column:id;primaryKey;autoIncrement"`
+	ClusterID         string    `gorm:"column:cluster_id;uniqueIndex:idx_usage_histogram_unique"`
+	Key               string    `gorm:"column:key;uniqueIndex:idx_usage_histogram_unique"`
+	Namespace         string    `gorm:"column:namespace;index"`
+	Resource          string    `gorm:"column:resource;uniqueIndex:idx_usage_histogram_unique"`
+	ResolutionSeconds int64     `gorm:"column:resolution_seconds;uniqueIndex:idx_usage_histogram_unique"`
+	Start             time.Time `gorm:"column:start;uniqueIndex:idx_usage_histogram_unique;index"`
+	Buckets           string    `gorm:"column:buckets"`
+	Count             float64   `gorm:"column:count"`
+	Max               float64   `gorm:"column:max"`
+	LastSampleAt      time.Time `gorm:"column:last_sample_at"`
+	UpdatedAt         time.Time `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (UsageHistogram) TableName() string {
+	return "usage_histograms"
+}
@@ -0,0 +1,185 @@
+package kubelet
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metricsprovider "github.com/truefoundry/cruisekube/pkg/adapters/metricsProvider"
+	"github.com/truefoundry/cruisekube/pkg/adapters/metricsProvider/sketch"
+	"github.com/truefoundry/cruisekube/pkg/logging"
+	"github.com/truefoundry/cruisekube/pkg/repository/storage"
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+	"github.com/truefoundry/cruisekube/pkg/types"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	SourceSummary       = "summary"
+	SourceMetricsServer = "metricsServer"
+)
+
+// The short histograms cover the cpu and memory windows of the stats, the long ones the 7 day windows,
+// the replicas, the ephemeral storage and the two weeks of usage series of the predictions.
+const (
+	shortResolution = 5 * time.Minute
+	shortRetention  = time.Hour
+	longResolution  = time.Hour
+	longRetention   = 15 * 24 * time.Hour
+)
+
+var _ metricsprovider.MetricsProvider = (*KubeletProvider)(nil)
+
+// KubeletProvider samples the usage of the containers from the kubelets or metrics-server and keeps it
+// in rolling histograms in the database, for clusters without prometheus.
+type KubeletProvider struct {
+	kubeClient *kubernetes.Clientset
+	storage    *storage.Storage
+	source     string
+}
+
+func NewKubeletProvider(kubeClient *kubernetes.Clientset, storage *storage.Storage, source string) (*KubeletProvider, error) {
+	if source == "" {
+		source = SourceSummary
+	}
+	if source != SourceSummary && source != SourceMetricsServer {
+		return nil, fmt.Errorf("invalid kubelet metrics source %q (expected %s|%s)", source, SourceSummary, SourceMetricsServer)
+	}
+	return &KubeletProvider{
+		kubeClient: kubeClient,
+		storage:    storage,
+		source:     source,
+	}, nil
+}
+
+type histogramKey struct {
+	Key      string
+	Resource types.UsageResource
+}
+
+// Sample reads the current usage of the running containers and adds it to the histograms of the current
+// slots. Like the prometheus queries, the usage of a workload container is the max over its pods.
+func (p *KubeletProvider) Sample(ctx context.Context, clusterID, targetNamespace string) error {
+	now := time.Now()
+
+	podList, err := p.kubeClient.CoreV1().Pods(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list pods: %w", err)
+	}
+	pods := make([]*corev1.Pod, 0, len(podList.Items))
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.Spec.NodeName != "" && pod.Status.Phase == corev1.PodRunning {
+			pods = append(pods, pod)
+		}
+	}
+
+	var usages map[utils.ContainerKey]containerUsage
+	if p.source == SourceMetricsServer {
+		usages, err = p.readMetricsServerUsage(ctx, targetNamespace)
+		if err != nil {
+			return err
+		}
+	} else {
+		usages = p.readSummaryUsage(ctx, pods)
+	}
+
+	samples := make(map[string]map[histogramKey]float64)
+	for _, pod := range pods {
+		workloadInfo := utils.GetWorkloadInfoFromPod(pod)
+		if workloadInfo == nil {
+			continue
+		}
+		namespaceSamples, ok := samples[pod.Namespace]
+		if !ok {
+			namespaceSamples = make(map[histogramKey]float64)
+			samples[pod.Namespace] = namespaceSamples
+		}
+		workloadKey := utils.GetWorkloadKey(workloadInfo.Kind, workloadInfo.Namespace, workloadInfo.Name)
+		namespaceSamples[histogramKey{Key: workloadKey, Resource: types.UsageResourceReplicas}]++
+
+		for _, container := range pod.Spec.Containers {
+			usage, ok := usages[utils.ContainerKey{Namespace: pod.Namespace, PodName: pod.Name, ContainerName: container.Name}]
+			if !ok {
+				continue
+			}
+			containerKey := utils.GetWorkloadContainerKey(workloadInfo.Kind, workloadInfo.Namespace, workloadInfo.Name, container.Name)
+			if usage.HasCPU {
+				key := histogramKey{Key: containerKey, Resource: types.UsageResourceCPU}
+				namespaceSamples[key] = max(namespaceSamples[key], usage.CPU)
+			}
+			if usage.HasMemory {
+				key := histogramKey{Key: containerKey, Resource: types.UsageResourceMemory}
+				namespaceSamples[key] = max(namespaceSamples[key], usage.Memory/utils.BytesPerMB)
+			}
+			if usage.HasEphemeralStorage {
+				key := histogramKey{Key: containerKey, Resource: types.UsageResourceEphemeralStorage}
+				namespaceSamples[key] = max(namespaceSamples[key], usage.EphemeralStorage/utils.BytesPerMB)
+			}
+		}
+	}
+
+	for namespace, namespaceSamples := range samples {
+		if err := p.recordSamples(clusterID, namespace, namespaceSamples, now); err != nil {
+			return fmt.Errorf("failed to record samples of namespace %s: %w", namespace, err)
+		}
+	}
+	logging.Infof(ctx, "Sampled the usage of %d pods in %d namespaces from %s", len(pods), len(samples), p.source)
+
+	if _, err := p.storage.DeleteOldUsageHistograms(clusterID, shortResolution, shortRetention); err != nil {
+		return err
+	}
+	if _, err := p.storage.DeleteOldUsageHistograms(clusterID, longResolution, longRetention); err != nil {
+		return err
+	}
+	return nil
+}
+
+// recordSamples adds the samples to the histograms of the current short and long slots. Replicas and
+// ephemeral storage only have long histograms.
+func (p *KubeletProvider) recordSamples(clusterID, namespace string, samples map[histogramKey]float64, now time.Time) error {
+	for _, resolution := range []time.Duration{shortResolution, longResolution} {
+		slotStart := now.Truncate(resolution)
+		existing, err := p.storage.GetUsageHistograms(clusterID, namespace, resolution, slotStart)
+		if err != nil {
+			return err
+		}
+		histograms := make(map[histogramKey]*types.UsageHistogram, len(existing))
+		for i := range existing {
+			if existing[i].Start.Equal(slotStart) {
+				histograms[histogramKey{Key: existing[i].Key, Resource: existing[i].Resource}] = &existing[i]
+			}
+		}
+
+		updated := make([]types.UsageHistogram, 0, len(samples))
+		for key, value := range samples {
+			if (key.Resource == types.UsageResourceReplicas || key.Resource == types.UsageResourceEphemeralStorage) && resolution != longResolution {
+				continue
+			}
+			histogram, ok := histograms[key]
+			if !ok {
+				histogram = &types.UsageHistogram{
+					Key:        key.Key,
+					Namespace:  namespace,
+					Resource:   key.Resource,
+					Resolution: resolution,
+					Start:      slotStart,
+				}
+			}
+			sketch.Add(histogram, value)
+			histogram.LastSampleAt = now
+			updated = append(updated, *histogram)
+		}
+		if err := p.storage.UpsertUsageHistograms(clusterID, updated); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *KubeletProvider) IsPSIEnabled(_ context.Context, _ string) bool {
+	return false
+}
@@ -0,0 +1,155 @@
+package kubelet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/truefoundry/cruisekube/pkg/logging"
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// containerUsage is a usage sample of a container, cpu in cores, memory and ephemeral storage in bytes.
+// metrics-server has no ephemeral storage usage.
+type containerUsage struct {
+	CPU                 float64
+	Memory              float64
+	EphemeralStorage    float64
+	HasCPU              bool
+	HasMemory           bool
+	HasEphemeralStorage bool
+}
+
+type fsStats struct {
+	UsedBytes *uint64 `json:"usedBytes"`
+}
+
+// statsSummary is the part of the kubelet /stats/summary response the samples are read from.
+type statsSummary struct {
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		Containers []struct {
+			Name string `json:"name"`
+			CPU  *struct {
+				UsageNanoCores *uint64 `json:"usageNanoCores"`
+			} `json:"cpu"`
+			Memory *struct {
+				WorkingSetBytes *uint64 `json:"workingSetBytes"`
+			} `json:"memory"`
+			Rootfs *fsStats `json:"rootfs"`
+			Logs   *fsStats `json:"logs"`
+		} `json:"containers"`
+	} `json:"pods"`
+}
+
+// podMetricsList is the part of the metrics.k8s.io PodMetricsList the samples are read from.
+type podMetricsList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Containers []struct {
+			Name  string            `json:"name"`
+			Usage map[string]string `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+// readSummaryUsage reads the usage of the containers from the /stats/summary endpoint of the kubelet of
+// every node the pods run on, through the api server node proxy.
+func (p *KubeletProvider) readSummaryUsage(ctx context.Context, pods []*corev1.Pod) map[utils.ContainerKey]containerUsage {
+	nodes := make(map[string]struct{})
+	for _, pod := range pods {
+		nodes[pod.Spec.NodeName] = struct{}{}
+	}
+
+	usages := make(map[utils.ContainerKey]containerUsage)
+	for node := range nodes {
+		raw, err := p.kubeClient.CoreV1().RESTClient().Get().
+			Resource("nodes").
+			Name(node).
+			SubResource("proxy").
+			Suffix("stats/summary").
+			Do(ctx).
+			Raw()
+		if err != nil {
+			logging.Warnf(ctx, "Failed to read stats summary of node %s: %v", node, err)
+			continue
+		}
+
+		var summary statsSummary
+		if err := json.Unmarshal(raw, &summary); err != nil {
+			logging.Warnf(ctx, "Failed to parse stats summary of node %s: %v", node, err)
+			continue
+		}
+		for _, pod := range summary.Pods {
+			for _, container := range pod.Containers {
+				usage := containerUsage{}
+				if container.CPU != nil && container.CPU.UsageNanoCores != nil {
+					usage.CPU = float64(*container.CPU.UsageNanoCores) / 1e9
+					usage.HasCPU = true
+				}
+				if container.Memory != nil && container.Memory.WorkingSetBytes != nil {
+					usage.Memory = float64(*container.Memory.WorkingSetBytes)
+					usage.HasMemory = true
+				}
+				// the kubelet counts the writable layer and the logs against the ephemeral storage of a container
+				for _, fs := range []*fsStats{container.Rootfs, container.Logs} {
+					if fs != nil && fs.UsedBytes != nil {
+						usage.EphemeralStorage += float64(*fs.UsedBytes)
+						usage.HasEphemeralStorage = true
+					}
+				}
+				key := utils.ContainerKey{Namespace: pod.PodRef.Namespace, PodName: pod.PodRef.Name, ContainerName: container.Name}
+				usages[key] = usage
+			}
+		}
+	}
+	return usages
+}
+
+// readMetricsServerUsage reads the usage of the containers from the metrics.k8s.io api of metrics-server.
+func (p *KubeletProvider) readMetricsServerUsage(ctx context.Context, targetNamespace string) (map[utils.ContainerKey]containerUsage, error) {
+	path := "/apis/metrics.k8s.io/v1beta1/pods"
+	if targetNamespace != "" {
+		path = fmt.Sprintf("/apis/metrics.k8s.io/v1beta1/namespaces/%s/pods", targetNamespace)
+	}
+	raw, err := p.kubeClient.CoreV1().RESTClient().Get().AbsPath(path).Do(ctx).Raw()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pod metrics: %w", err)
+	}
+
+	var list podMetricsList
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse pod metrics: %w", err)
+	}
+
+	usages := make(map[utils.ContainerKey]containerUsage)
+	for _, pod := range list.Items {
+		for _, container := range pod.Containers {
+			usage := containerUsage{}
+			if value, ok := container.Usage[string(corev1.ResourceCPU)]; ok {
+				if q, err := resource.ParseQuantity(value); err == nil {
+					usage.CPU = q.AsApproximateFloat64()
+					usage.HasCPU = true
+				}
+			}
+			if value, ok := container.Usage[string(corev1.ResourceMemory)]; ok {
+				if q, err := resource.ParseQuantity(value); err == nil {
+					usage.Memory = float64(q.Value())
+					usage.HasMemory = true
+				}
+			}
+			key := utils.ContainerKey{Namespace: pod.Metadata.Namespace, PodName: pod.Metadata.Name, ContainerName: container.Name}
+			usages[key] = usage
+		}
+	}
+	return usages, nil
+}
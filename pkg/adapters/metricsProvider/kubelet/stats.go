@@ -0,0 +1,152 @@
+package kubelet
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/adapters/metricsProvider/sketch"
+	"github.com/truefoundry/cruisekube/pkg/logging"
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+	"github.com/truefoundry/cruisekube/pkg/types"
+)
+
+// FetchStatsForNamespaces builds the stats from the sampled histograms. The samples do not keep the age of
+// the containers, so there is no startup profile.
+func (p *KubeletProvider) FetchStatsForNamespaces(ctx context.Context, clusterID string, namespaces []string, _ bool, _ time.Duration) (utils.NamespaceVsContainerMetrics, utils.NamespaceVsWorkloadMetrics, error) {
+	namespaceVsContainerMetrics := make(utils.NamespaceVsContainerMetrics)
+	namespaceVsWorkloadMetrics := make(utils.NamespaceVsWorkloadMetrics)
+	for _, namespace := range namespaces {
+		cache, workloadKeyVsWorkloadMetrics, err := p.fetchStatsForNamespace(ctx, clusterID, namespace)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch stats for namespace %s: %w", namespace, err)
+		}
+		namespaceVsContainerMetrics[namespace] = cache
+		namespaceVsWorkloadMetrics[namespace] = workloadKeyVsWorkloadMetrics
+	}
+	return namespaceVsContainerMetrics, namespaceVsWorkloadMetrics, nil
+}
+
+// fetchStatsForNamespace merges the histograms of the slots overlapping the lookback windows of the
+// prometheus queries and merges their percentiles into the cache like the prometheus results.
+func (p *KubeletProvider) fetchStatsForNamespace(ctx context.Context, clusterID, namespace string) (utils.WorkloadKeyVsContainerMetrics, utils.WorkloadKeyVsWorkloadMetrics, error) {
+	now := time.Now()
+	shortHistograms, err := p.storage.GetUsageHistograms(clusterID, namespace, shortResolution, now.Add(-utils.MemoryLookbackWindow).Truncate(shortResolution))
+	if err != nil {
+		return nil, nil, err
+	}
+	longHistograms, err := p.storage.GetUsageHistograms(clusterID, namespace, longResolution, now.Add(-utils.CPU7DayLookbackWindow).Truncate(longResolution))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cpuSince := now.Add(-utils.CPULookbackWindow).Truncate(shortResolution)
+	cpu := sketch.MergeByKey(shortHistograms, types.UsageResourceCPU, cpuSince)
+	memory := sketch.MergeByKey(shortHistograms, types.UsageResourceMemory, time.Time{})
+	cpu7Day := sketch.MergeByKey(longHistograms, types.UsageResourceCPU, time.Time{})
+	memory7Day := sketch.MergeByKey(longHistograms, types.UsageResourceMemory, time.Time{})
+	replicas := sketch.MergeByKey(longHistograms, types.UsageResourceReplicas, time.Time{})
+	ephemeralStorage := sketch.MergeByKey(longHistograms, types.UsageResourceEphemeralStorage, time.Time{})
+
+	cache := make(utils.WorkloadKeyVsContainerMetrics)
+	for _, q := range []struct {
+		histograms map[string]*types.UsageHistogram
+		percentile float64
+		metricType string
+		round      func(float64) float64
+	}{
+		{cpu, 0.50, "cpu_p50", utils.CeilCPU},
+		{cpu, 0.75, "cpu_p75", utils.CeilCPU},
+		{cpu, 1.0, "cpu_max", utils.CeilCPU},
+		{cpu7Day, 0.50, "cpu_p50_cpu_7day", utils.CeilCPU},
+		{cpu7Day, 0.75, "cpu_p75_cpu_7day", utils.CeilCPU},
+		{cpu7Day, 0.90, "cpu_p90_cpu_7day", utils.CeilCPU},
+		{cpu7Day, 0.99, "cpu_p99_cpu_7day", utils.CeilCPU},
+		{cpu7Day, 1.0, "cpu_max_cpu_7day", utils.CeilCPU},
+		{memory, 0.75, "memory_p75", utils.RoundMemory},
+		{memory, 1.0, "memory_max", utils.RoundMemory},
+		{memory7Day, 1.0, "memory_max_7day", utils.RoundMemory},
+		{ephemeralStorage, 0.75, "ephemeral_storage_p75", utils.RoundMemory},
+		{ephemeralStorage, 1.0, "ephemeral_storage_max", utils.RoundMemory},
+	} {
+		rawResults := make(utils.RawBatchResult, len(q.histograms))
+		for key, histogram := range q.histograms {
+			rawResults[key] = q.round(sketch.Quantile(histogram, q.percentile))
+		}
+		utils.MergeContainerRawResultsIntoCache(ctx, cache, rawResults, q.metricType, false)
+	}
+
+	oomResults, err := p.recentOOMMemory(clusterID, replicas, now.Add(-utils.MemoryLookbackWindow))
+	if err != nil {
+		return nil, nil, err
+	}
+	utils.MergeContainerRawResultsIntoCache(ctx, cache, oomResults, "oom_memory", false)
+
+	workloadKeyVsWorkloadMetrics := make(utils.WorkloadKeyVsWorkloadMetrics, len(replicas))
+	for workloadKey, histogram := range replicas {
+		workloadKeyVsWorkloadMetrics[workloadKey] = utils.WorkloadMetrics{MedianReplicas: math.Round(sketch.Quantile(histogram, 0.5))}
+	}
+	logging.Infof(ctx, "Built stats of %d workloads of namespace %s from usage histograms", len(workloadKeyVsWorkloadMetrics), namespace)
+	return cache, workloadKeyVsWorkloadMetrics, nil
+}
+
+// recentOOMMemory returns the memory limit in MB of the containers of the workloads killed for memory
+// since the given time.
+func (p *KubeletProvider) recentOOMMemory(clusterID string, workloads map[string]*types.UsageHistogram, since time.Time) (utils.RawBatchResult, error) {
+	rawResults := make(utils.RawBatchResult)
+	for workloadKey := range workloads {
+		events, err := p.storage.GetOOMEventsByWorkload(clusterID, workloadKey, since)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			rawResults[event.ContainerID] = max(rawResults[event.ContainerID], utils.RoundMemory(float64(event.MemoryLimit)/utils.BytesPerMB))
+		}
+	}
+	return rawResults, nil
+}
+
+// FetchUsageSeries returns the max of every hourly slot, stamped with the end of the slot like the range
+// queries.
+func (p *KubeletProvider) FetchUsageSeries(_ context.Context, clusterID, namespace, resourceType string, _ bool, _ time.Duration, start, end time.Time) ([]utils.SimpleTimeSeriesData, error) {
+	resource := types.UsageResource(resourceType)
+	if resource != types.UsageResourceCPU && resource != types.UsageResourceMemory {
+		return nil, fmt.Errorf("invalid resource type: %s", resourceType)
+	}
+	histograms, err := p.storage.GetUsageHistograms(clusterID, namespace, longResolution, start.Truncate(longResolution))
+	if err != nil {
+		return nil, err
+	}
+
+	seriesByKey := make(map[string]*utils.SimpleTimeSeriesData)
+	var keys []string
+	for _, histogram := range histograms {
+		if histogram.Resource != resource || !histogram.Start.Before(end) {
+			continue
+		}
+		// the slot in progress is stamped with the end of the range
+		slotEnd := histogram.Start.Add(longResolution)
+		if slotEnd.After(end) {
+			slotEnd = end
+		}
+		series, ok := seriesByKey[histogram.Key]
+		if !ok {
+			series = &utils.SimpleTimeSeriesData{EntityName: histogram.Key}
+			seriesByKey[histogram.Key] = series
+			keys = append(keys, histogram.Key)
+		}
+		value := histogram.Max
+		if resource == types.UsageResourceMemory {
+			value = utils.RoundMemory(value)
+		}
+		series.Timestamps = append(series.Timestamps, slotEnd.UTC().Format(time.RFC3339))
+		series.Values = append(series.Values, value)
+	}
+
+	result := make([]utils.SimpleTimeSeriesData, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *seriesByKey[key])
+	}
+	return result, nil
+}
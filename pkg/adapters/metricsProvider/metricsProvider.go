@@ -0,0 +1,22 @@
+package metricsprovider
+
+import (
+	"context"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+)
+
+// MetricsProvider is the usage backend the stats are built from.
+type MetricsProvider interface {
+	// FetchStatsForNamespaces returns the usage percentiles of every workload container and the replicas
+	// of every workload, over the lookback windows of the stats. With a startup window, the max cpu usage
+	// in the window after a container starts and past it are returned apart, and the 7 day cpu usage
+	// leaves out the window, when the provider can tell.
+	FetchStatsForNamespaces(ctx context.Context, clusterID string, namespaces []string, isPSIEnabled bool, startupWindow time.Duration) (utils.NamespaceVsContainerMetrics, utils.NamespaceVsWorkloadMetrics, error)
+	// FetchUsageSeries returns the hourly max usage of every workload container of a namespace between
+	// start and end. resourceType is cpu or memory. With a startup window, the cpu usage in the window
+	// after a container starts is left out when the provider can tell.
+	FetchUsageSeries(ctx context.Context, clusterID, namespace, resourceType string, isPSIEnabled bool, startupWindow time.Duration, start, end time.Time) ([]utils.SimpleTimeSeriesData, error)
+	IsPSIEnabled(ctx context.Context, clusterID string) bool
+}
@@ -10,7 +10,9 @@ import (
 
 	"github.com/prometheus/client_golang/api"
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	metricsprovider "github.com/truefoundry/cruisekube/pkg/adapters/metricsProvider"
 	"github.com/truefoundry/cruisekube/pkg/logging"
+	"github.com/truefoundry/cruisekube/pkg/repository/storage"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
@@ -33,10 +35,18 @@ type PrometheusClientConfig struct {
 	MaxConcurrentQueries int
 }
 
+var _ metricsprovider.MetricsProvider = (*PrometheusProvider)(nil)
+
 type PrometheusProvider struct {
 	client          v1.API
 	config          *PrometheusClientConfig
 	querySemaphores sync.Map
+
+	recordedSeriesMu sync.Mutex
+	recordedSeries   map[string]recordedSeries
+
+	// sketchStorage keeps the 7 day usage sketches when the stats are computed incrementally.
+	sketchStorage *storage.Storage
 }
 
 func NewPrometheusProvider(ctx context.Context, config *PrometheusClientConfig) (*PrometheusProvider, error) {
@@ -89,8 +99,9 @@ func NewPrometheusProvider(ctx context.Context, config *PrometheusClientConfig)
 	logging.Infof(ctx, "  - Idle connection timeout: %v", config.IdleConnTimeout)
 
 	return &PrometheusProvider{
-		client: client,
-		config: config,
+		client:         client,
+		config:         config,
+		recordedSeries: make(map[string]recordedSeries),
 	}, nil
 }
 
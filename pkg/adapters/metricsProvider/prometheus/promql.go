@@ -12,6 +12,7 @@ import (
 	"github.com/truefoundry/cruisekube/pkg/task/utils"
 	"github.com/truefoundry/cruisekube/pkg/telemetry"
 
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/common/model"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -162,7 +163,7 @@ func CompressQueryForLogging(query string) string {
 	return strings.Join(compressed, " ")
 }
 
-func (p *PrometheusProvider) FetchStatsForNamespaces(ctx context.Context, clusterId string, namespaces []string, isPSIEnabled bool) (utils.NamespaceVsContainerMetrics, utils.NamespaceVsWorkloadMetrics, error) {
+func (p *PrometheusProvider) FetchStatsForNamespaces(ctx context.Context, clusterId string, namespaces []string, isPSIEnabled bool, startupWindow time.Duration) (utils.NamespaceVsContainerMetrics, utils.NamespaceVsWorkloadMetrics, error) {
 	globalContainerCache := make(utils.NamespaceVsContainerMetrics)
 	globalWorkloadCache := make(utils.NamespaceVsWorkloadMetrics)
 
@@ -173,6 +174,7 @@ func (p *PrometheusProvider) FetchStatsForNamespaces(ctx context.Context, cluste
 		error                        error
 	}
 
+	recorded := p.detectRecordedSeries(ctx, clusterId)
 	resultsChan := make(chan namespaceResult, len(namespaces))
 	var wg sync.WaitGroup
 
@@ -183,7 +185,7 @@ func (p *PrometheusProvider) FetchStatsForNamespaces(ctx context.Context, cluste
 		go func(ns string) {
 			defer wg.Done()
 
-			cache, workloadKeyVsWorkloadMetrics, err := p.fetchStatsForNamespace(ctx, clusterId, ns, isPSIEnabled)
+			cache, workloadKeyVsWorkloadMetrics, err := p.fetchStatsForNamespace(ctx, clusterId, ns, isPSIEnabled, startupWindow, recorded)
 			resultsChan <- namespaceResult{
 				namespace:                    ns,
 				cache:                        cache,
@@ -196,6 +198,10 @@ func (p *PrometheusProvider) FetchStatsForNamespaces(ctx context.Context, cluste
 	wg.Wait()
 	close(resultsChan)
 
+	if p.sketchStorage != nil {
+		p.pruneSketches(ctx, clusterId)
+	}
+
 	successCount := 0
 	for result := range resultsChan {
 		if result.error == nil {
@@ -211,9 +217,65 @@ func (p *PrometheusProvider) FetchStatsForNamespaces(ctx context.Context, cluste
 	return globalContainerCache, globalWorkloadCache, nil
 }
 
-func (p *PrometheusProvider) fetchStatsForNamespace(ctx context.Context, clusterId string, namespace string, isPSIEnabled bool) (utils.WorkloadKeyVsContainerMetrics, utils.WorkloadKeyVsWorkloadMetrics, error) {
+// FetchUsageSeries returns the hourly max usage of every workload container of a namespace between
+// start and end, leaving out the cpu usage of containers in their startup window.
+func (p *PrometheusProvider) FetchUsageSeries(ctx context.Context, clusterId, namespace, resourceType string, isPSIEnabled bool, startupWindow time.Duration, start, end time.Time) ([]utils.SimpleTimeSeriesData, error) {
+	expressions := p.withoutStartupUsage(p.usageExpressions(namespace, p.detectRecordedSeries(ctx, clusterId).week), namespace, startupWindow)
+	var query string
+	switch resourceType {
+	case "cpu":
+		coreCPU := expressions.coreCPU
+		if isPSIEnabled {
+			coreCPU = expressions.coreCPUPSIAdjusted
+		}
+		query = utils.EncloseWithinQuantileOverTime(coreCPU, utils.TimeSeriesStepSize, 1.0)
+	case "memory":
+		query = utils.EncloseWithinMemoryCleanupFunction(utils.EncloseWithinQuantileOverTime(
+			expressions.memory,
+			utils.TimeSeriesStepSize,
+			1.0,
+		), utils.MemoryDecimalPlaces)
+	default:
+		return nil, fmt.Errorf("invalid resource type: %s", resourceType)
+	}
+
+	r := v1.Range{
+		Start: start,
+		End:   end,
+		Step:  utils.TimeSeriesStepSize,
+	}
+	logging.Infof(ctx, "Querying prometheus for %s usage series with query: %s", resourceType, CompressQueryForLogging(query))
+	value, _, err := p.client.QueryRange(ctx, query, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query prometheus for %s usage series: %w", resourceType, err)
+	}
+	matrix, ok := value.(model.Matrix)
+	if !ok {
+		return nil, fmt.Errorf("unable to convert %s usage series to matrix", resourceType)
+	}
+	return utils.ConvertMatrixToSimpleTimeSeriesData(matrix), nil
+}
+
+func (p *PrometheusProvider) IsPSIEnabled(ctx context.Context, clusterId string) bool {
+	query := "max(max_over_time(container_pressure_cpu_waiting_seconds_total[1m]))"
+
+	result, _, err := p.ExecuteQueryWithRetry(ctx, clusterId, query, "PSI_CHECK")
+	if err != nil {
+		logging.Errorf(ctx, "[isPSIEnabled] Error executing PSI query: %v", err)
+		return false
+	}
+
+	vector, ok := result.(model.Vector)
+	return ok && len(vector) != 0
+}
+
+func (p *PrometheusProvider) fetchStatsForNamespace(ctx context.Context, clusterId string, namespace string, isPSIEnabled bool, startupWindow time.Duration, recorded recordedSeries) (utils.WorkloadKeyVsContainerMetrics, utils.WorkloadKeyVsWorkloadMetrics, error) {
 	logging.Infof(ctx, "Executing all batch queries for namespace: %s", namespace)
 
+	recentExpressions := p.usageExpressions(namespace, recorded.recent)
+	// the startup peaks are kept apart from the 7 day stats, the recent max is split by the startup queries
+	weekExpressions := p.withoutStartupUsage(p.usageExpressions(namespace, recorded.week), namespace, startupWindow)
+
 	cache := make(utils.WorkloadKeyVsContainerMetrics)
 	workloadKeyVsWorkloadMetrics := make(utils.WorkloadKeyVsWorkloadMetrics)
 	var requests []ParallelQueryRequest
@@ -228,7 +290,7 @@ func (p *PrometheusProvider) fetchStatsForNamespace(ctx context.Context, cluster
 	}
 
 	for _, q := range cpuQueries {
-		query := p.buildBatchCPURecommendationQuery(namespace, q.percentile, false)
+		query := p.buildBatchCPURecommendationQuery(recentExpressions.coreCPU, q.percentile)
 		requests = append(requests, ParallelQueryRequest{
 			QueryID: fmt.Sprintf("%s-%s", namespace, q.key),
 			Query:   query,
@@ -237,7 +299,7 @@ func (p *PrometheusProvider) fetchStatsForNamespace(ctx context.Context, cluster
 
 	if isPSIEnabled {
 		for _, q := range cpuQueries {
-			query := p.buildBatchCPURecommendationQuery(namespace, q.percentile, true)
+			query := p.buildBatchCPURecommendationQuery(recentExpressions.coreCPUPSIAdjusted, q.percentile)
 			requests = append(requests, ParallelQueryRequest{
 				QueryID: fmt.Sprintf("%s-%s-psi", namespace, q.key),
 				Query:   query,
@@ -256,12 +318,15 @@ func (p *PrometheusProvider) fetchStatsForNamespace(ctx context.Context, cluster
 		{1.0, "cpu_max"},
 	}
 
-	for _, q := range cpu7DayQueries {
-		query := p.buildBatch7DayCPURecommendationQuery(namespace, q.percentile, false)
-		requests = append(requests, ParallelQueryRequest{
-			QueryID: fmt.Sprintf("%s-%s-cpu-7day", namespace, q.key),
-			Query:   query,
-		})
+	// the sketches replace the 7 day queries
+	if p.sketchStorage == nil {
+		for _, q := range cpu7DayQueries {
+			query := p.buildBatch7DayCPURecommendationQuery(weekExpressions.coreCPU, q.percentile)
+			requests = append(requests, ParallelQueryRequest{
+				QueryID: fmt.Sprintf("%s-%s-cpu-7day", namespace, q.key),
+				Query:   query,
+			})
+		}
 	}
 
 	memoryQueries := []struct {
@@ -273,27 +338,47 @@ func (p *PrometheusProvider) fetchStatsForNamespace(ctx context.Context, cluster
 	}
 
 	for _, q := range memoryQueries {
-		query := p.EncloseWithinMemoryCleanupFunction(p.buildBatchMemoryRecommendationQuery(namespace, q.percentile), MemoryDecimalPlaces)
+		query := p.EncloseWithinMemoryCleanupFunction(p.buildBatchMemoryRecommendationQuery(recentExpressions.memory, q.percentile), MemoryDecimalPlaces)
 		requests = append(requests, ParallelQueryRequest{
 			QueryID: fmt.Sprintf("%s-%s-memory", namespace, q.key),
 			Query:   query,
 		})
 	}
 
-	query := p.EncloseWithinMemoryCleanupFunction(p.buildBatch7DayMemoryRecommendationQuery(namespace, 1.0), MemoryDecimalPlaces)
-	requests = append(requests, ParallelQueryRequest{
-		QueryID: fmt.Sprintf("%s-%s-memory-7day", namespace, "memory_max"),
-		Query:   query,
-	})
+	if p.sketchStorage == nil {
+		query := p.EncloseWithinMemoryCleanupFunction(p.buildBatch7DayMemoryRecommendationQuery(weekExpressions.memory, 1.0), MemoryDecimalPlaces)
+		requests = append(requests, ParallelQueryRequest{
+			QueryID: fmt.Sprintf("%s-%s-memory-7day", namespace, "memory_max"),
+			Query:   query,
+		})
+	}
 
-	oomQuery := p.EncloseWithinMemoryCleanupFunction(p.buildBatchOOMMemoryQuery(namespace), MemoryDecimalPlaces)
+	oomQuery := p.EncloseWithinMemoryCleanupFunction(p.buildBatchOOMMemoryQuery(recentExpressions.oomMemory), MemoryDecimalPlaces)
 	requests = append(requests, ParallelQueryRequest{
 		QueryID: fmt.Sprintf("%s-oom-memory", namespace),
 		Query:   oomQuery,
 	})
 
-	replicaQueryExpr := p.buildBatchReplicaCountQuery(namespace)
-	replicaQuery := fmt.Sprintf("quantile_over_time(0.5, (%s)[%s:1h])", replicaQueryExpr, ReplicaLookbackWindow.String())
+	startupQueries := []struct {
+		startup bool
+		window  time.Duration
+		key     string
+	}{
+		{true, CPU7DayLookbackWindow, "startup_cpu_max"},
+		{false, CPULookbackWindow, "non_startup_cpu_max"},
+	}
+
+	if startupWindow > 0 {
+		for _, q := range startupQueries {
+			query := p.buildBatchStartupCPUMaxQuery(p.buildBatchStartupCPUExpression(namespace, startupWindow, q.startup), q.window)
+			requests = append(requests, ParallelQueryRequest{
+				QueryID: fmt.Sprintf("%s-%s", namespace, q.key),
+				Query:   query,
+			})
+		}
+	}
+
+	replicaQuery := fmt.Sprintf("quantile_over_time(0.5, (%s)[%s:1h])", weekExpressions.replicas, ReplicaLookbackWindow.String())
 	requests = append(requests, ParallelQueryRequest{
 		QueryID: fmt.Sprintf("%s-replica", namespace),
 		Query:   replicaQuery,
@@ -304,6 +389,16 @@ func (p *PrometheusProvider) fetchStatsForNamespace(ctx context.Context, cluster
 		return nil, nil, err
 	}
 
+	if p.sketchStorage != nil {
+		sketchResults, err := p.fetch7DayStatsFromSketches(ctx, clusterId, namespace, recorded, startupWindow)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to update usage sketches of namespace %s: %w", namespace, err)
+		}
+		for metricType, rawResults := range sketchResults {
+			utils.MergeContainerRawResultsIntoCache(ctx, cache, rawResults, metricType, false)
+		}
+	}
+
 	for _, q := range cpuQueries {
 		queryID := fmt.Sprintf("%s-%s", namespace, q.key)
 		if result, exists := results[queryID]; exists {
@@ -448,6 +543,28 @@ func (p *PrometheusProvider) fetchStatsForNamespace(ctx context.Context, cluster
 		}
 	}
 
+	for _, q := range startupQueries {
+		queryID := fmt.Sprintf("%s-%s", namespace, q.key)
+		if result, exists := results[queryID]; exists {
+			if result.Error != nil {
+				logging.Infof(ctx, "Error getting %s metrics for namespace %s: %v", q.key, namespace, result.Error)
+				continue
+			}
+
+			if len(result.Warnings) > 0 {
+				logging.Infof(ctx, "Warnings from %s query for namespace %s: %v", q.key, namespace, result.Warnings)
+			}
+
+			rawResults, err := p.parsePrometheusVectorResultForContainer(result.Result)
+			if err != nil {
+				logging.Infof(ctx, "Error parsing %s results for namespace %s: %v", q.key, namespace, err)
+				continue
+			}
+
+			utils.MergeContainerRawResultsIntoCache(ctx, cache, rawResults, q.key, false)
+		}
+	}
+
 	if result, exists := results[fmt.Sprintf("%s-replica", namespace)]; exists {
 		if result.Error != nil {
 			logging.Infof(ctx, "Error getting Replica metrics for namespace %s: %v", namespace, result.Error)
@@ -466,14 +583,13 @@ func (p *PrometheusProvider) fetchStatsForNamespace(ctx context.Context, cluster
 						logging.Infof(ctx, "Error parsing workload key: %s", workloadKey)
 						continue
 					}
+					kind, name = utils.ResolveWorkloadOwner(kind, namespace, name)
 					if kind == utils.ReplicaSetKind {
-						deploymentName, isDeployment := utils.ExtractWorkloadFromReplicaSet(name)
-						if isDeployment {
-							workloadKey = utils.GetWorkloadKey(utils.DeploymentKind, namespace, deploymentName)
-							workloadKeyVsWorkloadMetrics[workloadKey] = utils.WorkloadMetrics{MedianReplicas: replicaValue}
-						}
-					} else {
-						workloadKey = utils.GetWorkloadKey(kind, namespace, name)
+						continue
+					}
+					// the ReplicaSets of a Deployment or the Jobs of a CronJob overlap, the largest one counts
+					workloadKey = utils.GetWorkloadKey(kind, namespace, name)
+					if existing, ok := workloadKeyVsWorkloadMetrics[workloadKey]; !ok || replicaValue > existing.MedianReplicas {
 						workloadKeyVsWorkloadMetrics[workloadKey] = utils.WorkloadMetrics{MedianReplicas: replicaValue}
 					}
 				}
@@ -541,14 +657,43 @@ func (p *PrometheusProvider) BuildBatchCoreCPUExpression(namespace string, psiAd
 	return fmt.Sprintf(template, throttlingAwareCPU)
 }
 
+// buildBatchSteadyCoreCPUExpression is the core cpu expression without the usage of the containers that
+// started less than the startup window ago.
+func (p *PrometheusProvider) buildBatchSteadyCoreCPUExpression(namespace string, psiAdjusted bool, startupWindow time.Duration) string {
+	template := `(max by (created_by_kind, created_by_name, namespace, container) (
+		max by (created_by_kind, created_by_name, namespace, container, node) (
+			(
+				(%s)
+				unless on (namespace, pod, container)
+				(%s)
+			)
+			* on (namespace, pod, node) group_left(created_by_kind, created_by_name)
+			(%s)
+		)
+	) or vector(0))`
+
+	return fmt.Sprintf(template,
+		p.buildBatchCPUUsageExpression(namespace, psiAdjusted),
+		buildBatchStartedWithinExpression(namespace, startupWindow),
+		p.buildBatchPodInfoExpression(namespace),
+	)
+}
+
+// buildBatchStartedWithinExpression selects the containers that started less than the window ago.
+func buildBatchStartedWithinExpression(namespace string, window time.Duration) string {
+	template := `(time() - max by (namespace, pod, container) (
+		kube_pod_container_state_started{job="kube-state-metrics",namespace="%s"}
+	)) < %d`
+
+	return fmt.Sprintf(template, namespace, int(window.Seconds()))
+}
+
 func (p *PrometheusProvider) EncloseWithinQuantileOverTime(query string, quantileLookbackWindow time.Duration, percentile float64) string {
 	template := `quantile_over_time(%.2f, (%s)[%ds:1m])`
 	return fmt.Sprintf(template, percentile, query, int(quantileLookbackWindow.Seconds()))
 }
 
-func (p *PrometheusProvider) buildBatchCPURecommendationQuery(namespace string, percentile float64, psiAdjusted bool) string {
-	coreCPU := p.BuildBatchCoreCPUExpression(namespace, psiAdjusted)
-
+func (p *PrometheusProvider) buildBatchCPURecommendationQuery(coreCPU string, percentile float64) string {
 	template := `ceil(
       quantile_over_time(
         %.2f,
@@ -642,9 +787,7 @@ func (p *PrometheusProvider) EncloseWithinMemoryCleanupFunction(query string, me
 	return fmt.Sprintf(template, query, float64(BytesPerMB), memoryDecimalPlaces)
 }
 
-func (p *PrometheusProvider) buildBatchMemoryRecommendationQuery(namespace string, percentile float64) string {
-	memoryUsage := p.BuildBatchMemoryUsageExpression(namespace)
-
+func (p *PrometheusProvider) buildBatchMemoryRecommendationQuery(memoryUsage string, percentile float64) string {
 	template := `
       quantile_over_time(
         %.2f,
@@ -659,9 +802,7 @@ func (p *PrometheusProvider) buildBatchMemoryRecommendationQuery(namespace strin
 	)
 }
 
-func (p *PrometheusProvider) buildBatch7DayMemoryRecommendationQuery(namespace string, percentile float64) string {
-	memoryUsage := p.BuildBatchMemoryUsageExpression(namespace)
-
+func (p *PrometheusProvider) buildBatch7DayMemoryRecommendationQuery(memoryUsage string, percentile float64) string {
 	template := `
       quantile_over_time(
         %.2f,
@@ -676,9 +817,7 @@ func (p *PrometheusProvider) buildBatch7DayMemoryRecommendationQuery(namespace s
 	)
 }
 
-func (p *PrometheusProvider) buildBatch7DayCPURecommendationQuery(namespace string, percentile float64, psiAdjusted bool) string {
-	coreCPU := p.BuildBatchCoreCPUExpression(namespace, psiAdjusted)
-
+func (p *PrometheusProvider) buildBatch7DayCPURecommendationQuery(coreCPU string, percentile float64) string {
 	template := `ceil(
       quantile_over_time(
         %.2f,
@@ -697,17 +836,49 @@ func (p *PrometheusProvider) buildBatch7DayCPURecommendationQuery(namespace stri
 	)
 }
 
-func (p *PrometheusProvider) buildBatchReplicaCountQuery(namespace string) string {
-	podInfo := p.buildBatchPodInfoExpression(namespace)
-
-	template := `count by (created_by_kind, created_by_name, namespace) (%s)`
+// buildBatchStartupCPUExpression keeps the cpu usage of the containers that started less than the startup
+// window ago, or of the other containers when startup is false.
+func (p *PrometheusProvider) buildBatchStartupCPUExpression(namespace string, startupWindow time.Duration, startup bool) string {
+	operator := "unless"
+	if startup {
+		operator = "and"
+	}
+	template := `max by (created_by_kind, created_by_name, namespace, container) (
+		(
+			(%s)
+			%s on (namespace, pod, container)
+			(%s)
+		)
+		* on (namespace, pod, node) group_left(created_by_kind, created_by_name)
+		(%s)
+	)`
 
-	return fmt.Sprintf(template, podInfo)
+	return fmt.Sprintf(template,
+		p.buildBatchCPUUsageExpression(namespace, false),
+		operator,
+		buildBatchStartedWithinExpression(namespace, startupWindow),
+		p.buildBatchPodInfoExpression(namespace),
+	)
 }
 
-func (p *PrometheusProvider) buildBatchOOMMemoryQuery(namespace string) string {
-	oomExpression := p.buildBatchOOMMemoryExpression(namespace)
+func (p *PrometheusProvider) buildBatchStartupCPUMaxQuery(startupCPU string, lookbackWindow time.Duration) string {
+	template := `ceil(
+      max_over_time(
+        (%s)
+        [%s:1m]
+      )
+      * %.6f
+    ) / %.6f`
+
+	return fmt.Sprintf(template,
+		startupCPU,
+		lookbackWindow.String(),
+		CPUDecimalScale,
+		CPUDecimalScale,
+	)
+}
 
+func (p *PrometheusProvider) buildBatchOOMMemoryQuery(oomExpression string) string {
 	template := `max_over_time(
 		(%s)
 		[%s:1m]
@@ -716,8 +887,8 @@ func (p *PrometheusProvider) buildBatchOOMMemoryQuery(namespace string) string {
 	return fmt.Sprintf(template, oomExpression, MemoryLookbackWindow.String())
 }
 
-func (p *PrometheusProvider) buildBatchOOMMemoryExpression(namespace string) string {
-	podInfo := p.buildBatchPodInfoExpression(namespace)
+func buildBatchOOMMemoryExpression(namespace string) string {
+	podInfo := buildBatchPodInfoExpression(namespace)
 
 	template := `max by (created_by_kind, created_by_name, namespace, container) (
 		(
@@ -762,6 +933,14 @@ func BuildBatchMemoryUsageExpression(namespace string) string {
 	return fmt.Sprintf(template, namespace, podInfo)
 }
 
+func buildBatchReplicaCountExpression(namespace string) string {
+	podInfo := buildBatchPodInfoExpression(namespace)
+
+	template := `count by (created_by_kind, created_by_name, namespace) (%s)`
+
+	return fmt.Sprintf(template, podInfo)
+}
+
 func buildBatchPodInfoExpression(namespace string) string {
 	template := `max by (namespace, pod, container, node, created_by_kind, created_by_name) (
 		kube_pod_info{
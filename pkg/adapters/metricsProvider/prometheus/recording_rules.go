@@ -0,0 +1,208 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/logging"
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
+)
+
+// Series recorded by the recording rules, with the labels of the expressions they record.
+const (
+	RecordedCoreCPU            = "cruisekube:workload_container_cpu_usage:cores"
+	RecordedCoreCPUPSIAdjusted = "cruisekube:workload_container_cpu_usage_psi_adjusted:cores"
+	RecordedMemoryUsage        = "cruisekube:workload_container_memory_working_set:bytes"
+	RecordedOOMMemory          = "cruisekube:workload_container_oom_memory_limit:bytes"
+	RecordedReplicas           = "cruisekube:workload_replicas:count"
+)
+
+const (
+	RecordingRuleGroupName = "cruisekube.rules"
+	// RecordingRuleInterval matches the resolution of the subqueries of the stats.
+	RecordingRuleInterval = time.Minute
+
+	recordedSeriesCheckInterval = 10 * time.Minute
+	allNamespacesPlaceholder    = "__cruisekube_all_namespaces__"
+)
+
+type RecordingRulesFormat string
+
+const (
+	// RecordingRulesFormatPrometheusRule is a PrometheusRule resource of the prometheus operator.
+	RecordingRulesFormatPrometheusRule RecordingRulesFormat = "prometheusRule"
+	// RecordingRulesFormatRuleFile is a rule file for the rule_files of prometheus.
+	RecordingRulesFormatRuleFile RecordingRulesFormat = "ruleFile"
+)
+
+type RecordingRule struct {
+	Record string `yaml:"record"`
+	Expr   string `yaml:"expr"`
+}
+
+type RuleGroup struct {
+	Name     string          `yaml:"name"`
+	Interval string          `yaml:"interval"`
+	Rules    []RecordingRule `yaml:"rules"`
+}
+
+type ruleFile struct {
+	Groups []RuleGroup `yaml:"groups"`
+}
+
+type prometheusRule struct {
+	APIVersion string                 `yaml:"apiVersion"`
+	Kind       string                 `yaml:"kind"`
+	Metadata   prometheusRuleMetadata `yaml:"metadata"`
+	Spec       ruleFile               `yaml:"spec"`
+}
+
+type prometheusRuleMetadata struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+// BuildRecordingRuleGroup records the usage expressions of the stats queries of every namespace.
+func BuildRecordingRuleGroup() RuleGroup {
+	return RuleGroup{
+		Name:     RecordingRuleGroupName,
+		Interval: model.Duration(RecordingRuleInterval).String(),
+		Rules: []RecordingRule{
+			{Record: RecordedCoreCPU, Expr: allNamespacesExpression(func(namespace string) string {
+				return utils.BuildBatchCoreCPUExpression(namespace, false)
+			})},
+			{Record: RecordedCoreCPUPSIAdjusted, Expr: allNamespacesExpression(func(namespace string) string {
+				return utils.BuildBatchCoreCPUExpression(namespace, true)
+			})},
+			{Record: RecordedMemoryUsage, Expr: allNamespacesExpression(BuildBatchMemoryUsageExpression)},
+			{Record: RecordedOOMMemory, Expr: allNamespacesExpression(buildBatchOOMMemoryExpression)},
+			{Record: RecordedReplicas, Expr: allNamespacesExpression(buildBatchReplicaCountExpression)},
+		},
+	}
+}
+
+// RecordingRulesYAML renders the recording rules in the given format. name, namespace and labels are
+// only used by the PrometheusRule format.
+func RecordingRulesYAML(format RecordingRulesFormat, name, namespace string, labels map[string]string) ([]byte, error) {
+	rules := ruleFile{Groups: []RuleGroup{BuildRecordingRuleGroup()}}
+
+	var document any
+	switch format {
+	case RecordingRulesFormatRuleFile:
+		document = rules
+	case RecordingRulesFormatPrometheusRule:
+		document = prometheusRule{
+			APIVersion: "monitoring.coreos.com/v1",
+			Kind:       "PrometheusRule",
+			Metadata:   prometheusRuleMetadata{Name: name, Namespace: namespace, Labels: labels},
+			Spec:       rules,
+		}
+	default:
+		return nil, fmt.Errorf("invalid recording rules format %q (expected %s|%s)", format, RecordingRulesFormatPrometheusRule, RecordingRulesFormatRuleFile)
+	}
+
+	data, err := yaml.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal recording rules: %w", err)
+	}
+	return data, nil
+}
+
+// allNamespacesExpression builds an expression of a single namespace and makes it match every namespace.
+func allNamespacesExpression(build func(namespace string) string) string {
+	expression := build(allNamespacesPlaceholder)
+	expression = strings.ReplaceAll(expression, fmt.Sprintf(`namespace="%s"`, allNamespacesPlaceholder), `namespace!=""`)
+	return CompressQueryForLogging(expression)
+}
+
+// usageExpressions are the inner expressions of the stats queries of a namespace, built from the raw
+// metrics or selecting the recorded series.
+type usageExpressions struct {
+	coreCPU            string
+	coreCPUPSIAdjusted string
+	memory             string
+	oomMemory          string
+	replicas           string
+}
+
+func (p *PrometheusProvider) usageExpressions(namespace string, recorded bool) usageExpressions {
+	if recorded {
+		return usageExpressions{
+			coreCPU:            recordedSeriesSelector(RecordedCoreCPU, namespace),
+			coreCPUPSIAdjusted: recordedSeriesSelector(RecordedCoreCPUPSIAdjusted, namespace),
+			memory:             recordedSeriesSelector(RecordedMemoryUsage, namespace),
+			oomMemory:          recordedSeriesSelector(RecordedOOMMemory, namespace),
+			replicas:           recordedSeriesSelector(RecordedReplicas, namespace),
+		}
+	}
+	return usageExpressions{
+		coreCPU:            p.BuildBatchCoreCPUExpression(namespace, false),
+		coreCPUPSIAdjusted: p.BuildBatchCoreCPUExpression(namespace, true),
+		memory:             p.BuildBatchMemoryUsageExpression(namespace),
+		oomMemory:          buildBatchOOMMemoryExpression(namespace),
+		replicas:           buildBatchReplicaCountExpression(namespace),
+	}
+}
+
+// withoutStartupUsage replaces the cpu expressions by ones leaving out the containers in their startup
+// window. The recorded series are aggregated per workload already, so the raw metrics are used.
+func (p *PrometheusProvider) withoutStartupUsage(expressions usageExpressions, namespace string, startupWindow time.Duration) usageExpressions {
+	if startupWindow <= 0 {
+		return expressions
+	}
+	expressions.coreCPU = p.buildBatchSteadyCoreCPUExpression(namespace, false, startupWindow)
+	expressions.coreCPUPSIAdjusted = p.buildBatchSteadyCoreCPUExpression(namespace, true, startupWindow)
+	return expressions
+}
+
+func recordedSeriesSelector(record, namespace string) string {
+	return fmt.Sprintf(`%s{namespace="%s"}`, record, namespace)
+}
+
+// recordedSeries tells which lookback windows the recording rules already cover: recent for the cpu,
+// memory and OOM windows and week for the 7 day windows.
+type recordedSeries struct {
+	recent    bool
+	week      bool
+	checkedAt time.Time
+}
+
+// detectRecordedSeries checks how far back the recorded memory series go, the rules of the group are
+// evaluated together so it stands for all of them. The result is kept for a few minutes.
+func (p *PrometheusProvider) detectRecordedSeries(ctx context.Context, clusterId string) recordedSeries {
+	p.recordedSeriesMu.Lock()
+	cached, ok := p.recordedSeries[clusterId]
+	p.recordedSeriesMu.Unlock()
+	if ok && time.Since(cached.checkedAt) < recordedSeriesCheckInterval {
+		return cached
+	}
+
+	detected := recordedSeries{
+		recent:    p.hasRecordedSeriesAt(ctx, clusterId, MemoryLookbackWindow),
+		week:      p.hasRecordedSeriesAt(ctx, clusterId, Memory7DayLookbackWindow),
+		checkedAt: time.Now(),
+	}
+	logging.Infof(ctx, "Recorded series cover the recent windows: %v, the 7 day windows: %v", detected.recent, detected.week)
+
+	p.recordedSeriesMu.Lock()
+	p.recordedSeries[clusterId] = detected
+	p.recordedSeriesMu.Unlock()
+	return detected
+}
+
+func (p *PrometheusProvider) hasRecordedSeriesAt(ctx context.Context, clusterId string, offset time.Duration) bool {
+	query := fmt.Sprintf("count(last_over_time(%s[%ds] offset %ds))", RecordedMemoryUsage, int(RecordingRuleInterval.Seconds())*5, int(offset.Seconds()))
+	result, _, err := p.ExecuteQueryWithRetry(ctx, clusterId, query, "RECORDED_SERIES_CHECK")
+	if err != nil {
+		logging.Errorf(ctx, "Error checking the recorded series: %v", err)
+		return false
+	}
+	vector, ok := result.(model.Vector)
+	return ok && len(vector) > 0 && vector[0].Value > 0
+}
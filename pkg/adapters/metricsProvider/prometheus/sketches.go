@@ -0,0 +1,243 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/adapters/metricsProvider/sketch"
+	"github.com/truefoundry/cruisekube/pkg/logging"
+	"github.com/truefoundry/cruisekube/pkg/repository/storage"
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+	"github.com/truefoundry/cruisekube/pkg/types"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// The 7 day stats merge hourly sketches of the samples taken every minute, the slots of the last day
+// past the lookback window are kept so a late run still finds a full week.
+const (
+	sketchResolution = time.Hour
+	sketchRetention  = utils.CPU7DayLookbackWindow + 24*time.Hour
+	sketchStep       = time.Minute
+	sketchQueryChunk = 24 * time.Hour
+	// sketchSettleDelay keeps the last scrape intervals out of the delta, their samples may not all be
+	// ingested yet and a sample is never sketched twice.
+	sketchSettleDelay = 2 * time.Minute
+	// sketchStaleKeyAge is how far behind the newest sample a key's last sample can be before the key is
+	// considered gone, a gone key doesn't pull the start of the delta back.
+	sketchStaleKeyAge = sketchResolution
+)
+
+// EnableIncrementalStats builds the 7 day stats from sketches persisted in the storage, each run only
+// queries the samples since the previous run instead of the whole week.
+func (p *PrometheusProvider) EnableIncrementalStats(sketchStorage *storage.Storage) {
+	p.sketchStorage = sketchStorage
+}
+
+// pruneSketches deletes the sketches past the retention, once per run rather than once per namespace.
+func (p *PrometheusProvider) pruneSketches(ctx context.Context, clusterId string) {
+	deleted, err := p.sketchStorage.DeleteOldUsageHistograms(clusterId, sketchResolution, sketchRetention)
+	if err != nil {
+		logging.Errorf(ctx, "Failed to delete old usage sketches of cluster %s: %v", clusterId, err)
+		return
+	}
+	if deleted > 0 {
+		logging.Infof(ctx, "Deleted %d old usage sketches of cluster %s", deleted, clusterId)
+	}
+}
+
+// fetch7DayStatsFromSketches adds the cpu and memory samples since the last run to the hourly sketches of
+// the namespace and returns the 7 day percentiles keyed by metric type.
+func (p *PrometheusProvider) fetch7DayStatsFromSketches(ctx context.Context, clusterId, namespace string, recorded recordedSeries, startupWindow time.Duration) (map[string]utils.RawBatchResult, error) {
+	now := time.Now().Truncate(sketchStep)
+	since := now.Add(-utils.CPU7DayLookbackWindow).Truncate(sketchResolution)
+	histograms, err := p.sketchStorage.GetUsageHistograms(clusterId, namespace, sketchResolution, since)
+	if err != nil {
+		return nil, err
+	}
+
+	slots := make(map[string]*types.UsageHistogram, len(histograms))
+	for i := range histograms {
+		histogram := &histograms[i]
+		slots[sketchSlotKey(histogram.Key, histogram.Resource, histogram.Start)] = histogram
+	}
+
+	changed := make(map[string]*types.UsageHistogram)
+	for _, resource := range []types.UsageResource{types.UsageResourceCPU, types.UsageResourceMemory} {
+		lastSamples := lastSampleAtByKey(histograms, resource)
+		series, err := p.fetchSketchDelta(ctx, clusterId, namespace, resource, deltaStart(lastSamples), now.Add(-sketchSettleDelay), recorded, startupWindow)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range series {
+			for i, ts := range s.Timestamps {
+				t, err := utils.ParseTimeSeriesTimestamp(ts)
+				if err != nil || !t.After(lastSamples[s.EntityName]) {
+					continue
+				}
+				start := t.Truncate(sketchResolution)
+				slotKey := sketchSlotKey(s.EntityName, resource, start)
+				histogram, ok := slots[slotKey]
+				if !ok {
+					histogram = &types.UsageHistogram{
+						Key:        s.EntityName,
+						Namespace:  namespace,
+						Resource:   resource,
+						Resolution: sketchResolution,
+						Start:      start,
+					}
+					slots[slotKey] = histogram
+				}
+				value := s.Values[i]
+				if resource == types.UsageResourceMemory {
+					value /= utils.BytesPerMB
+				}
+				sketch.Add(histogram, value)
+				if t.After(histogram.LastSampleAt) {
+					histogram.LastSampleAt = t
+				}
+				changed[slotKey] = histogram
+			}
+		}
+	}
+
+	if len(changed) > 0 {
+		updated := make([]types.UsageHistogram, 0, len(changed))
+		for _, histogram := range changed {
+			updated = append(updated, *histogram)
+		}
+		if err := p.sketchStorage.UpsertUsageHistograms(clusterId, updated); err != nil {
+			return nil, err
+		}
+		logging.Infof(ctx, "Updated %d usage sketches of namespace %s", len(updated), namespace)
+	}
+
+	merged := make([]types.UsageHistogram, 0, len(slots))
+	for _, histogram := range slots {
+		merged = append(merged, *histogram)
+	}
+	cpu := sketch.MergeByKey(merged, types.UsageResourceCPU, since)
+	memory := sketch.MergeByKey(merged, types.UsageResourceMemory, since)
+
+	results := make(map[string]utils.RawBatchResult)
+	for _, q := range []struct {
+		histograms map[string]*types.UsageHistogram
+		percentile float64
+		metricType string
+		round      func(float64) float64
+	}{
+		{cpu, 0.50, "cpu_p50_cpu_7day", utils.CeilCPU},
+		{cpu, 0.75, "cpu_p75_cpu_7day", utils.CeilCPU},
+		{cpu, 0.90, "cpu_p90_cpu_7day", utils.CeilCPU},
+		{cpu, 0.99, "cpu_p99_cpu_7day", utils.CeilCPU},
+		{cpu, 1.0, "cpu_max_cpu_7day", utils.CeilCPU},
+		{memory, 1.0, "memory_max_7day", utils.RoundMemory},
+	} {
+		rawResults := make(utils.RawBatchResult, len(q.histograms))
+		for key, histogram := range q.histograms {
+			rawResults[key] = q.round(sketch.Quantile(histogram, q.percentile))
+		}
+		results[q.metricType] = rawResults
+	}
+	return results, nil
+}
+
+// fetchSketchDelta returns the per minute usage of every workload container of the namespace from the
+// given last sketched sample to now, at most a week back.
+func (p *PrometheusProvider) fetchSketchDelta(ctx context.Context, clusterId, namespace string, resource types.UsageResource, lastSample, now time.Time, recorded recordedSeries, startupWindow time.Duration) ([]utils.SimpleTimeSeriesData, error) {
+	start := now.Add(-utils.CPU7DayLookbackWindow)
+	if lastSample.After(start) {
+		start = lastSample.Truncate(sketchStep).Add(sketchStep)
+	}
+	if start.After(now) {
+		return nil, nil
+	}
+
+	// the recorded series of a recent cluster may not reach back to the last sample yet
+	expressions := p.withoutStartupUsage(p.usageExpressions(namespace, recorded.week || (recorded.recent && now.Sub(start) <= utils.MemoryLookbackWindow)), namespace, startupWindow)
+	var query string
+	switch resource {
+	case types.UsageResourceCPU:
+		query = expressions.coreCPU
+	case types.UsageResourceMemory:
+		query = expressions.memory
+	default:
+		return nil, fmt.Errorf("invalid sketch resource: %s", resource)
+	}
+
+	var series []utils.SimpleTimeSeriesData
+	for chunkStart := start; !chunkStart.After(now); chunkStart = chunkStart.Add(sketchQueryChunk) {
+		r := v1.Range{
+			Start: chunkStart,
+			End:   minTime(chunkStart.Add(sketchQueryChunk-sketchStep), now),
+			Step:  sketchStep,
+		}
+		matrix, err := p.queryRange(ctx, clusterId, query, r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s usage of namespace %s: %w", resource, namespace, err)
+		}
+		series = append(series, utils.ConvertMatrixToSimpleTimeSeriesData(matrix)...)
+	}
+	return series, nil
+}
+
+func (p *PrometheusProvider) queryRange(ctx context.Context, clusterId, query string, r v1.Range) (model.Matrix, error) {
+	p.acquireQuerySlot(clusterId)
+	defer p.releaseQuerySlot(ctx, clusterId)
+
+	queryCtx, cancel := p.createQueryContext(ctx)
+	defer cancel()
+
+	logging.Debugf(ctx, "Querying prometheus from %s to %s with query: %s", r.Start, r.End, CompressQueryForLogging(query))
+	value, _, err := p.client.QueryRange(queryCtx, query, r)
+	if err != nil {
+		return nil, err
+	}
+	matrix, ok := value.(model.Matrix)
+	if !ok {
+		return nil, fmt.Errorf("unable to convert range query result to matrix")
+	}
+	return matrix, nil
+}
+
+// lastSampleAtByKey returns the time of the last sketched sample of every key.
+func lastSampleAtByKey(histograms []types.UsageHistogram, resource types.UsageResource) map[string]time.Time {
+	last := make(map[string]time.Time)
+	for _, histogram := range histograms {
+		if histogram.Resource == resource && histogram.LastSampleAt.After(last[histogram.Key]) {
+			last[histogram.Key] = histogram.LastSampleAt
+		}
+	}
+	return last
+}
+
+// deltaStart is the earliest last sample of the keys still reporting, so a key whose samples arrived
+// late gets them sketched in the next run.
+func deltaStart(lastSamples map[string]time.Time) time.Time {
+	var newest time.Time
+	for _, last := range lastSamples {
+		if last.After(newest) {
+			newest = last
+		}
+	}
+	start := newest
+	for _, last := range lastSamples {
+		if last.Before(start) && newest.Sub(last) <= sketchStaleKeyAge {
+			start = last
+		}
+	}
+	return start
+}
+
+func sketchSlotKey(key string, resource types.UsageResource, start time.Time) string {
+	return fmt.Sprintf("%s|%s|%d", key, resource, start.Unix())
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
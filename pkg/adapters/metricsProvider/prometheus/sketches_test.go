@@ -0,0 +1,59 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeltaStart(t *testing.T) {
+	now := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		lastSamples map[string]time.Time
+		want        time.Time
+	}{
+		{
+			name:        "no keys",
+			lastSamples: map[string]time.Time{},
+			want:        time.Time{},
+		},
+		{
+			name:        "one key",
+			lastSamples: map[string]time.Time{"api": now},
+			want:        now,
+		},
+		{
+			name:        "key behind the newest is fetched from its last sample",
+			lastSamples: map[string]time.Time{"api": now, "worker": now.Add(-10 * time.Minute)},
+			want:        now.Add(-10 * time.Minute),
+		},
+		{
+			name:        "key at the stale age is still fetched",
+			lastSamples: map[string]time.Time{"api": now, "worker": now.Add(-sketchStaleKeyAge)},
+			want:        now.Add(-sketchStaleKeyAge),
+		},
+		{
+			name: "stale keys are ignored",
+			lastSamples: map[string]time.Time{
+				"api":     now,
+				"worker":  now.Add(-5 * time.Minute),
+				"deleted": now.Add(-sketchStaleKeyAge - time.Minute),
+				"old":     now.Add(-48 * time.Hour),
+			},
+			want: now.Add(-5 * time.Minute),
+		},
+		{
+			name:        "only stale keys besides the newest",
+			lastSamples: map[string]time.Time{"api": now, "deleted": now.Add(-24 * time.Hour)},
+			want:        now,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := deltaStart(tt.lastSamples); !got.Equal(tt.want) {
+				t.Errorf("Expected the delta to start at %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
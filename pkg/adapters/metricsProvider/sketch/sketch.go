@@ -0,0 +1,94 @@
+// Package sketch keeps usage samples in mergeable log-bucketed histograms, the layout of DDSketch, so
+// percentiles can be updated incrementally and merged across slots, pods and restarts.
+package sketch
+
+import (
+	"math"
+	"slices"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/types"
+)
+
+// Buckets grow by 5% from 0.001, so percentiles are at most 5% above the real usage.
+const (
+	bucketRatio    = 1.05
+	minBucketValue = 0.001
+)
+
+func bucketIndex(value float64) int {
+	if value < minBucketValue {
+		return 0
+	}
+	return 1 + int(math.Floor(math.Log(value/minBucketValue)/math.Log(bucketRatio)))
+}
+
+func bucketUpperBound(index int) float64 {
+	return minBucketValue * math.Pow(bucketRatio, float64(index))
+}
+
+func Add(histogram *types.UsageHistogram, value float64) {
+	if histogram.Buckets == nil {
+		histogram.Buckets = make(map[int]float64)
+	}
+	histogram.Buckets[bucketIndex(value)]++
+	histogram.Count++
+	histogram.Max = max(histogram.Max, value)
+}
+
+func MergeInto(merged *types.UsageHistogram, histogram types.UsageHistogram) {
+	if merged.Buckets == nil {
+		merged.Buckets = make(map[int]float64)
+	}
+	for index, count := range histogram.Buckets {
+		merged.Buckets[index] += count
+	}
+	merged.Count += histogram.Count
+	merged.Max = max(merged.Max, histogram.Max)
+	if histogram.LastSampleAt.After(merged.LastSampleAt) {
+		merged.LastSampleAt = histogram.LastSampleAt
+	}
+}
+
+// MergeByKey merges the histograms of a resource whose slot starts at or after since, by key.
+func MergeByKey(histograms []types.UsageHistogram, resource types.UsageResource, since time.Time) map[string]*types.UsageHistogram {
+	merged := make(map[string]*types.UsageHistogram)
+	for _, histogram := range histograms {
+		if histogram.Resource != resource || histogram.Start.Before(since) {
+			continue
+		}
+		m, ok := merged[histogram.Key]
+		if !ok {
+			m = &types.UsageHistogram{Key: histogram.Key, Namespace: histogram.Namespace, Resource: resource}
+			merged[histogram.Key] = m
+		}
+		MergeInto(m, histogram)
+	}
+	return merged
+}
+
+// Quantile returns the upper bound of the bucket holding the q quantile, capped at the max sample.
+func Quantile(histogram *types.UsageHistogram, q float64) float64 {
+	if histogram.Count == 0 {
+		return 0
+	}
+	if q >= 1 {
+		return histogram.Max
+	}
+
+	indexes := make([]int, 0, len(histogram.Buckets))
+	for index := range histogram.Buckets {
+		indexes = append(indexes, index)
+	}
+	slices.Sort(indexes)
+
+	target := q * histogram.Count
+	seen := 0.0
+	for _, index := range indexes {
+		seen += histogram.Buckets[index]
+		if seen >= target {
+			return min(bucketUpperBound(index), histogram.Max)
+		}
+	}
+	return histogram.Max
+}
@@ -0,0 +1,101 @@
+package sketch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/types"
+)
+
+func newHistogram(values ...float64) *types.UsageHistogram {
+	histogram := &types.UsageHistogram{}
+	for _, value := range values {
+		Add(histogram, value)
+	}
+	return histogram
+}
+
+func TestQuantile(t *testing.T) {
+	hundred := make([]float64, 0, 100)
+	for i := 1; i <= 100; i++ {
+		hundred = append(hundred, float64(i))
+	}
+
+	tests := []struct {
+		name      string
+		histogram *types.UsageHistogram
+		q         float64
+		wantMin   float64
+		wantMax   float64
+	}{
+		{"empty histogram", newHistogram(), 0.5, 0, 0},
+		{"q=0 is the bucket of the smallest sample", newHistogram(0.5, 1, 2), 0, 0.5, 0.5 * bucketRatio},
+		{"q=1 is the max sample", newHistogram(0.5, 1, 2), 1, 2, 2},
+		{"q above 1 is the max sample", newHistogram(0.5, 1, 2), 1.5, 2, 2},
+		{"q=0.9 is at most one bucket above the sample", newHistogram(hundred...), 0.9, 90, 90 * bucketRatio},
+		{"samples below the smallest bucket are capped at the max", newHistogram(0.0002, 0.0005), 0.5, 0.0005, 0.0005},
+		{"samples below the smallest bucket share its upper bound", newHistogram(0.0005, 1), 0.5, minBucketValue, minBucketValue},
+		{"zero samples", newHistogram(0, 0), 0.5, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Quantile(tt.histogram, tt.q)
+			if got < tt.wantMin || got > tt.wantMax {
+				t.Errorf("Expected the %v quantile in [%v, %v], got %v", tt.q, tt.wantMin, tt.wantMax, got)
+			}
+		})
+	}
+}
+
+func TestMergeByKey(t *testing.T) {
+	since := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	slot := func(key string, resource types.UsageResource, start time.Time, values ...float64) types.UsageHistogram {
+		histogram := newHistogram(values...)
+		histogram.Key = key
+		histogram.Namespace = "default"
+		histogram.Resource = resource
+		histogram.Start = start
+		histogram.LastSampleAt = start.Add(59 * time.Minute)
+		return *histogram
+	}
+
+	merged := MergeByKey([]types.UsageHistogram{
+		// the slot before since is left out, the one starting at since is kept
+		slot("api", types.UsageResourceCPU, since.Add(-time.Hour), 5),
+		slot("api", types.UsageResourceCPU, since, 1, 2),
+		slot("api", types.UsageResourceCPU, since.Add(time.Hour), 3),
+		slot("api", types.UsageResourceMemory, since, 100),
+		slot("worker", types.UsageResourceCPU, since.Add(2*time.Hour), 0.5),
+		slot("cron", types.UsageResourceCPU, since.Add(-2*time.Hour), 4),
+	}, types.UsageResourceCPU, since)
+
+	tests := []struct {
+		key              string
+		wantCount        float64
+		wantMax          float64
+		wantLastSampleAt time.Time
+	}{
+		{"api", 3, 3, since.Add(time.Hour + 59*time.Minute)},
+		{"worker", 1, 0.5, since.Add(2*time.Hour + 59*time.Minute)},
+	}
+	if len(merged) != len(tests) {
+		t.Fatalf("Expected %d merged keys, got %d: %v", len(tests), len(merged), merged)
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			histogram, ok := merged[tt.key]
+			if !ok {
+				t.Fatalf("Expected a merged histogram for %s", tt.key)
+			}
+			if histogram.Resource != types.UsageResourceCPU || histogram.Namespace != "default" {
+				t.Errorf("Expected a default cpu histogram, got %s %s", histogram.Namespace, histogram.Resource)
+			}
+			if histogram.Count != tt.wantCount || histogram.Max != tt.wantMax {
+				t.Errorf("Expected %v samples up to %v, got %v up to %v", tt.wantCount, tt.wantMax, histogram.Count, histogram.Max)
+			}
+			if !histogram.LastSampleAt.Equal(tt.wantLastSampleAt) {
+				t.Errorf("Expected the last sample at %v, got %v", tt.wantLastSampleAt, histogram.LastSampleAt)
+			}
+		})
+	}
+}
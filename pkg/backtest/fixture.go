@@ -0,0 +1,104 @@
+package backtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Fixture is a recording of the usage of workloads, one sample per step, that a backtest replays.
+type Fixture struct {
+	Start       time.Time `json:"start"`
+	StepSeconds int       `json:"stepSeconds"`
+	// NodeAllocatableCPU and NodeAllocatableMemory size the node all replicas are simulated on. When
+	// zero, the node fits exactly the original requests of all replicas.
+	NodeAllocatableCPU    float64           `json:"nodeAllocatableCPU,omitempty"`
+	NodeAllocatableMemory float64           `json:"nodeAllocatableMemory,omitempty"`
+	Workloads             []FixtureWorkload `json:"workloads"`
+}
+
+type FixtureWorkload struct {
+	Kind       string             `json:"kind"`
+	Namespace  string             `json:"namespace"`
+	Name       string             `json:"name"`
+	Replicas   int                `json:"replicas"`
+	Containers []FixtureContainer `json:"containers"`
+}
+
+// FixtureContainer holds the original resources of a container, cpu in cores and memory in MB, and
+// its usage at every step, the max across the replicas like the prometheus queries of the stats.
+type FixtureContainer struct {
+	Name          string    `json:"name"`
+	CPURequest    float64   `json:"cpuRequest"`
+	CPULimit      float64   `json:"cpuLimit,omitempty"`
+	MemoryRequest float64   `json:"memoryRequest"`
+	MemoryLimit   float64   `json:"memoryLimit,omitempty"`
+	CPU           []float64 `json:"cpu"`
+	Memory        []float64 `json:"memory"`
+}
+
+func (f *Fixture) Step() time.Duration {
+	return time.Duration(f.StepSeconds) * time.Second
+}
+
+// Steps returns the number of steps every container has samples for.
+func (f *Fixture) Steps() int {
+	steps := -1
+	for _, workload := range f.Workloads {
+		for _, container := range workload.Containers {
+			n := min(len(container.CPU), len(container.Memory))
+			if steps < 0 || n < steps {
+				steps = n
+			}
+		}
+	}
+	return max(steps, 0)
+}
+
+func (f *Fixture) Validate() error {
+	if f.StepSeconds <= 0 {
+		return fmt.Errorf("stepSeconds must be positive, got %d", f.StepSeconds)
+	}
+	if len(f.Workloads) == 0 {
+		return fmt.Errorf("fixture has no workloads")
+	}
+	for _, workload := range f.Workloads {
+		if workload.Kind == "" || workload.Namespace == "" || workload.Name == "" {
+			return fmt.Errorf("workload %s:%s:%s must have a kind, namespace and name", workload.Kind, workload.Namespace, workload.Name)
+		}
+		if workload.Replicas <= 0 {
+			return fmt.Errorf("workload %s/%s must have at least one replica", workload.Namespace, workload.Name)
+		}
+		if len(workload.Containers) == 0 {
+			return fmt.Errorf("workload %s/%s has no containers", workload.Namespace, workload.Name)
+		}
+	}
+	return nil
+}
+
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture: %w", err)
+	}
+	if err := fixture.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid fixture: %w", err)
+	}
+	return &fixture, nil
+}
+
+func (f *Fixture) Save(path string) error {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return fmt.Errorf("failed to marshal fixture: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write fixture: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/logging"
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// maxPointsPerQuery keeps every range query under the 11000 points prometheus returns per series.
+const maxPointsPerQuery = 10000
+
+type RecordOptions struct {
+	Namespaces []string
+	Start      time.Time
+	End        time.Time
+	Step       time.Duration
+}
+
+// Record builds a fixture from the usage stored in prometheus, using the same usage expressions as the
+// stats, and the current resources and replicas of the workloads. Missing samples repeat the previous
+// sample of the container.
+func Record(ctx context.Context, kubeClient *kubernetes.Clientset, promClient v1.API, opts RecordOptions) (*Fixture, error) {
+	if opts.Step <= 0 || !opts.End.After(opts.Start) {
+		return nil, fmt.Errorf("invalid recording range %s - %s with step %s", opts.Start, opts.End, opts.Step)
+	}
+	start := opts.Start.Truncate(opts.Step)
+	steps := int(opts.End.Sub(start)/opts.Step) + 1
+
+	fixture := &Fixture{
+		Start:       start.UTC(),
+		StepSeconds: int(opts.Step.Seconds()),
+	}
+	for _, namespace := range opts.Namespaces {
+		cpuQuery := utils.BuildBatchCoreCPUExpression(namespace, false)
+		cpuSeries, err := queryRangeSeries(ctx, promClient, cpuQuery, start, opts.Step, steps)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record cpu usage of namespace %s: %w", namespace, err)
+		}
+		memoryQuery := utils.EncloseWithinMemoryCleanupFunction(utils.BuildBatchMemoryUsageExpression(namespace), utils.MemoryDecimalPlaces)
+		memorySeries, err := queryRangeSeries(ctx, promClient, memoryQuery, start, opts.Step, steps)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record memory usage of namespace %s: %w", namespace, err)
+		}
+
+		workloads := recordWorkloads(ctx, kubeClient, cpuSeries, memorySeries)
+		logging.Infof(ctx, "Recorded %d workloads of namespace %s", len(workloads), namespace)
+		fixture.Workloads = append(fixture.Workloads, workloads...)
+	}
+	if err := fixture.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid recording: %w", err)
+	}
+	return fixture, nil
+}
+
+// queryRangeSeries returns the samples of every workload container, one per step, in chunks small
+// enough for prometheus.
+func queryRangeSeries(ctx context.Context, promClient v1.API, query string, start time.Time, step time.Duration, steps int) (map[string][]float64, error) {
+	result := make(map[string][]float64)
+	for offset := 0; offset < steps; offset += maxPointsPerQuery {
+		r := v1.Range{
+			Start: start.Add(time.Duration(offset) * step),
+			End:   start.Add(time.Duration(min(offset+maxPointsPerQuery, steps)-1) * step),
+			Step:  step,
+		}
+		logging.Debugf(ctx, "Querying prometheus from %s to %s with query: %s", r.Start, r.End, utils.CompressQueryForLogging(query))
+		value, _, err := promClient.QueryRange(ctx, query, r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query prometheus: %w", err)
+		}
+		matrix, ok := value.(model.Matrix)
+		if !ok {
+			return nil, fmt.Errorf("unable to convert range query result to matrix")
+		}
+		for _, series := range utils.ConvertMatrixToSimpleTimeSeriesData(matrix) {
+			samples, ok := result[series.EntityName]
+			if !ok {
+				samples = make([]float64, steps)
+				for i := range samples {
+					samples[i] = math.NaN()
+				}
+				result[series.EntityName] = samples
+			}
+			for i, ts := range series.Timestamps {
+				t, err := utils.ParseTimeSeriesTimestamp(ts)
+				if err != nil {
+					continue
+				}
+				slot := int(t.Sub(start) / step)
+				if slot >= 0 && slot < steps {
+					samples[slot] = series.Values[i]
+				}
+			}
+		}
+	}
+	for _, samples := range result {
+		fillMissing(samples)
+	}
+	return result, nil
+}
+
+func fillMissing(samples []float64) {
+	previous := 0.0
+	for i, v := range samples {
+		if math.IsNaN(v) {
+			samples[i] = previous
+			continue
+		}
+		previous = v
+	}
+}
+
+func recordWorkloads(ctx context.Context, kubeClient *kubernetes.Clientset, cpuSeries, memorySeries map[string][]float64) []FixtureWorkload {
+	containerKeysByWorkload := make(map[string][]string)
+	for key := range cpuSeries {
+		if _, ok := memorySeries[key]; !ok {
+			continue
+		}
+		kind, namespace, name, _, ok := utils.ParseWorkloadContainerKey(key)
+		if !ok {
+			continue
+		}
+		workloadKey := utils.GetWorkloadKey(kind, namespace, name)
+		containerKeysByWorkload[workloadKey] = append(containerKeysByWorkload[workloadKey], key)
+	}
+
+	workloadKeys := make([]string, 0, len(containerKeysByWorkload))
+	for workloadKey := range containerKeysByWorkload {
+		workloadKeys = append(workloadKeys, workloadKey)
+	}
+	sort.Strings(workloadKeys)
+
+	workloads := make([]FixtureWorkload, 0, len(workloadKeys))
+	for _, workloadKey := range workloadKeys {
+		kind, namespace, name, _ := utils.ParseWorkloadKey(workloadKey)
+		workloadObj, err := utils.GetWorkloadObject(ctx, kubeClient, nil, kind, namespace, name)
+		if err != nil {
+			logging.Warnf(ctx, "Skipping workload %s, could not get it: %v", workloadKey, err)
+			continue
+		}
+		replicas := 1
+		if selector, err := workloadObj.GetSelector(); err == nil {
+			if pods, err := utils.GetPods(ctx, kubeClient, namespace, selector); err == nil && len(pods.Items) > 0 {
+				replicas = len(pods.Items)
+			}
+		}
+
+		workload := FixtureWorkload{Kind: kind, Namespace: namespace, Name: name, Replicas: replicas}
+		for _, spec := range workloadObj.GetContainerSpecs(ctx, kubeClient) {
+			key := utils.GetWorkloadContainerKey(kind, namespace, name, spec.Name)
+			if _, ok := cpuSeries[key]; !ok {
+				continue
+			}
+			if _, ok := memorySeries[key]; !ok {
+				continue
+			}
+			workload.Containers = append(workload.Containers, newFixtureContainer(spec, cpuSeries[key], memorySeries[key]))
+		}
+		if len(workload.Containers) == 0 {
+			continue
+		}
+		workloads = append(workloads, workload)
+	}
+	return workloads
+}
+
+func newFixtureContainer(spec corev1.Container, cpu, memory []float64) FixtureContainer {
+	container := FixtureContainer{Name: spec.Name, CPU: cpu, Memory: memory}
+	if q, ok := spec.Resources.Requests[corev1.ResourceCPU]; ok {
+		container.CPURequest = float64(q.MilliValue()) / 1000.0
+	}
+	if q, ok := spec.Resources.Limits[corev1.ResourceCPU]; ok {
+		container.CPULimit = float64(q.MilliValue()) / 1000.0
+	}
+	if q, ok := spec.Resources.Requests[corev1.ResourceMemory]; ok {
+		container.MemoryRequest = float64(q.Value()) / utils.BytesPerMB
+	}
+	if q, ok := spec.Resources.Limits[corev1.ResourceMemory]; ok {
+		container.MemoryLimit = float64(q.Value()) / utils.BytesPerMB
+	}
+	return container
+}
@@ -0,0 +1,54 @@
+package backtest
+
+import "time"
+
+type Report struct {
+	Strategy   string    `json:"strategy"`
+	Forecaster string    `json:"forecaster"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	// Total sums the reports of all workloads.
+	Total     WorkloadReport   `json:"total"`
+	Workloads []WorkloadReport `json:"workloads"`
+}
+
+// WorkloadReport scores the recommendations of a workload over the simulated time after the warm up.
+// Requests are summed over the replicas, cpu is in cores and memory in MB.
+type WorkloadReport struct {
+	Workload string `json:"workload,omitempty"`
+
+	// UnderProvisionedCPUSeconds and UnderProvisionedMemorySeconds are the time a container of the
+	// workload used more than it requested.
+	UnderProvisionedCPUSeconds    float64 `json:"underProvisionedCPUSeconds"`
+	UnderProvisionedMemorySeconds float64 `json:"underProvisionedMemorySeconds"`
+	// ThrottledCPUSeconds estimates the cpu time the workload did not get, above its cpu limit or above
+	// its request while the node was short of cpu.
+	ThrottledCPUSeconds float64 `json:"throttledCPUSeconds"`
+	// OOMKills counts the times the memory usage went above the memory limit. OOMRiskEvents also counts
+	// the times the usage went above the request while the node was short of memory.
+	OOMKills      int `json:"oomKills"`
+	OOMRiskEvents int `json:"oomRiskEvents"`
+	Evictions     int `json:"evictions"`
+
+	OriginalCPURequest    float64 `json:"originalCPURequest"`
+	AverageCPURequest     float64 `json:"averageCPURequest"`
+	CoresSaved            float64 `json:"coresSaved"`
+	OriginalMemoryRequest float64 `json:"originalMemoryRequest"`
+	AverageMemoryRequest  float64 `json:"averageMemoryRequest"`
+	MemorySavedMB         float64 `json:"memorySavedMB"`
+}
+
+func (r *WorkloadReport) add(other WorkloadReport) {
+	r.UnderProvisionedCPUSeconds += other.UnderProvisionedCPUSeconds
+	r.UnderProvisionedMemorySeconds += other.UnderProvisionedMemorySeconds
+	r.ThrottledCPUSeconds += other.ThrottledCPUSeconds
+	r.OOMKills += other.OOMKills
+	r.OOMRiskEvents += other.OOMRiskEvents
+	r.Evictions += other.Evictions
+	r.OriginalCPURequest += other.OriginalCPURequest
+	r.AverageCPURequest += other.AverageCPURequest
+	r.CoresSaved += other.CoresSaved
+	r.OriginalMemoryRequest += other.OriginalMemoryRequest
+	r.AverageMemoryRequest += other.AverageMemoryRequest
+	r.MemorySavedMB += other.MemorySavedMB
+}
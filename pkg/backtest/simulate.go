@@ -0,0 +1,551 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/config"
+	"github.com/truefoundry/cruisekube/pkg/logging"
+	"github.com/truefoundry/cruisekube/pkg/task"
+	"github.com/truefoundry/cruisekube/pkg/task/applystrategies"
+	"github.com/truefoundry/cruisekube/pkg/task/forecasters"
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+	"github.com/truefoundry/cruisekube/pkg/types"
+)
+
+const (
+	DefaultStatsInterval = 15 * time.Minute
+	DefaultWarmUp        = 24 * time.Hour
+
+	simulatedNodeName = "backtest"
+	// sevenDayStatsRefreshInterval is how often the 7 day percentiles are recomputed, they barely move
+	// between two stats runs and sorting a week of samples every run is slow.
+	sevenDayStatsRefreshInterval = time.Hour
+	predictionStep               = time.Hour
+)
+
+type Config struct {
+	Strategy   string
+	Settings   config.RecommendationSettings
+	Forecaster forecasters.ForecasterConfig
+	Calendar   utils.CalendarConfig
+	// Stabilization damps the recommendations like the applyRecommendation task does.
+	Stabilization utils.StabilizationConfig
+	// StatsInterval is how often the stats are rebuilt and the recommendations applied.
+	StatsInterval time.Duration
+	// WarmUp is the recorded time used as history only, before the first recommendation.
+	WarmUp time.Duration
+}
+
+type simulatedContainer struct {
+	fixture *FixtureContainer
+
+	cpuRequest    float64
+	cpuLimit      float64
+	memoryRequest float64
+	memoryLimit   float64
+
+	overMemoryLimit bool
+	atMemoryRisk    bool
+	oomSteps        []int
+	oomLimits       []float64
+
+	sevenDayRefreshedAt int
+	cpu7Day             utils.CPU7DayStats
+	memory7DayMax       float64
+}
+
+type simulatedWorkload struct {
+	fixture    *FixtureWorkload
+	info       utils.WorkloadInfo
+	resources  []utils.OriginalContainerResources
+	containers []*simulatedContainer
+	stat       *utils.WorkloadStat
+	report     WorkloadReport
+
+	cpuRequestSum    float64
+	memoryRequestSum float64
+}
+
+type simulation struct {
+	fixture           *Fixture
+	config            Config
+	strategy          utils.OptimizationStrategy
+	forecaster        utils.Forecaster
+	calendars         *utils.PredictionCalendars
+	memoryLimitPolicy utils.MemoryLimitPolicy
+	stabilizer        *utils.RecommendationStabilizer
+	workloads         []*simulatedWorkload
+
+	step              time.Duration
+	allocatableCPU    float64
+	allocatableMemory float64
+}
+
+// Run replays the fixture through the stats and the strategy. Every stats interval it builds the stats
+// from the samples recorded so far, like the createStats task, and applies the recommendations of the
+// strategy, like the applyRecommendation task. Every sample after the warm up is then scored against
+// the requests and limits in place at that time. The recorded usage is taken as the demand, so usage
+// capped by the original limits is not recovered.
+func Run(ctx context.Context, fixture *Fixture, cfg Config) (*Report, error) {
+	if err := fixture.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid fixture: %w", err)
+	}
+	if cfg.StatsInterval <= 0 {
+		cfg.StatsInterval = DefaultStatsInterval
+	}
+	if cfg.WarmUp <= 0 {
+		cfg.WarmUp = DefaultWarmUp
+	}
+	strategy, err := applystrategies.GetStrategy(ctx, cfg.Strategy, cfg.Settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get strategy: %w", err)
+	}
+	forecaster, err := forecasters.GetForecaster(cfg.Forecaster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get forecaster: %w", err)
+	}
+	calendars, err := utils.NewPredictionCalendars(cfg.Calendar, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create prediction calendars: %w", err)
+	}
+	if err := cfg.Stabilization.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid stabilization config: %w", err)
+	}
+
+	s := newSimulation(fixture, cfg, strategy, forecaster)
+	s.calendars = calendars
+	steps := fixture.Steps()
+	warmUpSteps := max(s.stepsIn(cfg.WarmUp), 1)
+	if warmUpSteps >= steps {
+		return nil, fmt.Errorf("fixture has %d steps of %s, not more than the %s warm up", steps, s.step, cfg.WarmUp)
+	}
+	statsEvery := max(s.stepsIn(cfg.StatsInterval), 1)
+
+	for i := warmUpSteps; i < steps; i++ {
+		if (i-warmUpSteps)%statsEvery == 0 {
+			s.recommend(ctx, i)
+		}
+		s.score(i)
+	}
+	return s.buildReport(warmUpSteps, steps), nil
+}
+
+func newSimulation(fixture *Fixture, cfg Config, strategy utils.OptimizationStrategy, forecaster utils.Forecaster) *simulation {
+	s := &simulation{
+		fixture:           fixture,
+		config:            cfg,
+		strategy:          strategy,
+		forecaster:        forecaster,
+		memoryLimitPolicy: utils.NewMemoryLimitPolicy(cfg.Settings),
+		stabilizer:        utils.NewRecommendationStabilizer(cfg.Stabilization),
+		step:              fixture.Step(),
+		allocatableCPU:    fixture.NodeAllocatableCPU,
+		allocatableMemory: fixture.NodeAllocatableMemory,
+	}
+
+	var requestedCPU, requestedMemory float64
+	for i := range fixture.Workloads {
+		fixtureWorkload := &fixture.Workloads[i]
+		workload := &simulatedWorkload{
+			fixture: fixtureWorkload,
+			info: utils.WorkloadInfo{
+				Kind:      fixtureWorkload.Kind,
+				Namespace: fixtureWorkload.Namespace,
+				Name:      fixtureWorkload.Name,
+			},
+		}
+		workload.report.Workload = utils.GetWorkloadKey(fixtureWorkload.Kind, fixtureWorkload.Namespace, fixtureWorkload.Name)
+		replicas := float64(fixtureWorkload.Replicas)
+		for j := range fixtureWorkload.Containers {
+			fixtureContainer := &fixtureWorkload.Containers[j]
+			workload.containers = append(workload.containers, &simulatedContainer{
+				fixture:             fixtureContainer,
+				cpuRequest:          fixtureContainer.CPURequest,
+				cpuLimit:            fixtureContainer.CPULimit,
+				memoryRequest:       fixtureContainer.MemoryRequest,
+				memoryLimit:         fixtureContainer.MemoryLimit,
+				sevenDayRefreshedAt: -1,
+			})
+			workload.resources = append(workload.resources, utils.OriginalContainerResources{
+				Name:          fixtureContainer.Name,
+				Type:          types.AppContainer,
+				CPURequest:    fixtureContainer.CPURequest,
+				CPULimit:      fixtureContainer.CPULimit,
+				MemoryRequest: fixtureContainer.MemoryRequest,
+				MemoryLimit:   fixtureContainer.MemoryLimit,
+			})
+			workload.report.OriginalCPURequest += fixtureContainer.CPURequest * replicas
+			workload.report.OriginalMemoryRequest += fixtureContainer.MemoryRequest * replicas
+		}
+		requestedCPU += workload.report.OriginalCPURequest
+		requestedMemory += workload.report.OriginalMemoryRequest
+		s.workloads = append(s.workloads, workload)
+	}
+	if s.allocatableCPU <= 0 {
+		s.allocatableCPU = requestedCPU
+	}
+	if s.allocatableMemory <= 0 {
+		s.allocatableMemory = requestedMemory
+	}
+	return s
+}
+
+func (s *simulation) stepsIn(d time.Duration) int {
+	return int(d / s.step)
+}
+
+// recommend builds the stats as of the given step and applies the recommendations of the strategy.
+func (s *simulation) recommend(ctx context.Context, i int) {
+	now := s.fixture.Start.Add(time.Duration(i) * s.step)
+
+	nsVsContainerMetrics := make(utils.NamespaceVsContainerMetrics)
+	nsVsWorkloadMetrics := make(utils.NamespaceVsWorkloadMetrics)
+	cpuSeries := make(map[string][]utils.SimpleTimeSeriesData)
+	memorySeries := make(map[string][]utils.SimpleTimeSeriesData)
+	for _, workload := range s.workloads {
+		namespace := workload.info.Namespace
+		workloadKey := workload.report.Workload
+		if _, ok := nsVsContainerMetrics[namespace]; !ok {
+			nsVsContainerMetrics[namespace] = make(utils.WorkloadKeyVsContainerMetrics)
+			nsVsWorkloadMetrics[namespace] = make(utils.WorkloadKeyVsWorkloadMetrics)
+		}
+		containerMetrics := make(utils.ContainerNameVsContainerMetrics)
+		for _, container := range workload.containers {
+			containerMetrics[container.fixture.Name] = s.containerMetrics(container, i, workload.fixture.Replicas)
+			containerKey := utils.GetWorkloadContainerKey(workload.info.Kind, namespace, workload.info.Name, container.fixture.Name)
+			cpuSeries[namespace] = append(cpuSeries[namespace], s.hourlyMaxSeries(containerKey, container.fixture.CPU, i, now))
+			memorySeries[namespace] = append(memorySeries[namespace], s.hourlyMaxSeries(containerKey, container.fixture.Memory, i, now))
+		}
+		nsVsContainerMetrics[namespace][workloadKey] = containerMetrics
+		nsVsWorkloadMetrics[namespace][workloadKey] = utils.WorkloadMetrics{MedianReplicas: float64(workload.fixture.Replicas)}
+	}
+
+	horizon := s.config.Forecaster.GetHorizonSteps()
+	cpuPredictions := make(map[string]utils.SimplePrediction)
+	memoryPredictions := make(map[string]utils.SimplePrediction)
+	for namespace := range nsVsContainerMetrics {
+		calendar := s.calendars.ForNamespace(namespace)
+		for _, prediction := range utils.ComputeTimeSeriesPredictions(ctx, cpuSeries[namespace], s.forecaster, horizon, now, calendar) {
+			cpuPredictions[prediction.EntityName] = prediction.ToSimplePrediction()
+		}
+		for _, prediction := range utils.ComputeTimeSeriesPredictions(ctx, memorySeries[namespace], s.forecaster, horizon, now, calendar) {
+			memoryPredictions[prediction.EntityName] = prediction.ToSimplePrediction()
+		}
+	}
+
+	availableCPU, availableMemory := s.allocatableCPU, s.allocatableMemory
+	podInfos := make([]utils.PodInfo, 0)
+	for _, workload := range s.workloads {
+		workload.stat = task.BuildWorkloadStatFromMetrics(
+			ctx,
+			workload.info,
+			workload.resources,
+			s.fixture.Start,
+			now,
+			nsVsContainerMetrics,
+			nsVsWorkloadMetrics,
+			nil,
+			nil,
+			nil,
+			cpuPredictions,
+			memoryPredictions,
+			nil,
+		)
+		for replica := 0; replica < workload.fixture.Replicas; replica++ {
+			podInfo := workload.podInfo(replica)
+			if podInfo.Stats == nil || podInfo.IsGuaranteedPod() {
+				availableCPU -= podInfo.RequestedCPU
+				availableMemory -= podInfo.RequestedMemory
+				continue
+			}
+			podInfos = append(podInfos, podInfo)
+		}
+	}
+
+	result, err := s.strategy.OptimizeNode(nil, map[string]*types.WorkloadOverrideInfo{}, utils.NodeOptimizationData{
+		NodeName:          simulatedNodeName,
+		AllocatableCPU:    availableCPU,
+		AllocatableMemory: availableMemory,
+		PodInfos:          podInfos,
+	})
+	if err != nil {
+		logging.Errorf(ctx, "Error optimizing the simulated node at %s: %v", now, err)
+		return
+	}
+	s.stabilizer.StartRun(now, nil)
+	s.apply(result)
+	s.stabilizer.FinishRun()
+}
+
+func (w *simulatedWorkload) podInfo(replica int) utils.PodInfo {
+	podInfo := utils.PodInfo{
+		Namespace:    w.info.Namespace,
+		Name:         fmt.Sprintf("%s-%d", w.info.Name, replica),
+		WorkloadKind: w.info.Kind,
+		WorkloadName: w.info.Name,
+		Stats:        w.stat,
+	}
+	if w.stat != nil {
+		podInfo.ContinuousOptimization = w.stat.ContinuousOptimization
+	}
+	for _, container := range w.containers {
+		podInfo.RequestedCPU += container.cpuRequest
+		podInfo.RequestedMemory += container.memoryRequest
+		podInfo.LimitCPU += container.cpuLimit
+		podInfo.LimitMemory += container.memoryLimit
+		podInfo.ContainerResources = append(podInfo.ContainerResources, &utils.ContainerResources{
+			Name:          container.fixture.Name,
+			CPURequest:    container.cpuRequest,
+			CPULimit:      container.cpuLimit,
+			MemoryRequest: container.memoryRequest,
+			MemoryLimit:   container.memoryLimit,
+		})
+	}
+	return podInfo
+}
+
+// apply sets the recommendations like the applyRecommendation task does. All replicas of a workload
+// share their usage, so they get the largest recommendation of the replicas.
+func (s *simulation) apply(result utils.OptimizationResult) {
+	type recommendation struct {
+		cpu    float64
+		memory float64
+	}
+	recommendations := make(map[string]map[string]*recommendation)
+	evictedPods := make(map[string]map[string]bool)
+	for _, rec := range result.PodContainerRecommendations {
+		workloadKey := rec.PodInfo.Stats.WorkloadIdentifier
+		if recommendations[workloadKey] == nil {
+			recommendations[workloadKey] = make(map[string]*recommendation)
+			evictedPods[workloadKey] = make(map[string]bool)
+		}
+		current, ok := recommendations[workloadKey][rec.ContainerName]
+		if !ok {
+			current = &recommendation{}
+			recommendations[workloadKey][rec.ContainerName] = current
+		}
+		current.cpu = max(current.cpu, rec.CPU)
+		current.memory = max(current.memory, rec.Memory)
+		if rec.Evict {
+			evictedPods[workloadKey][rec.PodInfo.Name] = true
+		}
+	}
+
+	for _, workload := range s.workloads {
+		workloadKey := workload.report.Workload
+		workload.report.Evictions += len(evictedPods[workloadKey])
+		for _, container := range workload.containers {
+			rec, ok := recommendations[workloadKey][container.fixture.Name]
+			if !ok {
+				continue
+			}
+			cpuKey := utils.StabilizationKey{WorkloadID: workloadKey, ContainerName: container.fixture.Name, Resource: types.ApplyRunResourceCPU}
+			cpu := min(utils.EnforceMinimumCPU(s.stabilizer.Smooth(cpuKey, rec.cpu)), task.CPUClampValue)
+			cpu, _ = s.stabilizer.Stabilize(cpuKey, container.cpuRequest, cpu)
+			if math.Abs(cpu-container.cpuRequest) >= 0.001 {
+				s.stabilizer.RecordResize(cpuKey)
+			}
+			container.cpuRequest = cpu
+			if container.cpuLimit > 0 {
+				// a cpu limit cannot be removed in place, the apply task raises it to the node allocatable
+				container.cpuLimit = s.allocatableCPU
+			}
+
+			containerStat, err := workload.stat.GetContainerStats(container.fixture.Name)
+			if err != nil {
+				continue
+			}
+			memoryKey := utils.StabilizationKey{WorkloadID: workloadKey, ContainerName: container.fixture.Name, Resource: types.ApplyRunResourceMemory}
+			smoothedMemory := s.stabilizer.Smooth(memoryKey, rec.memory)
+			if container.memoryLimit > 0 && container.memoryRequest == container.memoryLimit {
+				memory := utils.EnforceMinimumMemory(2 * max(containerStat.Memory7Day.Max, containerStat.MemoryStats.OOMMemory))
+				memory, _ = s.stabilizer.Stabilize(memoryKey, container.memoryRequest, memory)
+				if memory >= container.memoryLimit {
+					if memory != container.memoryRequest {
+						s.stabilizer.RecordResize(memoryKey)
+					}
+					container.memoryRequest, container.memoryLimit = memory, memory
+				}
+				continue
+			}
+			memory, _ := s.stabilizer.Stabilize(memoryKey, container.memoryRequest, utils.EnforceMinimumMemory(smoothedMemory))
+			if memory != container.memoryRequest {
+				s.stabilizer.RecordResize(memoryKey)
+			}
+			container.memoryRequest = memory
+			container.memoryLimit = s.memoryLimitPolicy.InPlaceLimit(container.memoryRequest, container.memoryLimit, containerStat)
+		}
+	}
+}
+
+// score accounts one sample of every container against its current requests and limits.
+func (s *simulation) score(i int) {
+	stepSeconds := s.step.Seconds()
+
+	var cpuDemand, memoryDemand, cpuExcess float64
+	for _, workload := range s.workloads {
+		replicas := float64(workload.fixture.Replicas)
+		for _, container := range workload.containers {
+			cpuDemand += container.fixture.CPU[i] * replicas
+			memoryDemand += container.fixture.Memory[i] * replicas
+			cpuExcess += max(container.usableCPU(i)-container.cpuRequest, 0) * replicas
+		}
+	}
+	cpuShortage := max(cpuDemand-s.allocatableCPU, 0)
+	memoryShortage := memoryDemand > s.allocatableMemory
+
+	for _, workload := range s.workloads {
+		replicas := float64(workload.fixture.Replicas)
+		var cpuUnderProvisioned, memoryUnderProvisioned bool
+		for _, container := range workload.containers {
+			cpu, memory := container.fixture.CPU[i], container.fixture.Memory[i]
+			usableCPU := container.usableCPU(i)
+
+			throttled := cpu - usableCPU
+			if excess := usableCPU - container.cpuRequest; excess > 0 {
+				cpuUnderProvisioned = true
+				if cpuShortage > 0 && cpuExcess > 0 {
+					// the cpu the node is short of is taken from the containers above their requests, in
+					// proportion to how far above they are
+					throttled += min(excess, cpuShortage*excess/cpuExcess)
+				}
+			}
+			workload.report.ThrottledCPUSeconds += throttled * replicas * stepSeconds
+
+			overLimit := container.memoryLimit > 0 && memory > container.memoryLimit
+			if overLimit && !container.overMemoryLimit {
+				workload.report.OOMKills++
+				container.oomSteps = append(container.oomSteps, i)
+				container.oomLimits = append(container.oomLimits, container.memoryLimit)
+			}
+			container.overMemoryLimit = overLimit
+
+			overRequest := memory > container.memoryRequest
+			atRisk := overLimit || (overRequest && memoryShortage)
+			if atRisk && !container.atMemoryRisk {
+				workload.report.OOMRiskEvents++
+			}
+			container.atMemoryRisk = atRisk
+			memoryUnderProvisioned = memoryUnderProvisioned || overRequest
+
+			workload.cpuRequestSum += container.cpuRequest * replicas
+			workload.memoryRequestSum += container.memoryRequest * replicas
+		}
+		if cpuUnderProvisioned {
+			workload.report.UnderProvisionedCPUSeconds += stepSeconds
+		}
+		if memoryUnderProvisioned {
+			workload.report.UnderProvisionedMemorySeconds += stepSeconds
+		}
+	}
+}
+
+func (c *simulatedContainer) usableCPU(i int) float64 {
+	if c.cpuLimit > 0 {
+		return min(c.fixture.CPU[i], c.cpuLimit)
+	}
+	return c.fixture.CPU[i]
+}
+
+func (s *simulation) buildReport(warmUpSteps, steps int) *Report {
+	report := &Report{
+		Strategy:   s.strategy.GetName(),
+		Forecaster: s.forecaster.GetName(),
+		Start:      s.fixture.Start.Add(time.Duration(warmUpSteps) * s.step),
+		End:        s.fixture.Start.Add(time.Duration(steps) * s.step),
+	}
+	scoredSteps := float64(steps - warmUpSteps)
+	for _, workload := range s.workloads {
+		workload.report.AverageCPURequest = workload.cpuRequestSum / scoredSteps
+		workload.report.CoresSaved = workload.report.OriginalCPURequest - workload.report.AverageCPURequest
+		workload.report.AverageMemoryRequest = workload.memoryRequestSum / scoredSteps
+		workload.report.MemorySavedMB = workload.report.OriginalMemoryRequest - workload.report.AverageMemoryRequest
+		report.Workloads = append(report.Workloads, workload.report)
+		report.Total.add(workload.report)
+	}
+	return report
+}
+
+// containerMetrics computes the metrics the prometheus queries of the stats return, over the samples
+// before the given step.
+func (s *simulation) containerMetrics(c *simulatedContainer, i int, replicas int) *utils.ContainerMetrics {
+	cpu := sortedWindow(c.fixture.CPU, i, s.stepsIn(utils.CPULookbackWindow))
+	memory := sortedWindow(c.fixture.Memory, i, s.stepsIn(utils.MemoryLookbackWindow))
+
+	if c.sevenDayRefreshedAt < 0 || i-c.sevenDayRefreshedAt >= s.stepsIn(sevenDayStatsRefreshInterval) {
+		cpu7Day := sortedWindow(c.fixture.CPU, i, s.stepsIn(utils.CPU7DayLookbackWindow))
+		c.cpu7Day = utils.CPU7DayStats{
+			P50: ceilCPU(quantile(cpu7Day, 0.50)),
+			P75: ceilCPU(quantile(cpu7Day, 0.75)),
+			P90: ceilCPU(quantile(cpu7Day, 0.90)),
+			P99: ceilCPU(quantile(cpu7Day, 0.99)),
+			Max: ceilCPU(quantile(cpu7Day, 1.0)),
+		}
+		c.memory7DayMax = quantile(sortedWindow(c.fixture.Memory, i, s.stepsIn(utils.Memory7DayLookbackWindow)), 1.0)
+		c.sevenDayRefreshedAt = i
+	}
+
+	oomMemory := 0.0
+	for j, oomStep := range c.oomSteps {
+		if oomStep >= i-s.stepsIn(utils.MemoryLookbackWindow) {
+			oomMemory = max(oomMemory, c.oomLimits[j])
+		}
+	}
+
+	return &utils.ContainerMetrics{
+		CPUP50:         ceilCPU(quantile(cpu, 0.50)),
+		CPUP75:         ceilCPU(quantile(cpu, 0.75)),
+		CPUMax:         ceilCPU(quantile(cpu, 1.0)),
+		MemoryP75:      quantile(memory, 0.75),
+		MemoryMax:      quantile(memory, 1.0),
+		OOMMemory:      oomMemory,
+		CPU7Day:        c.cpu7Day,
+		Memory7Day:     utils.Memory7DayStats{Max: c.memory7DayMax},
+		MedianReplicas: float64(replicas),
+		HasCPUData:     true,
+		HasMemoryData:  true,
+	}
+}
+
+// hourlyMaxSeries returns the max of every hour of the prediction lookback before now, like the range
+// query of the simple predictions.
+func (s *simulation) hourlyMaxSeries(entityName string, samples []float64, i int, now time.Time) utils.SimpleTimeSeriesData {
+	series := utils.SimpleTimeSeriesData{EntityName: entityName}
+	hourSteps := max(s.stepsIn(predictionStep), 1)
+	for k := int(utils.ForecastLookbackWindow / predictionStep); k >= 0; k-- {
+		end := i - k*hourSteps
+		if end <= 0 {
+			continue
+		}
+		series.Timestamps = append(series.Timestamps, now.Add(-time.Duration(k)*predictionStep).UTC().Format(time.RFC3339))
+		series.Values = append(series.Values, slices.Max(samples[max(end-hourSteps, 0):end]))
+	}
+	return series
+}
+
+// sortedWindow returns the sorted samples of the window ending before step i.
+func sortedWindow(samples []float64, i, window int) []float64 {
+	sorted := slices.Clone(samples[max(i-max(window, 1), 0):i])
+	slices.Sort(sorted)
+	return sorted
+}
+
+// quantile interpolates between the closest ranks like quantile_over_time.
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := q * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	weight := rank - float64(lower)
+	return sorted[lower]*(1-weight) + sorted[upper]*weight
+}
+
+func ceilCPU(cpu float64) float64 {
+	return math.Ceil(cpu*utils.CPUDecimalScale) / utils.CPUDecimalScale
+}
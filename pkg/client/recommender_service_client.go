@@ -165,6 +165,51 @@ func (c *RecommenderServiceClient) makeRequest(ctx context.Context, method, endp
 	return nil
 }
 
+// makeConditionalRequest sends a GET with If-None-Match and returns whether the result was modified and
+// the new etag. The result is left untouched when the server answers 304.
+func (c *RecommenderServiceClient) makeConditionalRequest(ctx context.Context, endpoint, etag string, result interface{}) (bool, string, error) {
+	modified, newETag, err := c.fetchIfNoneMatch(ctx, endpoint, etag, result)
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	if clusterId, ok := contextutils.GetCluster(ctx); ok {
+		metrics.WebhookControllerAPICallsTotal.WithLabelValues(clusterId, status, endpoint).Inc()
+	}
+	return modified, newETag, err
+}
+
+func (c *RecommenderServiceClient) fetchIfNoneMatch(ctx context.Context, endpoint, etag string, result interface{}) (bool, string, error) {
+	headers := map[string]string{}
+	if etag != "" {
+		headers["If-None-Match"] = etag
+	}
+	resp, err := c.makeRawRequest(ctx, "GET", endpoint, headers, nil)
+	if err != nil {
+		return false, "", err
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			logging.Errorf(ctx, "Failed to close response body: %v", closeErr)
+		}
+	}()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return false, etag, nil
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return false, "", fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+	if err := json.Unmarshal(respBody, result); err != nil {
+		return false, "", fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return true, resp.Header.Get("ETag"), nil
+}
+
 func (c *RecommenderServiceClient) makeRawRequest(ctx context.Context, method, endpoint string, headers map[string]string, body io.Reader) (*http.Response, error) {
 	fullURL := c.host + endpoint
 	req, err := http.NewRequestWithContext(ctx, method, fullURL, body)
@@ -214,6 +259,58 @@ func (c *RecommenderServiceClient) GetClusterStats(ctx context.Context, clusterI
 	return &result, err
 }
 
+// StatsQuery narrows down the stats fetched with GetClusterStatsSince and GetWorkloadStats. ETag is the
+// etag of the previous response, the stats are nil when nothing changed.
+type StatsQuery struct {
+	Namespace string
+	Kind      string
+	Since     time.Time
+	ETag      string
+}
+
+func (q StatsQuery) values() url.Values {
+	query := url.Values{}
+	if q.Namespace != "" {
+		query.Set("namespace", q.Namespace)
+	}
+	if q.Kind != "" {
+		query.Set("kind", q.Kind)
+	}
+	if !q.Since.IsZero() {
+		query.Set("since", q.Since.Format(time.RFC3339))
+	}
+	return query
+}
+
+// GetClusterStatsSince returns the stats matching the query and their etag. With a since, the removed
+// workloads of the response had their stats deleted after it.
+func (c *RecommenderServiceClient) GetClusterStatsSince(ctx context.Context, clusterID string, query StatsQuery) (*types.StatsResponse, string, error) {
+	var result types.StatsResponse
+	endpoint := fmt.Sprintf("/api/v1/clusters/%s/stats", clusterID)
+	if values := query.values(); len(values) > 0 {
+		endpoint += "?" + values.Encode()
+	}
+	modified, etag, err := c.makeConditionalRequest(ctx, endpoint, query.ETag, &result)
+	if err != nil || !modified {
+		return nil, etag, err
+	}
+	return &result, etag, nil
+}
+
+// GetWorkloadStats returns the stat of a workload and its etag, only the since and etag of the query apply.
+func (c *RecommenderServiceClient) GetWorkloadStats(ctx context.Context, clusterID, workloadID string, query StatsQuery) (*types.WorkloadStat, string, error) {
+	var result types.WorkloadStat
+	endpoint := fmt.Sprintf("/api/v1/clusters/%s/workloads/%s/stats", clusterID, workloadID)
+	if !query.Since.IsZero() {
+		endpoint += "?" + url.Values{"since": {query.Since.Format(time.RFC3339)}}.Encode()
+	}
+	modified, etag, err := c.makeConditionalRequest(ctx, endpoint, query.ETag, &result)
+	if err != nil || !modified {
+		return nil, etag, err
+	}
+	return &result, etag, nil
+}
+
 func (c *RecommenderServiceClient) GetWorkloadAnalysis(ctx context.Context, clusterID string) ([]types.WorkloadAnalysisItem, error) {
 	var result []types.WorkloadAnalysisItem
 	endpoint := fmt.Sprintf("/api/v1/clusters/%s/workload-analysis", clusterID)
@@ -248,6 +345,27 @@ func (c *RecommenderServiceClient) Killswitch(ctx context.Context, clusterID str
 	return &result, err
 }
 
+// Rollback resizes pods back to their original resources. An empty namespace and workloadID roll back the whole cluster.
+func (c *RecommenderServiceClient) Rollback(ctx context.Context, clusterID, namespace, workloadID string, dryRun bool) (*types.RollbackResponse, error) {
+	var result types.RollbackResponse
+	query := url.Values{}
+	if namespace != "" {
+		query.Set("namespace", namespace)
+	}
+	if workloadID != "" {
+		query.Set("workload", workloadID)
+	}
+	if dryRun {
+		query.Set("dry_run", "true")
+	}
+	endpoint := fmt.Sprintf("/api/v1/clusters/%s/rollback", clusterID)
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	err := c.makeRequest(ctx, "POST", endpoint, nil, &result)
+	return &result, err
+}
+
 func (c *RecommenderServiceClient) ListWorkloads(ctx context.Context, clusterID string) ([]types.WorkloadOverrideInfo, error) {
 	var result []types.WorkloadOverrideInfo
 	endpoint := fmt.Sprintf("/api/v1/clusters/%s/workloads", clusterID)
@@ -267,6 +385,19 @@ func (c *RecommenderServiceClient) UpdateWorkloadOverrides(ctx context.Context,
 	return c.makeRequest(ctx, "POST", endpoint, overrides, nil)
 }
 
+// ListNamespaceOverrides returns the overrides of the namespaces of the cluster keyed by namespace.
+func (c *RecommenderServiceClient) ListNamespaceOverrides(ctx context.Context, clusterID string) (map[string]*types.Overrides, error) {
+	var result map[string]*types.Overrides
+	endpoint := fmt.Sprintf("/api/v1/clusters/%s/namespaces/overrides", clusterID)
+	err := c.makeRequest(ctx, "GET", endpoint, nil, &result)
+	return result, err
+}
+
+func (c *RecommenderServiceClient) UpdateNamespaceOverrides(ctx context.Context, clusterID, namespace string, overrides *types.Overrides) error {
+	endpoint := fmt.Sprintf("/api/v1/clusters/%s/namespaces/%s/overrides", clusterID, namespace)
+	return c.makeRequest(ctx, "POST", endpoint, overrides, nil)
+}
+
 func (c *RecommenderServiceClient) WebhookGetClusterStats(ctx context.Context, clusterID string) (*types.StatsResponse, error) {
 	var result types.StatsResponse
 	endpoint := fmt.Sprintf("/api/v1/webhook/clusters/%s/stats", clusterID)
@@ -281,6 +412,18 @@ func (c *RecommenderServiceClient) WebhookGetWorkloadOverrides(ctx context.Conte
 	return &result, err
 }
 
+// WebhookGetSnapshot returns the stats and overrides of the cluster and their etag. The snapshot is nil
+// when it still matches the given etag.
+func (c *RecommenderServiceClient) WebhookGetSnapshot(ctx context.Context, clusterID, etag string) (*types.WebhookSnapshot, string, error) {
+	var result types.WebhookSnapshot
+	endpoint := fmt.Sprintf("/api/v1/webhook/clusters/%s/snapshot", clusterID)
+	modified, newETag, err := c.makeConditionalRequest(ctx, endpoint, etag, &result)
+	if err != nil || !modified {
+		return nil, newETag, err
+	}
+	return &result, newETag, nil
+}
+
 func (c *RecommenderServiceClient) SetHost(host string) {
 	c.host = strings.TrimSuffix(host, "/")
 }
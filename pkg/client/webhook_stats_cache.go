@@ -0,0 +1,265 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/contextutils"
+	"github.com/truefoundry/cruisekube/pkg/logging"
+	"github.com/truefoundry/cruisekube/pkg/types"
+)
+
+// coldRefreshTimeout bounds the inline refresh of a cold cluster, it has to fit in the admission timeout.
+const coldRefreshTimeout = 5 * time.Second
+
+// A failed refresh is not retried before the backoff, doubling with every failure up to the max.
+const (
+	refreshBackoffBase = time.Second
+	refreshBackoffMax  = time.Minute
+)
+
+// unusedClusterTTL is how long a cluster no admission looked up stays cached.
+const unusedClusterTTL = time.Hour
+
+// WebhookStatsCache keeps the stats and overrides of the clusters the webhook admits pods for in memory,
+// indexed by workload id, so an admission does not fetch them from the controller.
+type WebhookStatsCache struct {
+	client          *RecommenderServiceClient
+	refreshInterval time.Duration
+	maxStaleness    time.Duration
+
+	mu       sync.Mutex
+	clusters map[string]*clusterSnapshot
+}
+
+type clusterSnapshot struct {
+	// refreshing lets a single refresh run at a time, a burst of admissions on a cold cluster fetches once.
+	refreshing chan struct{}
+
+	mu        sync.RWMutex
+	etag      string
+	stats     map[string]*types.WorkloadStat
+	overrides map[string]*types.Overrides
+	// aliases maps the workloads a custom workload manages its pods through to the custom workload.
+	aliases     map[string]string
+	refreshedAt time.Time
+	failures    int
+	retryAt     time.Time
+	lastUsedAt  time.Time
+}
+
+// NewWebhookStatsCache polls the snapshot of every cluster it was asked about each refresh interval. A
+// snapshot older than the max staleness is refreshed in the background by the next lookup.
+func NewWebhookStatsCache(client *RecommenderServiceClient, refreshInterval, maxStaleness time.Duration) *WebhookStatsCache {
+	return &WebhookStatsCache{
+		client:          client,
+		refreshInterval: refreshInterval,
+		maxStaleness:    maxStaleness,
+		clusters:        make(map[string]*clusterSnapshot),
+	}
+}
+
+// Start refreshes the known clusters in the background until the context is done, and drops the ones
+// no admission looked up for a while.
+func (c *WebhookStatsCache) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(c.refreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.evictUnused(ctx)
+				for clusterID, snapshot := range c.knownClusters() {
+					if !snapshot.canRetry() {
+						continue
+					}
+					if err := c.refresh(ctx, clusterID, snapshot); err != nil {
+						logging.Errorf(ctx, "Failed to refresh webhook stats cache of cluster %s: %v", clusterID, err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// Lookup returns the stat and overrides of a workload, either is nil when the controller has none. A
+// workload managed by a custom workload is looked up as the custom workload. A cluster is cold before its
+// first refresh, it is then refreshed inline and ok is false when that fails, the pod should be admitted
+// without adjustment. A stale snapshot is served while it is refreshed in the background.
+func (c *WebhookStatsCache) Lookup(ctx context.Context, clusterID, workloadID string) (*types.WorkloadStat, *types.Overrides, bool) {
+	snapshot := c.getOrCreate(clusterID)
+	switch {
+	case !snapshot.isLoaded():
+		if err := c.refreshCold(ctx, clusterID, snapshot); err != nil {
+			logging.Errorf(ctx, "Webhook stats cache of cluster %s is cold and could not be refreshed: %v", clusterID, err)
+			return nil, nil, false
+		}
+	case !c.isFresh(snapshot) && snapshot.canRetry():
+		go func() {
+			refreshCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), coldRefreshTimeout)
+			defer cancel()
+			if err := c.tryRefresh(refreshCtx, clusterID, snapshot); err != nil {
+				logging.Errorf(refreshCtx, "Failed to refresh stale webhook stats cache of cluster %s: %v", clusterID, err)
+			}
+		}()
+	}
+
+	snapshot.mu.RLock()
+	defer snapshot.mu.RUnlock()
+	if ownerID, ok := snapshot.aliases[workloadID]; ok {
+		workloadID = ownerID
+	}
+	return snapshot.stats[workloadID], snapshot.overrides[workloadID], true
+}
+
+// refreshCold fetches the snapshot of a cluster that has none, waiting for a refresh already running no
+// longer than the admission allows.
+func (c *WebhookStatsCache) refreshCold(ctx context.Context, clusterID string, snapshot *clusterSnapshot) error {
+	refreshCtx, cancel := context.WithTimeout(ctx, coldRefreshTimeout)
+	defer cancel()
+	select {
+	case snapshot.refreshing <- struct{}{}:
+	case <-refreshCtx.Done():
+		return fmt.Errorf("timed out waiting for a refresh: %w", refreshCtx.Err())
+	}
+	defer func() { <-snapshot.refreshing }()
+
+	// another admission may have refreshed the cluster while this one waited
+	if snapshot.isLoaded() {
+		return nil
+	}
+	if !snapshot.canRetry() {
+		return fmt.Errorf("backing off after %d failed refreshes", snapshot.failureCount())
+	}
+	return c.fetch(refreshCtx, clusterID, snapshot)
+}
+
+// tryRefresh refreshes the cluster unless a refresh is already running.
+func (c *WebhookStatsCache) tryRefresh(ctx context.Context, clusterID string, snapshot *clusterSnapshot) error {
+	select {
+	case snapshot.refreshing <- struct{}{}:
+	default:
+		return nil
+	}
+	defer func() { <-snapshot.refreshing }()
+	return c.fetch(ctx, clusterID, snapshot)
+}
+
+func (c *WebhookStatsCache) refresh(ctx context.Context, clusterID string, snapshot *clusterSnapshot) error {
+	select {
+	case snapshot.refreshing <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-snapshot.refreshing }()
+	return c.fetch(ctx, clusterID, snapshot)
+}
+
+// fetch downloads the snapshot of the cluster unless it did not change, the caller holds refreshing. A
+// failure is recorded so the cluster is not refreshed again before the backoff.
+func (c *WebhookStatsCache) fetch(ctx context.Context, clusterID string, snapshot *clusterSnapshot) error {
+	ctx = contextutils.WithCluster(ctx, clusterID)
+	snapshot.mu.RLock()
+	etag := snapshot.etag
+	snapshot.mu.RUnlock()
+
+	latest, newETag, err := c.client.WebhookGetSnapshot(ctx, clusterID, etag)
+
+	snapshot.mu.Lock()
+	defer snapshot.mu.Unlock()
+	if err != nil {
+		snapshot.failures++
+		snapshot.retryAt = time.Now().Add(min(refreshBackoffBase<<min(snapshot.failures-1, 16), refreshBackoffMax))
+		return fmt.Errorf("failed to fetch webhook snapshot: %w", err)
+	}
+	snapshot.failures = 0
+	snapshot.retryAt = time.Time{}
+	snapshot.refreshedAt = time.Now()
+	if latest == nil {
+		return nil
+	}
+
+	stats := make(map[string]*types.WorkloadStat, len(latest.Stats))
+	aliases := make(map[string]string)
+	for i := range latest.Stats {
+		workloadID := strings.ReplaceAll(latest.Stats[i].WorkloadIdentifier, "/", ":")
+		stats[workloadID] = &latest.Stats[i]
+		for _, ownedID := range latest.Stats[i].OwnedWorkloads {
+			aliases[ownedID] = workloadID
+		}
+	}
+	snapshot.etag = newETag
+	snapshot.stats = stats
+	snapshot.aliases = aliases
+	snapshot.overrides = latest.Overrides
+	logging.Infof(ctx, "Refreshed webhook stats cache of cluster %s with %d workloads", clusterID, len(stats))
+	return nil
+}
+
+func (c *WebhookStatsCache) isFresh(snapshot *clusterSnapshot) bool {
+	snapshot.mu.RLock()
+	defer snapshot.mu.RUnlock()
+	return !snapshot.refreshedAt.IsZero() && time.Since(snapshot.refreshedAt) <= c.maxStaleness
+}
+
+func (s *clusterSnapshot) isLoaded() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return !s.refreshedAt.IsZero()
+}
+
+func (s *clusterSnapshot) canRetry() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return !time.Now().Before(s.retryAt)
+}
+
+func (s *clusterSnapshot) failureCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.failures
+}
+
+func (c *WebhookStatsCache) getOrCreate(clusterID string) *clusterSnapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	snapshot, ok := c.clusters[clusterID]
+	if !ok {
+		snapshot = &clusterSnapshot{refreshing: make(chan struct{}, 1)}
+		c.clusters[clusterID] = snapshot
+	}
+	snapshot.mu.Lock()
+	snapshot.lastUsedAt = time.Now()
+	snapshot.mu.Unlock()
+	return snapshot
+}
+
+// evictUnused drops the clusters no admission looked up within the unused cluster ttl.
+func (c *WebhookStatsCache) evictUnused(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for clusterID, snapshot := range c.clusters {
+		snapshot.mu.RLock()
+		unused := time.Since(snapshot.lastUsedAt) > unusedClusterTTL
+		snapshot.mu.RUnlock()
+		if unused {
+			delete(c.clusters, clusterID)
+			logging.Infof(ctx, "Evicted webhook stats cache of unused cluster %s", clusterID)
+		}
+	}
+}
+
+func (c *WebhookStatsCache) knownClusters() map[string]*clusterSnapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clusters := make(map[string]*clusterSnapshot, len(c.clusters))
+	for clusterID, snapshot := range c.clusters {
+		clusters[clusterID] = snapshot
+	}
+	return clusters
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/truefoundry/cruisekube/pkg/types"
+
 	"gopkg.in/yaml.v2"
 )
 
@@ -14,6 +16,7 @@ const (
 	ModifyEqualCPUResourcesKey = "modifyequalcpuresources"
 	NodeLoadMonitoringKey      = "nodeloadmonitoring"
 	CleanupOOMEventsKey        = "cleanupoomevent"
+	SampleMetricsKey           = "samplemetrics"
 )
 
 type Config struct {
@@ -27,7 +30,9 @@ type Config struct {
 	RecommendationSettings RecommendationSettings `yaml:"recommendationSettings" mapstructure:"recommendationSettings"`
 	Telemetry              TelemetryConfig        `yaml:"telemetry" mapstructure:"telemetry"`
 	Metrics                MetricsConfig          `yaml:"metrics" mapstructure:"metrics"`
-	Custom                 map[string]interface{} `yaml:",inline" mapstructure:",remain"`
+	// CustomWorkloads are the custom resources owning pods that are handled like the built-in workloads.
+	CustomWorkloads []CustomWorkloadConfig `yaml:"customWorkloads" mapstructure:"customWorkloads"`
+	Custom          map[string]interface{} `yaml:",inline" mapstructure:",remain"`
 }
 
 func (c *Config) GetTaskConfig(taskName string) *TaskConfig {
@@ -37,6 +42,22 @@ func (c *Config) GetTaskConfig(taskName string) *TaskConfig {
 type Dependencies struct {
 	Local     LocalDeps     `yaml:"local" mapstructure:"local"`
 	InCluster InClusterDeps `yaml:"inCluster" mapstructure:"inCluster"`
+	// MetricsProvider selects where the usage behind the stats comes from.
+	MetricsProvider MetricsProviderConfig `yaml:"metricsProvider" mapstructure:"metricsProvider"`
+}
+
+type MetricsProviderConfig struct {
+	Type MetricsProviderType `yaml:"type" mapstructure:"type"`
+	// KubeletSource is read by the kubelet provider: summary for the kubelet /stats/summary endpoint or
+	// metricsServer for the metrics.k8s.io api.
+	KubeletSource string `yaml:"kubeletSource" mapstructure:"kubeletSource"`
+	// IncrementalStats makes the prometheus provider keep the 7 day stats in sketches stored in the
+	// database, and only query the usage since the previous run.
+	IncrementalStats bool `yaml:"incrementalStats" mapstructure:"incrementalStats"`
+}
+
+func (d Dependencies) UsesPrometheusMetricsProvider() bool {
+	return d.MetricsProvider.Type == "" || d.MetricsProvider.Type == MetricsProviderPrometheus
 }
 
 type LocalDeps struct {
@@ -76,6 +97,29 @@ type WebhookConfig struct {
 	CertsDir string    `yaml:"certsDir" mapstructure:"certsDir"`
 	DryRun   bool      `yaml:"dryRun" mapstructure:"dryRun"`
 	StatsURL URLConfig `yaml:"statsURL" mapstructure:"statsURL"`
+	// Cache keeps the stats and overrides of the controller in the webhook between admissions.
+	Cache WebhookCacheConfig `yaml:"cache" mapstructure:"cache"`
+}
+
+type WebhookCacheConfig struct {
+	RefreshIntervalSeconds int `yaml:"refreshIntervalSeconds" mapstructure:"refreshIntervalSeconds"`
+	// MaxStalenessSeconds is how old the cached stats may get before an admission refreshes them in the
+	// background, the stale stats are served meanwhile.
+	MaxStalenessSeconds int `yaml:"maxStalenessSeconds" mapstructure:"maxStalenessSeconds"`
+}
+
+// CustomWorkloadConfig describes a custom resource of an operator that owns its pods directly.
+type CustomWorkloadConfig struct {
+	Group   string `yaml:"group" mapstructure:"group"`
+	Version string `yaml:"version" mapstructure:"version"`
+	Kind    string `yaml:"kind" mapstructure:"kind"`
+	// Resource is the plural name of the kind in the api, like databases.
+	Resource string `yaml:"resource" mapstructure:"resource"`
+	// PodTemplatePath is the dot separated path to the pod template in the object, like spec.template.
+	PodTemplatePath string `yaml:"podTemplatePath" mapstructure:"podTemplatePath"`
+	// SelectorPath is the dot separated path to the label selector of the pods, like spec.selector. The labels
+	// of the pod template are used without it.
+	SelectorPath string `yaml:"selectorPath" mapstructure:"selectorPath"`
 }
 
 type DatabaseConfig struct {
@@ -94,6 +138,52 @@ type RecommendationSettings struct {
 	ApplyBlacklistedNamespaces []string `yaml:"applyBlacklistedNamespaces" mapstructure:"applyBlacklistedNamespaces"`
 	MaxConcurrentQueries       int      `yaml:"maxConcurrentQueries" mapstructure:"maxConcurrentQueries"`
 	OOMCooldownMinutes         int      `yaml:"oomCooldownMinutes" mapstructure:"oomCooldownMinutes"`
+	// RequestPercentile is the usage percentile used as the request. One of 50, 75, 90, 95, 99 or 100.
+	RequestPercentile float64 `yaml:"requestPercentile" mapstructure:"requestPercentile"`
+	// PmaxSource is where the peak usage is taken from: prediction, max or 7day_max.
+	PmaxSource string `yaml:"pmaxSource" mapstructure:"pmaxSource"`
+	// SafetyMarginType is either multiplicative or absolute.
+	SafetyMarginType   string  `yaml:"safetyMarginType" mapstructure:"safetyMarginType"`
+	CPUSafetyMargin    float64 `yaml:"cpuSafetyMargin" mapstructure:"cpuSafetyMargin"`
+	MemorySafetyMargin float64 `yaml:"memorySafetyMargin" mapstructure:"memorySafetyMargin"`
+	// MemoryLimitMode is how memory limits are set: keep, ratio_of_request, 7day_max_multiple or remove.
+	MemoryLimitMode string `yaml:"memoryLimitMode" mapstructure:"memoryLimitMode"`
+	// MemoryLimitRatio is the limit to request ratio of the ratio_of_request mode.
+	MemoryLimitRatio float64 `yaml:"memoryLimitRatio" mapstructure:"memoryLimitRatio"`
+	// MemoryLimitMultiplier is the multiplier of the 7day_max_multiple mode.
+	MemoryLimitMultiplier float64 `yaml:"memoryLimitMultiplier" mapstructure:"memoryLimitMultiplier"`
+	// MinMemoryLimitMB is the lowest memory limit set, in MB.
+	MinMemoryLimitMB float64 `yaml:"minMemoryLimitMB" mapstructure:"minMemoryLimitMB"`
+	// ApplyEphemeralStorage lets the webhook set ephemeral-storage requests and limits. Only the kubelet
+	// metrics provider collects the usage they are based on.
+	ApplyEphemeralStorage bool `yaml:"applyEphemeralStorage" mapstructure:"applyEphemeralStorage"`
+	// EphemeralStorageSafetyMargin scales the 7 day max usage into the request.
+	EphemeralStorageSafetyMargin float64 `yaml:"ephemeralStorageSafetyMargin" mapstructure:"ephemeralStorageSafetyMargin"`
+	// EphemeralStorageLimitRatio is the limit to request ratio of ephemeral storage.
+	EphemeralStorageLimitRatio float64 `yaml:"ephemeralStorageLimitRatio" mapstructure:"ephemeralStorageLimitRatio"`
+	// MinEphemeralStorageMB is the lowest ephemeral storage request set, in MB.
+	MinEphemeralStorageMB float64 `yaml:"minEphemeralStorageMB" mapstructure:"minEphemeralStorageMB"`
+}
+
+// Validate rejects the request percentiles, pmax sources and safety margins the workload overrides reject,
+// unset values fall back to their defaults.
+func (s RecommendationSettings) Validate() error {
+	policy := types.RecommendationOverrides{
+		CPUSafetyMargin:    &s.CPUSafetyMargin,
+		MemorySafetyMargin: &s.MemorySafetyMargin,
+	}
+	if s.RequestPercentile != 0 {
+		policy.RequestPercentile = &s.RequestPercentile
+	}
+	pmaxSource := types.PmaxSource(s.PmaxSource)
+	if pmaxSource != "" {
+		policy.PmaxSource = &pmaxSource
+	}
+	safetyMarginType := types.SafetyMarginType(s.SafetyMarginType)
+	if safetyMarginType != "" {
+		policy.SafetyMarginType = &safetyMarginType
+	}
+	return policy.Validate()
 }
 
 type TelemetryConfig struct {
@@ -116,6 +206,13 @@ const (
 	ClusterModeInCluster ControllerMode = "in-cluster"
 )
 
+type MetricsProviderType string
+
+const (
+	MetricsProviderPrometheus MetricsProviderType = "prometheus"
+	MetricsProviderKubelet    MetricsProviderType = "kubelet"
+)
+
 type ExecutionMode string
 
 const (
@@ -22,6 +22,9 @@ func LoadWithViperInstance(ctx context.Context, v *viper.Viper, configFilePath s
 	v.SetDefault("executionMode", string(ExecutionModeBoth))
 	v.SetDefault("dependencies.local.kubeconfigPath", "")
 	v.SetDefault("dependencies.local.prometheusURL", "")
+	v.SetDefault("dependencies.metricsProvider.type", string(MetricsProviderPrometheus))
+	v.SetDefault("dependencies.metricsProvider.kubeletSource", "summary")
+	v.SetDefault("dependencies.metricsProvider.incrementalStats", false)
 	v.SetDefault("controller.tasks.applyRecommendation.enabled", true)
 	v.SetDefault("controller.tasks.applyRecommendation.schedule", "5m")
 	v.SetDefault("controller.tasks.applyRecommendation.nodeStatsURL.host", "localhost:8080")
@@ -29,6 +32,17 @@ func LoadWithViperInstance(ctx context.Context, v *viper.Viper, configFilePath s
 	v.SetDefault("controller.tasks.applyRecommendation.overridesURL.host", "localhost:8080")
 	v.SetDefault("recommendationSettings.maxConcurrentQueries", 5)
 	v.SetDefault("recommendationSettings.oomCooldownMinutes", 5)
+	v.SetDefault("recommendationSettings.requestPercentile", 75)
+	v.SetDefault("recommendationSettings.pmaxSource", "prediction")
+	v.SetDefault("recommendationSettings.safetyMarginType", "multiplicative")
+	v.SetDefault("recommendationSettings.memoryLimitMode", "7day_max_multiple")
+	v.SetDefault("recommendationSettings.memoryLimitRatio", 2)
+	v.SetDefault("recommendationSettings.memoryLimitMultiplier", 2)
+	v.SetDefault("recommendationSettings.minMemoryLimitMB", 512)
+	v.SetDefault("recommendationSettings.applyEphemeralStorage", false)
+	v.SetDefault("recommendationSettings.ephemeralStorageSafetyMargin", 0.25)
+	v.SetDefault("recommendationSettings.ephemeralStorageLimitRatio", 2)
+	v.SetDefault("recommendationSettings.minEphemeralStorageMB", 100)
 	v.SetDefault("controller.tasks.cleanupOOMEvent.enabled", false)
 	v.SetDefault("controller.tasks.cleanupOOMEvent.schedule", "24h")
 	v.SetDefault("controller.tasks.cleanupOOMEvent.metadata.retentionDays", 7)
@@ -36,6 +50,8 @@ func LoadWithViperInstance(ctx context.Context, v *viper.Viper, configFilePath s
 	v.SetDefault("server.enableDevAPIs", false)
 	v.SetDefault("webhook.port", "8443")
 	v.SetDefault("webhook.certsDir", "/certs")
+	v.SetDefault("webhook.cache.refreshIntervalSeconds", 30)
+	v.SetDefault("webhook.cache.maxStalenessSeconds", 300)
 	v.SetDefault("db.filePath", "cruisekube.db")
 	v.SetDefault("telemetry.enabled", false)
 	v.SetDefault("telemetry.traceRatio", 0.1)
@@ -60,6 +76,13 @@ func LoadWithViperInstance(ctx context.Context, v *viper.Viper, configFilePath s
 }
 
 func (c *Config) Validate() error {
+	if err := c.ValidateCustomWorkloads(); err != nil {
+		return err
+	}
+	if err := c.RecommendationSettings.Validate(); err != nil {
+		return fmt.Errorf("invalid recommendationSettings: %w", err)
+	}
+
 	switch c.ExecutionMode {
 	case ExecutionModeWebhook:
 		return c.ValidateWebhookExecutionMode()
@@ -78,6 +101,22 @@ func (c *Config) Validate() error {
 	}
 }
 
+// ValidateCustomWorkloads checks every custom workload can be read, and that kinds are unique as workloads
+// are keyed by kind.
+func (c *Config) ValidateCustomWorkloads() error {
+	kinds := make(map[string]bool, len(c.CustomWorkloads))
+	for i, workload := range c.CustomWorkloads {
+		if workload.Version == "" || workload.Kind == "" || workload.Resource == "" || workload.PodTemplatePath == "" {
+			return fmt.Errorf("customWorkloads[%d]: version, kind, resource and podTemplatePath are required", i)
+		}
+		if kinds[workload.Kind] {
+			return fmt.Errorf("customWorkloads[%d]: kind %s is configured more than once", i, workload.Kind)
+		}
+		kinds[workload.Kind] = true
+	}
+	return nil
+}
+
 func (c *Config) ValidateWebhookExecutionMode() error {
 	valueMissing := false
 	if c.Webhook.Port == "" {
@@ -92,6 +131,10 @@ func (c *Config) ValidateWebhookExecutionMode() error {
 		fmt.Println("webhook.certsDir is required for webhook execution mode")
 		valueMissing = true
 	}
+	if c.Webhook.Cache.RefreshIntervalSeconds <= 0 {
+		fmt.Println("webhook.cache.refreshIntervalSeconds must be positive for webhook execution mode")
+		valueMissing = true
+	}
 
 	if valueMissing {
 		fmt.Println("Please provide all required webhook configuration values")
@@ -101,6 +144,15 @@ func (c *Config) ValidateWebhookExecutionMode() error {
 }
 
 func (c *Config) ValidateControllerExecutionMode() error {
+	switch c.Dependencies.MetricsProvider.Type {
+	case MetricsProviderPrometheus, "":
+	case MetricsProviderKubelet:
+		// prometheus is optional, the stats are built from the sampled usage
+		return nil
+	default:
+		return fmt.Errorf("invalid dependencies.metricsProvider.type: %s (expected prometheus|kubelet)", c.Dependencies.MetricsProvider.Type)
+	}
+
 	controllerMode := strings.TrimSpace(string(c.ControllerMode))
 	switch controllerMode {
 	case string(ClusterModeLocal):
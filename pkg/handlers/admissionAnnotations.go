@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// The decision paths recorded for a resource of a container.
+const (
+	decisionStatsMax      = "stats_max"
+	decisionPredictionMax = "prediction_max"
+	decisionOOMMemory     = "oom_memory"
+	decisionStartup       = "startup"
+)
+
+// skipAdmission admits the pod unadjusted, recording why on the pod.
+func skipAdmission(pod *corev1.Pod, reason string) []map[string]any {
+	return annotationPatches(pod, map[string]string{
+		utils.AdmissionDecisionAnnotation: "skipped: " + reason,
+	})
+}
+
+// explainAdmission records on the pod the resources its containers had before the patches and after them,
+// when the stats behind them were generated and the decision taken for each container.
+func explainAdmission(pod *corev1.Pod, patches []map[string]any, statsGeneratedAt time.Time, decisions []string) []map[string]any {
+	annotations := map[string]string{
+		utils.AdmissionDecisionAnnotation: strings.Join(decisions, "; "),
+		utils.StatsGeneratedAtAnnotation:  statsGeneratedAt.UTC().Format(time.RFC3339),
+	}
+
+	originals, recommended := resourcesBeforeAndAfterPatches(pod, patches)
+	if len(originals) > 0 {
+		originalsJSON, err := json.Marshal(originals)
+		if err == nil {
+			annotations[utils.OriginalResourcesAnnotation] = string(originalsJSON)
+		}
+		recommendedJSON, err := json.Marshal(recommended)
+		if err == nil {
+			annotations[utils.RecommendedResourcesAnnotation] = string(recommendedJSON)
+		}
+	}
+
+	return annotationPatches(pod, annotations)
+}
+
+// resourcesBeforeAndAfterPatches returns the resources of the containers the patches change, by container
+// name, as they are in the pod and once the patches are applied.
+func resourcesBeforeAndAfterPatches(pod *corev1.Pod, patches []map[string]any) (map[string]corev1.ResourceRequirements, map[string]corev1.ResourceRequirements) {
+	originals := make(map[string]corev1.ResourceRequirements)
+	recommended := make(map[string]corev1.ResourceRequirements)
+
+	for _, patch := range patches {
+		path, _ := patch["path"].(string)
+		// /spec/<containers|initContainers>/<index>/resources/<requests|limits>/<resource>
+		parts := strings.Split(path, "/")
+		if len(parts) != 7 || parts[1] != "spec" || parts[4] != "resources" {
+			continue
+		}
+		index, err := strconv.Atoi(parts[3])
+		if err != nil {
+			continue
+		}
+		var container *corev1.Container
+		switch {
+		case parts[2] == "containers" && index < len(pod.Spec.Containers):
+			container = &pod.Spec.Containers[index]
+		case parts[2] == "initContainers" && index < len(pod.Spec.InitContainers):
+			container = &pod.Spec.InitContainers[index]
+		default:
+			continue
+		}
+
+		after, exists := recommended[container.Name]
+		if !exists {
+			originals[container.Name] = *container.Resources.DeepCopy()
+			after = *container.Resources.DeepCopy()
+		}
+		resources := &after.Requests
+		if parts[5] == "limits" {
+			resources = &after.Limits
+		}
+		resourceName := corev1.ResourceName(parts[6])
+
+		switch patch["op"] {
+		case "remove":
+			delete(*resources, resourceName)
+		case "add", "replace":
+			value, ok := patch["value"].(string)
+			if !ok {
+				continue
+			}
+			quantity, err := resource.ParseQuantity(value)
+			if err != nil {
+				continue
+			}
+			if *resources == nil {
+				*resources = corev1.ResourceList{}
+			}
+			(*resources)[resourceName] = quantity
+		}
+		recommended[container.Name] = after
+	}
+
+	return originals, recommended
+}
+
+// annotationPatches sets the annotations on the pod, creating the annotations of the pod when it has none.
+func annotationPatches(pod *corev1.Pod, annotations map[string]string) []map[string]any {
+	if pod.Annotations == nil {
+		return []map[string]any{{
+			"op":    "add",
+			"path":  "/metadata/annotations",
+			"value": annotations,
+		}}
+	}
+
+	patches := make([]map[string]any, 0, len(annotations))
+	for _, key := range slices.Sorted(maps.Keys(annotations)) {
+		patches = append(patches, map[string]any{
+			"op":    "add",
+			"path":  "/metadata/annotations/" + escapeJSONPointer(key),
+			"value": annotations[key],
+		})
+	}
+	return patches
+}
+
+func escapeJSONPointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
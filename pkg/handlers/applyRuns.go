@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/logging"
+	"github.com/truefoundry/cruisekube/pkg/repository/storage"
+	"github.com/truefoundry/cruisekube/pkg/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultApplyRunsLimit    = 50
+	defaultApplyRunsLookback = 7 * 24 * time.Hour
+)
+
+// ListApplyRunsHandler lists the apply runs of a cluster, newest first. The namespace, pod and workload
+// query parameters narrow the result down to the runs that touched the matching pods.
+func ListApplyRunsHandler(c *gin.Context) {
+	clusterID := c.Param("clusterID")
+
+	filter := types.ApplyRunFilter{
+		Since:          time.Now().Add(-defaultApplyRunsLookback),
+		Limit:          defaultApplyRunsLimit,
+		Namespace:      c.Query("namespace"),
+		PodName:        c.Query("pod"),
+		WorkloadID:     c.Query("workload"),
+		IncludeEntries: c.Query("include_entries") == "true",
+	}
+	if since := c.Query("since"); since != "" {
+		parsed, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid since %q, expected RFC3339: %v", since, err),
+			})
+			return
+		}
+		filter.Since = parsed
+	}
+	if limit := c.Query("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid limit %q", limit),
+			})
+			return
+		}
+		filter.Limit = parsed
+	}
+
+	runs, err := storage.Stg.GetApplyRuns(clusterID, filter)
+	if err != nil {
+		logging.Errorf(c.Request.Context(), "Failed to get apply runs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to get apply runs: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cluster_id": clusterID,
+		"apply_runs": runs,
+	})
+}
+
+func GetApplyRunHandler(c *gin.Context) {
+	clusterID := c.Param("clusterID")
+	runID := c.Param("runID")
+
+	run, err := storage.Stg.GetApplyRun(clusterID, runID)
+	if err != nil {
+		logging.Errorf(c.Request.Context(), "Failed to get apply run %s: %v", runID, err)
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": fmt.Sprintf("Apply run %s not found", runID),
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": fmt.Sprintf("Failed to get apply run: %v", err),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
@@ -11,7 +11,6 @@ import (
 	"github.com/truefoundry/cruisekube/pkg/config"
 	"github.com/truefoundry/cruisekube/pkg/logging"
 	"github.com/truefoundry/cruisekube/pkg/task"
-	"github.com/truefoundry/cruisekube/pkg/task/applystrategies"
 	"github.com/truefoundry/cruisekube/pkg/task/utils"
 	"github.com/truefoundry/cruisekube/pkg/types"
 )
@@ -43,7 +42,12 @@ func generateRecommendationAnalysisForCluster(ctx context.Context, clusterID str
 		return nil, fmt.Errorf("error generating node recommendations: %w", err)
 	}
 
-	recommendationResults, err := recomTask.ApplyRecommendationsWithStrategy(ctx, nodeRecommendationMap, nil, applystrategies.NewAdjustAmongstPodsDistributedStrategy(ctx), false, true)
+	strategy, err := recomTask.GetStrategy(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting strategy: %w", err)
+	}
+
+	recommendationResults, err := recomTask.ApplyRecommendationsWithStrategy(ctx, nodeRecommendationMap, nil, nil, strategy, false, true)
 	if err != nil {
 		return nil, fmt.Errorf("error applying recommendations: %w", err)
 	}
@@ -116,6 +120,11 @@ func analyzeWorkloadStats(stat *utils.WorkloadStat, podName, containerName, node
 			containerStat.CPU7Day.P90, containerStat.CPU7Day.P75, containerStat.CPU7Day.P50)
 	}
 
+	ephemeralStorageUsage7Days := "N/A"
+	if err == nil {
+		ephemeralStorageUsage7Days = formatEphemeralStorageUsage(containerStat.EphemeralStorage)
+	}
+
 	spikeRange := 0.0
 	if containerStat != nil && containerStat.SimplePredictionsCPU != nil && containerStat.CPUStats != nil {
 		spikeRange = containerStat.SimplePredictionsCPU.MaxValue - containerStat.CPUStats.P50
@@ -147,5 +156,8 @@ func analyzeWorkloadStats(stat *utils.WorkloadStat, podName, containerName, node
 		CurrentRequestedMemory: math.Round(currentRequestedMemory),
 		RecommendedMemory:      math.Round(recommendedMemory),
 		MemoryDifference:       math.Round(memoryDifference),
+
+		EphemeralStorageUsage7Days: ephemeralStorageUsage7Days,
+		ExtendedResources:          stat.Constraints.GetExtendedResourceNames(),
 	}
 }
@@ -125,6 +125,9 @@ func HandleListClusters(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// HandleClusterStats serves the stats of a cluster, narrowed down by the namespace, kind and since query
+// parameters, and answers 304 when they match the If-None-Match of the request. With a since, the
+// workloads whose stats were deleted after it are listed as removed.
 func HandleClusterStats(c *gin.Context) {
 	ctx := c.Request.Context()
 	clusterID := c.Param("clusterID")
@@ -132,17 +135,62 @@ func HandleClusterStats(c *gin.Context) {
 	span := oteltrace.SpanFromContext(ctx)
 	span.SetAttributes(attribute.String("cluster", clusterID))
 
+	filter, err := parseStatsFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
 	logging.Infof(ctx, "Serving stats for cluster %s to %s", clusterID, c.ClientIP())
-	c.Header("Content-Type", "application/json")
-	var statsResponse types.StatsResponse
-	if err := storage.Stg.ReadClusterStats(clusterID, &statsResponse); err != nil {
+	stats, err := storage.Stg.GetFilteredClusterStats(clusterID, filter)
+	if err != nil {
+		logging.Errorf(ctx, "Failed to read cluster stats for %s: %v", clusterID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to read cluster stats for %s: %v", clusterID, err),
+		})
+		return
+	}
+	removed, err := storage.Stg.GetRemovedStatIDs(clusterID, filter)
+	if err != nil {
+		logging.Errorf(ctx, "Failed to read removed stats for %s: %v", clusterID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to read removed stats for %s: %v", clusterID, err),
+		})
+		return
+	}
+
+	writeJSONWithETag(c, types.StatsResponse{Stats: stats, Removed: removed}, types.StatsResponse{Stats: statsContent(stats), Removed: removed})
+}
+
+// HandleWebhookSnapshot serves the stats and overrides of a cluster, the webhook polls it with
+// If-None-Match and only downloads the snapshot when it changed.
+func HandleWebhookSnapshot(c *gin.Context) {
+	ctx := c.Request.Context()
+	clusterID := c.Param("clusterID")
+
+	span := oteltrace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String("cluster", clusterID))
+
+	stats, err := storage.Stg.GetAllStatsForCluster(clusterID)
+	if err != nil {
 		logging.Errorf(ctx, "Failed to read cluster stats for %s: %v", clusterID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": fmt.Sprintf("Failed to read cluster stats for %s: %v", clusterID, err),
 		})
 		return
 	}
-	c.JSON(http.StatusOK, statsResponse)
+	overrides, err := storage.Stg.GetClusterOverrides(clusterID)
+	if err != nil {
+		logging.Errorf(ctx, "Failed to read cluster overrides for %s: %v", clusterID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to read cluster overrides for %s: %v", clusterID, err),
+		})
+		return
+	}
+
+	writeJSONWithETag(c, types.WebhookSnapshot{Stats: stats, Overrides: overrides}, types.WebhookSnapshot{Stats: statsContent(stats), Overrides: overrides})
 }
 
 func HandlePrometheusProxy(c *gin.Context) {
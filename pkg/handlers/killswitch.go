@@ -15,6 +15,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -52,7 +53,7 @@ func KillswitchHandler(c *gin.Context) {
 	}
 
 	// Step 2: kill pods with adjusted resources
-	podsAnalyzed, podsKilled, killedPods, errors := analyzeAndKillPods(ctx, clients.KubeClient, dryRun)
+	podsAnalyzed, podsKilled, killedPods, errors := analyzeAndKillPods(ctx, clients.KubeClient, clients.DynamicClient, dryRun)
 	response.PodsAnalyzed = podsAnalyzed
 	response.PodsKilled = podsKilled
 	response.KilledPods = append(response.KilledPods, killedPods...)
@@ -94,7 +95,7 @@ func deleteMutatingWebhookConfiguration(ctx context.Context, kubeClient *kuberne
 	return nil
 }
 
-func analyzeAndKillPods(ctx context.Context, kubeClient *kubernetes.Clientset, dryRun bool) (int, int, []string, []string) {
+func analyzeAndKillPods(ctx context.Context, kubeClient *kubernetes.Clientset, dynamicClient dynamic.Interface, dryRun bool) (int, int, []string, []string) {
 	var errors []string
 	var killedPods []string
 	podsAnalyzed := 0
@@ -121,7 +122,7 @@ func analyzeAndKillPods(ctx context.Context, kubeClient *kubernetes.Clientset, d
 			continue
 		}
 
-		workloadObj, err := utils.GetWorkloadObject(ctx, kubeClient, workloadInfo.Kind, workloadInfo.Namespace, workloadInfo.Name)
+		workloadObj, err := utils.GetWorkloadObject(ctx, kubeClient, dynamicClient, workloadInfo.Kind, workloadInfo.Namespace, workloadInfo.Name)
 		if err != nil {
 			errors = append(errors, fmt.Sprintf("Failed to get workload %s/%s/%s: %v",
 				workloadInfo.Kind, workloadInfo.Namespace, workloadInfo.Name, err))
@@ -155,6 +156,10 @@ func analyzeAndKillPods(ctx context.Context, kubeClient *kubernetes.Clientset, d
 }
 
 func comparePodWithWorkload(ctx context.Context, kubeClient *kubernetes.Clientset, pod *corev1.Pod, workload utils.WorkloadObject) (bool, string) {
+	if _, adjusted := utils.GetPodOriginalResources(pod); adjusted {
+		return true, "resources adjusted by the webhook"
+	}
+
 	workloadContainers := workload.GetContainerSpecs(ctx, kubeClient)
 	workloadInitContainers := workload.GetInitContainerSpecs(ctx, kubeClient)
 
@@ -8,6 +8,7 @@ import (
 
 	"github.com/truefoundry/cruisekube/pkg/logging"
 	"github.com/truefoundry/cruisekube/pkg/repository/storage"
+	"github.com/truefoundry/cruisekube/pkg/task/applystrategies"
 	"github.com/truefoundry/cruisekube/pkg/task/utils"
 	"github.com/truefoundry/cruisekube/pkg/types"
 
@@ -55,6 +56,22 @@ func UpdateWorkloadOverridesHandler(c *gin.Context) {
 		return
 	}
 
+	if overrides != nil && overrides.Strategy != nil && *overrides.Strategy != "" && !applystrategies.IsRegisteredStrategy(*overrides.Strategy) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Unknown strategy %q, available strategies: %s", *overrides.Strategy, strings.Join(applystrategies.ListStrategies(), ", ")),
+		})
+		return
+	}
+
+	if overrides != nil {
+		if err := overrides.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid overrides: %v", err),
+			})
+			return
+		}
+	}
+
 	if err := storage.Stg.UpdateWorkloadOverrides(clusterID, workloadID, overrides); err != nil {
 		logging.Errorf(c.Request.Context(), "Failed to update workload overrides: %v", err)
 		if strings.Contains(err.Error(), "workload not found") {
@@ -89,3 +106,82 @@ func UpdateWorkloadOverridesHandler(c *gin.Context) {
 
 	logging.Infof(c.Request.Context(), "Successfully updated overrides for workload %s in cluster %s", workloadID, clusterID)
 }
+
+func ListNamespaceOverridesHandler(c *gin.Context) {
+	clusterID := c.Param("clusterID")
+	overrides, err := storage.Stg.GetNamespaceOverrides(clusterID)
+	if err != nil {
+		logging.Errorf(c.Request.Context(), "Failed to list namespace overrides: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to list namespace overrides: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, overrides)
+}
+
+func GetNamespaceOverridesHandler(c *gin.Context) {
+	clusterID := c.Param("clusterID")
+	namespace := c.Param("namespace")
+	overrides, err := storage.Stg.GetNamespaceOverrides(clusterID)
+	if err != nil {
+		logging.Errorf(c.Request.Context(), "Failed to get namespace overrides: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to get namespace overrides: %v", err),
+		})
+		return
+	}
+
+	namespaceOverrides, ok := overrides[namespace]
+	if !ok {
+		namespaceOverrides = &types.Overrides{}
+	}
+	c.JSON(http.StatusOK, namespaceOverrides)
+}
+
+// UpdateNamespaceOverridesHandler sets the overrides of a namespace, only the strategy can be set for a namespace.
+func UpdateNamespaceOverridesHandler(c *gin.Context) {
+	clusterID := c.Param("clusterID")
+	namespace := c.Param("namespace")
+	var overrides types.Overrides
+	if err := json.NewDecoder(c.Request.Body).Decode(&overrides); err != nil {
+		logging.Errorf(c.Request.Context(), "Failed to decode request body: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+
+	if overrides.Strategy != nil && *overrides.Strategy != "" && !applystrategies.IsRegisteredStrategy(*overrides.Strategy) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Unknown strategy %q, available strategies: %s", *overrides.Strategy, strings.Join(applystrategies.ListStrategies(), ", ")),
+		})
+		return
+	}
+
+	if overrides.EvictionRanking != nil || overrides.Enabled != nil || overrides.Recommendation != nil ||
+		overrides.ResizeGuaranteed != nil || overrides.ResourceBounds != nil || overrides.ContainerResourceBounds != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Only the strategy can be overridden for a namespace",
+		})
+		return
+	}
+
+	if err := storage.Stg.UpdateNamespaceOverrides(clusterID, namespace, &overrides); err != nil {
+		logging.Errorf(c.Request.Context(), "Failed to update namespace overrides: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update namespace overrides",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Namespace overrides updated successfully",
+		"cluster_id": clusterID,
+		"namespace":  namespace,
+		"overrides":  overrides,
+	})
+
+	logging.Infof(c.Request.Context(), "Successfully updated overrides for namespace %s in cluster %s", namespace, clusterID)
+}
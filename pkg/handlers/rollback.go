@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/cluster"
+	"github.com/truefoundry/cruisekube/pkg/logging"
+	"github.com/truefoundry/cruisekube/pkg/repository/storage"
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+	"github.com/truefoundry/cruisekube/pkg/types"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const RollbackStrategyName = "Rollback"
+
+// RollbackHandler resizes running pods back to the original container resources the webhook recorded on
+// them, or else to the ones recorded in the workload stats. Pods are resized in place, nothing is restarted. The namespace and workload query
+// parameters narrow the rollback down, without them the whole cluster is rolled back.
+func RollbackHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	mgr := c.MustGet("clusterManager").(cluster.Manager)
+	clusterID := c.Param("clusterID")
+
+	dryRun := c.Query("dry_run") == "true"
+	namespace := c.Query("namespace")
+	workloadID := c.Query("workload")
+	if workloadID != "" {
+		_, workloadNamespace, _, ok := utils.ParseWorkloadKey(workloadID)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid workload %q, expected kind:namespace:name", workloadID),
+			})
+			return
+		}
+		if namespace != "" && namespace != workloadNamespace {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Workload %s is not in namespace %s", workloadID, namespace),
+			})
+			return
+		}
+		namespace = workloadNamespace
+	}
+
+	clients, err := mgr.GetClusterClients(clusterID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Unable to fetch cluster clients for cluster %s: %v", clusterID, err),
+		})
+		return
+	}
+
+	stats, err := storage.Stg.GetAllStatsForCluster(clusterID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to get stats for cluster %s: %v", clusterID, err),
+		})
+		return
+	}
+
+	startedAt := time.Now()
+	response := rollbackPods(ctx, clients.KubeClient, stats, namespace, workloadID, dryRun)
+	response.Message = fmt.Sprintf("Rollback completed. Pods analyzed: %d, Pods resized: %d", response.PodsAnalyzed, response.PodsResized)
+	logging.Infof(ctx, "%s", response.Message)
+
+	run := &types.ApplyRun{
+		ID:         fmt.Sprintf("%s-rollback-%d", clusterID, startedAt.UnixNano()),
+		ClusterID:  clusterID,
+		Strategy:   RollbackStrategyName,
+		DryRun:     dryRun,
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+		Entries:    response.Changes,
+	}
+	if err := storage.Stg.InsertApplyRun(run); err != nil {
+		logging.Errorf(ctx, "Failed to persist rollback run %s: %v", run.ID, err)
+		response.Errors = append(response.Errors, fmt.Sprintf("Failed to persist rollback run: %v", err))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func rollbackPods(ctx context.Context, kubeClient *kubernetes.Clientset, stats []types.WorkloadStat, namespace, workloadID string, dryRun bool) *types.RollbackResponse {
+	response := &types.RollbackResponse{
+		DryRun:  dryRun,
+		Changes: make([]types.ApplyRunEntry, 0),
+		Errors:  make([]string, 0),
+	}
+
+	statsByWorkload := make(map[string]*types.WorkloadStat, len(stats))
+	for i := range stats {
+		statsByWorkload[stats[i].WorkloadIdentifier] = &stats[i]
+	}
+
+	podToWorkloadMap, allPods, err := utils.BuildPodToWorkloadMapping(ctx, kubeClient, namespace)
+	if err != nil {
+		response.Errors = append(response.Errors, fmt.Sprintf("Failed to build pod-to-workload mapping: %v", err))
+		return response
+	}
+
+	for _, pod := range allPods {
+		workloadInfo, exists := podToWorkloadMap[utils.PodKey{Namespace: pod.Namespace, PodName: pod.Name}]
+		if !exists {
+			continue
+		}
+		workloadKey := utils.GetWorkloadKey(workloadInfo.Kind, workloadInfo.Namespace, workloadInfo.Name)
+		if workloadID != "" && workloadKey != workloadID {
+			continue
+		}
+		podOriginals, hasPodOriginals := utils.GetPodOriginalResources(pod)
+		stat, exists := statsByWorkload[workloadKey]
+		if !hasPodOriginals && (!exists || len(stat.OriginalContainerResources) == 0) {
+			continue
+		}
+
+		response.PodsAnalyzed++
+		podResized := false
+		for _, container := range pod.Spec.Containers {
+			original, ok := originalContainerResources(container.Name, podOriginals, stat)
+			if !ok {
+				continue
+			}
+
+			cpuEntry, changed := rollbackContainerResource(
+				newRollbackEntry(pod, workloadKey, container.Name, types.ApplyRunResourceCPU),
+				container.Resources, corev1.ResourceCPU, original.CPURequest, original.CPULimit, utils.MinimumCPURecommendation, dryRun,
+				func(request, limit float64) (bool, string) {
+					return utils.UpdatePodCPUResources(ctx, kubeClient, pod, container.Name, request, limit)
+				},
+			)
+			if changed {
+				podResized = recordRollbackChange(response, cpuEntry) || podResized
+			}
+
+			memoryEntry, changed := rollbackContainerResource(
+				newRollbackEntry(pod, workloadKey, container.Name, types.ApplyRunResourceMemory),
+				container.Resources, corev1.ResourceMemory, original.MemoryRequest, original.MemoryLimit, 1, dryRun,
+				func(request, limit float64) (bool, string) {
+					return utils.UpdatePodMemoryResources(ctx, kubeClient, pod, container.Name, request, limit)
+				},
+			)
+			if changed {
+				podResized = recordRollbackChange(response, memoryEntry) || podResized
+			}
+		}
+		if podResized {
+			response.PodsResized++
+		}
+	}
+
+	return response
+}
+
+// originalContainerResources prefers the exact resources the webhook recorded on the pod to the ones of
+// the workload stats.
+func originalContainerResources(containerName string, podOriginals map[string]corev1.ResourceRequirements, stat *types.WorkloadStat) (*types.OriginalContainerResources, bool) {
+	if resources, exists := podOriginals[containerName]; exists {
+		return &types.OriginalContainerResources{
+			Name:          containerName,
+			CPURequest:    quantityInRecommendationUnits(resources.Requests, corev1.ResourceCPU),
+			CPULimit:      quantityInRecommendationUnits(resources.Limits, corev1.ResourceCPU),
+			MemoryRequest: quantityInRecommendationUnits(resources.Requests, corev1.ResourceMemory),
+			MemoryLimit:   quantityInRecommendationUnits(resources.Limits, corev1.ResourceMemory),
+		}, true
+	}
+	if stat == nil {
+		return nil, false
+	}
+	original, err := stat.GetOriginalContainerResource(containerName)
+	if err != nil {
+		return nil, false
+	}
+	return original, true
+}
+
+func recordRollbackChange(response *types.RollbackResponse, entry types.ApplyRunEntry) bool {
+	response.Changes = append(response.Changes, entry)
+	if entry.Action == types.ApplyRunActionFailed {
+		response.Errors = append(response.Errors, fmt.Sprintf("Failed to roll back %s of pod %s/%s container %s: %s", entry.Resource, entry.Namespace, entry.PodName, entry.ContainerName, entry.Reason))
+		return false
+	}
+	return true
+}
+
+func newRollbackEntry(pod *corev1.Pod, workloadKey, containerName string, resource types.ApplyRunResource) types.ApplyRunEntry {
+	return types.ApplyRunEntry{
+		Namespace:     pod.Namespace,
+		PodName:       pod.Name,
+		NodeName:      pod.Spec.NodeName,
+		WorkloadID:    workloadKey,
+		ContainerName: containerName,
+		Resource:      resource,
+		Action:        types.ApplyRunActionResized,
+		Reason:        "rollback to original resources",
+	}
+}
+
+// rollbackContainerResource resizes one resource of a container back to its original request and limit.
+// A limit can't be added or removed in place, so the limit is only restored when the container has one
+// and is never left below the request. It returns false when the container already matches.
+func rollbackContainerResource(
+	entry types.ApplyRunEntry,
+	resources corev1.ResourceRequirements,
+	resourceName corev1.ResourceName,
+	originalRequest, originalLimit, tolerance float64,
+	dryRun bool,
+	update func(request, limit float64) (bool, string),
+) (types.ApplyRunEntry, bool) {
+	if originalRequest == 0 {
+		return entry, false
+	}
+
+	currentRequest := quantityInRecommendationUnits(resources.Requests, resourceName)
+	currentLimit := quantityInRecommendationUnits(resources.Limits, resourceName)
+
+	targetLimit := 0.0
+	if currentLimit > 0 {
+		targetLimit = currentLimit
+		if originalLimit > 0 {
+			targetLimit = originalLimit
+		}
+		targetLimit = math.Max(targetLimit, originalRequest)
+	}
+
+	if math.Abs(currentRequest-originalRequest) < tolerance && math.Abs(currentLimit-targetLimit) < tolerance {
+		return entry, false
+	}
+
+	entry.OldRequest, entry.NewRequest = currentRequest, originalRequest
+	entry.OldLimit, entry.NewLimit = currentLimit, targetLimit
+	if dryRun {
+		return entry, true
+	}
+
+	if applied, errStr := update(originalRequest, targetLimit); !applied {
+		entry.Action = types.ApplyRunActionFailed
+		entry.Reason = errStr
+	}
+	return entry, true
+}
+
+// quantityInRecommendationUnits returns cpu in cores and memory in MB.
+func quantityInRecommendationUnits(resources corev1.ResourceList, resourceName corev1.ResourceName) float64 {
+	quantity, exists := resources[resourceName]
+	if !exists {
+		return 0
+	}
+	if resourceName == corev1.ResourceCPU {
+		return float64(quantity.MilliValue()) / 1000.0
+	}
+	return float64(quantity.Value()) / utils.BytesToMBDivisor
+}
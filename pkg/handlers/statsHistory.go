@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/logging"
+	"github.com/truefoundry/cruisekube/pkg/repository/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultStatsHistoryLookback = 7 * 24 * time.Hour
+
+// GetWorkloadStatsHistoryHandler returns the stats of a workload generated between the since and until
+// query parameters, oldest first.
+func GetWorkloadStatsHistoryHandler(c *gin.Context) {
+	clusterID := c.Param("clusterID")
+	workloadID := c.Param("workloadID")
+
+	until := time.Now()
+	if value := c.Query("until"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid until %q, expected RFC3339: %v", value, err),
+			})
+			return
+		}
+		until = parsed
+	}
+	since := until.Add(-defaultStatsHistoryLookback)
+	if value := c.Query("since"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid since %q, expected RFC3339: %v", value, err),
+			})
+			return
+		}
+		since = parsed
+	}
+
+	history, err := storage.Stg.GetWorkloadStatHistory(clusterID, workloadID, since, until)
+	if err != nil {
+		logging.Errorf(c.Request.Context(), "Failed to get stats history of workload %s: %v", workloadID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to get stats history: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cluster_id":  clusterID,
+		"workload_id": workloadID,
+		"history":     history,
+	})
+}
@@ -128,6 +128,9 @@ func ListWorkloadsHandler(c *gin.Context) {
 
 		evictionRanking := stat.EvictionRanking
 		enabled := true
+		strategy := ""
+		var recommendation *types.RecommendationOverrides
+		resizeGuaranteed := false
 		if overrides != nil {
 			if overrides.EvictionRanking != nil {
 				evictionRanking = *overrides.EvictionRanking
@@ -135,16 +138,30 @@ func ListWorkloadsHandler(c *gin.Context) {
 			if overrides.Enabled != nil {
 				enabled = *overrides.Enabled
 			}
+			if overrides.Strategy != nil {
+				strategy = *overrides.Strategy
+			}
+			recommendation = overrides.Recommendation
+			if overrides.ResizeGuaranteed != nil {
+				resizeGuaranteed = *overrides.ResizeGuaranteed
+			}
 		}
 
 		workloadExternalId := strings.ReplaceAll(stat.WorkloadIdentifier, "/", ":")
 		workload := types.WorkloadOverrideInfo{
-			WorkloadID:      workloadExternalId,
-			Name:            stat.Name,
-			Namespace:       stat.Namespace,
-			Kind:            stat.Kind,
-			EvictionRanking: evictionRanking,
-			Enabled:         enabled,
+			WorkloadID:       workloadExternalId,
+			Name:             stat.Name,
+			Namespace:        stat.Namespace,
+			Kind:             stat.Kind,
+			EvictionRanking:  evictionRanking,
+			Enabled:          enabled,
+			Strategy:         strategy,
+			Recommendation:   recommendation,
+			ResizeGuaranteed: resizeGuaranteed,
+		}
+		if overrides != nil {
+			workload.ResourceBounds = overrides.ResourceBounds
+			workload.ContainerResourceBounds = overrides.ContainerResourceBounds
 		}
 		workloads = append(workloads, workload)
 	}
@@ -14,6 +14,7 @@ import (
 	"github.com/truefoundry/cruisekube/pkg/config"
 	"github.com/truefoundry/cruisekube/pkg/logging"
 	"github.com/truefoundry/cruisekube/pkg/task/utils"
+	"github.com/truefoundry/cruisekube/pkg/types"
 
 	"github.com/gin-gonic/gin"
 	admissionv1 "k8s.io/api/admission/v1"
@@ -23,8 +24,14 @@ import (
 
 var (
 	excludedPodPrefixes = []string{}
+	webhookStatsCache   *client.WebhookStatsCache
 )
 
+// SetWebhookStatsCache sets the cache the admissions read the stats and overrides from.
+func SetWebhookStatsCache(cache *client.WebhookStatsCache) {
+	webhookStatsCache = cache
+}
+
 func MutateHandler(c *gin.Context) {
 	ctx := c.Request.Context()
 	clusterID := c.Param("clusterID")
@@ -128,18 +135,6 @@ func getPodName(pod *corev1.Pod) string {
 	return "unknown"
 }
 
-func findWorkloadStat(stats *utils.StatsResponse, workloadInfo *utils.WorkloadInfo) *utils.WorkloadStat {
-	identifier := utils.GetWorkloadKey(workloadInfo.Kind, workloadInfo.Namespace, workloadInfo.Name)
-
-	for _, stat := range stats.Stats {
-		if stat.WorkloadIdentifier == identifier {
-			return &stat
-		}
-	}
-
-	return nil
-}
-
 func cpuCoresToMillicores(cpuCores float64) string {
 	return fmt.Sprintf("%dm", int64(cpuCores*1000))
 }
@@ -153,59 +148,53 @@ func adjustResources(ctx context.Context, pod *corev1.Pod, clusterID string, cfg
 	workloadInfo := utils.GetWorkloadInfoFromPod(pod)
 	if workloadInfo == nil {
 		logging.Warnf(ctx, "Could not determine workload for pod %s/%s, allowing without adjustment", pod.Namespace, getPodName(pod))
-		return []map[string]any{}, nil
+		return skipAdmission(pod, "workload of the pod is unknown"), nil
 	}
 
 	logging.Infof(ctx, "Pod %s/%s belongs to workload: %s", pod.Namespace, getPodName(pod), utils.GetWorkloadKey(workloadInfo.Kind, workloadInfo.Namespace, workloadInfo.Name))
 
 	workloadID := strings.ReplaceAll(utils.GetWorkloadKey(workloadInfo.Kind, workloadInfo.Namespace, workloadInfo.Name), "/", ":")
 
-	recommenderClient := client.NewRecommenderServiceClientWithClusterToken(
-		cfg.Webhook.StatsURL.Host,
-		cfg.Webhook.StatsURL.TfyClusterToken,
-	)
-
-	workloadOverrides, err := recommenderClient.WebhookGetWorkloadOverrides(ctx, clusterID, workloadID)
-	if err != nil {
-		logging.Errorf(ctx, "Could not fetch overrides, allowing pod without adjustment: %v", err)
-		return []map[string]any{}, nil
+	if webhookStatsCache == nil {
+		logging.Errorf(ctx, "Webhook stats cache is not set, allowing pod without adjustment")
+		return skipAdmission(pod, "stats are not available"), nil
 	}
-
-	if workloadOverrides.Enabled != nil && !*workloadOverrides.Enabled {
-		logging.Infof(ctx, "Workload %s is disabled via overrides, skipping", workloadID)
-		return []map[string]any{}, nil
+	workloadStat, workloadOverrides, ok := webhookStatsCache.Lookup(ctx, clusterID, workloadID)
+	if !ok {
+		logging.Errorf(ctx, "Stats of cluster %s are not available, allowing pod without adjustment", clusterID)
+		return skipAdmission(pod, "stats are not available"), nil
 	}
 
-	statsResponse, err := recommenderClient.WebhookGetClusterStats(ctx, clusterID)
-	if err != nil {
-		logging.Errorf(ctx, "Could not fetch stats, allowing pod without adjustment: %v", err)
-		return []map[string]any{}, nil
+	if workloadOverrides == nil {
+		workloadOverrides = &types.Overrides{}
 	}
-	if len(statsResponse.Stats) == 0 {
-		logging.Infof(ctx, "No stats found for workload %s, allowing pod without adjustment", workloadID)
-		return []map[string]any{}, nil
+	if workloadOverrides.Enabled != nil && !*workloadOverrides.Enabled {
+		logging.Infof(ctx, "Workload %s is disabled via overrides, skipping", workloadID)
+		return skipAdmission(pod, "workload is disabled by overrides"), nil
 	}
 
-	workloadStat := findWorkloadStat(statsResponse, workloadInfo)
 	if workloadStat == nil {
 		logging.Infof(ctx, "No stat found for workload %s, allowing pod without adjustment", workloadID)
-		return []map[string]any{}, nil
+		return skipAdmission(pod, "workload has no stats"), nil
 	}
 
 	if workloadStat.IsHorizontallyAutoscaledOnCPU {
 		logging.Infof(ctx, "Workload %s is horizontally autoscaled on CPU, skipping", workloadID)
-		return []map[string]any{}, nil
+		return skipAdmission(pod, "workload is horizontally autoscaled on cpu"), nil
 	}
 
 	if workloadStat.CreationTime.After(time.Now().Add(-1 * time.Hour * time.Duration(cfg.RecommendationSettings.NewWorkloadThresholdHours))) {
 		logging.Infof(ctx, "Workload %s is from a new workload, skipping", workloadID)
-		return []map[string]any{}, nil
+		return skipAdmission(pod, "workload is new"), nil
 	}
 
 	containers := make([]corev1.Container, 0, len(pod.Spec.Containers)+len(pod.Spec.InitContainers))
 	containers = append(containers, pod.Spec.Containers...)
 	containers = append(containers, pod.Spec.InitContainers...)
+	memoryLimitPolicy := utils.NewMemoryLimitPolicy(cfg.RecommendationSettings)
+	ephemeralStoragePolicy := utils.NewEphemeralStoragePolicy(cfg.RecommendationSettings)
 	var patches []map[string]any
+	decisions := make([]string, 0, len(containers))
 	for i, container := range containers {
 		containerPath := fmt.Sprintf("/spec/containers/%d", i)
 		if i >= len(pod.Spec.Containers) {
@@ -246,27 +235,43 @@ func adjustResources(ctx context.Context, pod *corev1.Pod, clusterID string, cfg
 
 		if containerStat == nil || containerStat.CPUStats == nil || containerStat.MemoryStats == nil || containerStat.SimplePredictionsCPU == nil || containerStat.SimplePredictionsMemory == nil {
 			logging.Infof(ctx, "No stat found for container: %s in workload: %s/%s/%s", container.Name, workloadInfo.Kind, workloadInfo.Namespace, workloadInfo.Name)
+			decisions = append(decisions, container.Name+": skipped, container has no stats")
 			continue
 		}
 
 		recommendedCPU := containerStat.CPUStats.Max
+		cpuDecision := decisionStatsMax
 		if containerStat.SimplePredictionsCPU != nil && containerStat.SimplePredictionsCPU.MaxValue > 0 {
 			recommendedCPU = containerStat.SimplePredictionsCPU.MaxValue
+			cpuDecision = decisionPredictionMax
+		}
+
+		// a new pod is in its startup window, the apply task resizes it down once it has passed it
+		if workloadStat.StartupWindowMinutes > 0 && containerStat.Startup != nil && containerStat.Startup.CPUMax > recommendedCPU {
+			logging.Infof(ctx, "Container %s - Startup-sized CPU: %s for the first %d minutes", container.Name, cpuCoresToMillicores(containerStat.Startup.CPUMax), workloadStat.StartupWindowMinutes)
+			recommendedCPU = containerStat.Startup.CPUMax
+			cpuDecision = decisionStartup
 		}
 
 		recommendedMemory := containerStat.MemoryStats.Max
+		memoryDecision := decisionStatsMax
 		if containerStat.MemoryStats.OOMMemory > 0 && containerStat.MemoryStats.OOMMemory > containerStat.MemoryStats.Max {
 			recommendedMemory = containerStat.MemoryStats.OOMMemory
+			memoryDecision = decisionOOMMemory
 		} else if containerStat.SimplePredictionsMemory != nil && containerStat.SimplePredictionsMemory.MaxValue > 0 {
 			recommendedMemory = containerStat.SimplePredictionsMemory.MaxValue
+			memoryDecision = decisionPredictionMax
 		}
 
-		recommendedMemoryLimit := 2 * recommendedMemory
-		if containerStat.Memory7Day != nil && containerStat.Memory7Day.Max > 0 {
-			recommendedMemoryLimit = math.Max(2*containerStat.Memory7Day.Max, max(2*containerStat.MemoryStats.OOMMemory, recommendedMemoryLimit))
+		bounds := types.MergeResourceBounds(workloadOverrides.ResourceBounds, workloadOverrides.ContainerResourceBounds, container.Name)
+		if clampedCPU := bounds.ClampCPU(recommendedCPU); clampedCPU != recommendedCPU {
+			recommendedCPU = clampedCPU
+			cpuDecision += ", clamped by overrides"
+		}
+		if clampedMemory := bounds.ClampMemory(recommendedMemory); clampedMemory != recommendedMemory {
+			recommendedMemory = clampedMemory
+			memoryDecision += ", clamped by overrides"
 		}
-
-		recommendedMemoryLimitBytes := int64(math.Max(recommendedMemoryLimit, 512) * utils.BytesToMBDivisor)
 
 		logging.Infof(ctx, "Container %s - Recommended CPU: %s (max: %f)", container.Name, cpuCoresToMillicores(recommendedCPU), containerStat.CPUStats.Max)
 		logging.Infof(ctx, "Container %s - Recommended Memory: %s", container.Name, memoryBytesToMB(int64(recommendedMemory*utils.BytesToMBDivisor)))
@@ -284,10 +289,19 @@ func adjustResources(ctx context.Context, pod *corev1.Pod, clusterID string, cfg
 			currentMemoryLimit = container.Resources.Limits[corev1.ResourceMemory]
 		}
 
+		recommendedMemoryLimit, hasMemoryLimit := memoryLimitPolicy.Limit(recommendedMemory, float64(currentMemoryLimit.Value())/utils.BytesToMBDivisor, containerStat)
+		recommendedMemoryLimitBytes := int64(recommendedMemoryLimit * utils.BytesToMBDivisor)
+
 		// CPU
 		currentCPUMillicores := currentCPURequest.MilliValue()
 		recommendedCPUMillicores := math.Max(float64(recommendedCPU*1000), 1)
 
+		switch {
+		case currentCPUMillicores == 0:
+			cpuDecision = "kept, no request"
+		case workloadInfo.Kind == utils.DaemonSetKind:
+			cpuDecision = "kept, daemonset"
+		}
 		if currentCPUMillicores > 0 {
 			if workloadInfo.Kind != utils.DaemonSetKind {
 				patches = append(patches, map[string]any{
@@ -306,7 +320,14 @@ func adjustResources(ctx context.Context, pod *corev1.Pod, clusterID string, cfg
 
 		if !cfg.RecommendationSettings.DisableMemoryApplication && currentMemoryBytes > 0 && math.Abs(float64(recommendedMemoryBytes-currentMemoryBytes)) > thresholdBytes {
 			if workloadInfo.Kind == utils.DaemonSetKind {
-				if currentMemoryLimit.Value() > 0 {
+				memoryDecision += ", limit only for daemonset"
+				if currentMemoryLimit.Value() > 0 && !hasMemoryLimit {
+					patches = append(patches, map[string]any{
+						"op":   "remove",
+						"path": containerPath + "/resources/limits/memory",
+					})
+					logging.Infof(ctx, "Removed Memory limit of DaemonSet container %s", container.Name)
+				} else if currentMemoryLimit.Value() > 0 {
 					currentMemoryLimitBytes := currentMemoryLimit.Value()
 					finalMemoryLimitBytes := math.Max(float64(currentMemoryLimitBytes), math.Max(float64(recommendedMemoryLimitBytes), 16*utils.BytesToMBDivisor))
 
@@ -332,7 +353,14 @@ func adjustResources(ctx context.Context, pod *corev1.Pod, clusterID string, cfg
 						"value": memoryBytesToMB(recommendedMemoryBytes),
 					})
 				}
-				if currentMemoryLimit.Value() > 0 {
+				if !hasMemoryLimit {
+					if currentMemoryLimit.Value() > 0 {
+						patches = append(patches, map[string]any{
+							"op":   "remove",
+							"path": containerPath + "/resources/limits/memory",
+						})
+					}
+				} else if currentMemoryLimit.Value() > 0 {
 					patches = append(patches, map[string]any{
 						"op":    "replace",
 						"path":  containerPath + "/resources/limits/memory",
@@ -350,6 +378,15 @@ func adjustResources(ctx context.Context, pod *corev1.Pod, clusterID string, cfg
 			}
 		} else if cfg.RecommendationSettings.DisableMemoryApplication {
 			logging.Infof(ctx, "Skipping memory recommendation application for container %s since memory recommendationapplication is disabled", container.Name)
+			memoryDecision = "kept, disabled"
+		} else {
+			memoryDecision = "kept, no request or within 16MB"
+		}
+		decisions = append(decisions, fmt.Sprintf("%s: cpu=%s, memory=%s", container.Name, cpuDecision, memoryDecision))
+
+		// Ephemeral storage
+		if ephemeralStoragePolicy.Enabled {
+			patches = append(patches, ephemeralStoragePatches(ctx, container, containerPath, workloadInfo.Kind, containerStat, ephemeralStoragePolicy)...)
 		}
 	}
 
@@ -358,5 +395,43 @@ func adjustResources(ctx context.Context, pod *corev1.Pod, clusterID string, cfg
 		return []map[string]any{}, nil
 	}
 
-	return patches, nil
+	return append(patches, explainAdmission(pod, patches, workloadStat.UpdatedAt, decisions)...), nil
+}
+
+// ephemeralStoragePatches sets the ephemeral-storage request of a container and raises its limit, a limit
+// is never added. DaemonSet requests are kept, like their memory requests.
+func ephemeralStoragePatches(ctx context.Context, container corev1.Container, containerPath, kind string, containerStat *utils.ContainerStats, policy utils.EphemeralStoragePolicy) []map[string]any {
+	currentRequest := container.Resources.Requests[corev1.ResourceEphemeralStorage]
+	currentLimit := container.Resources.Limits[corev1.ResourceEphemeralStorage]
+	currentRequestMB := float64(currentRequest.Value()) / utils.BytesToMBDivisor
+	currentLimitMB := float64(currentLimit.Value()) / utils.BytesToMBDivisor
+
+	recommendedRequest, recommendedLimit, ok := policy.Recommend(containerStat, currentLimitMB)
+	if !ok {
+		logging.Infof(ctx, "No ephemeral storage stat found for container %s", container.Name)
+		return nil
+	}
+
+	var patches []map[string]any
+	if kind != utils.DaemonSetKind && math.Abs(recommendedRequest-currentRequestMB) > 16 {
+		op := "add"
+		if currentRequestMB > 0 {
+			op = "replace"
+		}
+		patches = append(patches, map[string]any{
+			"op":    op,
+			"path":  containerPath + "/resources/requests/ephemeral-storage",
+			"value": memoryBytesToMB(int64(recommendedRequest * utils.BytesToMBDivisor)),
+		})
+		logging.Infof(ctx, "Adjusted ephemeral storage request for container %s from %.0fMB to %.0fMB", container.Name, currentRequestMB, recommendedRequest)
+	}
+	if currentLimitMB > 0 && recommendedLimit > currentLimitMB {
+		patches = append(patches, map[string]any{
+			"op":    "replace",
+			"path":  containerPath + "/resources/limits/ephemeral-storage",
+			"value": memoryBytesToMB(int64(recommendedLimit * utils.BytesToMBDivisor)),
+		})
+		logging.Infof(ctx, "Raised ephemeral storage limit for container %s from %.0fMB to %.0fMB", container.Name, currentLimitMB, recommendedLimit)
+	}
+	return patches
 }
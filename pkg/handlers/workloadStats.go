@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/logging"
+	"github.com/truefoundry/cruisekube/pkg/repository/storage"
+	"github.com/truefoundry/cruisekube/pkg/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleWorkloadStats serves the stat of a single workload. Like the cluster stats it honors If-None-Match,
+// and answers 304 when the stat was not updated after the since query parameter.
+func HandleWorkloadStats(c *gin.Context) {
+	ctx := c.Request.Context()
+	clusterID := c.Param("clusterID")
+	workloadID := c.Param("workloadID")
+
+	filter, err := parseStatsFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	stat, err := storage.Stg.GetWorkloadStat(clusterID, workloadID)
+	if err != nil {
+		logging.Errorf(ctx, "Failed to get stat of workload %s: %v", workloadID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to get stat of workload %s: %v", workloadID, err),
+		})
+		return
+	}
+	if stat == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": fmt.Sprintf("No stat found for workload %s", workloadID),
+		})
+		return
+	}
+	if !filter.Since.IsZero() && !stat.UpdatedAt.After(filter.Since) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	writeJSONWithETag(c, stat, stat.Content())
+}
+
+// parseStatsFilter reads the namespace, kind and since query parameters.
+func parseStatsFilter(c *gin.Context) (types.StatsFilter, error) {
+	filter := types.StatsFilter{
+		Namespace: c.Query("namespace"),
+		Kind:      c.Query("kind"),
+	}
+	if since := c.Query("since"); since != "" {
+		parsed, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return filter, fmt.Errorf("invalid since %q, expected RFC3339: %w", since, err)
+		}
+		filter.Since = parsed
+	}
+	return filter, nil
+}
+
+// writeJSONWithETag tags the response with a hash of the content and answers 304 without a body when it
+// matches the If-None-Match of the request. The content leaves out what changes without the response
+// meaning anything new to the client, like when the stats were last generated.
+func writeJSONWithETag(c *gin.Context, value any, content any) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		logging.Errorf(c.Request.Context(), "Failed to marshal response: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to marshal response: %v", err),
+		})
+		return
+	}
+	contentJSON, err := json.Marshal(content)
+	if err != nil {
+		logging.Errorf(c.Request.Context(), "Failed to marshal response content: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to marshal response content: %v", err),
+		})
+		return
+	}
+
+	etag := fmt.Sprintf("%q", fmt.Sprintf("%x", sha256.Sum256(contentJSON)))
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json", body)
+}
+
+// statsContent returns the content of every stat.
+func statsContent(stats []types.WorkloadStat) []types.WorkloadStat {
+	content := make([]types.WorkloadStat, 0, len(stats))
+	for _, stat := range stats {
+		content = append(content, stat.Content())
+	}
+	return content
+}
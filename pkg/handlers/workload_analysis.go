@@ -63,21 +63,30 @@ func analyzeWorkload(stat utils.WorkloadStat) []types.WorkloadAnalysisItem {
 			}
 		}
 		analysis = append(analysis, types.WorkloadAnalysisItem{
-			WorkloadName:      workloadName,
-			WorkloadType:      workloadType,
-			WorkloadNamespace: workloadNamespace,
-			ContainerName:     containerName,
-			ContainerType:     containerType,
-			CPUUsage7Days:     cpuUsage7Days,
-			SpikeRange:        spikeRange,
-			RequestGap:        requestGap,
-			AutoscalingOnCPU:  autoscalingOnCPU,
-			BlockingKarpenter: blockingKarpenter,
+			WorkloadName:               workloadName,
+			WorkloadType:               workloadType,
+			WorkloadNamespace:          workloadNamespace,
+			ContainerName:              containerName,
+			ContainerType:              containerType,
+			CPUUsage7Days:              cpuUsage7Days,
+			SpikeRange:                 spikeRange,
+			RequestGap:                 requestGap,
+			AutoscalingOnCPU:           autoscalingOnCPU,
+			BlockingKarpenter:          blockingKarpenter,
+			EphemeralStorageUsage7Days: formatEphemeralStorageUsage(container.EphemeralStorage),
+			ExtendedResources:          stat.Constraints.GetExtendedResourceNames(),
 		})
 	}
 	return analysis
 }
 
+func formatEphemeralStorageUsage(stats *types.EphemeralStorageStats) string {
+	if stats == nil {
+		return "N/A"
+	}
+	return fmt.Sprintf("%.1f / %.1f", stats.Max, stats.P75)
+}
+
 func calculateSpikeRange(pMax, p50 float64) float64 {
 	return (pMax - p50)
 }
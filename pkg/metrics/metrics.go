@@ -109,6 +109,22 @@ var (
 		Help: "Total number of evictions in the cluster by cruisekube",
 	}, []string{"cluster"})
 
+	ClusterDeferredEvictionCount = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cruisekube_deferred_eviction_count",
+		Help: "Number of evictions deferred to the next run by the eviction budgets in the last apply run",
+	}, []string{"cluster"})
+
+	// Resize Verification Metrics
+	ClusterResizeOutcomeCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cruisekube_resize_outcome_count",
+		Help: "Total number of verified in-place resizes by outcome",
+	}, []string{"cluster", "outcome"})
+
+	ClusterPendingResizeCount = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cruisekube_pending_resize_count",
+		Help: "Number of in-place resizes not yet confirmed by the kubelet after the last apply run",
+	}, []string{"cluster"})
+
 	ClusterKarpenterConsolidationEvictionCount = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "cruisekube_karpenter_consolidation_eviction_count",
 		Help: "Total number of karpenter consolidation + eviction in the cluster",
@@ -156,6 +172,11 @@ var (
 		Help: "Total number of task runs",
 	}, []string{"cluster", "task_name", "status"})
 
+	StatsHistoryWriteFailureCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cruisekube_stats_history_write_failure_count",
+		Help: "Total number of stats writes whose stats history could not be appended",
+	}, []string{"cluster"})
+
 	// Webhook Metrics
 	WebhookControllerAPICallsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "cruisekube_webhook_controller_api_calls",
@@ -18,21 +18,51 @@ type Database interface {
 
 	// Get
 	GetStatsForCluster(clusterID string) ([]types.WorkloadStat, error)
+	GetFilteredStatsForCluster(clusterID string, filter types.StatsFilter) ([]types.WorkloadStat, error)
 	GetStatForWorkload(clusterID, workloadID string) (*types.WorkloadStat, error)
 	GetStatCountForCluster(clusterID string) (int, error)
 	GetStatCountForWorkload(clusterID, workloadID string) (int, error)
 	GetStatOverridesForWorkload(clusterID, workloadID string) (*types.Overrides, error)
+	GetStatOverridesForCluster(clusterID string) (map[string]*types.Overrides, error)
 
 	// Delete
 	DeleteStatsForCluster(clusterID string) error
 	DeleteStatForWorkload(clusterID, workloadID string) error
+	GetStatDeletionsForCluster(clusterID string, since time.Time) ([]string, error)
+	DeleteOldStatDeletions(clusterID string, olderThan time.Time) (int64, error)
 
 	// Update
 	UpdateStatOverridesForWorkload(clusterID, workloadID string, overrides *types.Overrides) error
 
+	// Namespace Overrides
+	GetNamespaceOverridesForCluster(clusterID string) (map[string]*types.Overrides, error)
+	UpsertNamespaceOverrides(clusterID, namespace string, overrides *types.Overrides) error
+
 	// OOM Events
 	InsertOOMEvent(event *types.OOMEvent) error
 	GetOOMEventsByWorkload(clusterID, workloadID string, since time.Time) ([]types.OOMEvent, error)
 	GetLatestOOMEventForContainer(clusterID, containerID, podName string) (*types.OOMEvent, error)
 	DeleteOldOOMEvents(clusterID string, olderThan time.Time) (int64, error)
+
+	// Apply Runs
+	InsertApplyRun(run *types.ApplyRun) error
+	GetApplyRuns(clusterID string, filter types.ApplyRunFilter) ([]types.ApplyRun, error)
+	GetApplyRun(clusterID, runID string) (*types.ApplyRun, error)
+	DeleteOldApplyRuns(clusterID string, olderThan time.Time) (int64, error)
+
+	// Stats History
+	InsertStatHistory(clusterID string, entries []types.StatHistoryEntry) error
+	GetStatHistoryForWorkload(clusterID, workloadID string, since, until time.Time) ([]types.StatHistoryEntry, error)
+	DownsampleStatHistory(clusterID string, from, to time.Time, interval time.Duration) (int64, error)
+	DeleteOldStatHistory(clusterID string, olderThan time.Time) (int64, error)
+
+	// Recommendation States
+	GetRecommendationStates(clusterID string) ([]types.RecommendationState, error)
+	UpsertRecommendationStates(clusterID string, states []types.RecommendationState) error
+	DeleteOldRecommendationStates(clusterID string, olderThan time.Time) (int64, error)
+
+	// Usage Histograms
+	UpsertUsageHistograms(clusterID string, histograms []types.UsageHistogram) error
+	GetUsageHistograms(clusterID, namespace string, resolution time.Duration, since time.Time) ([]types.UsageHistogram, error)
+	DeleteOldUsageHistograms(clusterID string, resolution time.Duration, olderThan time.Time) (int64, error)
 }
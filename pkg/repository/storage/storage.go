@@ -1,11 +1,14 @@
 package storage
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/truefoundry/cruisekube/pkg/logging"
+	"github.com/truefoundry/cruisekube/pkg/metrics"
 	"github.com/truefoundry/cruisekube/pkg/ports"
 	"github.com/truefoundry/cruisekube/pkg/types"
 	"gorm.io/gorm"
@@ -21,14 +24,22 @@ func NewStorageRepo(db ports.Database) (*Storage, error) {
 	return &Storage{DB: db}, nil
 }
 
-func (s *Storage) WriteClusterStats(clusterID string, statsResponse types.StatsResponse, generatedAt time.Time) error {
+// WriteClusterStats replaces the latest stat of every workload and appends it to the stats history. A
+// failed history write is logged and counted, it does not fail the latest stats the recommendations use.
+func (s *Storage) WriteClusterStats(ctx context.Context, clusterID string, statsResponse types.StatsResponse, generatedAt time.Time) error {
+	history := make([]types.StatHistoryEntry, 0, len(statsResponse.Stats))
 	for _, stat := range statsResponse.Stats {
 		workloadID := strings.ReplaceAll(stat.WorkloadIdentifier, "/", ":")
 		if err := s.DB.UpsertStat(clusterID, workloadID, stat, generatedAt); err != nil {
 			return fmt.Errorf("failed to write workload stat %s: %w", workloadID, err)
 		}
+		history = append(history, types.StatHistoryEntry{WorkloadID: workloadID, GeneratedAt: generatedAt, Stat: stat})
 	}
 
+	if err := s.DB.InsertStatHistory(clusterID, history); err != nil {
+		logging.Errorf(ctx, "Failed to write stats history of %d workloads for cluster %s: %v", len(history), clusterID, err)
+		metrics.StatsHistoryWriteFailureCount.WithLabelValues(clusterID).Inc()
+	}
 	return nil
 }
 
@@ -80,6 +91,72 @@ func (s *Storage) GetWorkloadOverrides(clusterID, workloadID string) (*types.Ove
 	return overrides, nil
 }
 
+func (s *Storage) GetFilteredClusterStats(clusterID string, filter types.StatsFilter) ([]types.WorkloadStat, error) {
+	stats, err := s.DB.GetFilteredStatsForCluster(clusterID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get filtered cluster stats: %w", err)
+	}
+	return stats, nil
+}
+
+// GetRemovedStatIDs returns the ids of the workloads matching the filter whose stats were deleted after
+// its since, none without a since.
+func (s *Storage) GetRemovedStatIDs(clusterID string, filter types.StatsFilter) ([]string, error) {
+	if filter.Since.IsZero() {
+		return nil, nil
+	}
+	workloadIDs, err := s.DB.GetStatDeletionsForCluster(clusterID, filter.Since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get removed stats: %w", err)
+	}
+	removed := make([]string, 0, len(workloadIDs))
+	for _, workloadID := range workloadIDs {
+		if filter.MatchesWorkloadID(workloadID) {
+			removed = append(removed, workloadID)
+		}
+	}
+	return removed, nil
+}
+
+// GetWorkloadStat returns nil without an error when the workload has no stat.
+func (s *Storage) GetWorkloadStat(clusterID, workloadID string) (*types.WorkloadStat, error) {
+	exists, err := s.DB.HasStatForWorkload(clusterID, workloadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check workload stat existence: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+	stat, err := s.DB.GetStatForWorkload(clusterID, workloadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workload stat: %w", err)
+	}
+	return stat, nil
+}
+
+func (s *Storage) GetClusterOverrides(clusterID string) (map[string]*types.Overrides, error) {
+	overrides, err := s.DB.GetStatOverridesForCluster(clusterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster overrides: %w", err)
+	}
+	return overrides, nil
+}
+
+func (s *Storage) GetNamespaceOverrides(clusterID string) (map[string]*types.Overrides, error) {
+	overrides, err := s.DB.GetNamespaceOverridesForCluster(clusterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get namespace overrides: %w", err)
+	}
+	return overrides, nil
+}
+
+func (s *Storage) UpdateNamespaceOverrides(clusterID, namespace string, overrides *types.Overrides) error {
+	if err := s.DB.UpsertNamespaceOverrides(clusterID, namespace, overrides); err != nil {
+		return fmt.Errorf("failed to update namespace overrides: %w", err)
+	}
+	return nil
+}
+
 func (s *Storage) GetAllStatsForCluster(clusterID string) ([]types.WorkloadStat, error) {
 	stats, err := s.DB.GetStatsForCluster(clusterID)
 	if err != nil {
@@ -154,3 +231,131 @@ func (s *Storage) DeleteOldOOMEvents(clusterID string, retentionDays int) (int64
 	}
 	return rowsAffected, nil
 }
+
+// Apply Run Methods
+func (s *Storage) InsertApplyRun(run *types.ApplyRun) error {
+	if err := s.DB.InsertApplyRun(run); err != nil {
+		return fmt.Errorf("failed to insert apply run: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) GetApplyRuns(clusterID string, filter types.ApplyRunFilter) ([]types.ApplyRun, error) {
+	runs, err := s.DB.GetApplyRuns(clusterID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get apply runs: %w", err)
+	}
+	return runs, nil
+}
+
+func (s *Storage) GetApplyRun(clusterID, runID string) (*types.ApplyRun, error) {
+	run, err := s.DB.GetApplyRun(clusterID, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get apply run: %w", err)
+	}
+	return run, nil
+}
+
+func (s *Storage) DeleteOldApplyRuns(clusterID string, retentionDays int) (int64, error) {
+	cutoffTime := time.Now().Add(-time.Duration(retentionDays) * 24 * time.Hour)
+
+	rowsAffected, err := s.DB.DeleteOldApplyRuns(clusterID, cutoffTime)
+	if err != nil {
+		return rowsAffected, fmt.Errorf("failed to delete old apply runs: %w", err)
+	}
+	return rowsAffected, nil
+}
+
+// Stats History Methods
+func (s *Storage) GetWorkloadStatHistory(clusterID, workloadID string, since, until time.Time) ([]types.StatHistoryEntry, error) {
+	entries, err := s.DB.GetStatHistoryForWorkload(clusterID, workloadID, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats history for workload: %w", err)
+	}
+	return entries, nil
+}
+
+// DownsampleStatHistory keeps one stat per workload and interval for the stats generated in the window
+// that ends downsampleAfter ago.
+func (s *Storage) DownsampleStatHistory(clusterID string, downsampleAfter, window, interval time.Duration) (int64, error) {
+	to := time.Now().Add(-downsampleAfter)
+
+	rowsAffected, err := s.DB.DownsampleStatHistory(clusterID, to.Add(-window), to, interval)
+	if err != nil {
+		return rowsAffected, fmt.Errorf("failed to downsample stats history: %w", err)
+	}
+	return rowsAffected, nil
+}
+
+func (s *Storage) DeleteOldStatHistory(clusterID string, retentionDays int) (int64, error) {
+	cutoffTime := time.Now().Add(-time.Duration(retentionDays) * 24 * time.Hour)
+
+	rowsAffected, err := s.DB.DeleteOldStatHistory(clusterID, cutoffTime)
+	if err != nil {
+		return rowsAffected, fmt.Errorf("failed to delete old stats history: %w", err)
+	}
+	return rowsAffected, nil
+}
+
+func (s *Storage) DeleteOldStatDeletions(clusterID string, retentionDays int) (int64, error) {
+	cutoffTime := time.Now().Add(-time.Duration(retentionDays) * 24 * time.Hour)
+
+	rowsAffected, err := s.DB.DeleteOldStatDeletions(clusterID, cutoffTime)
+	if err != nil {
+		return rowsAffected, fmt.Errorf("failed to delete old stat deletions: %w", err)
+	}
+	return rowsAffected, nil
+}
+
+// Recommendation State Methods
+func (s *Storage) GetRecommendationStates(clusterID string) ([]types.RecommendationState, error) {
+	states, err := s.DB.GetRecommendationStates(clusterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recommendation states: %w", err)
+	}
+	return states, nil
+}
+
+func (s *Storage) UpsertRecommendationStates(clusterID string, states []types.RecommendationState) error {
+	if err := s.DB.UpsertRecommendationStates(clusterID, states); err != nil {
+		return fmt.Errorf("failed to upsert recommendation states: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) DeleteOldRecommendationStates(clusterID string, retentionDays int) (int64, error) {
+	cutoffTime := time.Now().Add(-time.Duration(retentionDays) * 24 * time.Hour)
+
+	rowsAffected, err := s.DB.DeleteOldRecommendationStates(clusterID, cutoffTime)
+	if err != nil {
+		return rowsAffected, fmt.Errorf("failed to delete old recommendation states: %w", err)
+	}
+	return rowsAffected, nil
+}
+
+// Usage Histogram Methods
+func (s *Storage) UpsertUsageHistograms(clusterID string, histograms []types.UsageHistogram) error {
+	if err := s.DB.UpsertUsageHistograms(clusterID, histograms); err != nil {
+		return fmt.Errorf("failed to upsert usage histograms: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) GetUsageHistograms(clusterID, namespace string, resolution time.Duration, since time.Time) ([]types.UsageHistogram, error) {
+	histograms, err := s.DB.GetUsageHistograms(clusterID, namespace, resolution, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage histograms: %w", err)
+	}
+	return histograms, nil
+}
+
+// DeleteOldUsageHistograms deletes the histograms of a resolution whose slot started before the retention.
+func (s *Storage) DeleteOldUsageHistograms(clusterID string, resolution, retention time.Duration) (int64, error) {
+	cutoffTime := time.Now().Add(-retention)
+
+	rowsAffected, err := s.DB.DeleteOldUsageHistograms(clusterID, resolution, cutoffTime)
+	if err != nil {
+		return rowsAffected, fmt.Errorf("failed to delete old usage histograms: %w", err)
+	}
+	return rowsAffected, nil
+}
@@ -32,9 +32,17 @@ func SetupServerEngine(mgr cluster.Manager, authAPI gin.HandlerFunc, authWebhook
 		clusterGroup.GET("/prometheus-query", handlers.HandlePrometheusQuery)
 		clusterGroup.GET("/prometheus-config", handlers.GetPrometheusConfigHandler)
 		clusterGroup.POST("/killswitch", handlers.KillswitchHandler)
+		clusterGroup.POST("/rollback", handlers.RollbackHandler)
 		clusterGroup.GET("/workloads", handlers.ListWorkloadsHandler)
 		clusterGroup.GET("/workloads/:workloadID/overrides", handlers.GetWorkloadOverridesHandler)
 		clusterGroup.POST("/workloads/:workloadID/overrides", handlers.UpdateWorkloadOverridesHandler)
+		clusterGroup.GET("/workloads/:workloadID/stats", handlers.HandleWorkloadStats)
+		clusterGroup.GET("/namespaces/overrides", handlers.ListNamespaceOverridesHandler)
+		clusterGroup.GET("/namespaces/:namespace/overrides", handlers.GetNamespaceOverridesHandler)
+		clusterGroup.POST("/namespaces/:namespace/overrides", handlers.UpdateNamespaceOverridesHandler)
+		clusterGroup.GET("/workloads/:workloadID/stats-history", handlers.GetWorkloadStatsHistoryHandler)
+		clusterGroup.GET("/apply-runs", handlers.ListApplyRunsHandler)
+		clusterGroup.GET("/apply-runs/:runID", handlers.GetApplyRunHandler)
 	}
 
 	if enableDevAPIs {
@@ -56,6 +64,8 @@ func SetupServerEngine(mgr cluster.Manager, authAPI gin.HandlerFunc, authWebhook
 	webhookGroup := apiV1Group.Group("/webhook/clusters/:clusterID", authWebhook, ensureClusterExists)
 	{
 		webhookGroup.GET("/stats", handlers.HandleClusterStats)
+		webhookGroup.GET("/snapshot", handlers.HandleWebhookSnapshot)
+		webhookGroup.GET("/workloads/:workloadID/stats", handlers.HandleWorkloadStats)
 		webhookGroup.GET("/workloads/:workloadID/overrides", handlers.GetWorkloadOverridesHandler)
 	}
 
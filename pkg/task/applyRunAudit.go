@@ -0,0 +1,101 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/logging"
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+	"github.com/truefoundry/cruisekube/pkg/types"
+)
+
+const DefaultApplyRunRetentionDays = 30
+
+func newApplyRunEntry(nodeName string, podInfo utils.PodInfo, containerName string, resource types.ApplyRunResource) types.ApplyRunEntry {
+	entry := types.ApplyRunEntry{
+		Namespace:     podInfo.Namespace,
+		PodName:       podInfo.Name,
+		NodeName:      nodeName,
+		ContainerName: containerName,
+		Resource:      resource,
+		Action:        types.ApplyRunActionSkipped,
+	}
+	if podInfo.Stats != nil {
+		entry.WorkloadID = podInfo.Stats.WorkloadIdentifier
+	}
+	return entry
+}
+
+// recordSkippedPods adds an entry for every pod that was not handed to a strategy.
+func (r *RecommendationResult) recordSkippedPods() {
+	for _, pod := range r.NonOptimizablePods {
+		entry := newApplyRunEntry(r.NodeName, pod.PodInfo, "", types.ApplyRunResourcePod)
+		entry.Reason = pod.Reason
+		r.ApplyRunEntries = append(r.ApplyRunEntries, entry)
+	}
+}
+
+// startEvictionRun restores the eviction budgets from the persisted apply runs, so evictions performed
+// before a restart still count against them.
+func (a *ApplyRecommendationTask) startEvictionRun(ctx context.Context) {
+	var runs []types.ApplyRun
+	if a.storage != nil {
+		filter := types.ApplyRunFilter{Since: a.evictionPlanner.HistoryWindowStart(), IncludeEntries: true}
+		if filter.Since.IsZero() {
+			filter.Limit = 1
+		}
+		loaded, err := a.storage.GetApplyRuns(a.config.ClusterID, filter)
+		if err != nil {
+			logging.Errorf(ctx, "Error loading apply runs, using the eviction budgets in memory: %v", err)
+		} else {
+			runs = append(make([]types.ApplyRun, 0, len(loaded)), loaded...)
+		}
+	}
+	a.evictionPlanner.StartRun(runs)
+}
+
+// persistApplyRun stores the audit record of the run, including the verification of the resizes of the
+// previous runs, and drops the records past the retention. Only the changed, deferred and failed entries
+// are stored, the skipped ones are counted.
+func (a *ApplyRecommendationTask) persistApplyRun(ctx context.Context, strategyName string, dryRun bool, startedAt time.Time, recommendationResults []*RecommendationResult, verificationEntries []types.ApplyRunEntry) {
+	if a.storage == nil {
+		return
+	}
+
+	run := &types.ApplyRun{
+		ID:         fmt.Sprintf("%s-%d", a.config.ClusterID, startedAt.UnixNano()),
+		ClusterID:  a.config.ClusterID,
+		Strategy:   strategyName,
+		DryRun:     dryRun,
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+		Entries:    make([]types.ApplyRunEntry, 0, len(verificationEntries)),
+	}
+	entries := append(make([]types.ApplyRunEntry, 0, len(verificationEntries)), verificationEntries...)
+	for _, result := range recommendationResults {
+		entries = append(entries, result.ApplyRunEntries...)
+	}
+	for _, entry := range entries {
+		if entry.Action == types.ApplyRunActionSkipped {
+			run.UnchangedCount++
+			continue
+		}
+		run.Entries = append(run.Entries, entry)
+	}
+
+	if err := a.storage.InsertApplyRun(run); err != nil {
+		logging.Errorf(ctx, "Error persisting apply run %s: %v", run.ID, err)
+		return
+	}
+	logging.Infof(ctx, "Persisted apply run %s with %d entries and %d unchanged", run.ID, len(run.Entries), run.UnchangedCount)
+
+	deletedCount, err := a.storage.DeleteOldApplyRuns(a.config.ClusterID, a.config.Metadata.ApplyRunRetentionDays)
+	if err != nil {
+		logging.Errorf(ctx, "Error deleting old apply runs: %v", err)
+		return
+	}
+	if deletedCount > 0 {
+		logging.Infof(ctx, "Deleted %d old apply runs", deletedCount)
+	}
+}
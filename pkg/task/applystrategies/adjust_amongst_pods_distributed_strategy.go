@@ -6,6 +6,7 @@ import (
 	"math"
 	"sort"
 
+	"github.com/truefoundry/cruisekube/pkg/config"
 	"github.com/truefoundry/cruisekube/pkg/logging"
 	"github.com/truefoundry/cruisekube/pkg/task/utils"
 	"github.com/truefoundry/cruisekube/pkg/types"
@@ -13,15 +14,22 @@ import (
 	"k8s.io/client-go/kubernetes"
 )
 
-func NewAdjustAmongstPodsDistributedStrategy(ctx context.Context) utils.OptimizationStrategy {
-	return &AdjustAmongstPodsDistributedStrategy{}
+func init() {
+	RegisterStrategy(DefaultStrategyName, NewAdjustAmongstPodsDistributedStrategy)
+}
+
+func NewAdjustAmongstPodsDistributedStrategy(ctx context.Context, settings config.RecommendationSettings) utils.OptimizationStrategy {
+	return &AdjustAmongstPodsDistributedStrategy{
+		policy: newRecommendationPolicy(settings),
+	}
 }
 
 type AdjustAmongstPodsDistributedStrategy struct {
+	policy recommendationPolicy
 }
 
 func (s *AdjustAmongstPodsDistributedStrategy) GetName() string {
-	return "AdjustAmongstPodsDistributed"
+	return DefaultStrategyName
 }
 
 func (s *AdjustAmongstPodsDistributedStrategy) OptimizeNode(kubeClient *kubernetes.Clientset, overridesMap map[string]*types.WorkloadOverrideInfo, data utils.NodeOptimizationData) (utils.OptimizationResult, error) {
@@ -34,22 +42,35 @@ func (s *AdjustAmongstPodsDistributedStrategy) OptimizeNode(kubeClient *kubernet
 	podInfosClone := make([]utils.PodInfo, len(data.PodInfos))
 	copy(podInfosClone, data.PodInfos)
 
+	podMetricsCache := s.buildPodMetrics(overridesMap, podInfosClone)
+	podInfosClone = s.evictUntilFits(podInfosClone, podMetricsCache, data.AllocatableCPU, data.AllocatableMemory, &result)
+	s.distributeRest(podInfosClone, overridesMap, &result)
+
+	return result, nil
+}
+
+func (s *AdjustAmongstPodsDistributedStrategy) buildPodMetrics(overridesMap map[string]*types.WorkloadOverrideInfo, podInfos []utils.PodInfo) map[string]utils.PodMetrics {
 	podMetricsCache := make(map[string]utils.PodMetrics)
 
 	// Calculate the recommendation for each pod
-	for _, podInfo := range podInfosClone {
+	for _, podInfo := range podInfos {
 		podKey := utils.GetPodKey(podInfo.Namespace, podInfo.Name)
-		var totalRecommendedCPU, totalRecommendedMemory, maxRestCPU, maxRestMemory float64
+		policy := policyForPod(s.policy, overridesMap, podInfo)
+		var totalRecommendedCPU, totalRecommendedMemory, maxRestCPU, maxRestMemory, totalRestCPU, totalRestMemory float64
 		for _, containerRec := range podInfo.Stats.ContainerStats {
 			if containerRec.ContainerType == types.InitContainer {
 				continue
 			}
-			recommendedCPU, cpuRest, err := s.calculateForSpecificPercentile(podInfo, containerRec)
+			recommendedCPU, cpuRest, err := s.calculateForSpecificPercentile(podInfo, containerRec, policy)
 			if err != nil {
 				logging.Errorf(context.Background(), "Error calculating for specific percentile for container %s: %v", containerRec.ContainerName, err)
 				continue
 			}
-			recommendedMemory, memoryRest := s.getRecommendedAndRestMemory(containerRec)
+			recommendedMemory, memoryRest := s.getRecommendedAndRestMemory(containerRec, policy)
+			if podInfo.IsGuaranteedPod() {
+				recommendedCPU, cpuRest = reserveRestForGuaranteedPod(recommendedCPU, cpuRest)
+				recommendedMemory, memoryRest = reserveRestForGuaranteedPod(recommendedMemory, memoryRest)
+			}
 
 			if podInfo.WorkloadKind == utils.DaemonSetKind {
 				containerResource, err := podInfo.GetContainerResource(containerRec.ContainerName)
@@ -72,10 +93,15 @@ func (s *AdjustAmongstPodsDistributedStrategy) OptimizeNode(kubeClient *kubernet
 				}
 				memoryRest = 0
 			}
+			bounds := boundsForContainer(overridesMap, podInfo, containerRec.ContainerName)
+			recommendedCPU, cpuRest = boundRecommendation(recommendedCPU, cpuRest, bounds.ClampCPU)
+			recommendedMemory, memoryRest = boundRecommendation(recommendedMemory, memoryRest, bounds.ClampMemory)
 			totalRecommendedCPU += recommendedCPU
 			maxRestCPU = math.Max(maxRestCPU, cpuRest)
 			totalRecommendedMemory += recommendedMemory
 			maxRestMemory = math.Max(maxRestMemory, memoryRest)
+			totalRestCPU += cpuRest
+			totalRestMemory += memoryRest
 		}
 
 		var maxInitCPU, maxInitMemory float64
@@ -102,10 +128,22 @@ func (s *AdjustAmongstPodsDistributedStrategy) OptimizeNode(kubeClient *kubernet
 			TotalRecommendedMemory: max(totalRecommendedMemory, maxInitMemory),
 			MaxRestCPU:             maxRestCPU,
 			MaxRestMemory:          maxRestMemory,
+			TotalRestCPU:           totalRestCPU,
+			TotalRestMemory:        totalRestMemory,
 			EvictionRanking:        evictionRanking,
 		}
 	}
 
+	return podMetricsCache
+}
+
+func (s *AdjustAmongstPodsDistributedStrategy) evictUntilFits(
+	podInfosClone []utils.PodInfo,
+	podMetricsCache map[string]utils.PodMetrics,
+	allocatableCPU float64,
+	allocatableMemory float64,
+	result *utils.OptimizationResult,
+) []utils.PodInfo {
 	// Sort and perform eviction based on memory
 	sort.Slice(podInfosClone, func(i, j int) bool {
 		if podInfosClone[i].WorkloadKind == utils.DaemonSetKind && podInfosClone[j].WorkloadKind != utils.DaemonSetKind {
@@ -127,10 +165,10 @@ func (s *AdjustAmongstPodsDistributedStrategy) OptimizeNode(kubeClient *kubernet
 		}
 		return metrics_i.MaxRestMemory > metrics_j.MaxRestMemory
 	})
-	podInfosClone = s.performEvictionLoop(podInfosClone, podMetricsCache, data.AllocatableMemory,
+	podInfosClone = s.performEvictionLoop(podInfosClone, podMetricsCache, allocatableMemory,
 		func(metrics utils.PodMetrics) float64 { return metrics.TotalRecommendedMemory },
 		func(metrics utils.PodMetrics) float64 { return metrics.MaxRestMemory },
-		&result)
+		result)
 
 	// Sort and perform eviction based on CPU
 	sort.Slice(podInfosClone, func(i, j int) bool {
@@ -156,17 +194,19 @@ func (s *AdjustAmongstPodsDistributedStrategy) OptimizeNode(kubeClient *kubernet
 
 		return metrics_i.MaxRestCPU > metrics_j.MaxRestCPU
 	})
-	podInfosClone = s.performEvictionLoop(podInfosClone, podMetricsCache, data.AllocatableCPU,
+	podInfosClone = s.performEvictionLoop(podInfosClone, podMetricsCache, allocatableCPU,
 		func(metrics utils.PodMetrics) float64 { return metrics.TotalRecommendedCPU },
 		func(metrics utils.PodMetrics) float64 { return metrics.MaxRestCPU },
-		&result)
+		result)
 
+	return podInfosClone
+}
+
+func (s *AdjustAmongstPodsDistributedStrategy) distributeRest(podInfosClone []utils.PodInfo, overridesMap map[string]*types.WorkloadOverrideInfo, result *utils.OptimizationResult) {
 	maxRestCPU := 0.0
 	maxRestMemory := 0.0
 	totalRestCPU := 0.0
 	totalRestMemory := 0.0
-	totalRecommendedCPU := 0.0
-	totalRecommendedMemory := 0.0
 
 	containerMetrics := make([]struct {
 		pod               utils.PodInfo
@@ -177,10 +217,12 @@ func (s *AdjustAmongstPodsDistributedStrategy) OptimizeNode(kubeClient *kubernet
 		restMemory        float64
 		recommendedCPU    float64
 		recommendedMemory float64
+		bounds            *types.ResourceBounds
 	}, 0)
 
 	for _, pod := range podInfosClone {
 		if pod.Stats.ContainerStats != nil {
+			policy := policyForPod(s.policy, overridesMap, pod)
 			for _, containerStat := range pod.Stats.ContainerStats {
 				if containerStat.ContainerType == types.InitContainer {
 					continue
@@ -193,12 +235,19 @@ func (s *AdjustAmongstPodsDistributedStrategy) OptimizeNode(kubeClient *kubernet
 				currentCPU := currentResource.CPURequest
 				currentMemory := currentResource.MemoryRequest
 
-				recommendedCPU, restCPU, err := s.calculateForSpecificPercentile(pod, containerStat)
+				recommendedCPU, restCPU, err := s.calculateForSpecificPercentile(pod, containerStat, policy)
 				if err != nil {
 					logging.Errorf(context.Background(), "Error calculating for specific percentile for container %s: %v", containerStat.ContainerName, err)
 					continue
 				}
-				recommendedMemory, restMemory := s.getRecommendedAndRestMemory(containerStat)
+				recommendedMemory, restMemory := s.getRecommendedAndRestMemory(containerStat, policy)
+				if pod.IsGuaranteedPod() {
+					recommendedCPU, restCPU = reserveRestForGuaranteedPod(recommendedCPU, restCPU)
+					recommendedMemory, restMemory = reserveRestForGuaranteedPod(recommendedMemory, restMemory)
+				}
+				bounds := boundsForContainer(overridesMap, pod, containerStat.ContainerName)
+				recommendedCPU, restCPU = boundRecommendation(recommendedCPU, restCPU, bounds.ClampCPU)
+				recommendedMemory, restMemory = boundRecommendation(recommendedMemory, restMemory, bounds.ClampMemory)
 
 				containerMetrics = append(containerMetrics, struct {
 					pod               utils.PodInfo
@@ -209,6 +258,7 @@ func (s *AdjustAmongstPodsDistributedStrategy) OptimizeNode(kubeClient *kubernet
 					restMemory        float64
 					recommendedCPU    float64
 					recommendedMemory float64
+					bounds            *types.ResourceBounds
 				}{
 					pod:               pod,
 					containerStats:    containerStat,
@@ -218,14 +268,13 @@ func (s *AdjustAmongstPodsDistributedStrategy) OptimizeNode(kubeClient *kubernet
 					restMemory:        restMemory,
 					recommendedCPU:    recommendedCPU,
 					recommendedMemory: recommendedMemory,
+					bounds:            bounds,
 				})
 
 				maxRestCPU = math.Max(maxRestCPU, restCPU)
 				maxRestMemory = math.Max(maxRestMemory, restMemory)
 				totalRestCPU += restCPU
 				totalRestMemory += restMemory
-				totalRecommendedCPU += recommendedCPU
-				totalRecommendedMemory += recommendedMemory
 			}
 		}
 	}
@@ -235,18 +284,18 @@ func (s *AdjustAmongstPodsDistributedStrategy) OptimizeNode(kubeClient *kubernet
 	for _, metric := range containerMetrics {
 		var additionalCPU, additionalMemory float64
 
-		if totalRecommendedCPU > 0 {
+		if totalRestCPU > 0 {
 			cpuRatio := metric.restCPU / totalRestCPU
 			additionalCPU = maxRestCPU * cpuRatio
 		}
 
-		if totalRecommendedMemory > 0 {
+		if totalRestMemory > 0 {
 			memoryRatio := metric.restMemory / totalRestMemory
 			additionalMemory = maxRestMemory * memoryRatio
 		}
 
-		finalCPU := metric.recommendedCPU + additionalCPU
-		finalMemory := metric.recommendedMemory + additionalMemory
+		finalCPU := metric.bounds.ClampCPU(metric.recommendedCPU + additionalCPU)
+		finalMemory := metric.bounds.ClampMemory(metric.recommendedMemory + additionalMemory)
 
 		logging.Infof(context.Background(), "Distributed strategy for %s/%s/%s: base_cpu=%.3f, additional_cpu=%.3f, final_cpu=%.3f, base_memory=%.3f, additional_memory=%.3f, final_memory=%.3f",
 			metric.pod.Namespace, metric.pod.Name, metric.containerStats.ContainerName,
@@ -270,8 +319,6 @@ func (s *AdjustAmongstPodsDistributedStrategy) OptimizeNode(kubeClient *kubernet
 
 	result.MaxRestCPU = maxRestCPU
 	result.MaxRestMemory = maxRestMemory
-
-	return result, nil
 }
 
 func (s *AdjustAmongstPodsDistributedStrategy) performEvictionLoop(
@@ -405,42 +452,68 @@ func (s *AdjustAmongstPodsDistributedStrategy) performEvictionLoop(
 // 	}
 // }
 
-func (s *AdjustAmongstPodsDistributedStrategy) getPmax(containerRec utils.ContainerStats) (float64, error) {
-	if containerRec.SimplePredictionsCPU != nil {
-		return containerRec.SimplePredictionsCPU.MaxValue, nil
-	} else {
-		return 0.0, fmt.Errorf("no simple predictions found for container %s", containerRec.ContainerName)
+func (s *AdjustAmongstPodsDistributedStrategy) getPmax(containerRec utils.ContainerStats, source types.PmaxSource) (float64, error) {
+	switch source {
+	case types.PmaxSourceMax:
+		if containerRec.CPUStats != nil {
+			return containerRec.CPUStats.Max, nil
+		}
+	case types.PmaxSource7DayMax:
+		if containerRec.CPU7Day != nil {
+			return containerRec.CPU7Day.Max, nil
+		}
+	default:
+		if containerRec.SimplePredictionsCPU != nil {
+			return containerRec.SimplePredictionsCPU.MaxValue, nil
+		}
+	}
+	return 0.0, fmt.Errorf("no %s cpu stats found for container %s", source, containerRec.ContainerName)
+}
+
+func (s *AdjustAmongstPodsDistributedStrategy) getMemoryPmax(containerRec utils.ContainerStats, source types.PmaxSource) float64 {
+	switch source {
+	case types.PmaxSourceMax:
+		return containerRec.MemoryStats.Max
+	case types.PmaxSource7DayMax:
+		if containerRec.Memory7Day != nil {
+			return containerRec.Memory7Day.Max
+		}
+	default:
+		if containerRec.SimplePredictionsMemory != nil {
+			return containerRec.SimplePredictionsMemory.MaxValue
+		}
 	}
+	logging.Errorf(context.Background(), "Error: No %s memory stats found for container %s", source, containerRec.ContainerName)
+	return containerRec.MemoryStats.Max
 }
 
-func (s *AdjustAmongstPodsDistributedStrategy) getRecommendedAndRestMemory(containerRec utils.ContainerStats) (float64, float64) {
-	if containerRec.MemoryStats.OOMMemory > 0 && containerRec.MemoryStats.OOMMemory > containerRec.MemoryStats.P75 {
+func (s *AdjustAmongstPodsDistributedStrategy) getRecommendedAndRestMemory(containerRec utils.ContainerStats, policy recommendationPolicy) (float64, float64) {
+	px := percentileWithFallback(policy.RequestPercentile, containerRec.MemoryStats.GetPercentile(policy.RequestPercentile), containerRec.MemoryStats.P75)
+	if containerRec.MemoryStats.OOMMemory > 0 && containerRec.MemoryStats.OOMMemory > px {
 		logging.Infof(context.Background(), "Using OOM memory for container %s: %v", containerRec.ContainerName, containerRec.MemoryStats.OOMMemory)
 		// We are not underestimating the memory requests here, we are just using the OOM memory as the total recommended memory with no extra headspace
-		return containerRec.MemoryStats.OOMMemory, 0.0
-	}
-	if containerRec.SimplePredictionsMemory == nil {
-		logging.Errorf(context.Background(), "Error: No simple predictions found for container %s", containerRec.ContainerName)
-		return containerRec.MemoryStats.P75, containerRec.MemoryStats.Max - containerRec.MemoryStats.P75
-	} else {
-		return containerRec.MemoryStats.P75, containerRec.SimplePredictionsMemory.MaxValue - containerRec.MemoryStats.P75
+		return policy.applySafetyMargin(containerRec.MemoryStats.OOMMemory, policy.MemorySafetyMargin), 0.0
 	}
+	pmax := s.getMemoryPmax(containerRec, policy.PmaxSource)
+	return policy.applySafetyMargin(px, policy.MemorySafetyMargin), max(pmax-px, 0)
 }
 
-func (s *AdjustAmongstPodsDistributedStrategy) calculateForSpecificPercentile(pod utils.PodInfo, containerRec utils.ContainerStats) (float64, float64, error) {
-	x := 75.0
-	px := containerRec.CPUStats.P75
+func (s *AdjustAmongstPodsDistributedStrategy) calculateForSpecificPercentile(pod utils.PodInfo, containerRec utils.ContainerStats, policy recommendationPolicy) (float64, float64, error) {
+	x := policy.RequestPercentile
+	px := percentileWithFallback(x, containerRec.CPUStats.GetPercentile(x), containerRec.CPUStats.P75)
 	if containerRec.PSIAdjustedUsage != nil {
-		px = containerRec.PSIAdjustedUsage.P75
+		px = percentileWithFallback(x, containerRec.PSIAdjustedUsage.GetPercentile(x), containerRec.PSIAdjustedUsage.P75)
 	}
-	pmax, err := s.getPmax(containerRec)
+	pmax, err := s.getPmax(containerRec, policy.PmaxSource)
 	if err != nil {
 		return 0.0, 0.0, fmt.Errorf("error getting pmax for container %s: %w", containerRec.ContainerName, err)
 	}
 
-	request := px
+	// The safety margin only pads the request, the headroom for spikes is still measured from px
+	request := policy.applySafetyMargin(px, policy.CPUSafetyMargin)
 
-	rest := (pmax - px)
+	// px can exceed pmax, e.g. a PSI-adjusted P99 over the observed max, such a container has no headroom
+	rest := max(pmax-px, 0)
 
 	logging.Infof(context.Background(), "Variable diff calculation for %s/%s/%s: x=%.1f, px=%.3f, pmax=%.3f, request=%.3f, rest=%.3f",
 		pod.Namespace, pod.Name, containerRec.ContainerName,
@@ -0,0 +1,181 @@
+package applystrategies
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/truefoundry/cruisekube/pkg/config"
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+	"github.com/truefoundry/cruisekube/pkg/types"
+)
+
+func TestOptimizeNodeWithOnlyGuaranteedPods(t *testing.T) {
+	strategy := NewAdjustAmongstPodsDistributedStrategy(context.Background(), config.RecommendationSettings{})
+
+	podInfos := make([]utils.PodInfo, 0, 2)
+	for _, name := range []string{"api-0", "api-1"} {
+		podInfos = append(podInfos, utils.PodInfo{
+			Namespace:    "default",
+			Name:         name,
+			WorkloadKind: utils.StatefulSetKind,
+			WorkloadName: "api",
+			Stats: &utils.WorkloadStat{
+				WorkloadIdentifier: "StatefulSet:default:api",
+				ContainerStats: []utils.ContainerStats{{
+					ContainerName:           "api",
+					CPUStats:                &types.CPUStats{Max: 0.8, P50: 0.3, P75: 0.4},
+					MemoryStats:             &types.MemoryStats{Max: 600, P75: 400},
+					SimplePredictionsCPU:    &types.SimplePrediction{MaxValue: 0.6},
+					SimplePredictionsMemory: &types.SimplePrediction{MaxValue: 500},
+				}},
+			},
+			ContainerResources: []*utils.ContainerResources{{
+				Name:          "api",
+				CPURequest:    1,
+				CPULimit:      1,
+				MemoryRequest: 1024,
+				MemoryLimit:   1024,
+			}},
+		})
+	}
+
+	result, err := strategy.OptimizeNode(nil, nil, utils.NodeOptimizationData{
+		NodeName:          "node-1",
+		AllocatableCPU:    4,
+		AllocatableMemory: 8192,
+		PodInfos:          podInfos,
+	})
+	if err != nil {
+		t.Fatalf("Failed to optimize node: %v", err)
+	}
+	if len(result.PodContainerRecommendations) != 2 {
+		t.Fatalf("Expected 2 recommendations, got %d", len(result.PodContainerRecommendations))
+	}
+	for _, rec := range result.PodContainerRecommendations {
+		if rec.Evict {
+			t.Errorf("Expected pod %s to not be evicted", rec.PodInfo.Name)
+		}
+		if math.IsNaN(rec.CPU) || math.IsNaN(rec.Memory) {
+			t.Errorf("Expected finite recommendations for pod %s, got cpu %v and memory %v", rec.PodInfo.Name, rec.CPU, rec.Memory)
+		}
+		// the guaranteed pods keep their spike headroom in the request
+		if math.Abs(rec.CPU-0.6) > 1e-9 || math.Abs(rec.Memory-500) > 1e-9 {
+			t.Errorf("Expected pod %s to be recommended 0.6 cpu and 500 memory, got %v and %v", rec.PodInfo.Name, rec.CPU, rec.Memory)
+		}
+	}
+}
+
+func TestOptimizeNodeWithPSIAdjustedRequestAboveMax(t *testing.T) {
+	strategy := NewAdjustAmongstPodsDistributedStrategy(context.Background(), config.RecommendationSettings{
+		RequestPercentile: 99,
+		PmaxSource:        string(types.PmaxSourceMax),
+	})
+
+	newPod := func(name string, cpuStats *types.CPUStats, psiAdjustedUsage *types.PSIAdjustedUsageStats) utils.PodInfo {
+		return utils.PodInfo{
+			Namespace:    "default",
+			Name:         name,
+			WorkloadKind: utils.DeploymentKind,
+			WorkloadName: name,
+			Stats: &utils.WorkloadStat{
+				WorkloadIdentifier: "Deployment:default:" + name,
+				ContainerStats: []utils.ContainerStats{{
+					ContainerName:    "app",
+					CPUStats:         cpuStats,
+					PSIAdjustedUsage: psiAdjustedUsage,
+					MemoryStats:      &types.MemoryStats{Max: 600, P75: 400},
+				}},
+			},
+			ContainerResources: []*utils.ContainerResources{{
+				Name:          "app",
+				CPURequest:    2,
+				MemoryRequest: 1024,
+			}},
+		}
+	}
+
+	result, err := strategy.OptimizeNode(nil, nil, utils.NodeOptimizationData{
+		NodeName:          "node-1",
+		AllocatableCPU:    4,
+		AllocatableMemory: 8192,
+		PodInfos: []utils.PodInfo{
+			// the PSI-adjusted P99 of 1.5 cpu is above the observed max of 1 cpu
+			newPod("throttled", &types.CPUStats{Max: 1, P75: 0.6, P99: 0.9}, &types.PSIAdjustedUsageStats{P75: 1.2, P99: 1.5}),
+			newPod("spiky", &types.CPUStats{Max: 1.5, P75: 0.4, P99: 0.5}, nil),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to optimize node: %v", err)
+	}
+
+	recs := make(map[string]utils.PodContainerRecommendation, len(result.PodContainerRecommendations))
+	for _, rec := range result.PodContainerRecommendations {
+		recs[rec.PodInfo.Name] = rec
+	}
+	// the throttled pod adds no negative rest, the spiky pod gets the whole max rest of 1 cpu
+	for name, wantCPU := range map[string]float64{"throttled": 1.5, "spiky": 1.5} {
+		rec, ok := recs[name]
+		if !ok {
+			t.Fatalf("Expected a recommendation for pod %s, got %+v", name, recs)
+		}
+		if rec.Evict {
+			t.Errorf("Expected pod %s to not be evicted", name)
+		}
+		if math.Abs(rec.CPU-wantCPU) > 1e-9 {
+			t.Errorf("Expected pod %s to be recommended %v cpu, got %v", name, wantCPU, rec.CPU)
+		}
+	}
+}
+
+func TestRestIsNotNegative(t *testing.T) {
+	strategy := NewAdjustAmongstPodsDistributedStrategy(context.Background(), config.RecommendationSettings{}).(*AdjustAmongstPodsDistributedStrategy)
+	pod := utils.PodInfo{Namespace: "default", Name: "api-0"}
+
+	tests := []struct {
+		name       string
+		policy     recommendationPolicy
+		stats      utils.ContainerStats
+		wantCPU    float64
+		wantMemory float64
+	}{
+		{
+			name:   "PSI-adjusted P99 above the max",
+			policy: recommendationPolicy{RequestPercentile: 99, PmaxSource: types.PmaxSourceMax},
+			stats: utils.ContainerStats{
+				CPUStats:         &types.CPUStats{Max: 1, P75: 0.6, P99: 0.9},
+				PSIAdjustedUsage: &types.PSIAdjustedUsageStats{P75: 1.2, P99: 1.5},
+				MemoryStats:      &types.MemoryStats{Max: 600, P75: 400, P99: 600},
+			},
+			wantCPU:    1.5,
+			wantMemory: 600,
+		},
+		{
+			name:   "P75 above the prediction",
+			policy: recommendationPolicy{RequestPercentile: 75, PmaxSource: types.PmaxSourcePrediction},
+			stats: utils.ContainerStats{
+				CPUStats:                &types.CPUStats{Max: 1, P75: 0.6},
+				MemoryStats:             &types.MemoryStats{Max: 600, P75: 400},
+				SimplePredictionsCPU:    &types.SimplePrediction{MaxValue: 0.5},
+				SimplePredictionsMemory: &types.SimplePrediction{MaxValue: 300},
+			},
+			wantCPU:    0.6,
+			wantMemory: 400,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu, cpuRest, err := strategy.calculateForSpecificPercentile(pod, tt.stats, tt.policy)
+			if err != nil {
+				t.Fatalf("Failed to calculate the cpu: %v", err)
+			}
+			if math.Abs(cpu-tt.wantCPU) > 1e-9 || cpuRest != 0 {
+				t.Errorf("Expected %v cpu without rest, got %v with rest %v", tt.wantCPU, cpu, cpuRest)
+			}
+			memory, memoryRest := strategy.getRecommendedAndRestMemory(tt.stats, tt.policy)
+			if math.Abs(memory-tt.wantMemory) > 1e-9 || memoryRest != 0 {
+				t.Errorf("Expected %v memory without rest, got %v with rest %v", tt.wantMemory, memory, memoryRest)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package applystrategies
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/truefoundry/cruisekube/pkg/config"
+	"github.com/truefoundry/cruisekube/pkg/logging"
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+	"github.com/truefoundry/cruisekube/pkg/types"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+const ClusterBinPackingStrategyName = "ClusterBinPacking"
+
+func init() {
+	RegisterStrategy(ClusterBinPackingStrategyName, NewClusterBinPackingStrategy)
+}
+
+func NewClusterBinPackingStrategy(ctx context.Context, settings config.RecommendationSettings) utils.OptimizationStrategy {
+	return &ClusterBinPackingStrategy{
+		AdjustAmongstPodsDistributedStrategy: AdjustAmongstPodsDistributedStrategy{
+			policy: newRecommendationPolicy(settings),
+		},
+	}
+}
+
+// ClusterBinPackingStrategy sizes pods the same way as AdjustAmongstPodsDistributedStrategy, but plans
+// evictions for the whole cluster at once. Every evicted pod is assigned a target node that still has
+// room for it after the evictions, so evictions on one node do not cause evictions on the node the pod
+// lands on. A pod no node has room for is not evicted.
+type ClusterBinPackingStrategy struct {
+	AdjustAmongstPodsDistributedStrategy
+}
+
+func (s *ClusterBinPackingStrategy) GetName() string {
+	return ClusterBinPackingStrategyName
+}
+
+// binPackingNode tracks the room taken on a node. The kept pods take their recommendations and share the
+// largest rest among them, the pods planned onto the node take what the webhook admits them with, their
+// recommendation and their whole rest.
+type binPackingNode struct {
+	name              string
+	allocatableCPU    float64
+	allocatableMemory float64
+	usedCPU           float64
+	usedMemory        float64
+	maxRestCPU        float64
+	maxRestMemory     float64
+}
+
+type binPackingCandidate struct {
+	podInfo    utils.PodInfo
+	sourceNode string
+	metrics    utils.PodMetrics
+}
+
+func (n *binPackingNode) fits(metrics utils.PodMetrics) bool {
+	return n.usedCPU+admittedCPU(metrics)+n.maxRestCPU <= n.allocatableCPU &&
+		n.usedMemory+admittedMemory(metrics)+n.maxRestMemory <= n.allocatableMemory
+}
+
+// spareAfter is the fraction of the node left unused after placing the pod, summed over cpu and memory.
+func (n *binPackingNode) spareAfter(metrics utils.PodMetrics) float64 {
+	spare := 0.0
+	if n.allocatableCPU > 0 {
+		spare += (n.allocatableCPU - n.usedCPU - admittedCPU(metrics) - n.maxRestCPU) / n.allocatableCPU
+	}
+	if n.allocatableMemory > 0 {
+		spare += (n.allocatableMemory - n.usedMemory - admittedMemory(metrics) - n.maxRestMemory) / n.allocatableMemory
+	}
+	return spare
+}
+
+// keep records a pod staying on the node.
+func (n *binPackingNode) keep(metrics utils.PodMetrics) {
+	n.usedCPU += metrics.TotalRecommendedCPU
+	n.usedMemory += metrics.TotalRecommendedMemory
+	n.maxRestCPU = math.Max(n.maxRestCPU, metrics.MaxRestCPU)
+	n.maxRestMemory = math.Max(n.maxRestMemory, metrics.MaxRestMemory)
+}
+
+// place records a pod planned onto the node at the size the webhook admits it with.
+func (n *binPackingNode) place(metrics utils.PodMetrics) {
+	n.usedCPU += admittedCPU(metrics)
+	n.usedMemory += admittedMemory(metrics)
+}
+
+func admittedCPU(metrics utils.PodMetrics) float64 {
+	return metrics.TotalRecommendedCPU + metrics.TotalRestCPU
+}
+
+func admittedMemory(metrics utils.PodMetrics) float64 {
+	return metrics.TotalRecommendedMemory + metrics.TotalRestMemory
+}
+
+func (s *ClusterBinPackingStrategy) OptimizeCluster(kubeClient *kubernetes.Clientset, overridesMap map[string]*types.WorkloadOverrideInfo, data utils.ClusterOptimizationData) (utils.ClusterOptimizationResult, error) {
+	ctx := context.Background()
+	clusterResult := utils.ClusterOptimizationResult{
+		NodeResults: make(map[string]utils.OptimizationResult, len(data.Nodes)),
+		Evictions:   make([]utils.PlannedEviction, 0),
+	}
+
+	nodeNames := make([]string, 0, len(data.Nodes))
+	for nodeName := range data.Nodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+
+	// First pass: find the pods each node has to give up to fit its own recommendations
+	keptPods := make(map[string][]utils.PodInfo, len(data.Nodes))
+	nodes := make(map[string]*binPackingNode, len(data.Nodes))
+	overflowingNodes := make(map[string]bool)
+	candidates := make([]binPackingCandidate, 0)
+	for _, nodeName := range nodeNames {
+		nodeData := data.Nodes[nodeName]
+		result := utils.OptimizationResult{
+			PodContainerRecommendations: make([]utils.PodContainerRecommendation, 0),
+		}
+
+		podInfosClone := make([]utils.PodInfo, len(nodeData.PodInfos))
+		copy(podInfosClone, nodeData.PodInfos)
+
+		podMetricsCache := s.buildPodMetrics(overridesMap, podInfosClone)
+		kept := s.evictUntilFits(podInfosClone, podMetricsCache, nodeData.AllocatableCPU, nodeData.AllocatableMemory, &result)
+		keptPods[nodeName] = kept
+		clusterResult.NodeResults[nodeName] = result
+
+		node := &binPackingNode{
+			name:              nodeName,
+			allocatableCPU:    nodeData.AllocatableCPU,
+			allocatableMemory: nodeData.AllocatableMemory,
+		}
+		keptKeys := make(map[string]bool, len(kept))
+		for _, podInfo := range kept {
+			podKey := utils.GetPodKey(podInfo.Namespace, podInfo.Name)
+			keptKeys[podKey] = true
+			node.keep(podMetricsCache[podKey])
+		}
+		nodes[nodeName] = node
+
+		for _, podInfo := range nodeData.PodInfos {
+			podKey := utils.GetPodKey(podInfo.Namespace, podInfo.Name)
+			if keptKeys[podKey] {
+				continue
+			}
+			overflowingNodes[nodeName] = true
+			candidates = append(candidates, binPackingCandidate{
+				podInfo:    podInfo,
+				sourceNode: nodeName,
+				metrics:    podMetricsCache[podKey],
+			})
+		}
+	}
+
+	// Second pass: best fit decreasing placement of the evicted pods on the headroom of the nodes that do
+	// not evict themselves. A pod no node has room for stays where it is.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].metrics.TotalRecommendedMemory != candidates[j].metrics.TotalRecommendedMemory {
+			return candidates[i].metrics.TotalRecommendedMemory > candidates[j].metrics.TotalRecommendedMemory
+		}
+		return candidates[i].metrics.TotalRecommendedCPU > candidates[j].metrics.TotalRecommendedCPU
+	})
+	unplacedPods := make(map[string]bool)
+	for _, candidate := range candidates {
+		var target *binPackingNode
+		bestSpare := math.Inf(1)
+		for _, nodeName := range nodeNames {
+			node := nodes[nodeName]
+			if overflowingNodes[nodeName] || !node.fits(candidate.metrics) {
+				continue
+			}
+			if spare := node.spareAfter(candidate.metrics); spare < bestSpare {
+				bestSpare = spare
+				target = node
+			}
+		}
+
+		if target == nil {
+			logging.Warnf(ctx, "Keeping pod %s/%s on node %s as no node has enough headroom for it", candidate.podInfo.Namespace, candidate.podInfo.Name, candidate.sourceNode)
+			unplacedPods[utils.GetPodKey(candidate.podInfo.Namespace, candidate.podInfo.Name)] = true
+			keptPods[candidate.sourceNode] = append(keptPods[candidate.sourceNode], candidate.podInfo)
+			continue
+		}
+		target.place(candidate.metrics)
+		logging.Infof(ctx, "Planned eviction of pod %s/%s from node %s to node %s", candidate.podInfo.Namespace, candidate.podInfo.Name, candidate.sourceNode, target.name)
+		clusterResult.Evictions = append(clusterResult.Evictions, utils.PlannedEviction{
+			PodInfo:    candidate.podInfo,
+			SourceNode: candidate.sourceNode,
+			TargetNode: target.name,
+		})
+	}
+
+	// Finally size the pods that stay on each node. A node keeping a pod it could not give up is still
+	// short of room, so its pods are not grown.
+	for _, nodeName := range nodeNames {
+		result := clusterResult.NodeResults[nodeName]
+		overCommitted := false
+		recommendations := make([]utils.PodContainerRecommendation, 0, len(result.PodContainerRecommendations))
+		for _, rec := range result.PodContainerRecommendations {
+			if rec.Evict && unplacedPods[utils.GetPodKey(rec.PodInfo.Namespace, rec.PodInfo.Name)] {
+				overCommitted = true
+				continue
+			}
+			recommendations = append(recommendations, rec)
+		}
+		result.PodContainerRecommendations = recommendations
+
+		sized := utils.OptimizationResult{PodContainerRecommendations: make([]utils.PodContainerRecommendation, 0)}
+		s.distributeRest(keptPods[nodeName], overridesMap, &sized)
+		if overCommitted {
+			capAtCurrentRequests(sized.PodContainerRecommendations)
+		}
+		result.PodContainerRecommendations = append(result.PodContainerRecommendations, sized.PodContainerRecommendations...)
+		result.MaxRestCPU = sized.MaxRestCPU
+		result.MaxRestMemory = sized.MaxRestMemory
+		clusterResult.NodeResults[nodeName] = result
+	}
+
+	return clusterResult, nil
+}
@@ -0,0 +1,119 @@
+package applystrategies
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/truefoundry/cruisekube/pkg/config"
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+	"github.com/truefoundry/cruisekube/pkg/types"
+)
+
+func newBinPackingPod(name string, cpu, cpuRest, requestedCPU float64) utils.PodInfo {
+	return utils.PodInfo{
+		Namespace:    "default",
+		Name:         name,
+		WorkloadKind: utils.DeploymentKind,
+		WorkloadName: name,
+		Stats: &utils.WorkloadStat{
+			WorkloadIdentifier: "Deployment:default:" + name,
+			ContainerStats: []utils.ContainerStats{{
+				ContainerName:           "app",
+				CPUStats:                &types.CPUStats{Max: cpu + cpuRest, P75: cpu},
+				MemoryStats:             &types.MemoryStats{Max: 100, P75: 100},
+				SimplePredictionsCPU:    &types.SimplePrediction{MaxValue: cpu + cpuRest},
+				SimplePredictionsMemory: &types.SimplePrediction{MaxValue: 100},
+			}},
+		},
+		ContainerResources: []*utils.ContainerResources{{
+			Name:          "app",
+			CPURequest:    requestedCPU,
+			MemoryRequest: 100,
+		}},
+	}
+}
+
+func binPackingNodeData(allocatableCPU float64, podInfos ...utils.PodInfo) utils.NodeOptimizationData {
+	return utils.NodeOptimizationData{
+		AllocatableCPU:    allocatableCPU,
+		AllocatableMemory: 10000,
+		PodInfos:          podInfos,
+	}
+}
+
+func recommendationsByPod(result utils.OptimizationResult) map[string]utils.PodContainerRecommendation {
+	recs := make(map[string]utils.PodContainerRecommendation, len(result.PodContainerRecommendations))
+	for _, rec := range result.PodContainerRecommendations {
+		recs[rec.PodInfo.Name] = rec
+	}
+	return recs
+}
+
+func TestOptimizeClusterPlacesEvictedPods(t *testing.T) {
+	strategy := NewClusterBinPackingStrategy(context.Background(), config.RecommendationSettings{}).(*ClusterBinPackingStrategy)
+
+	result, err := strategy.OptimizeCluster(nil, nil, utils.ClusterOptimizationData{Nodes: map[string]utils.NodeOptimizationData{
+		// 1 + 0.8 cpu with a 0.6 spike do not fit, the pod with the largest rest leaves
+		"node-a": binPackingNodeData(2, newBinPackingPod("a-1", 1, 0.6, 1), newBinPackingPod("a-2", 0.8, 0.2, 0.8)),
+		// the shared rest of b-1 leaves no room for a-1 admitted at 1.6 cpu
+		"node-b": binPackingNodeData(2.2, newBinPackingPod("b-1", 0.5, 0.5, 0.5)),
+		// a node without pods of the strategy
+		"node-c": binPackingNodeData(2),
+	}})
+	if err != nil {
+		t.Fatalf("Failed to optimize cluster: %v", err)
+	}
+
+	if len(result.Evictions) != 1 {
+		t.Fatalf("Expected 1 planned eviction, got %+v", result.Evictions)
+	}
+	eviction := result.Evictions[0]
+	if eviction.PodInfo.Name != "a-1" || eviction.SourceNode != "node-a" || eviction.TargetNode != "node-c" {
+		t.Errorf("Expected a-1 to be planned from node-a to node-c, got %s from %s to %s", eviction.PodInfo.Name, eviction.SourceNode, eviction.TargetNode)
+	}
+
+	nodeA := recommendationsByPod(result.NodeResults["node-a"])
+	if !nodeA["a-1"].Evict {
+		t.Errorf("Expected a-1 to be evicted")
+	}
+	if rec := nodeA["a-2"]; rec.Evict || math.Abs(rec.CPU-1) > 1e-9 {
+		t.Errorf("Expected a-2 to keep its rest and be recommended 1 cpu, got %+v", rec)
+	}
+	if rec := recommendationsByPod(result.NodeResults["node-b"])["b-1"]; math.Abs(rec.CPU-1) > 1e-9 {
+		t.Errorf("Expected b-1 to be recommended 1 cpu, got %v", rec.CPU)
+	}
+	if recs := result.NodeResults["node-c"].PodContainerRecommendations; len(recs) != 0 {
+		t.Errorf("Expected no recommendations on node-c, got %+v", recs)
+	}
+}
+
+func TestOptimizeClusterKeepsUnplacedPods(t *testing.T) {
+	strategy := NewClusterBinPackingStrategy(context.Background(), config.RecommendationSettings{}).(*ClusterBinPackingStrategy)
+
+	result, err := strategy.OptimizeCluster(nil, nil, utils.ClusterOptimizationData{Nodes: map[string]utils.NodeOptimizationData{
+		"node-a": binPackingNodeData(2, newBinPackingPod("a-1", 1, 0.6, 1.2), newBinPackingPod("a-2", 0.8, 0.2, 0.8)),
+		"node-b": binPackingNodeData(2.2, newBinPackingPod("b-1", 0.5, 0.5, 0.5)),
+	}})
+	if err != nil {
+		t.Fatalf("Failed to optimize cluster: %v", err)
+	}
+	if len(result.Evictions) != 0 {
+		t.Fatalf("Expected no planned evictions, got %+v", result.Evictions)
+	}
+
+	// node-a is still short of room, its pods are not grown past their current requests
+	nodeA := recommendationsByPod(result.NodeResults["node-a"])
+	if len(nodeA) != 2 {
+		t.Fatalf("Expected recommendations for both pods on node-a, got %+v", nodeA)
+	}
+	for name, wantCPU := range map[string]float64{"a-1": 1.2, "a-2": 0.8} {
+		rec := nodeA[name]
+		if rec.Evict {
+			t.Errorf("Expected %s to not be evicted", name)
+		}
+		if math.Abs(rec.CPU-wantCPU) > 1e-9 {
+			t.Errorf("Expected %s to be capped at %v cpu, got %v", name, wantCPU, rec.CPU)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package applystrategies
+
+import (
+	"slices"
+
+	"github.com/truefoundry/cruisekube/pkg/config"
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+	"github.com/truefoundry/cruisekube/pkg/types"
+)
+
+const defaultRequestPercentile = 75.0
+
+// recommendationPolicy is the effective request percentile, pmax source and safety margin for a workload.
+type recommendationPolicy struct {
+	RequestPercentile  float64
+	PmaxSource         types.PmaxSource
+	SafetyMarginType   types.SafetyMarginType
+	CPUSafetyMargin    float64
+	MemorySafetyMargin float64
+}
+
+func newRecommendationPolicy(settings config.RecommendationSettings) recommendationPolicy {
+	policy := recommendationPolicy{
+		RequestPercentile:  settings.RequestPercentile,
+		PmaxSource:         types.PmaxSource(settings.PmaxSource),
+		SafetyMarginType:   types.SafetyMarginType(settings.SafetyMarginType),
+		CPUSafetyMargin:    max(settings.CPUSafetyMargin, 0),
+		MemorySafetyMargin: max(settings.MemorySafetyMargin, 0),
+	}
+	if !slices.Contains(types.SupportedRequestPercentiles, policy.RequestPercentile) {
+		policy.RequestPercentile = defaultRequestPercentile
+	}
+	if policy.PmaxSource == "" {
+		policy.PmaxSource = types.PmaxSourcePrediction
+	}
+	if policy.SafetyMarginType == "" {
+		policy.SafetyMarginType = types.SafetyMarginMultiplicative
+	}
+	return policy
+}
+
+// withOverrides returns the policy with the workload overrides applied on top.
+func (p recommendationPolicy) withOverrides(overrides *types.RecommendationOverrides) recommendationPolicy {
+	if overrides == nil || overrides.Validate() != nil {
+		return p
+	}
+	if overrides.RequestPercentile != nil {
+		p.RequestPercentile = *overrides.RequestPercentile
+	}
+	if overrides.PmaxSource != nil {
+		p.PmaxSource = *overrides.PmaxSource
+	}
+	if overrides.SafetyMarginType != nil {
+		p.SafetyMarginType = *overrides.SafetyMarginType
+	}
+	if overrides.CPUSafetyMargin != nil {
+		p.CPUSafetyMargin = *overrides.CPUSafetyMargin
+	}
+	if overrides.MemorySafetyMargin != nil {
+		p.MemorySafetyMargin = *overrides.MemorySafetyMargin
+	}
+	return p
+}
+
+func (p recommendationPolicy) applySafetyMargin(value float64, margin float64) float64 {
+	if p.SafetyMarginType == types.SafetyMarginAbsolute {
+		return value + margin
+	}
+	return value * (1 + margin)
+}
+
+func policyForPod(base recommendationPolicy, overridesMap map[string]*types.WorkloadOverrideInfo, podInfo utils.PodInfo) recommendationPolicy {
+	if podInfo.Stats == nil {
+		return base
+	}
+	overrides, ok := overridesMap[podInfo.Stats.WorkloadIdentifier]
+	if !ok {
+		return base
+	}
+	return base.withOverrides(overrides.Recommendation)
+}
+
+func boundsForContainer(overridesMap map[string]*types.WorkloadOverrideInfo, podInfo utils.PodInfo, containerName string) *types.ResourceBounds {
+	if podInfo.Stats == nil {
+		return nil
+	}
+	return overridesMap[podInfo.Stats.WorkloadIdentifier].BoundsForContainer(containerName)
+}
+
+// percentileWithFallback returns the percentile value, falling back to P75 for stats generated
+// before the percentile was recorded, and for higher percentiles recorded below P75.
+func percentileWithFallback(percentile float64, value float64, p75 float64) float64 {
+	if value <= 0 {
+		return p75
+	}
+	if percentile > defaultRequestPercentile && value < p75 {
+		return p75
+	}
+	return value
+}
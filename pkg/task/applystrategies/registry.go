@@ -0,0 +1,68 @@
+package applystrategies
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/truefoundry/cruisekube/pkg/config"
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+)
+
+const DefaultStrategyName = "AdjustAmongstPodsDistributed"
+
+type StrategyFactory func(ctx context.Context, settings config.RecommendationSettings) utils.OptimizationStrategy
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]StrategyFactory)
+)
+
+// RegisterStrategy makes a strategy selectable by name. Registering the same name twice panics.
+func RegisterStrategy(name string, factory StrategyFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("applystrategies: nil factory for strategy " + name)
+	}
+	if _, exists := registry[name]; exists {
+		panic("applystrategies: strategy already registered: " + name)
+	}
+	registry[name] = factory
+}
+
+// GetStrategy returns a new instance of the named strategy. An empty name resolves to the default strategy.
+func GetStrategy(ctx context.Context, name string, settings config.RecommendationSettings) (utils.OptimizationStrategy, error) {
+	if name == "" {
+		name = DefaultStrategyName
+	}
+
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown optimization strategy %q", name)
+	}
+	return factory(ctx, settings), nil
+}
+
+func IsRegisteredStrategy(name string) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	_, ok := registry[name]
+	return ok
+}
+
+func ListStrategies() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -5,6 +5,38 @@ import (
 	"github.com/truefoundry/cruisekube/pkg/types"
 )
 
+// boundRecommendation clamps the request to the workload bounds and trims the spike headroom so that
+// request plus headroom stays within the bounds as well.
+func boundRecommendation(recommended, rest float64, clampFn func(float64) float64) (float64, float64) {
+	bounded := clampFn(recommended)
+	return bounded, max(clampFn(recommended+rest)-bounded, 0)
+}
+
+// reserveRestForGuaranteedPod folds the spike headroom into the request. Guaranteed pods cannot burst
+// above their request, so they cannot share headroom with the other pods on the node.
+func reserveRestForGuaranteedPod(recommended, rest float64) (float64, float64) {
+	return recommended + max(rest, 0), 0
+}
+
+// capAtCurrentRequests keeps the recommendations from growing the requests of the containers.
+func capAtCurrentRequests(recommendations []utils.PodContainerRecommendation) {
+	for i, rec := range recommendations {
+		if rec.Evict {
+			continue
+		}
+		current, err := rec.PodInfo.GetContainerResource(rec.ContainerName)
+		if err != nil {
+			continue
+		}
+		if current.CPURequest > 0 {
+			recommendations[i].CPU = min(rec.CPU, current.CPURequest)
+		}
+		if current.MemoryRequest > 0 {
+			recommendations[i].Memory = min(rec.Memory, current.MemoryRequest)
+		}
+	}
+}
+
 func isEvictionExcludedPod(podInfo *utils.PodInfo, evictionRanking types.EvictionRanking) bool {
 	if podInfo.Stats.Constraints == nil {
 		return false
@@ -12,6 +44,10 @@ func isEvictionExcludedPod(podInfo *utils.PodInfo, evictionRanking types.Evictio
 	if podInfo.Stats.Constraints.ExcludedAnnotation {
 		return true
 	}
+	// an evicted pod may not find another node with the same extended resources
+	if podInfo.Stats.Constraints.ExtendedResources {
+		return true
+	}
 	if podInfo.WorkloadKind == utils.DaemonSetKind {
 		return true
 	}
@@ -0,0 +1,45 @@
+package forecasters
+
+import (
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+)
+
+const BucketMaxForecasterName = "BucketMax"
+
+func init() {
+	RegisterForecaster(BucketMaxForecasterName, NewBucketMaxForecaster)
+}
+
+func NewBucketMaxForecaster(config ForecasterConfig) utils.Forecaster {
+	return &BucketMaxForecaster{}
+}
+
+// BucketMaxForecaster forecasts every step with the max usage seen at the same hour of the day. It has
+// no trend, no decay and no spread, the point forecast is also the upper bound.
+type BucketMaxForecaster struct{}
+
+func (f *BucketMaxForecaster) GetName() string {
+	return BucketMaxForecasterName
+}
+
+func (f *BucketMaxForecaster) Forecast(series utils.TimeSeries, horizon int) (utils.Forecast, error) {
+	if len(series.Values) == 0 || len(series.Timestamps) != len(series.Values) {
+		return utils.Forecast{}, ErrInsufficientData
+	}
+
+	forecast := utils.Forecast{
+		Points:      make([]float64, horizon),
+		UpperBounds: make([]float64, horizon),
+	}
+	for k, target := range forecastTimes(series, horizon) {
+		bucketMax := 0.0
+		for i, t := range series.Timestamps {
+			if t.Hour() == target.Hour() {
+				bucketMax = max(bucketMax, series.Values[i])
+			}
+		}
+		forecast.Points[k] = bucketMax
+		forecast.UpperBounds[k] = bucketMax
+	}
+	return forecast, nil
+}
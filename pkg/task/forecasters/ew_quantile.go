@@ -0,0 +1,82 @@
+package forecasters
+
+import (
+	"math"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+)
+
+const (
+	EWQuantileForecasterName = "EWQuantile"
+
+	defaultHalfLifeDays = 2.0
+	// sameWeekdayWeight favours the samples of the same weekday, so weekly patterns show through.
+	sameWeekdayWeight = 2.0
+)
+
+func init() {
+	RegisterForecaster(EWQuantileForecasterName, NewEWQuantileForecaster)
+}
+
+func NewEWQuantileForecaster(config ForecasterConfig) utils.Forecaster {
+	f := &EWQuantileForecaster{
+		halfLifeDays: config.HalfLifeDays,
+		confidence:   config.Confidence,
+	}
+	if f.halfLifeDays <= 0 {
+		f.halfLifeDays = defaultHalfLifeDays
+	}
+	if f.confidence == 0 {
+		f.confidence = defaultConfidence
+	}
+	return f
+}
+
+// EWQuantileForecaster forecasts a step from the samples at the same hour of the day, weighted by an
+// exponential decay on their age and doubled for the same weekday. The point forecast is the weighted
+// median and the upper bound the weighted quantile at the configured confidence.
+type EWQuantileForecaster struct {
+	halfLifeDays float64
+	confidence   float64
+}
+
+func (f *EWQuantileForecaster) GetName() string {
+	return EWQuantileForecasterName
+}
+
+func (f *EWQuantileForecaster) Forecast(series utils.TimeSeries, horizon int) (utils.Forecast, error) {
+	if len(series.Values) == 0 || len(series.Timestamps) != len(series.Values) {
+		return utils.Forecast{}, ErrInsufficientData
+	}
+
+	forecast := utils.Forecast{
+		Points:      make([]float64, horizon),
+		UpperBounds: make([]float64, horizon),
+	}
+	for k, target := range forecastTimes(series, horizon) {
+		samples := f.weightedSamples(series, target, true)
+		if len(samples) == 0 {
+			samples = f.weightedSamples(series, target, false)
+		}
+		forecast.Points[k] = weightedQuantile(samples, 0.5)
+		forecast.UpperBounds[k] = weightedQuantile(samples, f.confidence)
+	}
+	return forecast, nil
+}
+
+func (f *EWQuantileForecaster) weightedSamples(series utils.TimeSeries, target time.Time, sameHourOnly bool) []weightedValue {
+	last := series.Timestamps[len(series.Timestamps)-1]
+	samples := make([]weightedValue, 0)
+	for i, t := range series.Timestamps {
+		if sameHourOnly && t.Hour() != target.Hour() {
+			continue
+		}
+		weight := math.Pow(0.5, last.Sub(t).Hours()/24/f.halfLifeDays)
+		if t.Weekday() == target.Weekday() {
+			weight *= sameWeekdayWeight
+		}
+		samples = append(samples, weightedValue{value: series.Values[i], weight: weight})
+	}
+	return samples
+}
@@ -0,0 +1,143 @@
+package forecasters
+
+import (
+	"math"
+
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+)
+
+const (
+	HoltWintersForecasterName = "HoltWinters"
+
+	// defaultSeasonLength is a week of hourly steps, dailySeasonLength is used while the history is
+	// shorter than two weeks.
+	defaultSeasonLength = 7 * 24
+	dailySeasonLength   = 24
+	defaultAlpha        = 0.3
+	defaultBeta         = 0.05
+	defaultGamma        = 0.2
+)
+
+func init() {
+	RegisterForecaster(HoltWintersForecasterName, NewHoltWintersForecaster)
+}
+
+func NewHoltWintersForecaster(config ForecasterConfig) utils.Forecaster {
+	f := &HoltWintersForecaster{
+		seasonLength: config.SeasonLength,
+		alpha:        config.Alpha,
+		beta:         config.Beta,
+		gamma:        config.Gamma,
+		z:            zScore(config.Confidence),
+	}
+	if f.seasonLength <= 0 {
+		f.seasonLength = defaultSeasonLength
+	}
+	if f.alpha == 0 {
+		f.alpha = defaultAlpha
+	}
+	if f.beta == 0 {
+		f.beta = defaultBeta
+	}
+	if f.gamma == 0 {
+		f.gamma = defaultGamma
+	}
+	return f
+}
+
+// HoltWintersForecaster is additive triple exponential smoothing: a level, a trend and one seasonal
+// component per step of the season, a week by default so that weekdays and weekends differ. The upper bound is the point forecast plus z times the spread of
+// the one step ahead errors, widening with the square root of the step.
+type HoltWintersForecaster struct {
+	seasonLength int
+	alpha        float64
+	beta         float64
+	gamma        float64
+	z            float64
+}
+
+func (f *HoltWintersForecaster) GetName() string {
+	return HoltWintersForecasterName
+}
+
+func (f *HoltWintersForecaster) Forecast(series utils.TimeSeries, horizon int) (utils.Forecast, error) {
+	if len(series.Timestamps) != len(series.Values) {
+		return utils.Forecast{}, ErrInsufficientData
+	}
+	values, sampled := regularize(series)
+	m := f.seasonLength
+	if len(values) < 2*m && m > dailySeasonLength {
+		m = dailySeasonLength
+	}
+	if len(values) < 2*m {
+		return utils.Forecast{}, ErrInsufficientData
+	}
+
+	firstSeasonMean, ok := sampledMean(values[:m], sampled[:m])
+	if !ok {
+		return utils.Forecast{}, ErrInsufficientData
+	}
+	secondSeasonMean, ok := sampledMean(values[m:2*m], sampled[m:2*m])
+	if !ok {
+		return utils.Forecast{}, ErrInsufficientData
+	}
+	level := firstSeasonMean
+	trend := (secondSeasonMean - firstSeasonMean) / float64(m)
+	seasonal := make([]float64, m)
+	for i := range m {
+		if sampled[i] {
+			seasonal[i] = values[i] - firstSeasonMean
+		}
+	}
+
+	sumSquaredErrors := 0.0
+	errorCount := 0
+	for t := m; t < len(values); t++ {
+		// a step without samples, such as an excluded period, carries the state forward untouched
+		if !sampled[t] {
+			level += trend
+			continue
+		}
+		season := seasonal[t%m]
+		prediction := level + trend + season
+		sumSquaredErrors += (values[t] - prediction) * (values[t] - prediction)
+		errorCount++
+
+		newLevel := f.alpha*(values[t]-season) + (1-f.alpha)*(level+trend)
+		trend = f.beta*(newLevel-level) + (1-f.beta)*trend
+		seasonal[t%m] = f.gamma*(values[t]-newLevel) + (1-f.gamma)*season
+		level = newLevel
+	}
+	if errorCount == 0 {
+		return utils.Forecast{}, ErrInsufficientData
+	}
+	sigma := math.Sqrt(sumSquaredErrors / float64(errorCount))
+
+	forecast := utils.Forecast{
+		Points:      make([]float64, horizon),
+		UpperBounds: make([]float64, horizon),
+	}
+	last := len(values) - 1
+	for k := 1; k <= horizon; k++ {
+		point := math.Max(level+float64(k)*trend+seasonal[(last+k)%m], 0)
+		forecast.Points[k-1] = point
+		forecast.UpperBounds[k-1] = point + f.z*sigma*math.Sqrt(float64(k))
+	}
+	return forecast, nil
+}
+
+// sampledMean is the mean of the sampled values, false when none was sampled.
+func sampledMean(values []float64, sampled []bool) (float64, bool) {
+	sum := 0.0
+	count := 0
+	for i, v := range values {
+		if sampled[i] {
+			sum += v
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return sum / float64(count), true
+}
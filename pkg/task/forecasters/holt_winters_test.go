@@ -0,0 +1,117 @@
+package forecasters
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+)
+
+// seriesStart is a Monday midnight.
+var seriesStart = time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+
+// dailyCycle peaks at 15 at 6 in the morning and bottoms out at 5 at 6 in the evening.
+func dailyCycle(hour int) float64 {
+	return 10 + 5*math.Sin(2*math.Pi*float64(hour%24)/24)
+}
+
+// weeklyCycle follows the daily cycle on weekdays and stays at 3 on weekends.
+func weeklyCycle(hour int) float64 {
+	if hour%(7*24) >= 5*24 {
+		return 3
+	}
+	return dailyCycle(hour)
+}
+
+// hourlySeries returns hourly samples from seriesStart, leaving out the hours skipped.
+func hourlySeries(hours int, value func(hour int) float64, skip func(hour int) bool) utils.TimeSeries {
+	series := utils.TimeSeries{Step: time.Hour}
+	for hour := range hours {
+		if skip != nil && skip(hour) {
+			continue
+		}
+		series.Timestamps = append(series.Timestamps, seriesStart.Add(time.Duration(hour)*time.Hour))
+		series.Values = append(series.Values, value(hour))
+	}
+	return series
+}
+
+func TestHoltWintersForecastSeasonal(t *testing.T) {
+	forecaster := NewHoltWintersForecaster(ForecasterConfig{})
+
+	tests := []struct {
+		name  string
+		hours int
+		value func(hour int) float64
+	}{
+		// three weeks ending on a Friday night, the weekend follows
+		{"weekly season", 19 * 24, weeklyCycle},
+		// three days are too short for a weekly season, a Thursday follows
+		{"daily season of a short history", 3 * 24, dailyCycle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forecast, err := forecaster.Forecast(hourlySeries(tt.hours, tt.value, nil), 48)
+			if err != nil {
+				t.Fatalf("Failed to forecast: %v", err)
+			}
+			if len(forecast.Points) != 48 || len(forecast.UpperBounds) != 48 {
+				t.Fatalf("Expected 48 points and upper bounds, got %d and %d", len(forecast.Points), len(forecast.UpperBounds))
+			}
+			for k, point := range forecast.Points {
+				want := tt.value(tt.hours + k)
+				if math.Abs(point-want) > 1 {
+					t.Errorf("Expected step %d to be forecast near %.2f, got %.2f", k+1, want, point)
+				}
+				if forecast.UpperBounds[k] < point {
+					t.Errorf("Expected the upper bound of step %d to be at least %.2f, got %.2f", k+1, point, forecast.UpperBounds[k])
+				}
+			}
+		})
+	}
+}
+
+func TestHoltWintersForecastInsufficientData(t *testing.T) {
+	forecaster := NewHoltWintersForecaster(ForecasterConfig{})
+	mismatched := hourlySeries(3*24, dailyCycle, nil)
+	mismatched.Values = mismatched.Values[1:]
+
+	tests := []struct {
+		name   string
+		series utils.TimeSeries
+	}{
+		{"empty series", utils.TimeSeries{Step: time.Hour}},
+		{"shorter than two days", hourlySeries(47, dailyCycle, nil)},
+		{"mismatched timestamps and values", mismatched},
+		{"second day without samples", hourlySeries(3*24, dailyCycle, func(hour int) bool { return hour >= 24 && hour < 48 })},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := forecaster.Forecast(tt.series, 2); !errors.Is(err, ErrInsufficientData) {
+				t.Errorf("Expected %v, got %v", ErrInsufficientData, err)
+			}
+		})
+	}
+}
+
+func TestHoltWintersForecastSkipsExcludedSteps(t *testing.T) {
+	forecaster := NewHoltWintersForecaster(ForecasterConfig{SeasonLength: 24})
+
+	complete, err := forecaster.Forecast(hourlySeries(4*24, dailyCycle, nil), 24)
+	if err != nil {
+		t.Fatalf("Failed to forecast the complete series: %v", err)
+	}
+	// the working hours of the third day are excluded, as on a holiday
+	withGap, err := forecaster.Forecast(hourlySeries(4*24, dailyCycle, func(hour int) bool { return hour >= 56 && hour < 66 }), 24)
+	if err != nil {
+		t.Fatalf("Failed to forecast the series with a gap: %v", err)
+	}
+
+	for k := range complete.Points {
+		if math.Abs(withGap.Points[k]-complete.Points[k]) > 0.5 {
+			t.Errorf("Expected step %d to be forecast at %.2f as without the gap, got %.2f", k+1, complete.Points[k], withGap.Points[k])
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package forecasters
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+)
+
+const (
+	DefaultForecasterName = HoltWintersForecasterName
+	DefaultHorizonSteps   = 2
+)
+
+// ForecasterConfig selects the forecaster used for the usage predictions and tunes it. Zero values
+// fall back to the defaults of each forecaster.
+type ForecasterConfig struct {
+	Name string `yaml:"name" json:"name" mapstructure:"name"`
+	// HorizonSteps is how many hourly steps ahead are forecast. MaxValue covers the whole horizon.
+	HorizonSteps int `yaml:"horizonSteps" json:"horizonSteps" mapstructure:"horizonSteps"`
+	// Confidence is the one sided confidence of the upper bounds, between 0.5 and 1.
+	Confidence float64 `yaml:"confidence" json:"confidence" mapstructure:"confidence"`
+
+	// SeasonLength is the number of steps in a Holt-Winters season, a week of hourly steps by default.
+	// Histories shorter than two seasons fall back to a daily season.
+	SeasonLength int     `yaml:"seasonLength" json:"seasonLength" mapstructure:"seasonLength"`
+	Alpha        float64 `yaml:"alpha" json:"alpha" mapstructure:"alpha"`
+	Beta         float64 `yaml:"beta" json:"beta" mapstructure:"beta"`
+	Gamma        float64 `yaml:"gamma" json:"gamma" mapstructure:"gamma"`
+
+	// HalfLifeDays is how fast old samples lose weight in the exponentially weighted quantiles.
+	HalfLifeDays float64 `yaml:"halfLifeDays" json:"halfLifeDays" mapstructure:"halfLifeDays"`
+}
+
+func (c ForecasterConfig) Validate() error {
+	if c.HorizonSteps < 0 {
+		return fmt.Errorf("horizonSteps must not be negative, got %d", c.HorizonSteps)
+	}
+	if c.Confidence != 0 && (c.Confidence < 0.5 || c.Confidence >= 1) {
+		return fmt.Errorf("confidence must be in [0.5, 1), got %v", c.Confidence)
+	}
+	for name, value := range map[string]float64{"alpha": c.Alpha, "beta": c.Beta, "gamma": c.Gamma} {
+		if value < 0 || value > 1 {
+			return fmt.Errorf("%s must be in [0, 1], got %v", name, value)
+		}
+	}
+	if c.SeasonLength < 0 {
+		return fmt.Errorf("seasonLength must not be negative, got %d", c.SeasonLength)
+	}
+	if c.HalfLifeDays < 0 {
+		return fmt.Errorf("halfLifeDays must not be negative, got %v", c.HalfLifeDays)
+	}
+	return nil
+}
+
+// GetHorizonSteps returns the configured horizon, or the default one.
+func (c ForecasterConfig) GetHorizonSteps() int {
+	if c.HorizonSteps <= 0 {
+		return DefaultHorizonSteps
+	}
+	return c.HorizonSteps
+}
+
+type ForecasterFactory func(config ForecasterConfig) utils.Forecaster
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]ForecasterFactory)
+)
+
+// RegisterForecaster makes a forecaster selectable by name. Registering the same name twice panics.
+func RegisterForecaster(name string, factory ForecasterFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("forecasters: nil factory for forecaster " + name)
+	}
+	if _, exists := registry[name]; exists {
+		panic("forecasters: forecaster already registered: " + name)
+	}
+	registry[name] = factory
+}
+
+// GetForecaster returns a new instance of the configured forecaster. An empty name resolves to the
+// default forecaster.
+func GetForecaster(config ForecasterConfig) (utils.Forecaster, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid forecaster config: %w", err)
+	}
+	name := config.Name
+	if name == "" {
+		name = DefaultForecasterName
+	}
+
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown forecaster %q, available forecasters: %v", name, ListForecasters())
+	}
+	return factory(config), nil
+}
+
+func ListForecasters() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
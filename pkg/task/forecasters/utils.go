@@ -0,0 +1,91 @@
+package forecasters
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+)
+
+const defaultConfidence = 0.95
+
+var ErrInsufficientData = errors.New("not enough samples to forecast")
+
+// zScore is the standard normal quantile of the one sided confidence.
+func zScore(confidence float64) float64 {
+	if confidence == 0 {
+		confidence = defaultConfidence
+	}
+	return math.Sqrt2 * math.Erfinv(2*confidence-1)
+}
+
+// regularize returns one value per step from the first to the last sample, and whether the step was
+// sampled. Samples falling into the same step keep the largest value, steps without samples, such as
+// those of the calendar exclusions, repeat the previous value.
+func regularize(series utils.TimeSeries) ([]float64, []bool) {
+	if len(series.Values) == 0 || series.Step <= 0 {
+		return nil, nil
+	}
+	start := series.Timestamps[0]
+	steps := int(math.Round(float64(series.Timestamps[len(series.Timestamps)-1].Sub(start))/float64(series.Step))) + 1
+	values := make([]float64, steps)
+	sampled := make([]bool, steps)
+	for i, t := range series.Timestamps {
+		slot := int(math.Round(float64(t.Sub(start)) / float64(series.Step)))
+		if slot < 0 || slot >= steps {
+			continue
+		}
+		if !sampled[slot] || series.Values[i] > values[slot] {
+			values[slot] = series.Values[i]
+		}
+		sampled[slot] = true
+	}
+	for i := 1; i < steps; i++ {
+		if !sampled[i] {
+			values[i] = values[i-1]
+		}
+	}
+	return values, sampled
+}
+
+// forecastTimes returns the time of every step of the horizon after the last sample.
+func forecastTimes(series utils.TimeSeries, horizon int) []time.Time {
+	last := series.Timestamps[len(series.Timestamps)-1]
+	times := make([]time.Time, horizon)
+	for k := range times {
+		times[k] = last.Add(time.Duration(k+1) * series.Step)
+	}
+	return times
+}
+
+type weightedValue struct {
+	value  float64
+	weight float64
+}
+
+// weightedQuantile returns the smallest value whose cumulative weight reaches the quantile.
+func weightedQuantile(values []weightedValue, quantile float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := make([]weightedValue, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].value < sorted[j].value
+	})
+
+	total := 0.0
+	for _, v := range sorted {
+		total += v.weight
+	}
+	cumulative := 0.0
+	for _, v := range sorted {
+		cumulative += v.weight
+		if cumulative >= quantile*total {
+			return v.value
+		}
+	}
+	return sorted[len(sorted)-1].value
+}
@@ -0,0 +1,172 @@
+package task
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/truefoundry/cruisekube/pkg/logging"
+	"github.com/truefoundry/cruisekube/pkg/metrics"
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+	"github.com/truefoundry/cruisekube/pkg/types"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// trackResize remembers a resize patched in this run so the next run can check the kubelet applied it.
+func (a *ApplyRecommendationTask) trackResize(pod *corev1.Pod, podInfo utils.PodInfo, entry types.ApplyRunEntry) {
+	a.resizeTracker.Track(utils.PendingResize{
+		PodInfo:       podInfo,
+		PodUID:        pod.UID,
+		NodeName:      entry.NodeName,
+		ContainerName: entry.ContainerName,
+		Resource:      entry.Resource,
+		OldRequest:    entry.OldRequest,
+		OldLimit:      entry.OldLimit,
+		NewRequest:    entry.NewRequest,
+		NewLimit:      entry.NewLimit,
+	})
+}
+
+// verifyPendingResizes checks the resizes of the previous runs against the pods. Deferred resizes are
+// patched again up to the configured retries, infeasible ones are handled by the infeasible policy.
+func (a *ApplyRecommendationTask) verifyPendingResizes(ctx context.Context) []types.ApplyRunEntry {
+	entries := make([]types.ApplyRunEntry, 0)
+	evictedPods := make(map[string]bool)
+	outcomes := make(map[utils.ResizeOutcome]int)
+
+	for _, resize := range a.resizeTracker.Pending() {
+		podKey := utils.GetPodKey(resize.PodInfo.Namespace, resize.PodInfo.Name)
+		if evictedPods[podKey] {
+			a.resizeTracker.Forget(resize)
+			continue
+		}
+
+		pod, err := a.kubeClient.CoreV1().Pods(resize.PodInfo.Namespace).Get(ctx, resize.PodInfo.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			a.resizeTracker.Forget(resize)
+			outcomes[utils.ResizeOutcomeSuperseded]++
+			metrics.ClusterResizeOutcomeCount.WithLabelValues(a.config.ClusterID, string(utils.ResizeOutcomeSuperseded)).Inc()
+			continue
+		}
+		if err != nil {
+			logging.Errorf(ctx, "Error getting pod %s to verify its resize: %v", podKey, err)
+			continue
+		}
+
+		state := utils.GetResizeState(pod, resize)
+		if pod.DeletionTimestamp != nil {
+			state = utils.ResizeStateSuperseded
+		}
+
+		var outcome utils.ResizeOutcome
+		var entry *types.ApplyRunEntry
+		switch state {
+		case utils.ResizeStateApplied:
+			a.resizeTracker.Forget(resize)
+			outcome = utils.ResizeOutcomeApplied
+		case utils.ResizeStateInProgress:
+			outcome = utils.ResizeOutcomeInProgress
+		case utils.ResizeStateSuperseded:
+			a.resizeTracker.Forget(resize)
+			outcome = utils.ResizeOutcomeSuperseded
+		case utils.ResizeStateDeferred:
+			if resize.Attempts < a.config.Metadata.ResizeVerification.MaxRetries {
+				outcome, entry = a.retryResize(ctx, pod, resize)
+				break
+			}
+			outcome, entry = a.handleInfeasibleResize(ctx, pod, resize, fmt.Sprintf("resize still deferred after %d retries", resize.Attempts))
+		case utils.ResizeStateInfeasible:
+			outcome, entry = a.handleInfeasibleResize(ctx, pod, resize, "resize is infeasible on the node")
+		}
+
+		if outcome == utils.ResizeOutcomeEvicted {
+			evictedPods[podKey] = true
+		}
+		outcomes[outcome]++
+		metrics.ClusterResizeOutcomeCount.WithLabelValues(a.config.ClusterID, string(outcome)).Inc()
+		if entry != nil {
+			entries = append(entries, *entry)
+		}
+	}
+
+	pendingCount := len(a.resizeTracker.Pending())
+	metrics.ClusterPendingResizeCount.WithLabelValues(a.config.ClusterID).Set(float64(pendingCount))
+	logging.Infof(ctx, "Verified resizes of previous runs: %v, %d still pending", outcomes, pendingCount)
+	return entries
+}
+
+func (a *ApplyRecommendationTask) retryResize(ctx context.Context, pod *corev1.Pod, resize utils.PendingResize) (utils.ResizeOutcome, *types.ApplyRunEntry) {
+	resize.Attempts++
+	a.resizeTracker.Track(resize)
+
+	entry := newPendingResizeEntry(resize)
+	entry.Action = types.ApplyRunActionResizeRetried
+	entry.Reason = fmt.Sprintf("resize deferred by the kubelet, retry %d of %d", resize.Attempts, a.config.Metadata.ResizeVerification.MaxRetries)
+	if applied, errStr := a.patchResize(ctx, pod, resize, resize.NewRequest, resize.NewLimit); !applied {
+		logging.Errorf(ctx, "Error retrying %s resize of pod %s/%s container %s: %s", resize.Resource, pod.Namespace, pod.Name, resize.ContainerName, errStr)
+		entry.Action = types.ApplyRunActionFailed
+		entry.Reason = errStr
+		return utils.ResizeOutcomeFailed, &entry
+	}
+	logging.Infof(ctx, "Retried deferred %s resize of pod %s/%s container %s", resize.Resource, pod.Namespace, pod.Name, resize.ContainerName)
+	return utils.ResizeOutcomeRetried, &entry
+}
+
+func (a *ApplyRecommendationTask) handleInfeasibleResize(ctx context.Context, pod *corev1.Pod, resize utils.PendingResize, reason string) (utils.ResizeOutcome, *types.ApplyRunEntry) {
+	switch a.config.Metadata.ResizeVerification.InfeasiblePolicy {
+	case utils.InfeasibleResizePolicyEvict:
+		entry := newApplyRunEntry(resize.NodeName, resize.PodInfo, "", types.ApplyRunResourcePod)
+		if budgetReason := a.evictionPlanner.ExceededBudget(resize.PodInfo); budgetReason != "" {
+			entry.Action = types.ApplyRunActionEvictionDeferred
+			entry.Reason = fmt.Sprintf("%s, %s", reason, budgetReason)
+			return utils.ResizeOutcomeEvictionDeferred, &entry
+		}
+		entry.Action = types.ApplyRunActionEvicted
+		entry.Reason = reason
+		if evicted, errStr := utils.EvictPod(ctx, a.kubeClient, pod); !evicted {
+			logging.Errorf(ctx, "Error evicting pod %s/%s after %s: %s", pod.Namespace, pod.Name, reason, errStr)
+			entry.Action = types.ApplyRunActionEvictionFailed
+			entry.Reason = errStr
+			return utils.ResizeOutcomeFailed, &entry
+		}
+		a.evictionPlanner.RecordEviction(resize.PodInfo)
+		a.resizeTracker.Forget(resize)
+		return utils.ResizeOutcomeEvicted, &entry
+	case utils.InfeasibleResizePolicyRevert:
+		entry := newPendingResizeEntry(resize)
+		entry.OldRequest, entry.NewRequest = resize.NewRequest, resize.OldRequest
+		entry.OldLimit, entry.NewLimit = resize.NewLimit, resize.OldLimit
+		entry.Action = types.ApplyRunActionResizeReverted
+		entry.Reason = reason
+		a.resizeTracker.Forget(resize)
+		if applied, errStr := a.patchResize(ctx, pod, resize, resize.OldRequest, resize.OldLimit); !applied {
+			logging.Errorf(ctx, "Error reverting %s resize of pod %s/%s container %s: %s", resize.Resource, pod.Namespace, pod.Name, resize.ContainerName, errStr)
+			entry.Action = types.ApplyRunActionFailed
+			entry.Reason = errStr
+			return utils.ResizeOutcomeFailed, &entry
+		}
+		return utils.ResizeOutcomeReverted, &entry
+	default:
+		a.resizeTracker.Forget(resize)
+		entry := newPendingResizeEntry(resize)
+		entry.Action = types.ApplyRunActionResizeAbandoned
+		entry.Reason = reason
+		return utils.ResizeOutcomeAbandoned, &entry
+	}
+}
+
+func (a *ApplyRecommendationTask) patchResize(ctx context.Context, pod *corev1.Pod, resize utils.PendingResize, request, limit float64) (bool, string) {
+	if resize.Resource == types.ApplyRunResourceCPU {
+		return utils.UpdatePodCPUResources(ctx, a.kubeClient, pod, resize.ContainerName, request, limit)
+	}
+	return utils.UpdatePodMemoryResources(ctx, a.kubeClient, pod, resize.ContainerName, request, limit)
+}
+
+func newPendingResizeEntry(resize utils.PendingResize) types.ApplyRunEntry {
+	entry := newApplyRunEntry(resize.NodeName, resize.PodInfo, resize.ContainerName, resize.Resource)
+	entry.OldRequest, entry.NewRequest = resize.OldRequest, resize.NewRequest
+	entry.OldLimit, entry.NewLimit = resize.OldLimit, resize.NewLimit
+	return entry
+}
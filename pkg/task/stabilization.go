@@ -0,0 +1,59 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/logging"
+	"github.com/truefoundry/cruisekube/pkg/task/utils"
+	"github.com/truefoundry/cruisekube/pkg/types"
+)
+
+// recommendationStateRetentionDays drops the states of workload containers that got no recommendation
+// for that long, like deleted workloads.
+const recommendationStateRetentionDays = 7
+
+func stabilizationKey(podInfo utils.PodInfo, containerName string, resource types.ApplyRunResource) utils.StabilizationKey {
+	key := utils.StabilizationKey{ContainerName: containerName, Resource: resource}
+	if podInfo.Stats != nil {
+		key.WorkloadID = podInfo.Stats.WorkloadIdentifier
+	}
+	return key
+}
+
+// startStabilizationRun loads the recommendation states persisted by the previous runs. When they
+// cannot be loaded the states kept in memory are used.
+func (a *ApplyRecommendationTask) startStabilizationRun(ctx context.Context, startedAt time.Time) {
+	var states []types.RecommendationState
+	if a.storage != nil {
+		loaded, err := a.storage.GetRecommendationStates(a.config.ClusterID)
+		if err != nil {
+			logging.Errorf(ctx, "Error loading recommendation states, using the states in memory: %v", err)
+		} else {
+			states = append(make([]types.RecommendationState, 0, len(loaded)), loaded...)
+		}
+	}
+	a.stabilizer.StartRun(startedAt, states)
+}
+
+// persistStabilizationRun stores the recommendation states changed by the run and drops the stale ones.
+func (a *ApplyRecommendationTask) persistStabilizationRun(ctx context.Context) {
+	states := a.stabilizer.FinishRun()
+	if a.storage == nil {
+		return
+	}
+
+	if err := a.storage.UpsertRecommendationStates(a.config.ClusterID, states); err != nil {
+		logging.Errorf(ctx, "Error persisting recommendation states: %v", err)
+		return
+	}
+
+	deletedCount, err := a.storage.DeleteOldRecommendationStates(a.config.ClusterID, recommendationStateRetentionDays)
+	if err != nil {
+		logging.Errorf(ctx, "Error deleting old recommendation states: %v", err)
+		return
+	}
+	if deletedCount > 0 {
+		logging.Infof(ctx, "Deleted %d old recommendation states", deletedCount)
+	}
+}
@@ -0,0 +1,74 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/logging"
+)
+
+const (
+	DefaultStatsHistoryRetentionDays             = 30
+	DefaultStatsHistoryDownsampleAfterHours      = 24
+	DefaultStatsHistoryDownsampleIntervalMinutes = 60
+
+	// statsHistoryDownsampleWindow is how far back every run looks for stats to downsample, stats missed
+	// while the task was not running stay until the retention drops them.
+	statsHistoryDownsampleWindow = 24 * time.Hour
+)
+
+// StatsHistoryConfig controls how long the stats of every run are kept and how they are thinned out
+// as they age.
+type StatsHistoryConfig struct {
+	RetentionDays int `yaml:"retentionDays" json:"retentionDays" mapstructure:"retentionDays"`
+	// DownsampleAfterHours is the age after which only the latest stat of every
+	// DownsampleIntervalMinutes is kept per workload.
+	DownsampleAfterHours      int `yaml:"downsampleAfterHours" json:"downsampleAfterHours" mapstructure:"downsampleAfterHours"`
+	DownsampleIntervalMinutes int `yaml:"downsampleIntervalMinutes" json:"downsampleIntervalMinutes" mapstructure:"downsampleIntervalMinutes"`
+}
+
+func (c *StatsHistoryConfig) setDefaults() {
+	if c.RetentionDays <= 0 {
+		c.RetentionDays = DefaultStatsHistoryRetentionDays
+	}
+	if c.DownsampleAfterHours <= 0 {
+		c.DownsampleAfterHours = DefaultStatsHistoryDownsampleAfterHours
+	}
+	if c.DownsampleIntervalMinutes <= 0 {
+		c.DownsampleIntervalMinutes = DefaultStatsHistoryDownsampleIntervalMinutes
+	}
+}
+
+// compactStatsHistory downsamples the stats history past its downsampling age and drops the stats and
+// the records of deleted stats past the retention.
+func (c *CreateStatsTask) compactStatsHistory(ctx context.Context) {
+	historyConfig := c.config.Metadata.StatsHistory
+
+	downsampledCount, err := c.storage.DownsampleStatHistory(
+		c.config.ClusterID,
+		time.Duration(historyConfig.DownsampleAfterHours)*time.Hour,
+		statsHistoryDownsampleWindow,
+		time.Duration(historyConfig.DownsampleIntervalMinutes)*time.Minute,
+	)
+	if err != nil {
+		logging.Errorf(ctx, "Error downsampling stats history: %v", err)
+	} else if downsampledCount > 0 {
+		logging.Infof(ctx, "Downsampled %d stats history entries", downsampledCount)
+	}
+
+	deletedCount, err := c.storage.DeleteOldStatHistory(c.config.ClusterID, historyConfig.RetentionDays)
+	if err != nil {
+		logging.Errorf(ctx, "Error deleting old stats history: %v", err)
+	} else if deletedCount > 0 {
+		logging.Infof(ctx, "Deleted %d old stats history entries", deletedCount)
+	}
+
+	deletedCount, err = c.storage.DeleteOldStatDeletions(c.config.ClusterID, historyConfig.RetentionDays)
+	if err != nil {
+		logging.Errorf(ctx, "Error deleting old stat deletions: %v", err)
+		return
+	}
+	if deletedCount > 0 {
+		logging.Infof(ctx, "Deleted %d old stat deletions", deletedCount)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/truefoundry/cruisekube/pkg/contextutils"
 	"github.com/truefoundry/cruisekube/pkg/logging"
 	"github.com/truefoundry/cruisekube/pkg/metrics"
+	"github.com/truefoundry/cruisekube/pkg/repository/storage"
 	"github.com/truefoundry/cruisekube/pkg/task/applystrategies"
 	"github.com/truefoundry/cruisekube/pkg/task/utils"
 	"github.com/truefoundry/cruisekube/pkg/types"
@@ -34,6 +35,9 @@ type RecommendationResult struct {
 	NonOptimizablePods          []utils.NonOptimizablePodInfo
 	MaxRestCPU                  float64
 	MaxRestMemory               float64
+	PlannedEvictions            []utils.PlannedEviction
+	DeferredEvictions           []utils.DeferredEviction
+	ApplyRunEntries             []types.ApplyRunEntry
 }
 
 type ApplyRecommendationMetadata struct {
@@ -41,6 +45,16 @@ type ApplyRecommendationMetadata struct {
 	NodeStatsURL config.URLConfig `yaml:"nodeStatsURL" json:"nodeStatsURL" mapstructure:"nodeStatsURL"`
 	OverridesURL config.URLConfig `yaml:"overridesURL" json:"overridesURL" mapstructure:"overridesURL"`
 	SkipMemory   bool             `yaml:"skipMemory" json:"skipMemory" mapstructure:"skipMemory"`
+	// Strategy is the registered optimization strategy used when no namespace or workload override applies.
+	Strategy string `yaml:"strategy" json:"strategy" mapstructure:"strategy"`
+	// EvictionBudget limits the evictions performed across runs, evictions over budget are deferred.
+	EvictionBudget utils.EvictionBudgetConfig `yaml:"evictionBudget" json:"evictionBudget" mapstructure:"evictionBudget"`
+	// ApplyRunRetentionDays is how long the audit records of apply runs are kept.
+	ApplyRunRetentionDays int `yaml:"applyRunRetentionDays" json:"applyRunRetentionDays" mapstructure:"applyRunRetentionDays"`
+	// ResizeVerification controls how resizes the kubelet did not apply are handled in the next run.
+	ResizeVerification utils.ResizeVerificationConfig `yaml:"resizeVerification" json:"resizeVerification" mapstructure:"resizeVerification"`
+	// Stabilization smooths the recommendations and holds back small or frequent resizes.
+	Stabilization utils.StabilizationConfig `yaml:"stabilization" json:"stabilization" mapstructure:"stabilization"`
 }
 
 type ApplyRecommendationTaskConfig struct {
@@ -57,25 +71,57 @@ type ApplyRecommendationTaskConfig struct {
 }
 
 type ApplyRecommendationTask struct {
-	config        *ApplyRecommendationTaskConfig
-	kubeClient    *kubernetes.Clientset
-	dynamicClient dynamic.Interface
-	promClient    *prometheus.PrometheusProvider
+	config          *ApplyRecommendationTaskConfig
+	kubeClient      *kubernetes.Clientset
+	dynamicClient   dynamic.Interface
+	promClient      *prometheus.PrometheusProvider
+	storage         *storage.Storage
+	evictionPlanner *utils.EvictionPlanner
+	resizeTracker   *utils.ResizeTracker
+	stabilizer      *utils.RecommendationStabilizer
+	// memoryLimitPolicy is shared with the admission webhook so resized pods get the same memory limits.
+	memoryLimitPolicy utils.MemoryLimitPolicy
 }
 
-func NewApplyRecommendationTask(ctx context.Context, kubeClient *kubernetes.Clientset, dynamicClient dynamic.Interface, promClient *prometheus.PrometheusProvider, config *ApplyRecommendationTaskConfig, taskConfig *config.TaskConfig) *ApplyRecommendationTask {
+func NewApplyRecommendationTask(ctx context.Context, kubeClient *kubernetes.Clientset, dynamicClient dynamic.Interface, promClient *prometheus.PrometheusProvider, storage *storage.Storage, config *ApplyRecommendationTaskConfig, taskConfig *config.TaskConfig) *ApplyRecommendationTask {
 	var applyRecommendationMetadata ApplyRecommendationMetadata
 	if err := taskConfig.ConvertMetadataToStruct(&applyRecommendationMetadata); err != nil {
 		logging.Errorf(ctx, "Error converting metadata to struct: %v", err)
 		return nil
 	}
+	if applyRecommendationMetadata.Strategy == "" {
+		applyRecommendationMetadata.Strategy = applystrategies.DefaultStrategyName
+	}
+	if !applystrategies.IsRegisteredStrategy(applyRecommendationMetadata.Strategy) {
+		logging.Errorf(ctx, "Unknown strategy %s, available strategies: %v", applyRecommendationMetadata.Strategy, applystrategies.ListStrategies())
+		return nil
+	}
+	if applyRecommendationMetadata.ApplyRunRetentionDays <= 0 {
+		applyRecommendationMetadata.ApplyRunRetentionDays = DefaultApplyRunRetentionDays
+	}
+	if applyRecommendationMetadata.ResizeVerification.InfeasiblePolicy == "" {
+		applyRecommendationMetadata.ResizeVerification.InfeasiblePolicy = utils.InfeasibleResizePolicyNone
+	}
+	if err := applyRecommendationMetadata.ResizeVerification.Validate(); err != nil {
+		logging.Errorf(ctx, "Invalid resize verification config: %v", err)
+		return nil
+	}
+	if err := applyRecommendationMetadata.Stabilization.Validate(); err != nil {
+		logging.Errorf(ctx, "Invalid stabilization config: %v", err)
+		return nil
+	}
 	config.Metadata = applyRecommendationMetadata
 
 	return &ApplyRecommendationTask{
-		config:        config,
-		kubeClient:    kubeClient,
-		dynamicClient: dynamicClient,
-		promClient:    promClient,
+		config:            config,
+		kubeClient:        kubeClient,
+		dynamicClient:     dynamicClient,
+		promClient:        promClient,
+		storage:           storage,
+		evictionPlanner:   utils.NewEvictionPlanner(applyRecommendationMetadata.EvictionBudget),
+		resizeTracker:     utils.NewResizeTracker(),
+		stabilizer:        utils.NewRecommendationStabilizer(applyRecommendationMetadata.Stabilization),
+		memoryLimitPolicy: utils.NewMemoryLimitPolicy(config.RecommendationSettings),
 	}
 }
 
@@ -100,6 +146,7 @@ func (a *ApplyRecommendationTask) Run(ctx context.Context) error {
 	ctx = contextutils.WithCluster(ctx, a.config.ClusterID)
 
 	applyChanges := !a.config.Metadata.DryRun
+	startedAt := time.Now()
 
 	if !a.config.IsClusterWriteAuthorized {
 		logging.Infof(ctx, "Cluster %s is not write authorized, skipping ApplyRecommendation task", a.config.ClusterID)
@@ -111,6 +158,13 @@ func (a *ApplyRecommendationTask) Run(ctx context.Context) error {
 		logging.Infof(ctx, "Cluster version is not above 1.33, running in dry run mode")
 	}
 
+	a.startEvictionRun(ctx)
+	a.startStabilizationRun(ctx, startedAt)
+	verificationEntries := make([]types.ApplyRunEntry, 0)
+	if applyChanges {
+		verificationEntries = a.verifyPendingResizes(ctx)
+	}
+
 	nodeRecommendationMap, err := a.GenerateNodeStatsForCluster(ctx)
 	if err != nil {
 		logging.Errorf(ctx, "Error generating node recommendations: %v", err)
@@ -134,11 +188,23 @@ func (a *ApplyRecommendationTask) Run(ctx context.Context) error {
 		overridesMap[override.WorkloadID] = &override
 	}
 
-	_, err = a.ApplyRecommendationsWithStrategy(
+	namespaceOverrides, err := recommenderClient.ListNamespaceOverrides(ctx, a.config.ClusterID)
+	if err != nil {
+		logging.Errorf(ctx, "Error loading namespace overrides from client: %v", err)
+		return fmt.Errorf("failed to list namespace overrides from recommender service: %w", err)
+	}
+
+	strategy, err := a.GetStrategy(ctx)
+	if err != nil {
+		return err
+	}
+
+	recommendationResults, err := a.ApplyRecommendationsWithStrategy(
 		ctx,
 		nodeRecommendationMap,
 		overridesMap,
-		applystrategies.NewAdjustAmongstPodsDistributedStrategy(ctx),
+		namespaceOverrides,
+		strategy,
 		applyChanges,
 		false,
 	)
@@ -146,14 +212,110 @@ func (a *ApplyRecommendationTask) Run(ctx context.Context) error {
 		logging.Errorf(ctx, "Error applying recommendations: %v", err)
 		return err
 	}
+	a.persistStabilizationRun(ctx)
+	a.persistApplyRun(ctx, strategy.GetName(), !applyChanges, startedAt, recommendationResults, verificationEntries)
 
 	return nil
 }
 
+// GetStrategy returns the strategy configured in the task metadata.
+func (a *ApplyRecommendationTask) GetStrategy(ctx context.Context) (utils.OptimizationStrategy, error) {
+	strategy, err := applystrategies.GetStrategy(ctx, a.config.Metadata.Strategy, a.config.RecommendationSettings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get optimization strategy: %w", err)
+	}
+	return strategy, nil
+}
+
+// resolveStrategyName picks the strategy for a pod: workload override, then namespace, then the default.
+func (a *ApplyRecommendationTask) resolveStrategyName(podInfo utils.PodInfo, overridesMap map[string]*types.WorkloadOverrideInfo, namespaceOverrides map[string]*types.Overrides, defaultStrategy string) string {
+	if podInfo.Stats != nil {
+		if overrides, ok := overridesMap[podInfo.Stats.WorkloadIdentifier]; ok && overrides.Strategy != "" {
+			return overrides.Strategy
+		}
+	}
+	if overrides, ok := namespaceOverrides[podInfo.Namespace]; ok && overrides.Strategy != nil && *overrides.Strategy != "" {
+		return *overrides.Strategy
+	}
+	return defaultStrategy
+}
+
+// groupPodsByStrategy splits the optimizable pods of a node by the strategy that should optimize them.
+// Unknown strategy names fall back to the default strategy.
+func (a *ApplyRecommendationTask) groupPodsByStrategy(
+	ctx context.Context,
+	podInfos []utils.PodInfo,
+	overridesMap map[string]*types.WorkloadOverrideInfo,
+	namespaceOverrides map[string]*types.Overrides,
+	defaultStrategy utils.OptimizationStrategy,
+	strategies map[string]utils.OptimizationStrategy,
+) ([]string, map[string][]utils.PodInfo) {
+	order := make([]string, 0)
+	groups := make(map[string][]utils.PodInfo)
+	for _, podInfo := range podInfos {
+		name := a.resolveStrategyName(podInfo, overridesMap, namespaceOverrides, defaultStrategy.GetName())
+		if _, ok := strategies[name]; !ok {
+			strategy, err := applystrategies.GetStrategy(ctx, name, a.config.RecommendationSettings)
+			if err != nil {
+				logging.Errorf(ctx, "Error resolving strategy for pod %s/%s, using %s: %v", podInfo.Namespace, podInfo.Name, defaultStrategy.GetName(), err)
+				name = defaultStrategy.GetName()
+			} else {
+				strategies[name] = strategy
+			}
+		}
+		if _, ok := groups[name]; !ok {
+			order = append(order, name)
+		}
+		groups[name] = append(groups[name], podInfo)
+	}
+	return order, groups
+}
+
+// nodePlan is the per node state collected before any strategy runs.
+type nodePlan struct {
+	nodeInfo        utils.NodeResourceInfo
+	result          *RecommendationResult
+	availableCPU    float64
+	availableMemory float64
+	strategyOrder   []string
+	strategyPods    map[string][]utils.PodInfo
+	// evictionTargetOf holds the cluster scope strategies planning evicted pods onto the node
+	evictionTargetOf map[string]bool
+}
+
+// availableFor returns the resources a strategy can hand out on the node. The current requests of pods
+// owned by other strategies are treated as untouchable.
+func (p *nodePlan) availableFor(strategyName string) (float64, float64) {
+	availableCPU := p.availableCPU
+	availableMemory := p.availableMemory
+	for otherName, otherPods := range p.strategyPods {
+		if otherName == strategyName {
+			continue
+		}
+		for _, podInfo := range otherPods {
+			availableCPU -= podInfo.RequestedCPU
+			availableMemory -= podInfo.RequestedMemory
+		}
+	}
+	return availableCPU, availableMemory
+}
+
+// receivesEvictionsFromOtherThan reports whether a cluster scope strategy other than the given one plans
+// evicted pods onto the node.
+func (p *nodePlan) receivesEvictionsFromOtherThan(strategyName string) bool {
+	for name := range p.evictionTargetOf {
+		if name != strategyName {
+			return true
+		}
+	}
+	return false
+}
+
 func (a *ApplyRecommendationTask) ApplyRecommendationsWithStrategy(
 	ctx context.Context,
 	nodeStatsMap map[string]utils.NodeResourceInfo,
 	overridesMap map[string]*types.WorkloadOverrideInfo,
+	namespaceOverrides map[string]*types.Overrides,
 	strategy utils.OptimizationStrategy,
 	applyChanges bool,
 	generateRecommendationOnly bool,
@@ -163,18 +325,18 @@ func (a *ApplyRecommendationTask) ApplyRecommendationsWithStrategy(
 		logging.Infof(ctx, "DRY RUN MODE: Changes will be calculated but not applied")
 	}
 
+	strategies := map[string]utils.OptimizationStrategy{strategy.GetName(): strategy}
 	recommendationResults := []*RecommendationResult{}
+	nodePlans := make(map[string]*nodePlan, len(nodeStatsMap))
 	for nodeName, nodeInfo := range nodeStatsMap {
-		// if nodeName != "ip-10-99-47-228.ec2.internal" {
-		// 	continue
-		// }
-		logging.Infof(ctx, "Processing node: %s", nodeName)
-
 		recommendationResult := &RecommendationResult{
 			NodeName:                    nodeName,
 			NodeInfo:                    nodeInfo,
 			PodContainerRecommendations: make([]utils.PodContainerRecommendation, 0),
 			NonOptimizablePods:          make([]utils.NonOptimizablePodInfo, 0),
+			PlannedEvictions:            make([]utils.PlannedEviction, 0),
+			DeferredEvictions:           make([]utils.DeferredEviction, 0),
+			ApplyRunEntries:             make([]types.ApplyRunEntry, 0),
 		}
 		recommendationResults = append(recommendationResults, recommendationResult)
 
@@ -192,24 +354,46 @@ func (a *ApplyRecommendationTask) ApplyRecommendationsWithStrategy(
 			availableCPU -= nonOptimizablePod.CurrentCPU
 		}
 
-		result, err := strategy.OptimizeNode(a.kubeClient, overridesMap, utils.NodeOptimizationData{
-			NodeName:          nodeName,
-			AllocatableCPU:    availableCPU,
-			AllocatableMemory: availableMemory,
-			PodInfos:          optimizablePods,
-		})
-		if err != nil {
-			logging.Errorf(ctx, "Error optimizing node %s: %v", nodeName, err)
-			continue
+		strategyOrder, strategyPods := a.groupPodsByStrategy(ctx, optimizablePods, overridesMap, namespaceOverrides, strategy, strategies)
+		nodePlans[nodeName] = &nodePlan{
+			nodeInfo:        nodeInfo,
+			result:          recommendationResult,
+			availableCPU:    availableCPU,
+			availableMemory: availableMemory,
+			strategyOrder:   strategyOrder,
+			strategyPods:    strategyPods,
 		}
+	}
+
+	clusterResults := a.optimizeClusterScopeStrategies(ctx, nodePlans, overridesMap, strategies)
+
+	for _, recommendationResult := range recommendationResults {
+		nodeName := recommendationResult.NodeName
+		// if nodeName != "ip-10-99-47-228.ec2.internal" {
+		// 	continue
+		// }
+		logging.Infof(ctx, "Processing node: %s", nodeName)
+
+		result := a.optimizeNode(ctx, nodeName, nodePlans[nodeName], overridesMap, strategies, clusterResults)
 		recommendationResult.PodContainerRecommendations = result.PodContainerRecommendations
 		recommendationResult.MaxRestCPU = result.MaxRestCPU
 		recommendationResult.MaxRestMemory = result.MaxRestMemory
+	}
+
+	allowedEvictions := make(map[string]bool)
+	if !generateRecommendationOnly {
+		allowedEvictions = a.planEvictions(ctx, recommendationResults, overridesMap)
+		a.holdIncreasesOnStabilizedNodes(ctx, recommendationResults)
+	}
+
+	for _, recommendationResult := range recommendationResults {
+		nodeName := recommendationResult.NodeName
 		if generateRecommendationOnly {
 			logging.Infof(ctx, "Skipping applying recommendations for node %s", nodeName)
 			continue
 		}
 
+		nodeInfo := nodePlans[nodeName].nodeInfo
 		podsOnNode, err := a.getFreshPodsOnNode(ctx, nodeName)
 		if err != nil {
 			logging.Errorf(ctx, "Error getting fresh pods on node %s: %v", nodeName, err)
@@ -218,21 +402,40 @@ func (a *ApplyRecommendationTask) ApplyRecommendationsWithStrategy(
 
 		podsToEvict := make(map[string]bool)
 		appliedRecommendations := make(map[string]utils.PodContainerRecommendation)
+		recommendationResult.recordSkippedPods()
 
-		for _, rec := range result.PodContainerRecommendations {
+		for _, rec := range recommendationResult.PodContainerRecommendations {
 			// TODO: Can we skip getting fresh pods when dry run is enabled?
 			freshPod, found := podsOnNode[utils.GetPodKey(rec.PodInfo.Namespace, rec.PodInfo.Name)]
 			if !found {
 				logging.Errorf(ctx, "Pod %s/%s not found on node %s", rec.PodInfo.Namespace, rec.PodInfo.Name, nodeName)
+				entry := newApplyRunEntry(nodeName, rec.PodInfo, rec.ContainerName, types.ApplyRunResourcePod)
+				entry.Reason = "pod not found on node"
+				recommendationResult.ApplyRunEntries = append(recommendationResult.ApplyRunEntries, entry)
 				continue
 			}
 
 			if rec.Evict {
+				if !allowedEvictions[utils.GetPodKey(rec.PodInfo.Namespace, rec.PodInfo.Name)] {
+					continue
+				}
 				podsToEvict[fmt.Sprintf("%s/%s", rec.PodInfo.Namespace, rec.PodInfo.Name)] = true
-				logging.Infof(ctx, "Evicting pod %s/%s", rec.PodInfo.Namespace, rec.PodInfo.Name)
+				logging.Infof(ctx, "Evicting pod %s/%s%s", rec.PodInfo.Namespace, rec.PodInfo.Name, evictionTargetSuffix(recommendationResult.PlannedEvictions, rec.PodInfo))
+				entry := newApplyRunEntry(nodeName, rec.PodInfo, "", types.ApplyRunResourcePod)
+				entry.Action = types.ApplyRunActionEvicted
+				entry.Reason = "pod does not fit the node after resizing" + evictionTargetSuffix(recommendationResult.PlannedEvictions, rec.PodInfo)
 				if applyChanges {
-					utils.EvictPod(ctx, a.kubeClient, freshPod)
+					evicted, errStr := utils.EvictPod(ctx, a.kubeClient, freshPod)
+					if !evicted {
+						logging.Errorf(ctx, "Error evicting pod %s/%s: %s", rec.PodInfo.Namespace, rec.PodInfo.Name, errStr)
+						entry.Action = types.ApplyRunActionEvictionFailed
+						entry.Reason = errStr
+						recommendationResult.ApplyRunEntries = append(recommendationResult.ApplyRunEntries, entry)
+						continue
+					}
+					a.evictionPlanner.RecordEviction(rec.PodInfo)
 				}
+				recommendationResult.ApplyRunEntries = append(recommendationResult.ApplyRunEntries, entry)
 				continue
 			}
 
@@ -243,20 +446,36 @@ func (a *ApplyRecommendationTask) ApplyRecommendationsWithStrategy(
 				}
 			}
 
-			applied, err := a.applyCPURecommendation(ctx, freshPod, currentContainerResources, rec, applyChanges, nodeInfo.AllocatableCPU)
+			cpuEntry := newApplyRunEntry(nodeName, rec.PodInfo, rec.ContainerName, types.ApplyRunResourceCPU)
+			applied, err := a.applyCPURecommendation(ctx, freshPod, currentContainerResources, rec, applyChanges, nodeInfo.AllocatableCPU, workloadOverridesForPod(rec.PodInfo, overridesMap), &cpuEntry)
 			if err != nil {
 				logging.Errorf(ctx, "Error applying CPU recommendation for pod %s/%s: %v", rec.PodInfo.Namespace, rec.PodInfo.Name, err)
+				cpuEntry.Action = types.ApplyRunActionFailed
+				cpuEntry.Reason = err.Error()
+			}
+			if applyChanges && cpuEntry.Action == types.ApplyRunActionResized {
+				a.trackResize(freshPod, rec.PodInfo, cpuEntry)
 			}
 			if applied {
 				appliedRecommendations[fmt.Sprintf("%s/%s", rec.PodInfo.Namespace, rec.PodInfo.Name)] = rec
 			}
+			recommendationResult.ApplyRunEntries = append(recommendationResult.ApplyRunEntries, cpuEntry)
 
+			memoryEntry := newApplyRunEntry(nodeName, rec.PodInfo, rec.ContainerName, types.ApplyRunResourceMemory)
 			if !a.config.RecommendationSettings.DisableMemoryApplication && !a.config.Metadata.SkipMemory {
-				applied, skipped, err := a.applyMemoryRecommendation(ctx, freshPod, currentContainerResources, rec, applyChanges)
+				applied, skipped, err := a.applyMemoryRecommendation(ctx, freshPod, currentContainerResources, rec, applyChanges, workloadOverridesForPod(rec.PodInfo, overridesMap), &memoryEntry)
 				if skipped {
 					logging.Infof(ctx, "Skipping memory recommendation for pod %s/%s: %v", rec.PodInfo.Namespace, rec.PodInfo.Name, err)
+					if err != nil {
+						memoryEntry.Reason = err.Error()
+					}
 				} else if err != nil {
 					logging.Errorf(ctx, "Error applying memory recommendation for pod %s/%s: %v", rec.PodInfo.Namespace, rec.PodInfo.Name, err)
+					memoryEntry.Action = types.ApplyRunActionFailed
+					memoryEntry.Reason = err.Error()
+				}
+				if applyChanges && memoryEntry.Action == types.ApplyRunActionResized {
+					a.trackResize(freshPod, rec.PodInfo, memoryEntry)
 				}
 
 				if applied {
@@ -264,7 +483,16 @@ func (a *ApplyRecommendationTask) ApplyRecommendationsWithStrategy(
 				}
 			} else {
 				logging.Infof(ctx, "Skipping memory recommendation application for pod since memory recommendationapplication is disabled: %s/%s", rec.PodInfo.Namespace, rec.PodInfo.Name)
+				memoryEntry.Reason = "memory recommendation application is disabled"
 			}
+			recommendationResult.ApplyRunEntries = append(recommendationResult.ApplyRunEntries, memoryEntry)
+		}
+
+		for _, eviction := range recommendationResult.DeferredEvictions {
+			entry := newApplyRunEntry(nodeName, eviction.PodInfo, "", types.ApplyRunResourcePod)
+			entry.Action = types.ApplyRunActionEvictionDeferred
+			entry.Reason = eviction.Reason
+			recommendationResult.ApplyRunEntries = append(recommendationResult.ApplyRunEntries, entry)
 		}
 
 		logging.Infof(ctx, "Successfully applied %d recommendations and evicted %d pods", len(appliedRecommendations), len(podsToEvict))
@@ -283,20 +511,195 @@ func (a *ApplyRecommendationTask) ApplyRecommendationsWithStrategy(
 	return recommendationResults, nil
 }
 
+// optimizeClusterScopeStrategies runs every cluster scope strategy once over all nodes, and records the
+// planned evictions on the source node's result. Nodes the strategy owns no pods on are passed without
+// pods so they can take evicted pods.
+func (a *ApplyRecommendationTask) optimizeClusterScopeStrategies(
+	ctx context.Context,
+	nodePlans map[string]*nodePlan,
+	overridesMap map[string]*types.WorkloadOverrideInfo,
+	strategies map[string]utils.OptimizationStrategy,
+) map[string]utils.ClusterOptimizationResult {
+	clusterResults := make(map[string]utils.ClusterOptimizationResult)
+	for name, strategy := range strategies {
+		clusterStrategy, ok := strategy.(utils.ClusterOptimizationStrategy)
+		if !ok {
+			continue
+		}
+
+		data := utils.ClusterOptimizationData{Nodes: make(map[string]utils.NodeOptimizationData)}
+		ownedNodes := 0
+		for nodeName, plan := range nodePlans {
+			pods, ok := plan.strategyPods[name]
+			if ok {
+				ownedNodes++
+			}
+			availableCPU, availableMemory := plan.availableFor(name)
+			data.Nodes[nodeName] = utils.NodeOptimizationData{
+				NodeName:          nodeName,
+				AllocatableCPU:    availableCPU,
+				AllocatableMemory: availableMemory,
+				PodInfos:          pods,
+			}
+		}
+		if ownedNodes == 0 {
+			continue
+		}
+
+		logging.Infof(ctx, "Optimizing %d nodes using cluster scope strategy %s", ownedNodes, name)
+		result, err := clusterStrategy.OptimizeCluster(a.kubeClient, overridesMap, data)
+		if err != nil {
+			logging.Errorf(ctx, "Error optimizing cluster using strategy %s: %v", name, err)
+			continue
+		}
+		for _, eviction := range result.Evictions {
+			if plan, ok := nodePlans[eviction.SourceNode]; ok {
+				plan.result.PlannedEvictions = append(plan.result.PlannedEvictions, eviction)
+			}
+			if plan, ok := nodePlans[eviction.TargetNode]; ok {
+				if plan.evictionTargetOf == nil {
+					plan.evictionTargetOf = make(map[string]bool)
+				}
+				plan.evictionTargetOf[name] = true
+			}
+		}
+		clusterResults[name] = result
+	}
+	return clusterResults
+}
+
+// optimizeNode merges the results of every strategy owning pods on the node. Node scope strategies
+// run here, cluster scope strategies have already been run by optimizeClusterScopeStrategies. The pods
+// of a strategy are not grown on a node another strategy plans evicted pods onto, that strategy counted
+// them at their current requests.
+func (a *ApplyRecommendationTask) optimizeNode(
+	ctx context.Context,
+	nodeName string,
+	plan *nodePlan,
+	overridesMap map[string]*types.WorkloadOverrideInfo,
+	strategies map[string]utils.OptimizationStrategy,
+	clusterResults map[string]utils.ClusterOptimizationResult,
+) utils.OptimizationResult {
+	result := utils.OptimizationResult{
+		PodContainerRecommendations: make([]utils.PodContainerRecommendation, 0),
+	}
+	for _, name := range plan.strategyOrder {
+		var groupResult utils.OptimizationResult
+		if clusterResult, ok := clusterResults[name]; ok {
+			groupResult = clusterResult.NodeResults[nodeName]
+		} else {
+			availableCPU, availableMemory := plan.availableFor(name)
+			var err error
+			groupResult, err = strategies[name].OptimizeNode(a.kubeClient, overridesMap, utils.NodeOptimizationData{
+				NodeName:          nodeName,
+				AllocatableCPU:    availableCPU,
+				AllocatableMemory: availableMemory,
+				PodInfos:          plan.strategyPods[name],
+			})
+			if err != nil {
+				logging.Errorf(ctx, "Error optimizing node %s using strategy %s: %v", nodeName, name, err)
+				continue
+			}
+		}
+		holdIncreases := plan.receivesEvictionsFromOtherThan(name)
+		for _, rec := range groupResult.PodContainerRecommendations {
+			if holdIncreases && rec.HoldIncreasesReason == "" {
+				rec.HoldIncreasesReason = "evicted pods planned onto the node"
+			}
+			result.PodContainerRecommendations = append(result.PodContainerRecommendations, rec)
+		}
+		result.MaxRestCPU = math.Max(result.MaxRestCPU, groupResult.MaxRestCPU)
+		result.MaxRestMemory = math.Max(result.MaxRestMemory, groupResult.MaxRestMemory)
+	}
+	return result
+}
+
+// planEvictions applies the eviction budgets to the evictions requested on all nodes. It records the
+// deferred evictions on the node results, holds the increases on their nodes and returns the keys of
+// the pods that can be evicted now.
+func (a *ApplyRecommendationTask) planEvictions(
+	ctx context.Context,
+	recommendationResults []*RecommendationResult,
+	overridesMap map[string]*types.WorkloadOverrideInfo,
+) map[string]bool {
+	resultsByNode := make(map[string]*RecommendationResult, len(recommendationResults))
+	candidates := make([]utils.EvictionCandidate, 0)
+	for _, recommendationResult := range recommendationResults {
+		resultsByNode[recommendationResult.NodeName] = recommendationResult
+		for _, rec := range recommendationResult.PodContainerRecommendations {
+			if !rec.Evict {
+				continue
+			}
+			candidates = append(candidates, utils.EvictionCandidate{
+				PodInfo:         rec.PodInfo,
+				NodeName:        recommendationResult.NodeName,
+				EvictionRanking: evictionRankingForPod(rec.PodInfo, overridesMap),
+			})
+		}
+	}
+
+	allowed, deferred := a.evictionPlanner.Plan(candidates)
+	for _, eviction := range deferred {
+		logging.Infof(ctx, "Deferring eviction of pod %s/%s on node %s: %s", eviction.PodInfo.Namespace, eviction.PodInfo.Name, eviction.NodeName, eviction.Reason)
+		if recommendationResult, ok := resultsByNode[eviction.NodeName]; ok {
+			recommendationResult.DeferredEvictions = append(recommendationResult.DeferredEvictions, eviction)
+		}
+	}
+	// The pods left on a node were sized expecting the deferred pods to leave it, growing them now
+	// could overcommit the node
+	for _, recommendationResult := range recommendationResults {
+		if len(recommendationResult.DeferredEvictions) == 0 {
+			continue
+		}
+		for i := range recommendationResult.PodContainerRecommendations {
+			recommendationResult.PodContainerRecommendations[i].HoldIncreasesReason = "eviction deferred on the node"
+		}
+	}
+	metrics.ClusterDeferredEvictionCount.WithLabelValues(a.config.ClusterID).Set(float64(len(deferred)))
+
+	allowedEvictions := make(map[string]bool, len(allowed))
+	for _, candidate := range allowed {
+		allowedEvictions[utils.GetPodKey(candidate.PodInfo.Namespace, candidate.PodInfo.Name)] = true
+	}
+	return allowedEvictions
+}
+
+func evictionRankingForPod(podInfo utils.PodInfo, overridesMap map[string]*types.WorkloadOverrideInfo) types.EvictionRanking {
+	evictionRanking := types.EvictionRankingHigh
+	if podInfo.Stats == nil {
+		return evictionRanking
+	}
+	if podInfo.Stats.EvictionRanking != 0 {
+		evictionRanking = podInfo.Stats.EvictionRanking
+	}
+	if overrides, ok := overridesMap[podInfo.Stats.WorkloadIdentifier]; ok && overrides.EvictionRanking != 0 {
+		evictionRanking = overrides.EvictionRanking
+	}
+	return evictionRanking
+}
+
+func evictionTargetSuffix(plannedEvictions []utils.PlannedEviction, podInfo utils.PodInfo) string {
+	for _, eviction := range plannedEvictions {
+		if eviction.PodInfo.Namespace == podInfo.Namespace && eviction.PodInfo.Name == podInfo.Name && eviction.TargetNode != "" {
+			return fmt.Sprintf(" (expected to land on node %s)", eviction.TargetNode)
+		}
+	}
+	return ""
+}
+
 func (a *ApplyRecommendationTask) applyMemoryRecommendation(
 	ctx context.Context,
 	pod *corev1.Pod,
 	currentContainerResources corev1.ResourceRequirements,
 	rec utils.PodContainerRecommendation,
 	applyChanges bool,
+	overrides *types.WorkloadOverrideInfo,
+	entry *types.ApplyRunEntry,
 ) (bool, bool, error) {
 	containerStat, err := rec.PodInfo.Stats.GetContainerStats(rec.ContainerName)
 	if err != nil {
 		return false, true, fmt.Errorf("error getting container stats for pod %s/%s: %w", rec.PodInfo.Namespace, rec.PodInfo.Name, err)
 	}
-	recommendedMemoryRequest := utils.EnforceMinimumMemory(rec.Memory)
-	recommendedMemoryLimit := utils.EnforceMinimumMemory(max(containerStat.Memory7Day.Max, containerStat.MemoryStats.OOMMemory) * 2)
-
 	containerResource, err := rec.PodInfo.GetContainerResource(rec.ContainerName)
 	if err != nil {
 		return false, true, fmt.Errorf("error getting container resource for pod %s/%s: %w", rec.PodInfo.Namespace, rec.PodInfo.Name, err)
@@ -308,30 +711,38 @@ func (a *ApplyRecommendationTask) applyMemoryRecommendation(
 	}
 	currentMemoryRequest := float64(currentMemoryRequestQuantity.Value()) / utils.BytesToMBDivisor
 	if math.Abs(currentMemoryRequest-containerResource.MemoryRequest) > utils.MinimumMemoryRecommendation {
-		logging.Infof(ctx, "pod %s/%s memory has changed too much from %.1f MB to %.1f MB, skipping applying memory recommendation", rec.PodInfo.Namespace, rec.PodInfo.Name, currentMemoryRequest, recommendedMemoryRequest)
+		logging.Infof(ctx, "pod %s/%s memory has changed too much from %.1f MB to %.1f MB, skipping applying memory recommendation", rec.PodInfo.Namespace, rec.PodInfo.Name, containerResource.MemoryRequest, currentMemoryRequest)
+		entry.Reason = fmt.Sprintf("memory has changed too much from %.1f MB to %.1f MB", containerResource.MemoryRequest, currentMemoryRequest)
 		return false, true, nil
 	}
 
+	bounds := overrides.BoundsForContainer(rec.ContainerName)
+	key := stabilizationKey(rec.PodInfo, rec.ContainerName, types.ApplyRunResourceMemory)
+	recommendedMemoryRequest := bounds.ClampMemory(utils.EnforceMinimumMemory(a.stabilizer.Smooth(key, rec.Memory)))
+
 	currentMemoryLimitQuantity := currentContainerResources.Limits[corev1.ResourceMemory]
 	currentMemoryLimit := float64(currentMemoryLimitQuantity.Value()) / utils.BytesToMBDivisor
 
+	var recommendedMemoryLimit float64
 	if currentMemoryRequest == currentMemoryLimit {
-		// We are setting both limit and request to 2 * max memory usage to be safe. This is to avoid any issues with OOM kills.
-		recommendedMemoryRequest = utils.EnforceMinimumMemory(2 * max(containerStat.Memory7Day.Max, containerStat.MemoryStats.OOMMemory))
+		// The request follows the limit set by the memory limit policy so the two stay equal, sizing the
+		// request to the limit avoids OOM kills of a container that can't use more than its request.
+		recommendedMemoryRequest = bounds.ClampMemory(a.memoryLimitPolicy.InPlaceLimit(recommendedMemoryRequest, currentMemoryLimit, containerStat))
+		recommendedMemoryRequest, entry.Reason = a.stabilizer.Stabilize(key, currentMemoryRequest, recommendedMemoryRequest)
+		recommendedMemoryRequest, entry.Reason = holdIncrease(rec, currentMemoryRequest, recommendedMemoryRequest, entry.Reason)
 		recommendedMemoryLimit = recommendedMemoryRequest
 		logging.Infof(ctx, "equal memory limit and request pod %s/%s memory limit updated: %v -> %v", rec.PodInfo.Namespace, rec.PodInfo.Name, currentMemoryLimit, recommendedMemoryLimit)
 		if recommendedMemoryLimit < currentMemoryLimit {
 			// TODO: will be possible from 1.34
 			return false, true, fmt.Errorf("cannot decrease memory limit from %.1f MB to %.1f MB", currentMemoryLimit, recommendedMemoryLimit)
 		}
+	} else {
+		recommendedMemoryRequest, entry.Reason = a.stabilizer.Stabilize(key, currentMemoryRequest, recommendedMemoryRequest)
+		recommendedMemoryRequest, entry.Reason = holdIncrease(rec, currentMemoryRequest, recommendedMemoryRequest, entry.Reason)
+		recommendedMemoryLimit = a.memoryLimitPolicy.InPlaceLimit(recommendedMemoryRequest, currentMemoryLimit, containerStat)
 	}
-	if recommendedMemoryLimit < currentMemoryLimit {
-		recommendedMemoryLimit = math.Ceil(currentMemoryLimit)
-	}
-	if currentMemoryLimit == 0 {
-		// cannot set memory limit when it is unset
-		recommendedMemoryLimit = 0
-	}
+	entry.OldRequest, entry.NewRequest = currentMemoryRequest, recommendedMemoryRequest
+	entry.OldLimit, entry.NewLimit = currentMemoryLimit, recommendedMemoryLimit
 
 	if math.Abs(recommendedMemoryRequest-currentMemoryRequest) > 0 {
 		if applyChanges {
@@ -350,12 +761,18 @@ func (a *ApplyRecommendationTask) applyMemoryRecommendation(
 				return false, false, fmt.Errorf("update call returned false for pod %s/%s", rec.PodInfo.Namespace, rec.PodInfo.Name)
 			}
 			logging.Infof(ctx, "pod %v/%v memory request updated: %v -> %v", rec.PodInfo.Namespace, rec.PodInfo.Name, currentMemoryRequest, recommendedMemoryRequest)
+			entry.Action = types.ApplyRunActionResized
+			a.stabilizer.RecordResize(key)
 			return true, false, nil
 		} else {
 			logging.Infof(ctx, "[dry run] pod %v/%v memory request updated: %v -> %v", rec.PodInfo.Namespace, rec.PodInfo.Name, currentMemoryRequest, recommendedMemoryRequest)
+			entry.Action = types.ApplyRunActionResized
 			return true, false, nil
 		}
 	} else {
+		if entry.Reason == "" {
+			entry.Reason = "memory request is unchanged"
+		}
 		return false, false, nil
 	}
 }
@@ -367,10 +784,13 @@ func (a *ApplyRecommendationTask) applyCPURecommendation(
 	rec utils.PodContainerRecommendation,
 	applyChanges bool,
 	allocatableCPU float64,
+	overrides *types.WorkloadOverrideInfo,
+	entry *types.ApplyRunEntry,
 ) (bool, error) {
 	currentCPURequestQuantity, exists := currentContainerResources.Requests[corev1.ResourceCPU]
 	if !exists {
 		logging.Infof(ctx, "container %s in pod %s has no CPU request, wont be able to change it", rec.ContainerName, rec.PodInfo.Name)
+		entry.Reason = "container has no cpu request"
 		return false, nil
 	}
 	currentCPURequest := float64(currentCPURequestQuantity.MilliValue()) / 1000.0
@@ -381,16 +801,21 @@ func (a *ApplyRecommendationTask) applyCPURecommendation(
 	}
 	if math.Abs(currentCPURequest-containerResource.CPURequest) > utils.MinimumCPURecommendation {
 		logging.Infof(ctx, "pod %s/%s cpu has changed too much from %.1f to %.1f, skipping applying cpu recommendation", rec.PodInfo.Namespace, rec.PodInfo.Name, currentCPURequest, containerResource.CPURequest)
+		entry.Reason = fmt.Sprintf("cpu has changed too much from %.3f to %.3f", containerResource.CPURequest, currentCPURequest)
 		return false, nil
 	}
 
 	currentCPULimitQuantity := currentContainerResources.Limits[corev1.ResourceCPU]
 	currentCPULimit := float64(currentCPULimitQuantity.MilliValue()) / 1000.0
 
-	recommendedCPURequest := utils.EnforceMinimumCPU(rec.CPU)
+	key := stabilizationKey(rec.PodInfo, rec.ContainerName, types.ApplyRunResourceCPU)
+	recommendedCPURequest := utils.EnforceMinimumCPU(a.stabilizer.Smooth(key, rec.CPU))
 	if recommendedCPURequest > CPUClampValue {
 		recommendedCPURequest = CPUClampValue
 	}
+	recommendedCPURequest = overrides.BoundsForContainer(rec.ContainerName).ClampCPU(recommendedCPURequest)
+	recommendedCPURequest, entry.Reason = a.stabilizer.Stabilize(key, currentCPURequest, recommendedCPURequest)
+	recommendedCPURequest, entry.Reason = holdIncrease(rec, currentCPURequest, recommendedCPURequest, entry.Reason)
 
 	// since i cannot remove cpu limit from a burstable pod, i will set the limit to the allocatable cpu
 	recommendedCPULimit := allocatableCPU
@@ -398,9 +823,17 @@ func (a *ApplyRecommendationTask) applyCPURecommendation(
 		recommendedCPULimit = 0.0
 	}
 	if rec.PodInfo.IsGuaranteedPod() {
-		logging.Infof(ctx, "pod %s/%s is a guaranteed pod, skipping any kind of cpu changes", rec.PodInfo.Namespace, rec.PodInfo.Name)
-		return true, nil
+		if overrides == nil || !overrides.ResizeGuaranteed {
+			logging.Infof(ctx, "pod %s/%s is a guaranteed pod, skipping any kind of cpu changes", rec.PodInfo.Namespace, rec.PodInfo.Name)
+			entry.Reason = "guaranteed pod"
+			return true, nil
+		}
+		// keep the limit equal to the request so the pod stays in the Guaranteed QoS class
+		recommendedCPURequest = guaranteedCPURecommendation(recommendedCPURequest, currentCPURequest)
+		recommendedCPULimit = recommendedCPURequest
 	}
+	entry.OldRequest, entry.NewRequest = currentCPURequest, recommendedCPURequest
+	entry.OldLimit, entry.NewLimit = currentCPULimit, recommendedCPULimit
 
 	if math.Abs(recommendedCPURequest-currentCPURequest) >= 0.001 || math.Abs(recommendedCPULimit-currentCPULimit) >= 0.001 {
 		if applyChanges {
@@ -419,16 +852,89 @@ func (a *ApplyRecommendationTask) applyCPURecommendation(
 				return false, fmt.Errorf("update call returned false for pod %s/%s", rec.PodInfo.Namespace, rec.PodInfo.Name)
 			}
 			logging.Infof(ctx, "pod %v/%v cpu request updated: %v -> %v", rec.PodInfo.Namespace, rec.PodInfo.Name, currentCPURequest, recommendedCPURequest)
+			entry.Action = types.ApplyRunActionResized
+			if math.Abs(recommendedCPURequest-currentCPURequest) >= 0.001 {
+				a.stabilizer.RecordResize(key)
+			}
 			return true, nil
 		} else {
 			logging.Infof(ctx, "[dry run] pod %v/%v cpu request updated: %v -> %v", rec.PodInfo.Namespace, rec.PodInfo.Name, currentCPURequest, recommendedCPURequest)
+			entry.Action = types.ApplyRunActionResized
 			return true, nil
 		}
 	} else {
+		if entry.Reason == "" {
+			entry.Reason = "cpu request is unchanged"
+		}
 		return false, nil
 	}
 }
 
+// holdIncreasesOnStabilizedNodes holds the increases on the nodes where the stabilizer keeps a container
+// above its recommended decrease, the strategy fitted the other pods of the node expecting it to land.
+func (a *ApplyRecommendationTask) holdIncreasesOnStabilizedNodes(ctx context.Context, recommendationResults []*RecommendationResult) {
+	checkMemory := !a.config.RecommendationSettings.DisableMemoryApplication && !a.config.Metadata.SkipMemory
+	for _, recommendationResult := range recommendationResults {
+		heldBack := false
+		for _, rec := range recommendationResult.PodContainerRecommendations {
+			if rec.Evict {
+				continue
+			}
+			containerResource, err := rec.PodInfo.GetContainerResource(rec.ContainerName)
+			if err != nil {
+				continue
+			}
+			cpuKey := stabilizationKey(rec.PodInfo, rec.ContainerName, types.ApplyRunResourceCPU)
+			memoryKey := stabilizationKey(rec.PodInfo, rec.ContainerName, types.ApplyRunResourceMemory)
+			if a.stabilizer.HoldsBackDecrease(cpuKey, containerResource.CPURequest, rec.CPU) ||
+				(checkMemory && a.stabilizer.HoldsBackDecrease(memoryKey, containerResource.MemoryRequest, rec.Memory)) {
+				heldBack = true
+				break
+			}
+		}
+		if !heldBack {
+			continue
+		}
+		logging.Infof(ctx, "Holding increases on node %s, a decrease on it is held back", recommendationResult.NodeName)
+		for i := range recommendationResult.PodContainerRecommendations {
+			if recommendationResult.PodContainerRecommendations[i].HoldIncreasesReason == "" {
+				recommendationResult.PodContainerRecommendations[i].HoldIncreasesReason = "decrease held back on the node"
+			}
+		}
+	}
+}
+
+// holdIncrease keeps the request at its current value when the recommendation would grow it on a node
+// whose planned changes do not all land in this run.
+func holdIncrease(rec utils.PodContainerRecommendation, current, recommended float64, reason string) (float64, string) {
+	if rec.HoldIncreasesReason != "" && recommended > current {
+		return current, rec.HoldIncreasesReason
+	}
+	return recommended, reason
+}
+
+// canResizeGuaranteedPod reports whether the workload of a Guaranteed QoS pod opted into resizes.
+func canResizeGuaranteedPod(podInfo utils.PodInfo, overridesMap map[string]*types.WorkloadOverrideInfo) bool {
+	overrides := workloadOverridesForPod(podInfo, overridesMap)
+	return overrides != nil && overrides.ResizeGuaranteed
+}
+
+func workloadOverridesForPod(podInfo utils.PodInfo, overridesMap map[string]*types.WorkloadOverrideInfo) *types.WorkloadOverrideInfo {
+	if podInfo.Stats == nil {
+		return nil
+	}
+	return overridesMap[podInfo.Stats.WorkloadIdentifier]
+}
+
+// guaranteedCPURecommendation rounds the recommendation up to whole cores when the pod currently
+// requests whole cores, so pods relying on exclusive cpus from the static cpu manager keep them.
+func guaranteedCPURecommendation(recommendedCPU, currentCPU float64) float64 {
+	if currentCPU >= 1 && currentCPU == math.Trunc(currentCPU) {
+		return math.Max(1, math.Ceil(recommendedCPU))
+	}
+	return recommendedCPU
+}
+
 func (a *ApplyRecommendationTask) getFreshPodsOnNode(ctx context.Context, nodeName string) (map[string]*corev1.Pod, error) {
 	pods, err := a.kubeClient.CoreV1().Pods("").List(ctx, metav1.ListOptions{
 		FieldSelector: fmt.Sprintf("spec.nodeName=%s", nodeName),
@@ -456,6 +962,7 @@ func (a *ApplyRecommendationTask) segregateOptimizableNonOptimizablePods(ctx con
 				PodNamespace:  podInfo.Namespace,
 				CurrentCPU:    podInfo.RequestedCPU,
 				CurrentMemory: podInfo.RequestedMemory,
+				Reason:        "namespace is blacklisted",
 			})
 			continue
 		}
@@ -467,11 +974,12 @@ func (a *ApplyRecommendationTask) segregateOptimizableNonOptimizablePods(ctx con
 				PodNamespace:  podInfo.Namespace,
 				CurrentCPU:    podInfo.RequestedCPU,
 				CurrentMemory: podInfo.RequestedMemory,
+				Reason:        "no stats found for workload",
 			})
 			continue
 		}
 
-		if podInfo.IsGuaranteedPod() {
+		if podInfo.IsGuaranteedPod() && !canResizeGuaranteedPod(podInfo, overridesMap) {
 			logging.Infof(ctx, "Pod %s/%s is a guaranteed pod, skipping", podInfo.Namespace, podInfo.Name)
 			nonOptimizablePods = append(nonOptimizablePods, utils.NonOptimizablePodInfo{
 				PodInfo:       podInfo,
@@ -479,6 +987,7 @@ func (a *ApplyRecommendationTask) segregateOptimizableNonOptimizablePods(ctx con
 				PodNamespace:  podInfo.Namespace,
 				CurrentCPU:    podInfo.RequestedCPU,
 				CurrentMemory: podInfo.RequestedMemory,
+				Reason:        "guaranteed pod",
 			})
 			continue
 		}
@@ -491,6 +1000,7 @@ func (a *ApplyRecommendationTask) segregateOptimizableNonOptimizablePods(ctx con
 				PodNamespace:  podInfo.Namespace,
 				CurrentCPU:    podInfo.RequestedCPU,
 				CurrentMemory: podInfo.RequestedMemory,
+				Reason:        "disabled via workload overrides",
 			})
 			continue
 		}
@@ -503,6 +1013,20 @@ func (a *ApplyRecommendationTask) segregateOptimizableNonOptimizablePods(ctx con
 				PodNamespace:  podInfo.Namespace,
 				CurrentCPU:    podInfo.RequestedCPU,
 				CurrentMemory: podInfo.RequestedMemory,
+				Reason:        "new workload",
+			})
+			continue
+		}
+
+		if podInfo.IsInStartupWindow(time.Now()) {
+			logging.Infof(ctx, "Pod %s/%s is in its startup window, skipping", podInfo.Namespace, podInfo.Name)
+			nonOptimizablePods = append(nonOptimizablePods, utils.NonOptimizablePodInfo{
+				PodInfo:       podInfo,
+				PodName:       podInfo.Name,
+				PodNamespace:  podInfo.Namespace,
+				CurrentCPU:    podInfo.RequestedCPU,
+				CurrentMemory: podInfo.RequestedMemory,
+				Reason:        "in startup window",
 			})
 			continue
 		}
@@ -515,6 +1039,7 @@ func (a *ApplyRecommendationTask) segregateOptimizableNonOptimizablePods(ctx con
 				PodNamespace:  podInfo.Namespace,
 				CurrentCPU:    podInfo.RequestedCPU,
 				CurrentMemory: podInfo.RequestedMemory,
+				Reason:        "horizontally autoscaled on cpu",
 			})
 			continue
 		}
@@ -531,6 +1056,10 @@ func (a *ApplyRecommendationTask) GenerateNodeStatsForCluster(ctx context.Contex
 
 	targetNamespace := ""
 
+	if err := utils.RefreshWorkloadOwners(ctx, a.kubeClient, a.dynamicClient, targetNamespace); err != nil {
+		logging.Errorf(ctx, "Error refreshing workload owners, using the previous ones: %v", err)
+	}
+
 	podToWorkloadMap, allPods, err := utils.BuildPodToWorkloadMapping(ctx, a.kubeClient, targetNamespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build pod-to-workload mapping: %w", err)
@@ -5,12 +5,12 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/prometheus/common/model"
-	"github.com/truefoundry/cruisekube/pkg/adapters/metricsProvider/prometheus"
+	metricsprovider "github.com/truefoundry/cruisekube/pkg/adapters/metricsProvider"
 	"github.com/truefoundry/cruisekube/pkg/config"
 	"github.com/truefoundry/cruisekube/pkg/contextutils"
 	"github.com/truefoundry/cruisekube/pkg/logging"
 	"github.com/truefoundry/cruisekube/pkg/repository/storage"
+	"github.com/truefoundry/cruisekube/pkg/task/forecasters"
 	"github.com/truefoundry/cruisekube/pkg/task/utils"
 	"github.com/truefoundry/cruisekube/pkg/types"
 	corev1 "k8s.io/api/core/v1"
@@ -21,6 +21,16 @@ import (
 
 type CreateStatsMetadata struct {
 	SkipMemory bool `yaml:"skipMemory" json:"skipMemory" mapstructure:"skipMemory"`
+	// Forecaster selects the forecaster behind the simple predictions used as pmax.
+	Forecaster forecasters.ForecasterConfig `yaml:"forecaster" json:"forecaster" mapstructure:"forecaster"`
+	// Calendar sets the timezone of the prediction buckets and the periods left out of the usage history.
+	Calendar utils.CalendarConfig `yaml:"calendar" json:"calendar" mapstructure:"calendar"`
+	// StatsHistory sets the retention and downsampling of the stats kept for every run.
+	StatsHistory StatsHistoryConfig `yaml:"statsHistory" json:"statsHistory" mapstructure:"statsHistory"`
+	// StartupWindowMinutes records the cpu usage in the first minutes after a container starts as a
+	// startup profile, apart from the steady usage the cpu stats and predictions are built from. 0
+	// disables it.
+	StartupWindowMinutes int `yaml:"startupWindowMinutes" json:"startupWindowMinutes" mapstructure:"startupWindowMinutes"`
 }
 
 type CreateStatsTaskConfig struct {
@@ -37,27 +47,43 @@ type CreateStatsTaskConfig struct {
 }
 
 type CreateStatsTask struct {
-	kubeClient    *kubernetes.Clientset
-	dynamicClient dynamic.Interface
-	promClient    *prometheus.PrometheusProvider
-	storage       *storage.Storage
-	config        *CreateStatsTaskConfig
+	kubeClient      *kubernetes.Clientset
+	dynamicClient   dynamic.Interface
+	metricsProvider metricsprovider.MetricsProvider
+	storage         *storage.Storage
+	config          *CreateStatsTaskConfig
+	forecaster      utils.Forecaster
+	calendars       *utils.PredictionCalendars
 }
 
-func NewCreateStatsTask(ctx context.Context, kubeClient *kubernetes.Clientset, dynamicClient dynamic.Interface, promClient *prometheus.PrometheusProvider, storage *storage.Storage, config *CreateStatsTaskConfig, taskConfig *config.TaskConfig) *CreateStatsTask {
+func NewCreateStatsTask(ctx context.Context, kubeClient *kubernetes.Clientset, dynamicClient dynamic.Interface, metricsProvider metricsprovider.MetricsProvider, storage *storage.Storage, config *CreateStatsTaskConfig, taskConfig *config.TaskConfig) *CreateStatsTask {
 	var createStatsMetadata CreateStatsMetadata
 	if err := taskConfig.ConvertMetadataToStruct(&createStatsMetadata); err != nil {
 		logging.Errorf(ctx, "Error converting metadata to struct: %v", err)
 		return nil
 	}
+	forecaster, err := forecasters.GetForecaster(createStatsMetadata.Forecaster)
+	if err != nil {
+		logging.Errorf(ctx, "Error creating forecaster: %v", err)
+		return nil
+	}
+	calendars, err := utils.NewPredictionCalendars(createStatsMetadata.Calendar, config.ClusterID)
+	if err != nil {
+		logging.Errorf(ctx, "Error creating prediction calendars: %v", err)
+		return nil
+	}
+
+	createStatsMetadata.StatsHistory.setDefaults()
 
 	config.Metadata = createStatsMetadata
 	return &CreateStatsTask{
-		kubeClient:    kubeClient,
-		dynamicClient: dynamicClient,
-		promClient:    promClient,
-		storage:       storage,
-		config:        config,
+		kubeClient:      kubeClient,
+		dynamicClient:   dynamicClient,
+		metricsProvider: metricsProvider,
+		storage:         storage,
+		config:          config,
+		forecaster:      forecaster,
+		calendars:       calendars,
 	}
 }
 
@@ -86,13 +112,17 @@ func (c *CreateStatsTask) Run(ctx context.Context) error {
 	startTime := time.Now().UTC()
 	logging.Infof(ctx, "Running task: CreateStats")
 
-	workloadList, err := utils.ListAllWorkloads(ctx, c.kubeClient, targetNamespace)
+	if err := utils.RefreshWorkloadOwners(ctx, c.kubeClient, c.dynamicClient, targetNamespace); err != nil {
+		logging.Errorf(ctx, "Error refreshing workload owners, using the previous ones: %v", err)
+	}
+
+	workloadList, err := utils.ListAllWorkloads(ctx, c.kubeClient, c.dynamicClient, targetNamespace)
 	if err != nil {
 		logging.Errorf(ctx, "Error getting workload list: %v", err)
 		return fmt.Errorf("failed to list workloads: %w", err)
 	}
 
-	isPSIEnabled := c.isPSIEnabled(ctx)
+	isPSIEnabled := c.metricsProvider.IsPSIEnabled(ctx, c.config.ClusterID)
 	logging.Infof(ctx, "PSI is enabled: %v", isPSIEnabled)
 
 	uniqueWorkloads := make(map[string]utils.WorkloadInfo)
@@ -118,20 +148,20 @@ func (c *CreateStatsTask) Run(ctx context.Context) error {
 	namespaces := utils.ExtractUniqueNamespaces(uniqueWorkloads)
 	logging.Infof(ctx, "Found %d unique namespaces to process: %v", len(namespaces), namespaces)
 
-	namespaceQueryResults, namespaceVsWorkloadMetrics, err := c.promClient.FetchStatsForNamespaces(ctx, c.config.ClusterID, namespaces, isPSIEnabled)
+	namespaceQueryResults, namespaceVsWorkloadMetrics, err := c.metricsProvider.FetchStatsForNamespaces(ctx, c.config.ClusterID, namespaces, isPSIEnabled, time.Duration(c.config.Metadata.StartupWindowMinutes)*time.Minute)
 	if err != nil {
 		logging.Errorf(ctx, "Error executing batch queries: %v", err)
 		return fmt.Errorf("failed to fetch stats for namespaces: %w", err)
 	}
 
-	namespaceVsSimpleCPUPredictions, err := utils.PredictSimpleStatsFromTimeSeriesModel(ctx, namespaces, c.promClient.GetClient(), "cpu", isPSIEnabled)
+	namespaceVsSimpleCPUPredictions, err := utils.PredictSimpleStatsFromTimeSeriesModel(ctx, namespaces, c.usageSeriesFetcher("cpu", isPSIEnabled), "cpu", c.forecaster, c.config.Metadata.Forecaster.GetHorizonSteps(), c.calendars)
 	if err != nil {
 		logging.Errorf(ctx, "Error predicting simple CPU stats from time series model: %v", err)
 		return fmt.Errorf("failed to predict simple CPU stats: %w", err)
 	}
 	var namespaceVsSimpleMemoryPredictions map[string]map[string]utils.SimplePrediction
 	if !c.config.Metadata.SkipMemory {
-		namespaceVsSimpleMemoryPredictions, err = utils.PredictSimpleStatsFromTimeSeriesModel(ctx, namespaces, c.promClient.GetClient(), "memory", isPSIEnabled)
+		namespaceVsSimpleMemoryPredictions, err = utils.PredictSimpleStatsFromTimeSeriesModel(ctx, namespaces, c.usageSeriesFetcher("memory", isPSIEnabled), "memory", c.forecaster, c.config.Metadata.Forecaster.GetHorizonSteps(), c.calendars)
 		if err != nil {
 			logging.Errorf(ctx, "Error predicting simple memory stats from time series model: %v", err)
 			return fmt.Errorf("failed to predict simple memory stats: %w", err)
@@ -189,22 +219,18 @@ func (c *CreateStatsTask) Run(ctx context.Context) error {
 			return err
 		}
 	}
+	c.compactStatsHistory(ctx)
 
 	logging.Infof(ctx, "Task completed in %v", time.Since(startTime))
 	return nil
 }
 
-func (c *CreateStatsTask) isPSIEnabled(ctx context.Context) bool {
-	query := "max(max_over_time(container_pressure_cpu_waiting_seconds_total[1m]))"
-
-	result, _, err := c.promClient.ExecuteQueryWithRetry(ctx, c.config.ClusterID, query, "PSI_CHECK")
-	if err != nil {
-		logging.Errorf(ctx, "[isPSIEnabled] Error executing PSI query: %v", err)
-		return false
+// usageSeriesFetcher reads the usage series of a resource from the metrics provider of the task.
+func (c *CreateStatsTask) usageSeriesFetcher(resourceType string, isPSIEnabled bool) utils.UsageSeriesFetcher {
+	return func(ctx context.Context, namespace string, start, end time.Time) ([]utils.SimpleTimeSeriesData, error) {
+		startupWindow := time.Duration(c.config.Metadata.StartupWindowMinutes) * time.Minute
+		return c.metricsProvider.FetchUsageSeries(ctx, c.config.ClusterID, namespace, resourceType, isPSIEnabled, startupWindow, start, end)
 	}
-
-	vector := result.(model.Vector)
-	return len(vector) != 0
 }
 
 func (c *CreateStatsTask) writeStatsToFile(ctx context.Context, clusterID string, newStats []*utils.WorkloadStat, generatedAt time.Time) error {
@@ -217,7 +243,7 @@ func (c *CreateStatsTask) writeStatsToFileForCluster(ctx context.Context, cluste
 		allStats.Stats = append(allStats.Stats, *stat)
 	}
 
-	if err := c.storage.WriteClusterStats(clusterID, allStats, generatedAt); err != nil {
+	if err := c.storage.WriteClusterStats(ctx, clusterID, allStats, generatedAt); err != nil {
 		return fmt.Errorf("error writing stats for cluster %s: %w", clusterID, err)
 	}
 
@@ -251,7 +277,7 @@ func (c *CreateStatsTask) prepareStatsFromMetrics(
 		return nil
 	}
 
-	workloadObj, err := utils.GetWorkloadObject(ctx, kubeClient, workloadInfo.Kind, workloadInfo.Namespace, workloadInfo.Name)
+	workloadObj, err := utils.GetWorkloadObject(ctx, kubeClient, dynamicClient, workloadInfo.Kind, workloadInfo.Namespace, workloadInfo.Name)
 	if err != nil {
 		logging.Errorf(ctx, "Error getting workload %s: %v", workloadKey, err)
 		return nil
@@ -262,21 +288,31 @@ func (c *CreateStatsTask) prepareStatsFromMetrics(
 
 	containerResources := c.getAllContainerResourcesFromContainerSpecs(ctx, containerSpecs, initContainerSpecs)
 
-	workloadStat := utils.BuildContainerStatFromCache(ctx, workloadInfo, workloadKeyVsContainerMetrics, containerResources)
+	workloadStat := BuildWorkloadStatFromMetrics(
+		ctx,
+		workloadInfo,
+		containerResources,
+		workloadObj.GetCreationTime(),
+		time.Now(),
+		nsVsContainerMetrics,
+		nsVsWorkloadMetrics,
+		containerKeyVsCPUPrediction,
+		containerKeyVsCPUPredictionPSIAdjusted,
+		containerKeyVsMemoryPrediction,
+		workloadContainerKeyVsSimpleCPUPrediction,
+		workloadContainerKeyVsSimpleMemoryPrediction,
+		workloadHPAMap,
+	)
 	if workloadStat == nil {
-		logging.Errorf(ctx, "Could not build container stat for %s", workloadKey)
 		return nil
 	}
 
-	workloadStat.CreationTime = workloadObj.GetCreationTime()
-	workloadStat.UpdatedAt = time.Now()
-
-	// Check if this workload is horizontally autoscaled on CPU
-	if workloadHPAMap != nil {
-		if isAutoscaled, exists := workloadHPAMap[workloadKey]; exists && isAutoscaled {
-			workloadStat.IsHorizontallyAutoscaledOnCPU = true
+	for _, containerStat := range workloadStat.ContainerStats {
+		if containerStat.Startup != nil {
+			workloadStat.StartupWindowMinutes = c.config.Metadata.StartupWindowMinutes
 		}
 	}
+	workloadStat.OwnedWorkloads = utils.OwnedWorkloadIDs(workloadInfo)
 
 	// Detect workload constraints
 	if dynamicClient != nil {
@@ -288,6 +324,43 @@ func (c *CreateStatsTask) prepareStatsFromMetrics(
 		}
 	}
 
+	return workloadStat
+}
+
+// BuildWorkloadStatFromMetrics builds the stat of a workload from its container resources, metrics and
+// predictions. It does not talk to the cluster, so stats can also be built from recorded metrics.
+func BuildWorkloadStatFromMetrics(
+	ctx context.Context,
+	workloadInfo utils.WorkloadInfo,
+	containerResources []utils.OriginalContainerResources,
+	creationTime time.Time,
+	updatedAt time.Time,
+	nsVsContainerMetrics utils.NamespaceVsContainerMetrics,
+	nsVsWorkloadMetrics utils.NamespaceVsWorkloadMetrics,
+	containerKeyVsCPUPrediction map[string]utils.WorkloadPrediction,
+	containerKeyVsCPUPredictionPSIAdjusted map[string]utils.WorkloadPrediction,
+	containerKeyVsMemoryPrediction map[string]utils.WorkloadPrediction,
+	workloadContainerKeyVsSimpleCPUPrediction map[string]utils.SimplePrediction,
+	workloadContainerKeyVsSimpleMemoryPrediction map[string]utils.SimplePrediction,
+	workloadHPAMap map[string]bool,
+) *utils.WorkloadStat {
+	workloadKey := utils.GetWorkloadKey(workloadInfo.Kind, workloadInfo.Namespace, workloadInfo.Name)
+	workloadStat := utils.BuildContainerStatFromCache(ctx, workloadInfo, nsVsContainerMetrics[workloadInfo.Namespace], containerResources)
+	if workloadStat == nil {
+		logging.Errorf(ctx, "Could not build container stat for %s", workloadKey)
+		return nil
+	}
+
+	workloadStat.CreationTime = creationTime
+	workloadStat.UpdatedAt = updatedAt
+
+	// Check if this workload is horizontally autoscaled on CPU
+	if workloadHPAMap != nil {
+		if isAutoscaled, exists := workloadHPAMap[workloadKey]; exists && isAutoscaled {
+			workloadStat.IsHorizontallyAutoscaledOnCPU = true
+		}
+	}
+
 	for i := range workloadStat.ContainerStats {
 		containerStat := &workloadStat.ContainerStats[i]
 
@@ -409,4 +482,11 @@ func (c *CreateStatsTask) setResourceRequestAndLimit(container *corev1.Container
 	if memLimit := container.Resources.Limits[corev1.ResourceMemory]; !memLimit.IsZero() {
 		containerRes.MemoryLimit = float64(memLimit.Value()) / utils.BytesPerMB
 	}
+
+	if storageRequest := container.Resources.Requests[corev1.ResourceEphemeralStorage]; !storageRequest.IsZero() {
+		containerRes.EphemeralStorageRequest = float64(storageRequest.Value()) / utils.BytesPerMB
+	}
+	if storageLimit := container.Resources.Limits[corev1.ResourceEphemeralStorage]; !storageLimit.IsZero() {
+		containerRes.EphemeralStorageLimit = float64(storageLimit.Value()) / utils.BytesPerMB
+	}
 }
@@ -373,7 +373,12 @@ func (f *FetchMetricsTask) fetchWorkloadStatsAge(ctx context.Context) {
 	ages := make([]float64, 0, len(stats))
 
 	for _, stat := range stats {
-		age := now.Sub(stat.UpdatedAt).Minutes()
+		// an unchanged stat keeps its updated_at, the age is since it was last generated
+		generatedAt := stat.GeneratedAt
+		if generatedAt.IsZero() {
+			generatedAt = stat.UpdatedAt
+		}
+		age := now.Sub(generatedAt).Minutes()
 		ages = append(ages, age)
 	}
 
@@ -134,7 +134,7 @@ func (m *ModifyEqualCPUResourcesTask) getAllPodsAcrossNamespaces(ctx context.Con
 func (m *ModifyEqualCPUResourcesTask) processWorkload(ctx context.Context, workloadInfo utils.WorkloadLabelSelectorList) int {
 	workloadKey := utils.GetWorkloadKey(workloadInfo.Kind, workloadInfo.Namespace, workloadInfo.Name)
 
-	workloadObj, err := utils.GetWorkloadObject(ctx, m.kubeClient, workloadInfo.Kind, workloadInfo.Namespace, workloadInfo.Name)
+	workloadObj, err := utils.GetWorkloadObject(ctx, m.kubeClient, m.dynamicClient, workloadInfo.Kind, workloadInfo.Namespace, workloadInfo.Name)
 	if err != nil {
 		logging.Errorf(ctx, "Error getting workload %s: %v", workloadKey, err)
 		return 0
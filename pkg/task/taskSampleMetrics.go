@@ -0,0 +1,57 @@
+package task
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/truefoundry/cruisekube/pkg/adapters/metricsProvider/kubelet"
+	"github.com/truefoundry/cruisekube/pkg/contextutils"
+)
+
+type SampleMetricsTaskConfig struct {
+	Name            string
+	Enabled         bool
+	Schedule        string
+	ClusterID       string
+	TargetNamespace string
+}
+
+// SampleMetricsTask feeds the usage histograms of the kubelet metrics provider. Its schedule is the
+// sampling interval.
+type SampleMetricsTask struct {
+	config   *SampleMetricsTaskConfig
+	provider *kubelet.KubeletProvider
+}
+
+func NewSampleMetricsTask(_ context.Context, provider *kubelet.KubeletProvider, config *SampleMetricsTaskConfig) *SampleMetricsTask {
+	return &SampleMetricsTask{
+		config:   config,
+		provider: provider,
+	}
+}
+
+func (t *SampleMetricsTask) GetCoreTask() any {
+	return t
+}
+
+func (t *SampleMetricsTask) GetName() string {
+	return t.config.Name
+}
+
+func (t *SampleMetricsTask) GetSchedule() string {
+	return t.config.Schedule
+}
+
+func (t *SampleMetricsTask) IsEnabled() bool {
+	return t.config.Enabled
+}
+
+func (t *SampleMetricsTask) Run(ctx context.Context) error {
+	ctx = contextutils.WithTask(ctx, t.config.Name)
+	ctx = contextutils.WithCluster(ctx, t.config.ClusterID)
+
+	if err := t.provider.Sample(ctx, t.config.ClusterID, t.config.TargetNamespace); err != nil {
+		return fmt.Errorf("failed to sample metrics: %w", err)
+	}
+	return nil
+}
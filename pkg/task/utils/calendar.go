@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	// the controller image has no timezone database
+	_ "time/tzdata"
+)
+
+// CalendarConfig sets the timezone of the weekly and hourly prediction buckets, per cluster and per
+// namespace, and the periods left out of the usage history, like load tests or holidays.
+type CalendarConfig struct {
+	// Timezone is the IANA name of the default timezone, UTC when empty.
+	Timezone string `yaml:"timezone" json:"timezone" mapstructure:"timezone"`
+	// ClusterTimezones maps a cluster id to its timezone.
+	ClusterTimezones map[string]string `yaml:"clusterTimezones" json:"clusterTimezones" mapstructure:"clusterTimezones"`
+	// NamespaceTimezones maps a namespace to its timezone, it wins over the cluster timezone.
+	NamespaceTimezones map[string]string   `yaml:"namespaceTimezones" json:"namespaceTimezones" mapstructure:"namespaceTimezones"`
+	Exclusions         []CalendarExclusion `yaml:"exclusions" json:"exclusions" mapstructure:"exclusions"`
+}
+
+// CalendarExclusion leaves a whole day, or the period from Start to End, out of the usage history.
+// Dates and times without an offset are read in the timezone of the namespace.
+type CalendarExclusion struct {
+	Name  string `yaml:"name" json:"name" mapstructure:"name"`
+	Date  string `yaml:"date" json:"date" mapstructure:"date"`
+	Start string `yaml:"start" json:"start" mapstructure:"start"`
+	End   string `yaml:"end" json:"end" mapstructure:"end"`
+	// Namespaces limits the exclusion to some namespaces, it applies to all when empty.
+	Namespaces []string `yaml:"namespaces" json:"namespaces" mapstructure:"namespaces"`
+}
+
+var calendarTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"}
+
+func (e CalendarExclusion) timeRange(location *time.Location) (TimeRange, error) {
+	if e.Date != "" {
+		if e.Start != "" || e.End != "" {
+			return TimeRange{}, fmt.Errorf("exclusion %q must have either a date or a start and an end", e.Name)
+		}
+		day, err := time.ParseInLocation("2006-01-02", e.Date, location)
+		if err != nil {
+			return TimeRange{}, fmt.Errorf("invalid date of exclusion %q: %w", e.Name, err)
+		}
+		return TimeRange{Start: day, End: day.AddDate(0, 0, 1)}, nil
+	}
+
+	start, err := parseCalendarTime(e.Start, location)
+	if err != nil {
+		return TimeRange{}, fmt.Errorf("invalid start of exclusion %q: %w", e.Name, err)
+	}
+	end, err := parseCalendarTime(e.End, location)
+	if err != nil {
+		return TimeRange{}, fmt.Errorf("invalid end of exclusion %q: %w", e.Name, err)
+	}
+	if !end.After(start) {
+		return TimeRange{}, fmt.Errorf("exclusion %q must end after it starts", e.Name)
+	}
+	return TimeRange{Start: start, End: end}, nil
+}
+
+func (e CalendarExclusion) appliesTo(namespace string) bool {
+	return len(e.Namespaces) == 0 || slices.Contains(e.Namespaces, namespace)
+}
+
+func parseCalendarTime(value string, location *time.Location) (time.Time, error) {
+	for _, layout := range calendarTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is neither RFC 3339 nor a date", value)
+}
+
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// PredictionCalendar is the timezone of the prediction buckets of a namespace and the periods left out
+// of its usage history. The zero value is UTC without exclusions.
+type PredictionCalendar struct {
+	Location   *time.Location
+	Exclusions []TimeRange
+}
+
+func (c PredictionCalendar) GetLocation() *time.Location {
+	if c.Location == nil {
+		return time.UTC
+	}
+	return c.Location
+}
+
+func (c PredictionCalendar) IsExcluded(t time.Time) bool {
+	for _, exclusion := range c.Exclusions {
+		if !t.Before(exclusion.Start) && t.Before(exclusion.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// PredictionCalendars resolves the calendar of every namespace of a cluster. A nil value gives every
+// namespace the zero calendar.
+type PredictionCalendars struct {
+	config          CalendarConfig
+	clusterLocation *time.Location
+	locations       map[string]*time.Location
+}
+
+// NewPredictionCalendars loads the timezones of the config and checks its exclusions.
+func NewPredictionCalendars(config CalendarConfig, clusterID string) (*PredictionCalendars, error) {
+	calendars := &PredictionCalendars{
+		config:    config,
+		locations: make(map[string]*time.Location),
+	}
+
+	clusterTimezone := config.Timezone
+	for id, timezone := range config.ClusterTimezones {
+		// viper lowercases map keys, so cluster ids are matched without case
+		if strings.EqualFold(id, clusterID) {
+			clusterTimezone = timezone
+		}
+	}
+	location, err := time.LoadLocation(clusterTimezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load timezone %q: %w", clusterTimezone, err)
+	}
+	calendars.clusterLocation = location
+
+	for namespace, timezone := range config.NamespaceTimezones {
+		location, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load timezone %q of namespace %s: %w", timezone, namespace, err)
+		}
+		calendars.locations[namespace] = location
+	}
+
+	for _, exclusion := range config.Exclusions {
+		if _, err := exclusion.timeRange(calendars.clusterLocation); err != nil {
+			return nil, err
+		}
+	}
+	return calendars, nil
+}
+
+func (c *PredictionCalendars) ForNamespace(namespace string) PredictionCalendar {
+	if c == nil {
+		return PredictionCalendar{}
+	}
+	location, ok := c.locations[namespace]
+	if !ok {
+		location = c.clusterLocation
+	}
+
+	calendar := PredictionCalendar{Location: location}
+	for _, exclusion := range c.config.Exclusions {
+		if !exclusion.appliesTo(namespace) {
+			continue
+		}
+		// the exclusions were checked when the calendars were created
+		if timeRange, err := exclusion.timeRange(location); err == nil {
+			calendar.Exclusions = append(calendar.Exclusions, timeRange)
+		}
+	}
+	return calendar
+}
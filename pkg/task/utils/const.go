@@ -13,12 +13,32 @@ const (
 	TrueValue = "true"
 )
 
+// Annotations the webhook records on the pods it admits, to explain how their resources were decided.
+const (
+	// OriginalResourcesAnnotation holds the resources of the adjusted containers before admission, as json
+	// by container name.
+	OriginalResourcesAnnotation = "cruisekube.truefoundry.com/original-resources"
+	// RecommendedResourcesAnnotation holds the resources the webhook set, as json by container name.
+	RecommendedResourcesAnnotation = "cruisekube.truefoundry.com/recommended-resources"
+	// StatsGeneratedAtAnnotation is when the stats the recommendation comes from were generated.
+	StatsGeneratedAtAnnotation = "cruisekube.truefoundry.com/stats-generated-at"
+	// AdmissionDecisionAnnotation is the decision path taken for each container, or why the pod was skipped.
+	AdmissionDecisionAnnotation = "cruisekube.truefoundry.com/admission-decision"
+)
+
 const (
 	DeploymentKind  = "Deployment"
 	StatefulSetKind = "StatefulSet"
 	DaemonSetKind   = "DaemonSet"
 	ReplicaSetKind  = "ReplicaSet"
 	RolloutKind     = "Rollout"
+	JobKind         = "Job"
+	CronJobKind     = "CronJob"
+)
+
+const (
+	// RolloutPodTemplateHashLabel is set by Argo Rollouts on its pods in place of pod-template-hash.
+	RolloutPodTemplateHashLabel = "rollouts-pod-template-hash"
 )
 
 const (
@@ -33,6 +53,9 @@ const (
 	ResolutionMinutes   = 1
 	CPUDecimalScale     = 1000.0
 
+	// ForecastLookbackWindow covers the two weekly seasons the forecasters are initialised with.
+	ForecastLookbackWindow = 14 * 24 * time.Hour
+
 	BytesPerMB                 = 1_000_000
 	MemoryDecimalPlaces        = 1
 	RecentStatsLookbackMinutes = 10
@@ -0,0 +1,203 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/config"
+	"github.com/truefoundry/cruisekube/pkg/logging"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CustomWorkloadKind is a custom resource owning pods, read through the dynamic client.
+type CustomWorkloadKind struct {
+	GVR             schema.GroupVersionResource
+	Kind            string
+	PodTemplatePath []string
+	SelectorPath    []string
+}
+
+// customWorkloadKinds holds the configured custom workloads by kind, it is set once at startup.
+var customWorkloadKinds = map[string]CustomWorkloadKind{}
+
+// RegisterCustomWorkloads makes the configured custom workloads known to the workload discovery, the stats
+// and the webhook.
+func RegisterCustomWorkloads(workloads []config.CustomWorkloadConfig) error {
+	kinds := make(map[string]CustomWorkloadKind, len(workloads))
+	for _, workload := range workloads {
+		switch workload.Kind {
+		case DeploymentKind, StatefulSetKind, DaemonSetKind, ReplicaSetKind, RolloutKind, JobKind, CronJobKind:
+			return fmt.Errorf("custom workload kind %s is a built-in kind", workload.Kind)
+		}
+		kind := CustomWorkloadKind{
+			GVR: schema.GroupVersionResource{
+				Group:    workload.Group,
+				Version:  workload.Version,
+				Resource: workload.Resource,
+			},
+			Kind:            workload.Kind,
+			PodTemplatePath: strings.Split(workload.PodTemplatePath, "."),
+		}
+		if workload.SelectorPath != "" {
+			kind.SelectorPath = strings.Split(workload.SelectorPath, ".")
+		}
+		kinds[workload.Kind] = kind
+	}
+	customWorkloadKinds = kinds
+	return nil
+}
+
+// customWorkloadKindForOwner returns the custom workload of an owner reference of a pod.
+func customWorkloadKindForOwner(ownerRef metav1.OwnerReference) (CustomWorkloadKind, bool) {
+	kind, ok := customWorkloadKinds[ownerRef.Kind]
+	if !ok {
+		return CustomWorkloadKind{}, false
+	}
+	groupVersion, err := schema.ParseGroupVersion(ownerRef.APIVersion)
+	if err != nil || groupVersion.Group != kind.GVR.Group {
+		return CustomWorkloadKind{}, false
+	}
+	return kind, true
+}
+
+// isOwnedByCustomWorkload reports whether an object is owned by a custom workload.
+func isOwnedByCustomWorkload(ownerReferences []metav1.OwnerReference) bool {
+	return slices.ContainsFunc(ownerReferences, func(ownerRef metav1.OwnerReference) bool {
+		_, ok := customWorkloadKindForOwner(ownerRef)
+		return ok
+	})
+}
+
+// CustomWorkloadWrapper wraps a custom resource to implement WorkloadObject
+type CustomWorkloadWrapper struct {
+	*unstructured.Unstructured
+	WorkloadKind CustomWorkloadKind
+}
+
+func (w CustomWorkloadWrapper) GetContainerSpecs(ctx context.Context, kubeClient *kubernetes.Clientset) []corev1.Container {
+	podSpec := w.podSpecFromPods(ctx, kubeClient)
+	if podSpec == nil {
+		return nil
+	}
+	return podSpec.Containers
+}
+
+func (w CustomWorkloadWrapper) GetInitContainerSpecs(ctx context.Context, kubeClient *kubernetes.Clientset) []corev1.Container {
+	podSpec := w.podSpecFromPods(ctx, kubeClient)
+	if podSpec == nil {
+		return nil
+	}
+	return podSpec.InitContainers
+}
+
+// podSpecFromPods returns the spec of a running pod of the workload, or the one of its template.
+func (w CustomWorkloadWrapper) podSpecFromPods(ctx context.Context, kubeClient *kubernetes.Clientset) *corev1.PodSpec {
+	podTemplate, err := w.PodTemplate()
+	if err != nil {
+		logging.Errorf(ctx, "Error getting pod template for %s %s/%s: %v", w.WorkloadKind.Kind, w.GetNamespace(), w.GetName(), err)
+		return nil
+	}
+
+	selector, err := w.GetSelector()
+	if err != nil {
+		logging.Errorf(ctx, "Error getting selector for %s %s/%s: %v", w.WorkloadKind.Kind, w.GetNamespace(), w.GetName(), err)
+		return &podTemplate.Spec
+	}
+
+	// getting fresh pods as dynamically injected containers are not tracked in workload spec
+	pods, err := GetPods(ctx, kubeClient, w.GetNamespace(), selector)
+	if err != nil || len(pods.Items) == 0 {
+		logging.Warnf(ctx, "Could not get pods for %s %s/%s, falling back to template: %v", w.WorkloadKind.Kind, w.GetNamespace(), w.GetName(), err)
+		return &podTemplate.Spec
+	}
+
+	return &pods.Items[0].Spec
+}
+
+// PodTemplate reads the pod template at the configured path of the object.
+func (w CustomWorkloadWrapper) PodTemplate() (*corev1.PodTemplateSpec, error) {
+	templateObject, found, err := unstructured.NestedMap(w.Object, w.WorkloadKind.PodTemplatePath...)
+	if err != nil || !found {
+		return nil, fmt.Errorf("no pod template at %s: %w", strings.Join(w.WorkloadKind.PodTemplatePath, "."), err)
+	}
+
+	var podTemplate corev1.PodTemplateSpec
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(templateObject, &podTemplate); err != nil {
+		return nil, fmt.Errorf("error converting pod template: %w", err)
+	}
+	return &podTemplate, nil
+}
+
+func (w CustomWorkloadWrapper) GetSelector() (labels.Selector, error) {
+	if len(w.WorkloadKind.SelectorPath) == 0 {
+		podTemplate, err := w.PodTemplate()
+		if err != nil {
+			return nil, err
+		}
+		if len(podTemplate.Labels) == 0 {
+			return nil, fmt.Errorf("pod template of %s %s/%s has no labels", w.WorkloadKind.Kind, w.GetNamespace(), w.GetName())
+		}
+		return labels.SelectorFromSet(podTemplate.Labels), nil
+	}
+
+	selectorObject, found, err := unstructured.NestedMap(w.Object, w.WorkloadKind.SelectorPath...)
+	if err != nil || !found {
+		return nil, fmt.Errorf("no selector at %s: %w", strings.Join(w.WorkloadKind.SelectorPath, "."), err)
+	}
+
+	var labelSelector metav1.LabelSelector
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(selectorObject, &labelSelector); err != nil {
+		return nil, fmt.Errorf("error converting selector: %w", err)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&labelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("LabelSelectorAsSelector failed for selector %v: %w", labelSelector, err)
+	}
+	return selector, nil
+}
+
+func (w CustomWorkloadWrapper) GetCreationTime() time.Time {
+	return w.GetCreationTimestamp().Time
+}
+
+func getCustomWorkloadObject(ctx context.Context, dynamicClient dynamic.Interface, kind CustomWorkloadKind, namespace, name string) (WorkloadObject, error) {
+	if dynamicClient == nil {
+		return nil, fmt.Errorf("error getting %s %s/%s: no dynamic client", kind.Kind, namespace, name)
+	}
+	object, err := dynamicClient.Resource(kind.GVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting %s %s/%s: %w", kind.Kind, namespace, name, err)
+	}
+	return CustomWorkloadWrapper{Unstructured: object, WorkloadKind: kind}, nil
+}
+
+// listCustomWorkloads lists the objects of every custom workload, a kind whose CRD is missing is skipped.
+func listCustomWorkloads(ctx context.Context, dynamicClient dynamic.Interface, targetNamespace string) []WorkloadInfo {
+	var workloads []WorkloadInfo
+	for _, kind := range customWorkloadKinds {
+		objects, err := dynamicClient.Resource(kind.GVR).Namespace(targetNamespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			logging.Errorf(ctx, "Could not list %s: %v", kind.GVR.Resource, err)
+			continue
+		}
+		for _, object := range objects.Items {
+			workloads = append(workloads, WorkloadInfo{
+				Kind:      kind.Kind,
+				Namespace: object.GetNamespace(),
+				Name:      object.GetName(),
+			})
+		}
+	}
+	return workloads
+}
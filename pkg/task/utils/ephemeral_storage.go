@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"math"
+
+	"github.com/truefoundry/cruisekube/pkg/config"
+)
+
+const (
+	defaultEphemeralStorageSafetyMargin = 0.25
+	defaultEphemeralStorageLimitRatio   = 2.0
+	defaultMinEphemeralStorage          = 100.0
+)
+
+// EphemeralStoragePolicy recommends the ephemeral-storage request and limit of a container from its 7 day
+// usage. Going over the limit gets the pod evicted, so the request covers the max usage. Values are in MB.
+type EphemeralStoragePolicy struct {
+	Enabled      bool
+	SafetyMargin float64
+	LimitRatio   float64
+	MinRequest   float64
+}
+
+func NewEphemeralStoragePolicy(settings config.RecommendationSettings) EphemeralStoragePolicy {
+	policy := EphemeralStoragePolicy{
+		Enabled:      settings.ApplyEphemeralStorage,
+		SafetyMargin: settings.EphemeralStorageSafetyMargin,
+		LimitRatio:   settings.EphemeralStorageLimitRatio,
+		MinRequest:   settings.MinEphemeralStorageMB,
+	}
+	if policy.SafetyMargin < 0 {
+		policy.SafetyMargin = defaultEphemeralStorageSafetyMargin
+	}
+	if policy.LimitRatio < 1 {
+		policy.LimitRatio = defaultEphemeralStorageLimitRatio
+	}
+	if policy.MinRequest <= 0 {
+		policy.MinRequest = defaultMinEphemeralStorage
+	}
+	return policy
+}
+
+// Recommend returns the request and the limit for a container, or false when it has no ephemeral storage
+// stats. The limit is never below the current limit, a current limit of 0 meaning the container has none.
+func (p EphemeralStoragePolicy) Recommend(containerStat *ContainerStats, currentLimit float64) (float64, float64, bool) {
+	if containerStat == nil || containerStat.EphemeralStorage == nil {
+		return 0, 0, false
+	}
+	request := math.Max(math.Ceil(containerStat.EphemeralStorage.Max*(1+p.SafetyMargin)), p.MinRequest)
+	limit := math.Max(p.LimitRatio*request, currentLimit)
+	return request, limit, true
+}
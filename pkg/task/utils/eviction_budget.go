@@ -0,0 +1,251 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/types"
+)
+
+// EvictionBudgetLimits caps evictions within the budget window. Zero values mean no limit.
+type EvictionBudgetLimits struct {
+	MaxEvictions int `yaml:"maxEvictions" json:"maxEvictions" mapstructure:"maxEvictions"`
+	// MaxWorkloadReplicaPercent caps the evicted share of a workload's replicas. At least one pod of
+	// a workload can always be evicted.
+	MaxWorkloadReplicaPercent float64 `yaml:"maxWorkloadReplicaPercent" json:"maxWorkloadReplicaPercent" mapstructure:"maxWorkloadReplicaPercent"`
+}
+
+type EvictionBudgetConfig struct {
+	// WindowMinutes is the sliding window the budgets apply to. Zero applies the budgets per run.
+	WindowMinutes int                             `yaml:"windowMinutes" json:"windowMinutes" mapstructure:"windowMinutes"`
+	Cluster       EvictionBudgetLimits            `yaml:"cluster" json:"cluster" mapstructure:"cluster"`
+	Namespaces    map[string]EvictionBudgetLimits `yaml:"namespaces" json:"namespaces" mapstructure:"namespaces"`
+}
+
+type EvictionCandidate struct {
+	PodInfo         PodInfo
+	NodeName        string
+	EvictionRanking types.EvictionRanking
+}
+
+type DeferredEviction struct {
+	PodInfo  PodInfo `json:"pod_info"`
+	NodeName string  `json:"node_name"`
+	Reason   string  `json:"reason"`
+}
+
+type evictionRecord struct {
+	at        time.Time
+	namespace string
+	workload  string
+}
+
+// EvictionPlanner decides which of the evictions requested by the strategies can happen in this run.
+// Evictions over budget are deferred and get priority in the next run if they are still requested.
+type EvictionPlanner struct {
+	mu        sync.Mutex
+	config    EvictionBudgetConfig
+	history   []evictionRecord
+	carryOver map[string]int
+	now       func() time.Time
+}
+
+func NewEvictionPlanner(config EvictionBudgetConfig) *EvictionPlanner {
+	return &EvictionPlanner{
+		config:    config,
+		carryOver: make(map[string]int),
+		now:       time.Now,
+	}
+}
+
+// Plan orders the candidates by priority and splits them into evictions allowed by the budgets and
+// deferred evictions. Deferred evictions carried over from the previous run come first, the rest are
+// ordered by eviction ranking, keeping the order of the strategies within a ranking.
+func (p *EvictionPlanner) Plan(candidates []EvictionCandidate) ([]EvictionCandidate, []DeferredEviction) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pruneHistory()
+
+	ordered := make([]EvictionCandidate, len(candidates))
+	copy(ordered, candidates)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		carriedI, okI := p.carryOver[candidateKey(ordered[i])]
+		carriedJ, okJ := p.carryOver[candidateKey(ordered[j])]
+		if okI != okJ {
+			return okI
+		}
+		if okI && carriedI != carriedJ {
+			return carriedI < carriedJ
+		}
+		return ordered[i].EvictionRanking > ordered[j].EvictionRanking
+	})
+
+	clusterCount, namespaceCounts, workloadCounts := p.countHistory()
+
+	allowed := make([]EvictionCandidate, 0, len(ordered))
+	deferred := make([]DeferredEviction, 0)
+	for _, candidate := range ordered {
+		namespace := candidate.PodInfo.Namespace
+		workload, replicas := workloadOf(candidate.PodInfo)
+		if reason := p.exceededBudget(namespace, workload, replicas, clusterCount, namespaceCounts[namespace], workloadCounts[workload]); reason != "" {
+			deferred = append(deferred, DeferredEviction{
+				PodInfo:  candidate.PodInfo,
+				NodeName: candidate.NodeName,
+				Reason:   reason,
+			})
+			continue
+		}
+		allowed = append(allowed, candidate)
+		clusterCount++
+		namespaceCounts[namespace]++
+		if workload != "" {
+			workloadCounts[workload]++
+		}
+	}
+
+	p.carryOver = make(map[string]int, len(deferred))
+	for i, eviction := range deferred {
+		p.carryOver[GetPodKey(eviction.PodInfo.Namespace, eviction.PodInfo.Name)] = i
+	}
+	return allowed, deferred
+}
+
+// ExceededBudget returns why evicting the pod now would exceed the budgets, or an empty string when
+// it fits. Unlike Plan it leaves the deferred evictions carried over untouched.
+func (p *EvictionPlanner) ExceededBudget(podInfo PodInfo) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pruneHistory()
+	clusterCount, namespaceCounts, workloadCounts := p.countHistory()
+	workload, replicas := workloadOf(podInfo)
+	return p.exceededBudget(podInfo.Namespace, workload, replicas, clusterCount, namespaceCounts[podInfo.Namespace], workloadCounts[workload])
+}
+
+// StartRun marks the start of an apply run. Budgets applied per run start over here. The evictions and
+// deferred evictions of the persisted apply runs, most recent first, replace the ones in memory so the
+// budgets survive restarts. Nil runs keep the ones in memory.
+func (p *EvictionPlanner) StartRun(runs []types.ApplyRun) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if runs != nil {
+		p.restore(runs)
+	}
+	if p.config.WindowMinutes <= 0 {
+		p.history = p.history[:0]
+	}
+}
+
+// HistoryWindowStart is the start of the window whose apply runs StartRun needs, zero when only the
+// last run is needed.
+func (p *EvictionPlanner) HistoryWindowStart() time.Time {
+	if p.config.WindowMinutes <= 0 {
+		return time.Time{}
+	}
+	return p.now().Add(-time.Duration(p.config.WindowMinutes) * time.Minute)
+}
+
+func (p *EvictionPlanner) restore(runs []types.ApplyRun) {
+	p.history = p.history[:0]
+	p.carryOver = make(map[string]int)
+	for i, run := range runs {
+		for _, entry := range run.Entries {
+			switch {
+			case entry.Action == types.ApplyRunActionEvicted && !run.DryRun:
+				p.history = append(p.history, evictionRecord{
+					at:        run.StartedAt,
+					namespace: entry.Namespace,
+					workload:  entry.WorkloadID,
+				})
+			case entry.Action == types.ApplyRunActionEvictionDeferred && i == 0:
+				p.carryOver[GetPodKey(entry.Namespace, entry.PodName)] = len(p.carryOver)
+			}
+		}
+	}
+}
+
+// RecordEviction counts a performed eviction against the budgets of the current window.
+func (p *EvictionPlanner) RecordEviction(podInfo PodInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	workload, _ := workloadOf(podInfo)
+	p.history = append(p.history, evictionRecord{
+		at:        p.now(),
+		namespace: podInfo.Namespace,
+		workload:  workload,
+	})
+}
+
+func (p *EvictionPlanner) exceededBudget(namespace, workload string, replicas int32, clusterCount, namespaceCount, workloadCount int) string {
+	window := p.windowDescription()
+	if limit := p.config.Cluster.MaxEvictions; limit > 0 && clusterCount >= limit {
+		return fmt.Sprintf("cluster eviction budget of %d %s exhausted", limit, window)
+	}
+
+	namespaceLimits, hasNamespaceLimits := p.config.Namespaces[namespace]
+	if limit := namespaceLimits.MaxEvictions; hasNamespaceLimits && limit > 0 && namespaceCount >= limit {
+		return fmt.Sprintf("namespace %s eviction budget of %d %s exhausted", namespace, limit, window)
+	}
+
+	replicaPercent := p.config.Cluster.MaxWorkloadReplicaPercent
+	if hasNamespaceLimits && namespaceLimits.MaxWorkloadReplicaPercent > 0 {
+		replicaPercent = namespaceLimits.MaxWorkloadReplicaPercent
+	}
+	if workload != "" && replicaPercent > 0 && replicas > 0 {
+		limit := max(1, int(math.Floor(float64(replicas)*replicaPercent/100)))
+		if workloadCount >= limit {
+			return fmt.Sprintf("workload %s eviction budget of %.0f%% of %d replicas (%d pods) %s exhausted", workload, replicaPercent, replicas, limit, window)
+		}
+	}
+	return ""
+}
+
+func (p *EvictionPlanner) windowDescription() string {
+	if p.config.WindowMinutes <= 0 {
+		return "per run"
+	}
+	return fmt.Sprintf("per %d minutes", p.config.WindowMinutes)
+}
+
+func (p *EvictionPlanner) countHistory() (int, map[string]int, map[string]int) {
+	namespaceCounts := make(map[string]int)
+	workloadCounts := make(map[string]int)
+	for _, record := range p.history {
+		namespaceCounts[record.namespace]++
+		if record.workload != "" {
+			workloadCounts[record.workload]++
+		}
+	}
+	return len(p.history), namespaceCounts, workloadCounts
+}
+
+func (p *EvictionPlanner) pruneHistory() {
+	if p.config.WindowMinutes <= 0 {
+		return
+	}
+	cutoff := p.now().Add(-time.Duration(p.config.WindowMinutes) * time.Minute)
+	kept := p.history[:0]
+	for _, record := range p.history {
+		if record.at.After(cutoff) {
+			kept = append(kept, record)
+		}
+	}
+	p.history = kept
+}
+
+func candidateKey(candidate EvictionCandidate) string {
+	return GetPodKey(candidate.PodInfo.Namespace, candidate.PodInfo.Name)
+}
+
+func workloadOf(podInfo PodInfo) (string, int32) {
+	if podInfo.Stats == nil {
+		return "", 0
+	}
+	return podInfo.Stats.WorkloadIdentifier, podInfo.Stats.Replicas
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"math"
+
+	"github.com/truefoundry/cruisekube/pkg/config"
+	"github.com/truefoundry/cruisekube/pkg/types"
+)
+
+const (
+	defaultMemoryLimitRatio      = 2.0
+	defaultMemoryLimitMultiplier = 2.0
+	defaultMinMemoryLimit        = 512.0
+)
+
+// MemoryLimitPolicy decides the memory limit of a container from its recommended request. It is shared by
+// the admission webhook and the apply task so pods get the same limits either way. Values are in MB.
+type MemoryLimitPolicy struct {
+	Mode       types.MemoryLimitMode
+	Ratio      float64
+	Multiplier float64
+	MinLimit   float64
+}
+
+func NewMemoryLimitPolicy(settings config.RecommendationSettings) MemoryLimitPolicy {
+	policy := MemoryLimitPolicy{
+		Mode:       types.MemoryLimitMode(settings.MemoryLimitMode),
+		Ratio:      settings.MemoryLimitRatio,
+		Multiplier: settings.MemoryLimitMultiplier,
+		MinLimit:   settings.MinMemoryLimitMB,
+	}
+	switch policy.Mode {
+	case types.MemoryLimitModeKeep, types.MemoryLimitModeRatioOfRequest, types.MemoryLimitMode7DayMaxMultiple, types.MemoryLimitModeRemove:
+	default:
+		policy.Mode = types.MemoryLimitMode7DayMaxMultiple
+	}
+	if policy.Ratio < 1 {
+		policy.Ratio = defaultMemoryLimitRatio
+	}
+	if policy.Multiplier < 1 {
+		policy.Multiplier = defaultMemoryLimitMultiplier
+	}
+	if policy.MinLimit <= 0 {
+		policy.MinLimit = defaultMinMemoryLimit
+	}
+	return policy
+}
+
+// Limit returns the memory limit for a container with the given recommended request and current limit,
+// a current limit of 0 meaning the container has none. It returns false when the container should not
+// have a limit. The limit is never below the request.
+func (p MemoryLimitPolicy) Limit(recommendedRequest, currentLimit float64, containerStat *ContainerStats) (float64, bool) {
+	switch p.Mode {
+	case types.MemoryLimitModeKeep:
+		if currentLimit <= 0 {
+			return 0, false
+		}
+		return math.Max(currentLimit, recommendedRequest), true
+	case types.MemoryLimitModeRemove:
+		return 0, false
+	case types.MemoryLimitModeRatioOfRequest:
+		return math.Max(math.Max(p.Ratio*recommendedRequest, p.MinLimit), recommendedRequest), true
+	default:
+		peak := recommendedRequest
+		if containerStat != nil && containerStat.Memory7Day != nil {
+			peak = math.Max(peak, containerStat.Memory7Day.Max)
+		}
+		if containerStat != nil && containerStat.MemoryStats != nil {
+			peak = math.Max(peak, containerStat.MemoryStats.OOMMemory)
+		}
+		return math.Max(math.Max(p.Multiplier*peak, p.MinLimit), recommendedRequest), true
+	}
+}
+
+// InPlaceLimit is Limit for a running container. A limit can't be added or removed in place, so a
+// container without a limit keeps none and a container with a limit keeps the current one when the
+// policy would remove it. Lowering the limit in place is not supported either, so it is never lowered.
+func (p MemoryLimitPolicy) InPlaceLimit(recommendedRequest, currentLimit float64, containerStat *ContainerStats) float64 {
+	if currentLimit <= 0 {
+		return 0
+	}
+	limit, hasLimit := p.Limit(recommendedRequest, currentLimit, containerStat)
+	if !hasLimit {
+		limit = math.Max(currentLimit, recommendedRequest)
+	}
+	return math.Max(limit, math.Ceil(currentLimit))
+}
@@ -1,5 +1,7 @@
 package utils
 
+import "math"
+
 type WorkloadKeyVsWorkloadMetrics map[string]WorkloadMetrics
 
 type WorkloadMetrics struct {
@@ -32,10 +34,14 @@ type ContainerMetrics struct {
 	Memory7Day Memory7DayStats
 	CPU7Day    CPU7DayStats
 
+	EphemeralStorageP75 float64
+	EphemeralStorageMax float64
+
 	MedianReplicas float64
 
-	HasCPUData    bool
-	HasMemoryData bool
+	HasCPUData              bool
+	HasMemoryData           bool
+	HasEphemeralStorageData bool
 }
 
 type PSIAdjustedUsage struct {
@@ -55,3 +61,14 @@ type WorkloadKeyVsContainerMetrics map[string]ContainerNameVsContainerMetrics
 type NamespaceVsContainerMetrics map[string]WorkloadKeyVsContainerMetrics
 
 type NamespaceVsWorkloadMetrics map[string]WorkloadKeyVsWorkloadMetrics
+
+// CeilCPU rounds cpu cores up to the millicore like the cpu queries.
+func CeilCPU(cpu float64) float64 {
+	return math.Ceil(cpu*CPUDecimalScale) / CPUDecimalScale
+}
+
+// RoundMemory rounds MB to the decimal places of the memory queries.
+func RoundMemory(memory float64) float64 {
+	scale := math.Pow(10, MemoryDecimalPlaces)
+	return math.Round(memory*scale) / scale
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
 type NodeResourceInfo struct {
@@ -40,6 +41,8 @@ type PodInfo struct {
 	ContinuousOptimization bool                  `json:"continuous_optimization"`
 	Stats                  *WorkloadStat         `json:"stats,omitempty"`
 	ContainerResources     []*ContainerResources `json:"container_resources,omitempty"`
+	// StartedAt is when the last of the running containers of the pod started.
+	StartedAt time.Time `json:"started_at"`
 }
 
 func (p *PodInfo) IsGuaranteedPod() bool {
@@ -54,6 +57,15 @@ func (p *PodInfo) IsGuaranteedPod() bool {
 	return true
 }
 
+// IsInStartupWindow reports whether a container of the pod started within the startup window of a
+// workload with a startup profile, the pod then keeps the startup-sized requests set at admission.
+func (p *PodInfo) IsInStartupWindow(now time.Time) bool {
+	if p.Stats == nil || p.Stats.StartupWindowMinutes <= 0 || p.StartedAt.IsZero() {
+		return false
+	}
+	return now.Before(p.StartedAt.Add(p.Stats.GetStartupWindow()))
+}
+
 func (p *PodInfo) GetContainerResource(containerName string) (*ContainerResources, error) {
 	for _, containerResource := range p.ContainerResources {
 		if strings.EqualFold(containerResource.Name, containerName) {
@@ -175,6 +175,11 @@ func CreateNodeStatsMapping(
 			for _, container := range pod.Spec.Containers {
 				podInfo.ContainerResources = append(podInfo.ContainerResources, getCurrentContainerResources(&container))
 			}
+			for _, status := range pod.Status.ContainerStatuses {
+				if status.State.Running != nil && status.State.Running.StartedAt.After(podInfo.StartedAt) {
+					podInfo.StartedAt = status.State.Running.StartedAt.Time
+				}
+			}
 
 			nodeInfo.Pods = append(nodeInfo.Pods, podInfo)
 		}
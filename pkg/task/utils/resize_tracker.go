@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/truefoundry/cruisekube/pkg/types"
+
+	corev1 "k8s.io/api/core/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+type InfeasibleResizePolicy string
+
+const (
+	// InfeasibleResizePolicyNone leaves the pod as it is and stops tracking the resize.
+	InfeasibleResizePolicyNone InfeasibleResizePolicy = "none"
+	// InfeasibleResizePolicyEvict evicts the pod so it is rescheduled with the new resources.
+	InfeasibleResizePolicyEvict InfeasibleResizePolicy = "evict"
+	// InfeasibleResizePolicyRevert resizes the container back to the resources it had before.
+	InfeasibleResizePolicyRevert InfeasibleResizePolicy = "revert"
+)
+
+type ResizeVerificationConfig struct {
+	// MaxRetries is how often a deferred resize is patched again before it is handled like an
+	// infeasible one. Zero disables retries.
+	MaxRetries       int                    `yaml:"maxRetries" json:"maxRetries" mapstructure:"maxRetries"`
+	InfeasiblePolicy InfeasibleResizePolicy `yaml:"infeasiblePolicy" json:"infeasiblePolicy" mapstructure:"infeasiblePolicy"`
+}
+
+func (c ResizeVerificationConfig) Validate() error {
+	if c.MaxRetries < 0 {
+		return fmt.Errorf("maxRetries must not be negative, got %d", c.MaxRetries)
+	}
+	switch c.InfeasiblePolicy {
+	case InfeasibleResizePolicyNone, InfeasibleResizePolicyEvict, InfeasibleResizePolicyRevert:
+		return nil
+	default:
+		return fmt.Errorf("unknown infeasible resize policy %q", c.InfeasiblePolicy)
+	}
+}
+
+type ResizeState string
+
+const (
+	ResizeStateApplied    ResizeState = "applied"
+	ResizeStateInProgress ResizeState = "in_progress"
+	ResizeStateDeferred   ResizeState = "deferred"
+	ResizeStateInfeasible ResizeState = "infeasible"
+	// ResizeStateSuperseded means the container spec no longer asks for the tracked resources, the pod
+	// was resized again or recreated since.
+	ResizeStateSuperseded ResizeState = "superseded"
+)
+
+// ResizeOutcome is what the verification did with a pending resize. It is exported as a metric label.
+type ResizeOutcome string
+
+const (
+	ResizeOutcomeApplied          ResizeOutcome = "applied"
+	ResizeOutcomeInProgress       ResizeOutcome = "in_progress"
+	ResizeOutcomeRetried          ResizeOutcome = "retried"
+	ResizeOutcomeEvicted          ResizeOutcome = "evicted"
+	ResizeOutcomeEvictionDeferred ResizeOutcome = "eviction_deferred"
+	ResizeOutcomeReverted         ResizeOutcome = "reverted"
+	ResizeOutcomeAbandoned        ResizeOutcome = "abandoned"
+	ResizeOutcomeSuperseded       ResizeOutcome = "superseded"
+	ResizeOutcomeFailed           ResizeOutcome = "failed"
+)
+
+// PendingResize is an in-place resize patched by the apply task that the kubelet has not confirmed yet.
+// CPU values are in cores and memory values in MB.
+type PendingResize struct {
+	PodInfo       PodInfo
+	PodUID        k8stypes.UID
+	NodeName      string
+	ContainerName string
+	Resource      types.ApplyRunResource
+	OldRequest    float64
+	OldLimit      float64
+	NewRequest    float64
+	NewLimit      float64
+	Attempts      int
+}
+
+func (r PendingResize) Key() string {
+	return fmt.Sprintf("%s/%s/%s/%s", r.PodInfo.Namespace, r.PodInfo.Name, r.ContainerName, r.Resource)
+}
+
+// ResizeTracker keeps the resizes of the previous runs until the kubelet applied them or they were
+// handled otherwise.
+type ResizeTracker struct {
+	mu      sync.Mutex
+	pending map[string]PendingResize
+}
+
+func NewResizeTracker() *ResizeTracker {
+	return &ResizeTracker{
+		pending: make(map[string]PendingResize),
+	}
+}
+
+// Track starts tracking a resize, replacing an older resize of the same container resource.
+func (t *ResizeTracker) Track(resize PendingResize) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pending[resize.Key()] = resize
+}
+
+func (t *ResizeTracker) Forget(resize PendingResize) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.pending, resize.Key())
+}
+
+// Pending returns the tracked resizes ordered by pod and container.
+func (t *ResizeTracker) Pending() []PendingResize {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pending := make([]PendingResize, 0, len(t.pending))
+	for _, resize := range t.pending {
+		pending = append(pending, resize)
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Key() < pending[j].Key()
+	})
+	return pending
+}
+
+// podResizeStatusProposed is the resize status clusters before 1.33 reported for a resize the kubelet had
+// not looked at yet, it was dropped from the API with the resize conditions.
+const podResizeStatusProposed corev1.PodResizeStatus = "Proposed"
+
+// GetResizeState compares the pod with the tracked resize. The resize conditions of the pod are reported
+// for the whole pod, so a deferred or infeasible resize of any container applies to all of them. The
+// deprecated resize status is only read when the pod has no resize condition.
+func GetResizeState(pod *corev1.Pod, resize PendingResize) ResizeState {
+	if pod.UID != resize.PodUID {
+		return ResizeStateSuperseded
+	}
+
+	resourceName := corev1.ResourceName(resize.Resource)
+	var specResources *corev1.ResourceRequirements
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == resize.ContainerName {
+			specResources = &pod.Spec.Containers[i].Resources
+		}
+	}
+	if specResources == nil || !resourceMatches(specResources.Requests, resourceName, resize.NewRequest) {
+		return ResizeStateSuperseded
+	}
+
+	if state, ok := resizeStateFromConditions(pod.Status.Conditions); ok {
+		return state
+	}
+
+	switch pod.Status.Resize { //nolint:staticcheck,exhaustive // fallback for clusters without the resize conditions
+	case corev1.PodResizeStatusDeferred:
+		return ResizeStateDeferred
+	case corev1.PodResizeStatusInfeasible:
+		return ResizeStateInfeasible
+	case podResizeStatusProposed, corev1.PodResizeStatusInProgress:
+		return ResizeStateInProgress
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != resize.ContainerName || status.Resources == nil {
+			continue
+		}
+		if !resourceMatches(status.Resources.Requests, resourceName, resize.NewRequest) {
+			return ResizeStateInProgress
+		}
+	}
+	return ResizeStateApplied
+}
+
+// resizeStateFromConditions reads the PodResizePending and PodResizeInProgress conditions. A pending resize
+// takes precedence, with both set a newer resize is waiting behind the one in progress.
+func resizeStateFromConditions(conditions []corev1.PodCondition) (ResizeState, bool) {
+	inProgress := false
+	for _, condition := range conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		if condition.Type == corev1.PodResizePending {
+			switch condition.Reason {
+			case corev1.PodReasonInfeasible:
+				return ResizeStateInfeasible, true
+			case corev1.PodReasonDeferred:
+				return ResizeStateDeferred, true
+			}
+		}
+		if condition.Type == corev1.PodResizePending || condition.Type == corev1.PodResizeInProgress {
+			inProgress = true
+		}
+	}
+	if inProgress {
+		return ResizeStateInProgress, true
+	}
+	return "", false
+}
+
+func resourceMatches(resources corev1.ResourceList, resourceName corev1.ResourceName, expected float64) bool {
+	quantity, exists := resources[resourceName]
+	if !exists {
+		return false
+	}
+	if resourceName == corev1.ResourceCPU {
+		return math.Abs(float64(quantity.MilliValue())/1000.0-expected) < MinimumCPURecommendation
+	}
+	return math.Abs(float64(quantity.Value())/BytesToMBDivisor-expected) < 1
+}
@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/truefoundry/cruisekube/pkg/types"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestGetResizeState(t *testing.T) {
+	resize := PendingResize{
+		PodUID:        "pod-uid",
+		ContainerName: "app",
+		Resource:      types.ApplyRunResourceCPU,
+		OldRequest:    1,
+		NewRequest:    0.5,
+	}
+
+	newPod := func(statusCPU string, resizeStatus corev1.PodResizeStatus, conditions ...corev1.PodCondition) *corev1.Pod {
+		pod := &corev1.Pod{
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name: "app",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+					},
+				}},
+			},
+			Status: corev1.PodStatus{
+				Resize:     resizeStatus,
+				Conditions: conditions,
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name: "app",
+					Resources: &corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(statusCPU)},
+					},
+				}},
+			},
+		}
+		pod.UID = "pod-uid"
+		return pod
+	}
+	pending := func(reason string) corev1.PodCondition {
+		return corev1.PodCondition{Type: corev1.PodResizePending, Status: corev1.ConditionTrue, Reason: reason}
+	}
+	inProgress := corev1.PodCondition{Type: corev1.PodResizeInProgress, Status: corev1.ConditionTrue}
+
+	tests := []struct {
+		name string
+		pod  *corev1.Pod
+		want ResizeState
+	}{
+		{
+			name: "applied without conditions",
+			pod:  newPod("500m", ""),
+			want: ResizeStateApplied,
+		},
+		{
+			name: "not yet reflected in the container status",
+			pod:  newPod("1", ""),
+			want: ResizeStateInProgress,
+		},
+		{
+			name: "deferred condition",
+			pod:  newPod("1", "", pending(corev1.PodReasonDeferred)),
+			want: ResizeStateDeferred,
+		},
+		{
+			name: "infeasible condition",
+			pod:  newPod("1", "", pending(corev1.PodReasonInfeasible)),
+			want: ResizeStateInfeasible,
+		},
+		{
+			name: "in progress condition",
+			pod:  newPod("1", "", inProgress),
+			want: ResizeStateInProgress,
+		},
+		{
+			name: "deferred resize waiting behind one in progress",
+			pod:  newPod("1", "", inProgress, pending(corev1.PodReasonDeferred)),
+			want: ResizeStateDeferred,
+		},
+		{
+			name: "false conditions are ignored",
+			pod: newPod("500m", "", corev1.PodCondition{
+				Type:   corev1.PodResizePending,
+				Status: corev1.ConditionFalse,
+				Reason: corev1.PodReasonInfeasible,
+			}),
+			want: ResizeStateApplied,
+		},
+		{
+			name: "conditions take precedence over the resize status",
+			pod:  newPod("1", corev1.PodResizeStatusInProgress, pending(corev1.PodReasonInfeasible)),
+			want: ResizeStateInfeasible,
+		},
+		{
+			name: "deferred status without conditions",
+			pod:  newPod("1", corev1.PodResizeStatusDeferred),
+			want: ResizeStateDeferred,
+		},
+		{
+			name: "infeasible status without conditions",
+			pod:  newPod("1", corev1.PodResizeStatusInfeasible),
+			want: ResizeStateInfeasible,
+		},
+		{
+			name: "proposed status without conditions",
+			pod:  newPod("500m", podResizeStatusProposed),
+			want: ResizeStateInProgress,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetResizeState(tt.pod, resize); got != tt.want {
+				t.Errorf("Expected resize state %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("recreated pod", func(t *testing.T) {
+		pod := newPod("500m", "")
+		pod.UID = "other-uid"
+		if got := GetResizeState(pod, resize); got != ResizeStateSuperseded {
+			t.Errorf("Expected resize state %s, got %s", ResizeStateSuperseded, got)
+		}
+	})
+
+	t.Run("resized again", func(t *testing.T) {
+		pod := newPod("500m", "", pending(corev1.PodReasonInfeasible))
+		pod.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU] = resource.MustParse("2")
+		if got := GetResizeState(pod, resize); got != ResizeStateSuperseded {
+			t.Errorf("Expected resize state %s, got %s", ResizeStateSuperseded, got)
+		}
+	})
+}
@@ -0,0 +1,199 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/types"
+)
+
+// StabilizationConfig damps the recommendations before they are applied. The zero value applies every
+// recommendation as is.
+type StabilizationConfig struct {
+	// MinRelativeChange is the smallest change of a request, relative to the current request, that is
+	// applied. 0.1 holds back changes under 10%.
+	MinRelativeChange float64 `yaml:"minRelativeChange" json:"minRelativeChange" mapstructure:"minRelativeChange"`
+	// SmoothingFactor is the weight of the latest recommendation in the exponential moving average of
+	// the recommendations, 1 disables the smoothing.
+	SmoothingFactor float64 `yaml:"smoothingFactor" json:"smoothingFactor" mapstructure:"smoothingFactor"`
+	// ScaleUpCooldownMinutes and ScaleDownCooldownMinutes hold back increases and decreases of a
+	// request for that long after the last resize of the workload container.
+	ScaleUpCooldownMinutes   int `yaml:"scaleUpCooldownMinutes" json:"scaleUpCooldownMinutes" mapstructure:"scaleUpCooldownMinutes"`
+	ScaleDownCooldownMinutes int `yaml:"scaleDownCooldownMinutes" json:"scaleDownCooldownMinutes" mapstructure:"scaleDownCooldownMinutes"`
+}
+
+func (c StabilizationConfig) Validate() error {
+	if c.MinRelativeChange < 0 {
+		return fmt.Errorf("minRelativeChange must not be negative, got %v", c.MinRelativeChange)
+	}
+	if c.SmoothingFactor < 0 || c.SmoothingFactor > 1 {
+		return fmt.Errorf("smoothingFactor must be between 0 and 1, got %v", c.SmoothingFactor)
+	}
+	if c.ScaleUpCooldownMinutes < 0 || c.ScaleDownCooldownMinutes < 0 {
+		return fmt.Errorf("cool-downs must not be negative, got %d and %d minutes", c.ScaleUpCooldownMinutes, c.ScaleDownCooldownMinutes)
+	}
+	return nil
+}
+
+func (c StabilizationConfig) smoothingFactor() float64 {
+	if c.SmoothingFactor <= 0 {
+		return 1
+	}
+	return c.SmoothingFactor
+}
+
+type StabilizationKey struct {
+	WorkloadID    string
+	ContainerName string
+	Resource      types.ApplyRunResource
+}
+
+type stabilizationRun struct {
+	smoothedSum   float64
+	smoothedCount int
+	resized       bool
+}
+
+// RecommendationStabilizer smooths the recommendations of every workload container over the runs and
+// holds back the changes that are too small or come too soon after the last resize. All replicas of a
+// workload are judged against the state at the start of the run, so they are resized together.
+type RecommendationStabilizer struct {
+	mu        sync.Mutex
+	config    StabilizationConfig
+	states    map[StabilizationKey]types.RecommendationState
+	runs      map[StabilizationKey]*stabilizationRun
+	startedAt time.Time
+}
+
+func NewRecommendationStabilizer(config StabilizationConfig) *RecommendationStabilizer {
+	return &RecommendationStabilizer{
+		config: config,
+		states: make(map[StabilizationKey]types.RecommendationState),
+		runs:   make(map[StabilizationKey]*stabilizationRun),
+	}
+}
+
+// StartRun starts a run at now with the given states, the states kept in memory are used when states
+// is nil.
+func (s *RecommendationStabilizer) StartRun(now time.Time, states []types.RecommendationState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if states != nil {
+		s.states = make(map[StabilizationKey]types.RecommendationState, len(states))
+		for _, state := range states {
+			s.states[StabilizationKey{WorkloadID: state.WorkloadID, ContainerName: state.ContainerName, Resource: state.Resource}] = state
+		}
+	}
+	s.runs = make(map[StabilizationKey]*stabilizationRun)
+	s.startedAt = now
+}
+
+// Smooth returns the moving average of the recommendation with the average of the previous runs.
+func (s *RecommendationStabilizer) Smooth(key StabilizationKey, recommended float64) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	smoothed := s.smooth(key, recommended)
+	run := s.run(key)
+	run.smoothedSum += smoothed
+	run.smoothedCount++
+	return smoothed
+}
+
+// Stabilize returns the request to apply, which is the current request with the reason when the
+// change is held back.
+func (s *RecommendationStabilizer) Stabilize(key StabilizationKey, current, recommended float64) (float64, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stabilize(key, current, recommended)
+}
+
+// HoldsBackDecrease reports whether the smoothing or the hold-back would leave the request above a
+// recommended decrease. It does not record the recommendation in the run.
+func (s *RecommendationStabilizer) HoldsBackDecrease(key StabilizationKey, current, recommended float64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if recommended >= current {
+		return false
+	}
+	applied, _ := s.stabilize(key, current, s.smooth(key, recommended))
+	return applied > recommended
+}
+
+func (s *RecommendationStabilizer) smooth(key StabilizationKey, recommended float64) float64 {
+	if state, ok := s.states[key]; ok && state.SmoothedValue > 0 {
+		alpha := s.config.smoothingFactor()
+		return alpha*recommended + (1-alpha)*state.SmoothedValue
+	}
+	return recommended
+}
+
+func (s *RecommendationStabilizer) stabilize(key StabilizationKey, current, recommended float64) (float64, string) {
+	if current <= 0 || recommended == current {
+		return recommended, ""
+	}
+	change := math.Abs(recommended-current) / current
+	if change < s.config.MinRelativeChange {
+		return current, fmt.Sprintf("%s change of %.1f%% is below the %.1f%% threshold", key.Resource, 100*change, 100*s.config.MinRelativeChange)
+	}
+
+	state, ok := s.states[key]
+	if !ok || state.LastResizedAt.IsZero() {
+		return recommended, ""
+	}
+	direction, cooldown := "increase", time.Duration(s.config.ScaleUpCooldownMinutes)*time.Minute
+	if recommended < current {
+		direction, cooldown = "decrease", time.Duration(s.config.ScaleDownCooldownMinutes)*time.Minute
+	}
+	if until := state.LastResizedAt.Add(cooldown); s.startedAt.Before(until) {
+		return current, fmt.Sprintf("%s %s held back by the cool-down until %s", key.Resource, direction, until.Format(time.RFC3339))
+	}
+	return recommended, ""
+}
+
+// RecordResize starts the cool-downs of the workload container with the current run.
+func (s *RecommendationStabilizer) RecordResize(key StabilizationKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.run(key).resized = true
+}
+
+// FinishRun folds the run into the states and returns the states it changed.
+func (s *RecommendationStabilizer) FinishRun() []types.RecommendationState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := make([]types.RecommendationState, 0, len(s.runs))
+	for key, run := range s.runs {
+		state, ok := s.states[key]
+		if !ok {
+			state = types.RecommendationState{WorkloadID: key.WorkloadID, ContainerName: key.ContainerName, Resource: key.Resource}
+		}
+		if run.smoothedCount > 0 {
+			state.SmoothedValue = run.smoothedSum / float64(run.smoothedCount)
+		}
+		if run.resized {
+			state.LastResizedAt = s.startedAt
+		}
+		state.UpdatedAt = s.startedAt
+		s.states[key] = state
+		changed = append(changed, state)
+	}
+	s.runs = make(map[StabilizationKey]*stabilizationRun)
+	return changed
+}
+
+func (s *RecommendationStabilizer) run(key StabilizationKey) *stabilizationRun {
+	run, ok := s.runs[key]
+	if !ok {
+		run = &stabilizationRun{}
+		s.runs[key] = run
+	}
+	return run
+}
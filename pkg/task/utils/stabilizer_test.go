@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/truefoundry/cruisekube/pkg/types"
+)
+
+func TestRecommendationStabilizerStabilize(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	key := StabilizationKey{WorkloadID: "Deployment:default:api", ContainerName: "api", Resource: types.ApplyRunResourceCPU}
+	config := StabilizationConfig{
+		MinRelativeChange:        0.1,
+		ScaleUpCooldownMinutes:   10,
+		ScaleDownCooldownMinutes: 60,
+	}
+
+	tests := []struct {
+		name        string
+		states      []types.RecommendationState
+		current     float64
+		recommended float64
+		want        float64
+		heldBack    bool
+	}{
+		{
+			name:        "change below the threshold",
+			current:     1,
+			recommended: 0.95,
+			want:        1,
+			heldBack:    true,
+		},
+		{
+			name:        "change above the threshold without state",
+			current:     1,
+			recommended: 0.5,
+			want:        0.5,
+		},
+		{
+			name:        "no current request",
+			current:     0,
+			recommended: 0.5,
+			want:        0.5,
+		},
+		{
+			name:        "decrease within the cool-down",
+			states:      []types.RecommendationState{{WorkloadID: key.WorkloadID, ContainerName: key.ContainerName, Resource: key.Resource, LastResizedAt: now.Add(-30 * time.Minute)}},
+			current:     1,
+			recommended: 0.5,
+			want:        1,
+			heldBack:    true,
+		},
+		{
+			name:        "increase after its cool-down",
+			states:      []types.RecommendationState{{WorkloadID: key.WorkloadID, ContainerName: key.ContainerName, Resource: key.Resource, LastResizedAt: now.Add(-30 * time.Minute)}},
+			current:     1,
+			recommended: 2,
+			want:        2,
+		},
+		{
+			name:        "decrease after its cool-down",
+			states:      []types.RecommendationState{{WorkloadID: key.WorkloadID, ContainerName: key.ContainerName, Resource: key.Resource, LastResizedAt: now.Add(-2 * time.Hour)}},
+			current:     1,
+			recommended: 0.5,
+			want:        0.5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stabilizer := NewRecommendationStabilizer(config)
+			stabilizer.StartRun(now, tt.states)
+
+			got, reason := stabilizer.Stabilize(key, tt.current, tt.recommended)
+			if got != tt.want {
+				t.Errorf("Expected request %v, got %v", tt.want, got)
+			}
+			if (reason != "") != tt.heldBack {
+				t.Errorf("Expected held back %v, got reason %q", tt.heldBack, reason)
+			}
+			if held := stabilizer.HoldsBackDecrease(key, tt.current, tt.recommended); held != (tt.heldBack && tt.recommended < tt.current) {
+				t.Errorf("Expected the decrease held back to be %v, got %v", tt.heldBack && tt.recommended < tt.current, held)
+			}
+		})
+	}
+}
+
+func TestRecommendationStabilizerSmooth(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	key := StabilizationKey{WorkloadID: "Deployment:default:api", ContainerName: "api", Resource: types.ApplyRunResourceMemory}
+	stabilizer := NewRecommendationStabilizer(StabilizationConfig{SmoothingFactor: 0.5})
+
+	// the first run has no state to smooth with
+	stabilizer.StartRun(now, []types.RecommendationState{})
+	if got := stabilizer.Smooth(key, 1000); got != 1000 {
+		t.Errorf("Expected the first recommendation to be kept, got %v", got)
+	}
+	states := stabilizer.FinishRun()
+	if len(states) != 1 || states[0].SmoothedValue != 1000 || !states[0].LastResizedAt.IsZero() {
+		t.Fatalf("Expected a state smoothed to 1000 without a resize, got %+v", states)
+	}
+
+	// a run started without states keeps the ones in memory, replicas are averaged into the state
+	stabilizer.StartRun(now.Add(time.Hour), nil)
+	if got := stabilizer.Smooth(key, 500); got != 750 {
+		t.Errorf("Expected the recommendation to be smoothed to 750, got %v", got)
+	}
+	if got := stabilizer.Smooth(key, 700); got != 850 {
+		t.Errorf("Expected the recommendation to be smoothed to 850, got %v", got)
+	}
+	stabilizer.RecordResize(key)
+	states = stabilizer.FinishRun()
+	if len(states) != 1 || states[0].SmoothedValue != 800 || !states[0].LastResizedAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("Expected a state smoothed to 800 resized at the run start, got %+v", states)
+	}
+
+	// a restarted controller restores the states of the database
+	restored := NewRecommendationStabilizer(StabilizationConfig{SmoothingFactor: 0.5})
+	restored.StartRun(now.Add(2*time.Hour), states)
+	if got := restored.Smooth(key, 400); math.Abs(got-600) > 1e-9 {
+		t.Errorf("Expected the recommendation to be smoothed with the restored state to 600, got %v", got)
+	}
+	if !restored.HoldsBackDecrease(key, 1000, 400) {
+		t.Error("Expected a decrease lagging behind the smoothed value to be held back")
+	}
+}
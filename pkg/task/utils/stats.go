@@ -16,6 +16,8 @@ type CPUStats = types.CPUStats
 type MemoryStats = types.MemoryStats
 type Memory7DayStats = types.Memory7DayStats
 type CPU7DayStats = types.CPU7DayStats
+type EphemeralStorageStats = types.EphemeralStorageStats
+type StartupStats = types.StartupStats
 type MLPercentilesCPU = types.MLPercentilesCPU
 type MLPercentilesCPUPSIAdjusted = types.MLPercentilesCPUPSIAdjusted
 type MLPercentilesMemory = types.MLPercentilesMemory
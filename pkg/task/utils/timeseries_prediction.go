@@ -6,17 +6,18 @@ import (
 	"fmt"
 	"maps"
 	"slices"
-	"strings"
 	"time"
 
 	"github.com/truefoundry/cruisekube/pkg/logging"
 
 	"github.com/prometheus/common/model"
-
-	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 )
 
-const timeStepSize = 60 * time.Minute
+const (
+	TimeSeriesStepSize = 60 * time.Minute
+	// legacyTimeSeriesTimestampLayout is the UTC layout without an offset the series used to be sent in.
+	legacyTimeSeriesTimestampLayout = "2006-01-02T15:04:05"
+)
 
 // TimeSeriesRequest represents the request payload for the time series model
 type TimeSeriesRequest struct {
@@ -24,43 +25,42 @@ type TimeSeriesRequest struct {
 	ForecastHorizon int                    `json:"forecast_horizon"`
 }
 
-func combineReplicasetsToDeployments(matrix model.Matrix) model.Matrix {
-	deploymentGroups := make(map[string][]*model.SampleStream)
+// combineOwnersToWorkloads combines the series of the ReplicaSets of a Deployment and of the Jobs of a
+// CronJob, and of the workloads of a custom workload, into a series of the workload.
+func combineOwnersToWorkloads(matrix model.Matrix) model.Matrix {
+	workloadGroups := make(map[string][]*model.SampleStream)
 	var combinedMatrix model.Matrix
 
 	for _, sample := range matrix {
-		if string(sample.Metric["created_by_kind"]) != ReplicaSetKind {
+		ownerKind := string(sample.Metric["created_by_kind"])
+		ownerName := string(sample.Metric["created_by_name"])
+		kind, name := ResolveWorkloadOwner(ownerKind, string(sample.Metric["namespace"]), ownerName)
+		if kind == ownerKind {
 			combinedMatrix = append(combinedMatrix, sample)
 			continue
 		}
 
-		replicasetName := string(sample.Metric["created_by_name"])
-		lastDash := strings.LastIndex(replicasetName, "-")
-		deploymentName := replicasetName[:lastDash]
-
 		groupKey := GetWorkloadContainerKey(
-			DeploymentKind,
+			kind,
 			string(sample.Metric["namespace"]),
-			deploymentName,
+			name,
 			string(sample.Metric["container"]))
 
-		deploymentGroups[groupKey] = append(deploymentGroups[groupKey], sample)
+		workloadGroups[groupKey] = append(workloadGroups[groupKey], sample)
 	}
 
-	for _, samples := range deploymentGroups {
+	for _, samples := range workloadGroups {
 		if len(samples) == 0 {
 			continue
 		}
 
 		templateSample := samples[0]
-		replicasetName := string(templateSample.Metric["created_by_name"])
-		lastDash := strings.LastIndex(replicasetName, "-")
-		deploymentName := replicasetName[:lastDash]
+		kind, name := ResolveWorkloadOwner(string(templateSample.Metric["created_by_kind"]), string(templateSample.Metric["namespace"]), string(templateSample.Metric["created_by_name"]))
 
 		newMetric := make(model.Metric)
 		maps.Copy(newMetric, templateSample.Metric)
-		newMetric["created_by_kind"] = DeploymentKind
-		newMetric["created_by_name"] = model.LabelValue(deploymentName)
+		newMetric["created_by_kind"] = model.LabelValue(kind)
+		newMetric["created_by_name"] = model.LabelValue(name)
 
 		combinedValues := combineTimeSeriesByMax(samples)
 
@@ -109,13 +109,19 @@ func EncloseWithinMemoryCleanupFunction(query string, memoryDecimalPlaces int) s
 	return fmt.Sprintf(template, query, float64(BytesPerMB), memoryDecimalPlaces)
 }
 
-func computeSimpleTimeSeriesPredictions(ctx context.Context, timeseriesData []SimpleTimeSeriesData) []SimplePredictionResponse {
-	logging.Infof(ctx, "Computing simple time series predictions for %d entities", len(timeseriesData))
+// ComputeTimeSeriesPredictions keeps the weekly, hourly and current maxima of every series as of now and
+// asks the forecaster for the next horizon steps. Without a forecaster, or when it fails, the max of the
+// weekly, hourly and current predictions is used as MaxValue. The weekly and hourly buckets cover the
+// last MLLookbackWindow and follow the timezone of the calendar, and the samples it excludes are left out
+// of them and of the forecast.
+func ComputeTimeSeriesPredictions(ctx context.Context, timeseriesData []SimpleTimeSeriesData, forecaster Forecaster, horizon int, now time.Time, calendar PredictionCalendar) []SimplePredictionResponse {
+	logging.Infof(ctx, "Computing time series predictions for %d entities", len(timeseriesData))
 
 	var results []SimplePredictionResponse
-	currentTime := time.Now().UTC()
+	currentTime := now.In(calendar.GetLocation())
 	currentHour := currentTime.Hour()
 	currentWeekday := currentTime.Weekday()
+	bucketsStart := currentTime.Add(-MLLookbackWindow)
 
 	for idx, entity := range timeseriesData {
 		entityName := entity.EntityName
@@ -132,12 +138,12 @@ func computeSimpleTimeSeriesPredictions(ctx context.Context, timeseriesData []Si
 
 		parsed := make([]time.Time, 0, len(timestamps))
 		for _, ts := range timestamps {
-			t, err := time.Parse("2006-01-02T15:04:05", ts)
+			t, err := ParseTimeSeriesTimestamp(ts)
 			if err != nil {
 				logging.Errorf(ctx, "Could not parse timestamp '%s' for entity %s: %v", ts, entityName, err)
 				continue
 			}
-			parsed = append(parsed, t.UTC())
+			parsed = append(parsed, t.In(calendar.GetLocation()))
 		}
 
 		if len(parsed) != len(values) {
@@ -145,20 +151,27 @@ func computeSimpleTimeSeriesPredictions(ctx context.Context, timeseriesData []Si
 			continue
 		}
 
-		weeklyPrediction := 0.0
+		history := TimeSeries{Step: TimeSeriesStepSize}
 		for i, t := range parsed {
-			if t.Weekday() == currentWeekday && t.Hour() == currentHour {
-				weeklyPrediction = max(weeklyPrediction, values[i])
+			if !calendar.IsExcluded(t) {
+				history.Timestamps = append(history.Timestamps, t)
+				history.Values = append(history.Values, values[i])
 			}
 		}
 
+		weeklyPrediction := 0.0
 		hourlyPrediction := 0.0
-		for i, t := range parsed {
-			if t.Hour() == currentHour {
-				hourlyPrediction = max(hourlyPrediction, values[i])
+		for i, t := range history.Timestamps {
+			if t.Before(bucketsStart) || t.Hour() != currentHour {
+				continue
+			}
+			hourlyPrediction = max(hourlyPrediction, history.Values[i])
+			if t.Weekday() == currentWeekday {
+				weeklyPrediction = max(weeklyPrediction, history.Values[i])
 			}
 		}
 
+		// the current usage is kept even during an exclusion, it is what the workload needs right now
 		currentPrediction := 0.0
 		oneHourAgo := currentTime.Add(-1 * time.Hour)
 		for i, t := range parsed {
@@ -167,24 +180,47 @@ func computeSimpleTimeSeriesPredictions(ctx context.Context, timeseriesData []Si
 			}
 		}
 
-		maxValue := max(weeklyPrediction, hourlyPrediction, currentPrediction)
-
-		results = append(results, SimplePredictionResponse{
+		response := SimplePredictionResponse{
 			EntityName:        entityName,
 			WeeklyPrediction:  weeklyPrediction,
 			HourlyPrediction:  hourlyPrediction,
 			CurrentPrediction: currentPrediction,
-			MaxValue:          maxValue,
-		})
+			MaxValue:          max(weeklyPrediction, hourlyPrediction, currentPrediction),
+		}
+
+		if forecaster != nil && len(history.Values) > 0 {
+			forecast, err := forecaster.Forecast(history, horizon)
+			if err != nil {
+				logging.Debugf(ctx, "Forecaster %s failed for entity %s, using the max of the buckets: %v", forecaster.GetName(), entityName, err)
+			} else if len(forecast.UpperBounds) > 0 {
+				response.Forecaster = forecaster.GetName()
+				response.PointForecast = slices.Max(forecast.Points)
+				response.UpperBound = slices.Max(forecast.UpperBounds)
+				response.MaxValue = max(response.UpperBound, currentPrediction)
+			}
+		}
+
+		results = append(results, response)
 	}
 
-	logging.Infof(ctx, "Generated simple predictions for %d entities", len(results))
+	logging.Infof(ctx, "Generated predictions for %d entities", len(results))
 	return results
 }
 
-func convertMatrixToSimpleTimeSeriesData(matrix model.Matrix) []SimpleTimeSeriesData {
+// ParseTimeSeriesTimestamp parses the timestamps of SimpleTimeSeriesData. Timestamps without an offset
+// are in UTC.
+func ParseTimeSeriesTimestamp(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(legacyTimeSeriesTimestampLayout, value)
+}
+
+// ConvertMatrixToSimpleTimeSeriesData returns one series per workload container, with the ReplicaSets of a
+// Deployment combined into the Deployment and the Jobs of a CronJob into the CronJob.
+func ConvertMatrixToSimpleTimeSeriesData(matrix model.Matrix) []SimpleTimeSeriesData {
 	var result []SimpleTimeSeriesData
-	combinedMatrix := combineReplicasetsToDeployments(matrix)
+	combinedMatrix := combineOwnersToWorkloads(matrix)
 
 	for _, sample := range combinedMatrix {
 		workloadContainerKey := GetWorkloadContainerKey(
@@ -196,7 +232,7 @@ func convertMatrixToSimpleTimeSeriesData(matrix model.Matrix) []SimpleTimeSeries
 		var timestamps []string
 		var values []float64
 		for _, v := range sample.Values {
-			timestamps = append(timestamps, v.Timestamp.Time().UTC().Format("2006-01-02T15:04:05"))
+			timestamps = append(timestamps, v.Timestamp.Time().UTC().Format(time.RFC3339))
 			values = append(values, float64(v.Value))
 		}
 
@@ -211,7 +247,11 @@ func convertMatrixToSimpleTimeSeriesData(matrix model.Matrix) []SimpleTimeSeries
 	return result
 }
 
-func PredictSimpleStatsFromTimeSeriesModel(ctx context.Context, namespaces []string, promClient v1.API, resourceType string, isPSIEnabled bool) (map[string]map[string]SimplePrediction, error) {
+// UsageSeriesFetcher returns the hourly max usage of every workload container of a namespace between
+// start and end.
+type UsageSeriesFetcher func(ctx context.Context, namespace string, start, end time.Time) ([]SimpleTimeSeriesData, error)
+
+func PredictSimpleStatsFromTimeSeriesModel(ctx context.Context, namespaces []string, fetchSeries UsageSeriesFetcher, resourceType string, forecaster Forecaster, horizon int, calendars *PredictionCalendars) (map[string]map[string]SimplePrediction, error) {
 	logging.Infof(ctx, "Predicting simple %s stats from time series model for namespaces: %v", resourceType, namespaces)
 	result := make(map[string]map[string]SimplePrediction)
 
@@ -219,91 +259,26 @@ func PredictSimpleStatsFromTimeSeriesModel(ctx context.Context, namespaces []str
 		ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
 		defer cancel()
 		end := time.Now()
-		start := end.Add(-MLLookbackWindow)
-		r := v1.Range{
-			Start: start,
-			End:   end,
-			Step:  timeStepSize,
-		}
-
-		var matrix model.Matrix
-		var err error
-
-		if resourceType == "memory" {
-			matrix, err = fetchMemoryMatrix(ctx, promClient, namespace, r)
-			if err != nil {
-				logging.Errorf(ctx, "Error fetching memory matrix for namespace %s: %v", namespace, err)
-				return nil, err
-			}
-		} else {
-			var query string
-			switch resourceType {
-			case "cpu":
-				query = EncloseWithinQuantileOverTime(
-					BuildBatchCoreCPUExpression(namespace, isPSIEnabled),
-					timeStepSize,
-					1.0,
-				)
-			default:
-				logging.Errorf(ctx, "Invalid resource type: %s", resourceType)
-				continue
-			}
-
-			logging.Infof(ctx, "Querying prometheus for simple %s predictions with query: %s", resourceType, CompressQueryForLogging(query))
-			val, _, err := promClient.QueryRange(ctx, query, r)
-			if err != nil {
-				logging.Errorf(ctx, "Error querying prometheus for simple %s predictions: %v", resourceType, err)
-				return nil, fmt.Errorf("failed to query prometheus for simple %s predictions: %w", resourceType, err)
-			}
+		start := end.Add(-ForecastLookbackWindow)
 
-			var ok bool
-			matrix, ok = val.(model.Matrix)
-			if !ok {
-				logging.Errorf(ctx, "Unable to convert to matrix for simple %s predictions", resourceType)
-				return nil, fmt.Errorf("unable to convert to matrix for simple %s predictions", resourceType)
-			}
+		timeseriesData, err := fetchSeries(ctx, namespace, start, end)
+		if err != nil {
+			logging.Errorf(ctx, "Error fetching %s usage series for namespace %s: %v", resourceType, namespace, err)
+			return nil, fmt.Errorf("failed to fetch %s usage series for namespace %s: %w", resourceType, namespace, err)
 		}
-
-		timeseriesData := convertMatrixToSimpleTimeSeriesData(matrix)
 		if len(timeseriesData) == 0 {
 			logging.Infof(ctx, "No timeseries data for namespace %s", namespace)
 			continue
 		}
 
-		predictions := computeSimpleTimeSeriesPredictions(ctx, timeseriesData)
+		predictions := ComputeTimeSeriesPredictions(ctx, timeseriesData, forecaster, horizon, time.Now(), calendars.ForNamespace(namespace))
 
 		namespaceResult := make(map[string]SimplePrediction)
 		for _, pred := range predictions {
-			namespaceResult[pred.EntityName] = SimplePrediction{
-				WeeklyPrediction:  pred.WeeklyPrediction,
-				HourlyPrediction:  pred.HourlyPrediction,
-				CurrentPrediction: pred.CurrentPrediction,
-				MaxValue:          pred.MaxValue,
-			}
+			namespaceResult[pred.EntityName] = pred.ToSimplePrediction()
 		}
 		result[namespace] = namespaceResult
 	}
 
 	return result, nil
 }
-
-func fetchMemoryMatrix(ctx context.Context, promClient v1.API, namespace string, r v1.Range) (model.Matrix, error) {
-	memoryQuery := EncloseWithinMemoryCleanupFunction(EncloseWithinQuantileOverTime(
-		BuildBatchMemoryUsageExpression(namespace),
-		timeStepSize,
-		1.0,
-	), MemoryDecimalPlaces)
-
-	logging.Infof(ctx, "Querying prometheus for memory predictions with query: %s", CompressQueryForLogging(memoryQuery))
-	memoryVal, _, err := promClient.QueryRange(ctx, memoryQuery, r)
-	if err != nil {
-		return nil, fmt.Errorf("error querying prometheus for memory predictions: %w", err)
-	}
-
-	memoryMatrix, ok := memoryVal.(model.Matrix)
-	if !ok {
-		return nil, fmt.Errorf("unable to convert memory result to matrix")
-	}
-
-	return memoryMatrix, nil
-}
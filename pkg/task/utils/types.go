@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"time"
+
 	"github.com/truefoundry/cruisekube/pkg/types"
 
 	"k8s.io/apimachinery/pkg/labels"
@@ -70,10 +72,25 @@ type SimplePredictionResponse struct {
 	HourlyPrediction  float64 `json:"hourly_prediction"`
 	CurrentPrediction float64 `json:"current_prediction"`
 	MaxValue          float64 `json:"max_value"`
+	Forecaster        string  `json:"forecaster,omitempty"`
+	PointForecast     float64 `json:"point_forecast,omitempty"`
+	UpperBound        float64 `json:"upper_bound,omitempty"`
 }
 
 type SimplePrediction = types.SimplePrediction
 
+func (r SimplePredictionResponse) ToSimplePrediction() SimplePrediction {
+	return SimplePrediction{
+		WeeklyPrediction:  r.WeeklyPrediction,
+		HourlyPrediction:  r.HourlyPrediction,
+		CurrentPrediction: r.CurrentPrediction,
+		MaxValue:          r.MaxValue,
+		Forecaster:        r.Forecaster,
+		PointForecast:     r.PointForecast,
+		UpperBound:        r.UpperBound,
+	}
+}
+
 type ThrottledWorkload struct {
 	WorkloadInfo    WorkloadInfo
 	ThrottlingRatio float64
@@ -85,7 +102,11 @@ type PodMetrics struct {
 	TotalRecommendedMemory float64
 	MaxRestCPU             float64
 	MaxRestMemory          float64
-	EvictionRanking        types.EvictionRanking
+	// TotalRestCPU and TotalRestMemory add up the rest of all containers, a recreated pod is admitted
+	// with its recommendation and the whole rest
+	TotalRestCPU    float64
+	TotalRestMemory float64
+	EvictionRanking types.EvictionRanking
 }
 
 type NonOptimizablePodInfo struct {
@@ -95,6 +116,7 @@ type NonOptimizablePodInfo struct {
 	CurrentCPU     float64 `json:"current_cpu"`
 	CurrentMemory  float64 `json:"current_memory"`
 	ContainerCount int     `json:"container_count"`
+	Reason         string  `json:"reason,omitempty"`
 }
 
 type PodContainerRecommendation struct {
@@ -103,6 +125,9 @@ type PodContainerRecommendation struct {
 	CPU           float64 `json:"recommended_cpu"`
 	Memory        float64 `json:"recommended_memory"`
 	Evict         bool    `json:"evict"`
+	// HoldIncreasesReason keeps the requests from growing when set, the node was fitted expecting a
+	// change that does not land in this run: a deferred eviction or a held back decrease.
+	HoldIncreasesReason string `json:"hold_increases_reason,omitempty"`
 }
 
 type NodeOptimizationData struct {
@@ -118,7 +143,51 @@ type OptimizationResult struct {
 	MaxRestMemory               float64                      `json:"max_rest_memory"`
 }
 
+// TimeSeries is a series sampled every Step, oldest sample first. Samples can be missing.
+type TimeSeries struct {
+	Timestamps []time.Time
+	Values     []float64
+	Step       time.Duration
+}
+
+// Forecast holds one point forecast and one upper bound per step of the horizon.
+type Forecast struct {
+	Points      []float64
+	UpperBounds []float64
+}
+
+type Forecaster interface {
+	GetName() string
+	// Forecast predicts the horizon steps following the last sample of the series.
+	Forecast(series TimeSeries, horizon int) (Forecast, error)
+}
+
 type OptimizationStrategy interface {
 	GetName() string
 	OptimizeNode(kubeClient *kubernetes.Clientset, overridesMap map[string]*types.WorkloadOverrideInfo, data NodeOptimizationData) (OptimizationResult, error)
 }
+
+type ClusterOptimizationData struct {
+	// Nodes holds the optimizable pods of every node, keyed by node name. Nodes the strategy owns no pods
+	// on are included without pods, they can still take evicted pods.
+	Nodes map[string]NodeOptimizationData
+}
+
+type PlannedEviction struct {
+	PodInfo    PodInfo `json:"pod_info"`
+	SourceNode string  `json:"source_node"`
+	// TargetNode is where the pod is expected to be rescheduled
+	TargetNode string `json:"target_node,omitempty"`
+}
+
+type ClusterOptimizationResult struct {
+	NodeResults map[string]OptimizationResult `json:"node_results"`
+	Evictions   []PlannedEviction             `json:"evictions"`
+}
+
+// ClusterOptimizationStrategy is implemented by strategies that plan all nodes of a cluster together.
+// It still implements OptimizeNode so it can be used wherever a node-scope strategy is expected.
+type ClusterOptimizationStrategy interface {
+	OptimizationStrategy
+	OptimizeCluster(kubeClient *kubernetes.Clientset, overridesMap map[string]*types.WorkloadOverrideInfo, data ClusterOptimizationData) (ClusterOptimizationResult, error)
+}
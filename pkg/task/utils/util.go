@@ -180,10 +180,12 @@ func createPodToWorkloadMapping(pods []*corev1.Pod, workloadCache []WorkloadLabe
 
 		for _, workload := range workloadCache {
 			if workload.Namespace == pod.Namespace && workload.Selector.Matches(podLabels) {
+				// a workload owned by a custom workload shares the stats of its owner
+				kind, name := ResolveWorkloadOwner(workload.Kind, workload.Namespace, workload.Name)
 				podToWorkloadMap[podKey] = WorkloadInfo{
-					Kind:      workload.Kind,
+					Kind:      kind,
 					Namespace: workload.Namespace,
-					Name:      workload.Name,
+					Name:      name,
 				}
 				break
 			}
@@ -208,12 +210,7 @@ func MergeContainerRawResultsIntoCache(ctx context.Context, cache WorkloadKeyVsC
 			continue
 		}
 
-		if kind == ReplicaSetKind {
-			if deploymentName, isDeployment := ExtractWorkloadFromReplicaSet(workloadName); isDeployment {
-				kind = DeploymentKind
-				workloadName = deploymentName
-			}
-		}
+		kind, workloadName = ResolveWorkloadOwner(kind, namespace, workloadName)
 
 		workloadKey := GetWorkloadKey(kind, namespace, workloadName)
 
@@ -253,6 +250,8 @@ func updateStandardMetrics(metrics *ContainerMetrics, value float64, metricType
 		metrics.HasCPUData = true
 	case updateMemory7DayMetrics(metrics, value, metricType):
 		metrics.HasMemoryData = true
+	case updateEphemeralStorageMetrics(metrics, value, metricType):
+		metrics.HasEphemeralStorageData = true
 	case metricType == "median_replicas":
 		metrics.MedianReplicas = value
 	}
@@ -336,6 +335,18 @@ func updateMemory7DayMetrics(metrics *ContainerMetrics, value float64, metricTyp
 	return true
 }
 
+func updateEphemeralStorageMetrics(metrics *ContainerMetrics, value float64, metricType string) bool {
+	switch metricType {
+	case "ephemeral_storage_p75":
+		updateMaxValue(&metrics.EphemeralStorageP75, value)
+	case "ephemeral_storage_max":
+		updateMaxValue(&metrics.EphemeralStorageMax, value)
+	default:
+		return false
+	}
+	return true
+}
+
 func updatePSIAdjustedMetrics(metrics *ContainerMetrics, value float64, metricType string) {
 	if metrics.PSIAdjustedUsage == nil {
 		metrics.PSIAdjustedUsage = &PSIAdjustedUsage{}
@@ -402,11 +413,18 @@ func BuildContainerStatFromCache(ctx context.Context, workloadInfo WorkloadInfo,
 			Max: metrics.CPUMax,
 			P50: metrics.CPUP50,
 			P75: metrics.CPUP75,
+			P90: metrics.CPUP90,
+			P95: metrics.CPUP95,
+			P99: metrics.CPUP99,
 		}
 
 		memoryStats := &MemoryStats{
 			Max:       metrics.MemoryMax,
+			P50:       metrics.MemoryP50,
 			P75:       metrics.MemoryP75,
+			P90:       metrics.MemoryP90,
+			P95:       metrics.MemoryP95,
+			P99:       metrics.MemoryP99,
 			OOMMemory: metrics.OOMMemory,
 		}
 
@@ -428,6 +446,29 @@ func BuildContainerStatFromCache(ctx context.Context, workloadInfo WorkloadInfo,
 				Max: metrics.PSIAdjustedUsage.CPUMax,
 				P75: metrics.PSIAdjustedUsage.CPUP75,
 				P50: metrics.PSIAdjustedUsage.CPUP50,
+				P90: metrics.PSIAdjustedUsage.CPUP90,
+				P95: metrics.PSIAdjustedUsage.CPUP95,
+				P99: metrics.PSIAdjustedUsage.CPUP99,
+			}
+		}
+
+		// with a startup profile the max is the steady usage, the startup peak is kept apart
+		var startupStats *StartupStats
+		if metrics.StartupCPUMax > 0 {
+			startupStats = &StartupStats{
+				CPUMax:       metrics.StartupCPUMax,
+				SteadyCPUMax: metrics.NonStartupCPUMax,
+			}
+			if metrics.NonStartupCPUMax > 0 {
+				cpuStats.Max = metrics.NonStartupCPUMax
+			}
+		}
+
+		var ephemeralStorageStats *EphemeralStorageStats
+		if metrics.HasEphemeralStorageData {
+			ephemeralStorageStats = &EphemeralStorageStats{
+				Max: metrics.EphemeralStorageMax,
+				P75: metrics.EphemeralStorageP75,
 			}
 		}
 
@@ -439,6 +480,8 @@ func BuildContainerStatFromCache(ctx context.Context, workloadInfo WorkloadInfo,
 			Memory7Day:       memory7Day,
 			CPU7Day:          cpu7Day,
 			PSIAdjustedUsage: psiAdjustedUsageStats,
+			EphemeralStorage: ephemeralStorageStats,
+			Startup:          startupStats,
 		})
 	}
 
@@ -477,12 +520,7 @@ func ParseThrottlingResults(ctx context.Context, throttlingResults RawBatchResul
 			continue
 		}
 
-		if kind == ReplicaSetKind {
-			if deploymentName, isDeployment := ExtractWorkloadFromReplicaSet(workloadName); isDeployment {
-				kind = DeploymentKind
-				workloadName = deploymentName
-			}
-		}
+		kind, workloadName = ResolveWorkloadOwner(kind, namespace, workloadName)
 
 		workloadInfo := WorkloadInfo{
 			Kind:      kind,
@@ -554,6 +592,16 @@ func ExtractWorkloadFromReplicaSet(replicaSetName string) (string, bool) {
 	return replicaSetName, false
 }
 
+// ExtractCronJobFromJob returns the CronJob of a Job, the CronJob controller suffixes the name of its Jobs
+// with their scheduled time in minutes.
+func ExtractCronJobFromJob(jobName string) (string, bool) {
+	re := regexp.MustCompile(`^(.+)-[0-9]{8,}$`)
+	if matches := re.FindStringSubmatch(jobName); len(matches) == 2 {
+		return matches[1], true
+	}
+	return jobName, false
+}
+
 func GetPodKey(namespace, name string) string {
 	return fmt.Sprintf("%s:%s", namespace, name)
 }
@@ -594,40 +642,54 @@ func CheckIfClusterAbove133(ctx context.Context, kubeClient *kubernetes.Clientse
 	return false
 }
 
+// GetWorkloadInfoFromPod returns the workload the stats of the pod are kept for, following the owner
+// references of its ReplicaSet, Job or workload up to a Deployment, Rollout, CronJob or custom workload.
 func GetWorkloadInfoFromPod(pod *corev1.Pod) *WorkloadInfo {
 	if len(pod.OwnerReferences) == 0 {
 		return nil
 	}
 
-	var workloadRef *metav1.OwnerReference
+	var workloadInfo *WorkloadInfo
 	for _, ownerRef := range pod.OwnerReferences {
-		if ownerRef.Kind == StatefulSetKind || ownerRef.Kind == DaemonSetKind {
-			workloadRef = &ownerRef
-			break
+		if _, ok := customWorkloadKindForOwner(ownerRef); ok {
+			return &WorkloadInfo{Kind: ownerRef.Kind, Namespace: pod.Namespace, Name: ownerRef.Name}
 		}
-		if ownerRef.Kind == ReplicaSetKind {
-			re := regexp.MustCompile(`-[a-z0-9]+$`)
-			deploymentName := re.ReplaceAllString(ownerRef.Name, "")
-			workloadRef = &metav1.OwnerReference{
-				Kind: DeploymentKind,
-				Name: deploymentName,
+		switch ownerRef.Kind {
+		case StatefulSetKind, DaemonSetKind:
+			kind, name := ResolveWorkloadOwner(ownerRef.Kind, pod.Namespace, ownerRef.Name)
+			return &WorkloadInfo{Kind: kind, Namespace: pod.Namespace, Name: name}
+		case ReplicaSetKind:
+			kind, name := ResolveWorkloadOwner(ReplicaSetKind, pod.Namespace, ownerRef.Name)
+			if hash, ok := pod.Labels[RolloutPodTemplateHashLabel]; ok && kind == ReplicaSetKind {
+				kind, name = ResolveWorkloadOwner(RolloutKind, pod.Namespace, strings.TrimSuffix(ownerRef.Name, "-"+hash))
+			} else if kind == ReplicaSetKind {
+				re := regexp.MustCompile(`-[a-z0-9]+$`)
+				kind, name = ResolveWorkloadOwner(DeploymentKind, pod.Namespace, re.ReplaceAllString(ownerRef.Name, ""))
 			}
+			workloadInfo = &WorkloadInfo{Kind: kind, Namespace: pod.Namespace, Name: name}
+		case JobKind:
+			kind, name := ResolveWorkloadOwner(JobKind, pod.Namespace, ownerRef.Name)
+			workloadInfo = &WorkloadInfo{Kind: kind, Namespace: pod.Namespace, Name: name}
 		}
 	}
+	return workloadInfo
+}
 
-	if workloadRef == nil {
-		return nil
-	}
+func PtrTo[T any](v T) *T { return &v }
 
-	return &WorkloadInfo{
-		Kind:      workloadRef.Kind,
-		Namespace: pod.Namespace,
-		Name:      workloadRef.Name,
+// GetPodOriginalResources returns the container resources the webhook recorded before adjusting the pod.
+func GetPodOriginalResources(pod *corev1.Pod) (map[string]corev1.ResourceRequirements, bool) {
+	value, exists := pod.Annotations[OriginalResourcesAnnotation]
+	if !exists {
+		return nil, false
 	}
+	var originals map[string]corev1.ResourceRequirements
+	if err := json.Unmarshal([]byte(value), &originals); err != nil {
+		return nil, false
+	}
+	return originals, true
 }
 
-func PtrTo[T any](v T) *T { return &v }
-
 // IsSidecarContainer checks if an InitContainer is a sidecar container
 func IsSidecarContainer(initContainer corev1.Container) bool {
 	return initContainer.RestartPolicy != nil && *initContainer.RestartPolicy == corev1.ContainerRestartPolicyAlways
@@ -11,6 +11,7 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -186,8 +187,185 @@ func (d DaemonSetWrapper) GetCreationTime() time.Time {
 	return d.CreationTimestamp.Time
 }
 
-// GetWorkloadObject retrieves a workload object by kind, namespace, and name
-func GetWorkloadObject(ctx context.Context, kubeClient *kubernetes.Clientset, kind, namespace, name string) (WorkloadObject, error) {
+// RolloutGVR is the resource of Argo Rollouts, they are read through the dynamic client so the Argo types
+// are not a dependency.
+var RolloutGVR = schema.GroupVersionResource{
+	Group:    "argoproj.io",
+	Version:  "v1alpha1",
+	Resource: "rollouts",
+}
+
+// Rollout is the part of an Argo Rollout cruisekube reads.
+type Rollout struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              RolloutSpec `json:"spec"`
+}
+
+type RolloutSpec struct {
+	Selector *metav1.LabelSelector  `json:"selector,omitempty"`
+	Template corev1.PodTemplateSpec `json:"template"`
+	// WorkloadRef points to a Deployment the Rollout takes its selector and template from.
+	WorkloadRef *autoscalingv2.CrossVersionObjectReference `json:"workloadRef,omitempty"`
+}
+
+// RolloutWrapper wraps an Argo Rollout to implement WorkloadObject
+type RolloutWrapper struct {
+	*Rollout
+}
+
+func (r RolloutWrapper) GetContainerSpecs(ctx context.Context, kubeClient *kubernetes.Clientset) []corev1.Container {
+	selector, err := r.GetSelector()
+	if err != nil {
+		logging.Errorf(ctx, "Error getting selector for rollout %s/%s: %v", r.Namespace, r.Name, err)
+		return r.Spec.Template.Spec.Containers
+	}
+
+	// getting fresh pods as dynamically injected containers are not tracked in workload spec
+	pods, err := GetPods(ctx, kubeClient, r.Namespace, selector)
+	if err != nil || len(pods.Items) == 0 {
+		logging.Warnf(ctx, "Could not get pods for rollout %s/%s, falling back to template: %v", r.Namespace, r.Name, err)
+		return r.Spec.Template.Spec.Containers
+	}
+
+	return pods.Items[0].Spec.Containers
+}
+
+func (r RolloutWrapper) GetInitContainerSpecs(ctx context.Context, kubeClient *kubernetes.Clientset) []corev1.Container {
+	selector, err := r.GetSelector()
+	if err != nil {
+		logging.Errorf(ctx, "Error getting selector for rollout %s/%s: %v", r.Namespace, r.Name, err)
+		return r.Spec.Template.Spec.InitContainers
+	}
+
+	// getting fresh pods as dynamically injected containers are not tracked in workload spec
+	pods, err := GetPods(ctx, kubeClient, r.Namespace, selector)
+	if err != nil || len(pods.Items) == 0 {
+		logging.Warnf(ctx, "Could not get pods for rollout %s/%s, falling back to template: %v", r.Namespace, r.Name, err)
+		return r.Spec.Template.Spec.InitContainers
+	}
+
+	return pods.Items[0].Spec.InitContainers
+}
+
+func (r RolloutWrapper) GetSelector() (labels.Selector, error) {
+	selector, err := metav1.LabelSelectorAsSelector(r.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("LabelSelectorAsSelector failed for selector %v: %w", r.Spec.Selector, err)
+	}
+	return selector, nil
+}
+
+func (r RolloutWrapper) GetCreationTime() time.Time {
+	return r.CreationTimestamp.Time
+}
+
+// JobWrapper wraps batchv1.Job to implement WorkloadObject, for the Jobs not created by a CronJob
+type JobWrapper struct {
+	*batchv1.Job
+}
+
+func (j JobWrapper) GetContainerSpecs(ctx context.Context, kubeClient *kubernetes.Clientset) []corev1.Container {
+	selector, err := j.GetSelector()
+	if err != nil {
+		logging.Errorf(ctx, "Error getting selector for job %s/%s: %v", j.Namespace, j.Name, err)
+		return j.Spec.Template.Spec.Containers
+	}
+
+	// getting fresh pods as dynamically injected containers are not tracked in workload spec
+	pods, err := GetPods(ctx, kubeClient, j.Namespace, selector)
+	if err != nil || len(pods.Items) == 0 {
+		logging.Warnf(ctx, "Could not get pods for job %s/%s, falling back to template: %v", j.Namespace, j.Name, err)
+		return j.Spec.Template.Spec.Containers
+	}
+
+	return pods.Items[0].Spec.Containers
+}
+
+func (j JobWrapper) GetInitContainerSpecs(ctx context.Context, kubeClient *kubernetes.Clientset) []corev1.Container {
+	selector, err := j.GetSelector()
+	if err != nil {
+		logging.Errorf(ctx, "Error getting selector for job %s/%s: %v", j.Namespace, j.Name, err)
+		return j.Spec.Template.Spec.InitContainers
+	}
+
+	// getting fresh pods as dynamically injected containers are not tracked in workload spec
+	pods, err := GetPods(ctx, kubeClient, j.Namespace, selector)
+	if err != nil || len(pods.Items) == 0 {
+		logging.Warnf(ctx, "Could not get pods for job %s/%s, falling back to template: %v", j.Namespace, j.Name, err)
+		return j.Spec.Template.Spec.InitContainers
+	}
+
+	return pods.Items[0].Spec.InitContainers
+}
+
+func (j JobWrapper) GetSelector() (labels.Selector, error) {
+	selector, err := metav1.LabelSelectorAsSelector(j.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("LabelSelectorAsSelector failed for selector %v: %w", j.Spec.Selector, err)
+	}
+	return selector, nil
+}
+
+func (j JobWrapper) GetCreationTime() time.Time {
+	return j.CreationTimestamp.Time
+}
+
+// CronJobWrapper wraps batchv1.CronJob to implement WorkloadObject. Its Jobs come and go, so the
+// containers are read from the job template rather than from running pods.
+type CronJobWrapper struct {
+	*batchv1.CronJob
+}
+
+func (c CronJobWrapper) GetContainerSpecs(_ context.Context, _ *kubernetes.Clientset) []corev1.Container {
+	return c.Spec.JobTemplate.Spec.Template.Spec.Containers
+}
+
+func (c CronJobWrapper) GetInitContainerSpecs(_ context.Context, _ *kubernetes.Clientset) []corev1.Container {
+	return c.Spec.JobTemplate.Spec.Template.Spec.InitContainers
+}
+
+// GetSelector fails, the pods of the Jobs of a CronJob share no label the CronJob knows of.
+func (c CronJobWrapper) GetSelector() (labels.Selector, error) {
+	return nil, fmt.Errorf("cronjob %s/%s has no pod selector", c.Namespace, c.Name)
+}
+
+func (c CronJobWrapper) GetCreationTime() time.Time {
+	return c.CreationTimestamp.Time
+}
+
+// getRollout reads a Rollout through the dynamic client, with the selector and template of the Deployment
+// it references when it has a workloadRef.
+func getRollout(ctx context.Context, kubeClient *kubernetes.Clientset, dynamicClient dynamic.Interface, namespace, name string) (*Rollout, error) {
+	rolloutUnstructured, err := dynamicClient.Resource(RolloutGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting rollout %s/%s: %w", namespace, name, err)
+	}
+	return rolloutFromUnstructured(ctx, kubeClient, rolloutUnstructured)
+}
+
+func rolloutFromUnstructured(ctx context.Context, kubeClient *kubernetes.Clientset, rolloutUnstructured *unstructured.Unstructured) (*Rollout, error) {
+	var rollout Rollout
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(rolloutUnstructured.Object, &rollout); err != nil {
+		return nil, fmt.Errorf("error converting rollout %s/%s: %w", rolloutUnstructured.GetNamespace(), rolloutUnstructured.GetName(), err)
+	}
+
+	if ref := rollout.Spec.WorkloadRef; ref != nil && ref.Kind == DeploymentKind {
+		deployment, err := kubeClient.AppsV1().Deployments(rollout.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error getting deployment %s/%s referenced by rollout %s: %w", rollout.Namespace, ref.Name, rollout.Name, err)
+		}
+		rollout.Spec.Template = deployment.Spec.Template
+		if rollout.Spec.Selector == nil {
+			rollout.Spec.Selector = deployment.Spec.Selector
+		}
+	}
+	return &rollout, nil
+}
+
+// GetWorkloadObject retrieves a workload object by kind, namespace, and name. Rollouts and custom workloads
+// need the dynamic client, it may be nil otherwise.
+func GetWorkloadObject(ctx context.Context, kubeClient *kubernetes.Clientset, dynamicClient dynamic.Interface, kind, namespace, name string) (WorkloadObject, error) {
 	switch kind {
 	case DeploymentKind:
 		deployment, err := kubeClient.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
@@ -210,13 +388,42 @@ func GetWorkloadObject(ctx context.Context, kubeClient *kubernetes.Clientset, ki
 		}
 		return DaemonSetWrapper{daemonSet}, nil
 
+	case RolloutKind:
+		if dynamicClient == nil {
+			return nil, fmt.Errorf("error getting rollout %s/%s: no dynamic client", namespace, name)
+		}
+		rollout, err := getRollout(ctx, kubeClient, dynamicClient, namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		return RolloutWrapper{rollout}, nil
+
+	case JobKind:
+		job, err := kubeClient.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error getting job %s/%s: %w", namespace, name, err)
+		}
+		return JobWrapper{job}, nil
+
+	case CronJobKind:
+		cronJob, err := kubeClient.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error getting cronjob %s/%s: %w", namespace, name, err)
+		}
+		return CronJobWrapper{cronJob}, nil
+
 	default:
+		if customKind, ok := customWorkloadKinds[kind]; ok {
+			return getCustomWorkloadObject(ctx, dynamicClient, customKind, namespace, name)
+		}
 		return nil, fmt.Errorf("unsupported workload kind: %s", kind)
 	}
 }
 
-// ListAllWorkloads lists all workloads of all supported types in a namespace
-func ListAllWorkloads(ctx context.Context, kubeClient *kubernetes.Clientset, targetNamespace string) ([]WorkloadInfo, error) {
+// ListAllWorkloads lists all workloads of all supported types in a namespace. Rollouts and custom workloads
+// are only listed with a dynamic client. A built-in workload owned by a custom workload is left out, its
+// custom workload is listed instead so the pods are not counted twice.
+func ListAllWorkloads(ctx context.Context, kubeClient *kubernetes.Clientset, dynamicClient dynamic.Interface, targetNamespace string) ([]WorkloadInfo, error) {
 	var workloads []WorkloadInfo
 
 	// List Deployments
@@ -225,7 +432,7 @@ func ListAllWorkloads(ctx context.Context, kubeClient *kubernetes.Clientset, tar
 		logging.Infof(ctx, "Could not list deployments: %v", err)
 	} else {
 		for _, deployment := range deployments.Items {
-			if deployment.Spec.Selector != nil {
+			if deployment.Spec.Selector != nil && !isOwnedByCustomWorkload(deployment.OwnerReferences) {
 				workloads = append(workloads, WorkloadInfo{
 					Kind:      DeploymentKind,
 					Namespace: deployment.Namespace,
@@ -241,7 +448,7 @@ func ListAllWorkloads(ctx context.Context, kubeClient *kubernetes.Clientset, tar
 		logging.Errorf(ctx, "Could not list statefulsets: %v", err)
 	} else {
 		for _, statefulSet := range statefulSets.Items {
-			if statefulSet.Spec.Selector != nil {
+			if statefulSet.Spec.Selector != nil && !isOwnedByCustomWorkload(statefulSet.OwnerReferences) {
 				workloads = append(workloads, WorkloadInfo{
 					Kind:      StatefulSetKind,
 					Namespace: statefulSet.Namespace,
@@ -257,7 +464,7 @@ func ListAllWorkloads(ctx context.Context, kubeClient *kubernetes.Clientset, tar
 		logging.Errorf(ctx, "Could not list daemonsets: %v", err)
 	} else {
 		for _, daemonSet := range daemonSets.Items {
-			if daemonSet.Spec.Selector != nil {
+			if daemonSet.Spec.Selector != nil && !isOwnedByCustomWorkload(daemonSet.OwnerReferences) {
 				workloads = append(workloads, WorkloadInfo{
 					Kind:      DaemonSetKind,
 					Namespace: daemonSet.Namespace,
@@ -267,9 +474,69 @@ func ListAllWorkloads(ctx context.Context, kubeClient *kubernetes.Clientset, tar
 		}
 	}
 
+	// List Rollouts, the CRD is missing from clusters without Argo Rollouts
+	if dynamicClient != nil {
+		rollouts, err := dynamicClient.Resource(RolloutGVR).Namespace(targetNamespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			logging.Infof(ctx, "Could not list rollouts: %v", err)
+		} else {
+			for _, rollout := range rollouts.Items {
+				if isOwnedByCustomWorkload(rollout.GetOwnerReferences()) {
+					continue
+				}
+				workloads = append(workloads, WorkloadInfo{
+					Kind:      RolloutKind,
+					Namespace: rollout.GetNamespace(),
+					Name:      rollout.GetName(),
+				})
+			}
+		}
+		workloads = append(workloads, listCustomWorkloads(ctx, dynamicClient, targetNamespace)...)
+	}
+
+	// List CronJobs, their Jobs share the stats of the CronJob
+	cronJobs, err := kubeClient.BatchV1().CronJobs(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		logging.Errorf(ctx, "Could not list cronjobs: %v", err)
+	} else {
+		for _, cronJob := range cronJobs.Items {
+			if isOwnedByCustomWorkload(cronJob.OwnerReferences) {
+				continue
+			}
+			workloads = append(workloads, WorkloadInfo{
+				Kind:      CronJobKind,
+				Namespace: cronJob.Namespace,
+				Name:      cronJob.Name,
+			})
+		}
+	}
+
+	// List Jobs not created by a CronJob
+	jobs, err := kubeClient.BatchV1().Jobs(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		logging.Errorf(ctx, "Could not list jobs: %v", err)
+	} else {
+		for _, job := range jobs.Items {
+			if job.Spec.Selector == nil || isOwnedBy(job.OwnerReferences, CronJobKind) || isOwnedByCustomWorkload(job.OwnerReferences) {
+				continue
+			}
+			workloads = append(workloads, WorkloadInfo{
+				Kind:      JobKind,
+				Namespace: job.Namespace,
+				Name:      job.Name,
+			})
+		}
+	}
+
 	return workloads, nil
 }
 
+func isOwnedBy(ownerReferences []metav1.OwnerReference, kind string) bool {
+	return slices.ContainsFunc(ownerReferences, func(ownerRef metav1.OwnerReference) bool {
+		return ownerRef.Kind == kind
+	})
+}
+
 // ListAllWorkloadsWithSelectors lists all workloads with their label selectors
 func ListAllWorkloadsWithSelectors(ctx context.Context, kubeClient *kubernetes.Clientset, targetNamespace string) ([]WorkloadLabelSelectorList, error) {
 	var workloads []WorkloadLabelSelectorList
@@ -408,12 +675,6 @@ func CheckHPAOnCPU(ctx context.Context, dynamicClient dynamic.Interface, targetN
 		hpaNamespace := hpa.Namespace
 		hpaTargetName := hpa.Spec.ScaleTargetRef.Name
 		hpaTargetKind := hpa.Spec.ScaleTargetRef.Kind
-		hpaTargetAPIVersion := hpa.Spec.ScaleTargetRef.APIVersion
-
-		// Argo Rollouts are treated as Deployments for workload management purposes since they extend Kubernetes Deployment functionality.
-		if hpaTargetKind == RolloutKind && hpaTargetAPIVersion == "argoproj.io/v1alpha1" {
-			hpaTargetKind = DeploymentKind
-		}
 
 		if hpaTargetName != "" && hpaTargetKind != "" {
 			targetKey := GetWorkloadKey(hpaTargetKind, hpaNamespace, hpaTargetName)
@@ -430,7 +691,14 @@ func CheckHPAOnCPU(ctx context.Context, dynamicClient dynamic.Interface, targetN
 
 func DetectWorkloadConstraints(ctx context.Context, kubeClient *kubernetes.Clientset, dynamicClient dynamic.Interface, workloadObj WorkloadObject, pdbCache map[string][]policyv1.PodDisruptionBudget) (*WorkloadConstraints, error) {
 	constraints := &WorkloadConstraints{}
-	if _, ok := workloadObj.(DaemonSetWrapper); ok {
+	podTemplate := getPodTemplateSpec(workloadObj)
+	if podTemplate != nil {
+		constraints.ExtendedResourceNames = extendedResourceNames(podTemplate)
+		constraints.ExtendedResources = len(constraints.ExtendedResourceNames) > 0
+	}
+	// the pods of daemonsets and batch workloads are never evicted
+	switch workloadObj.(type) {
+	case DaemonSetWrapper, JobWrapper, CronJobWrapper:
 		constraints.Blocking = false
 		return constraints, nil
 	}
@@ -442,7 +710,6 @@ func DetectWorkloadConstraints(ctx context.Context, kubeClient *kubernetes.Clien
 
 	constraints.PDB = checkWorkloadAgainstPDBs(ctx, workloadObj.GetNamespace(), selector, pdbCache)
 
-	podTemplate := getPodTemplateSpec(workloadObj)
 	if podTemplate != nil {
 		constraints.DoNotDisruptAnnotation = checkDoNotDisruptAnnotation(podTemplate)
 		constraints.Volume = checkVolumes(podTemplate)
@@ -459,7 +726,8 @@ func DetectWorkloadConstraints(ctx context.Context, kubeClient *kubernetes.Clien
 			constraints.Affinity ||
 			// constraints.TopologySpreadConstraint ||
 			constraints.PodAntiAffinity ||
-			constraints.ExcludedAnnotation
+			constraints.ExcludedAnnotation ||
+			constraints.ExtendedResources
 
 	return constraints, nil
 }
@@ -545,11 +813,52 @@ func getPodTemplateSpec(workloadObj WorkloadObject) *corev1.PodTemplateSpec {
 		return &w.Spec.Template
 	case DaemonSetWrapper:
 		return &w.Spec.Template
+	case RolloutWrapper:
+		return &w.Spec.Template
+	case JobWrapper:
+		return &w.Spec.Template
+	case CronJobWrapper:
+		return &w.Spec.JobTemplate.Spec.Template
+	case CustomWorkloadWrapper:
+		podTemplate, err := w.PodTemplate()
+		if err != nil {
+			return nil
+		}
+		return podTemplate
 	default:
 		return nil
 	}
 }
 
+// extendedResourceNames returns the sorted extended resources, like nvidia.com/gpu, requested or limited
+// by the containers of the pod template.
+func extendedResourceNames(podTemplate *corev1.PodTemplateSpec) []string {
+	var names []string
+	containers := slices.Concat(podTemplate.Spec.InitContainers, podTemplate.Spec.Containers)
+	for _, container := range containers {
+		for _, resources := range []corev1.ResourceList{container.Resources.Requests, container.Resources.Limits} {
+			for name := range resources {
+				if isExtendedResourceName(name) && !slices.Contains(names, string(name)) {
+					names = append(names, string(name))
+				}
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// isExtendedResourceName follows the kubernetes definition: a fully qualified name outside of the
+// kubernetes.io domain, and not a quota name.
+func isExtendedResourceName(name corev1.ResourceName) bool {
+	value := string(name)
+	domain, _, ok := strings.Cut(value, "/")
+	if !ok || strings.HasPrefix(value, corev1.DefaultResourceRequestsPrefix) {
+		return false
+	}
+	return domain != "kubernetes.io" && !strings.HasSuffix(domain, ".kubernetes.io")
+}
+
 func checkExcludedAnnotation(podTemplate *corev1.PodTemplateSpec) bool {
 	if podTemplate.Annotations == nil {
 		return false
@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/truefoundry/cruisekube/pkg/logging"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// maxOwnerDepth bounds the owner references followed from an object, a Job of a CronJob of a custom
+// workload is the longest chain.
+const maxOwnerDepth = 4
+
+// workloadOwnerIndex maps the objects pods are owned by to the workload their stats are kept for.
+type workloadOwnerIndex struct {
+	mu     sync.RWMutex
+	loaded bool
+	owners map[string]WorkloadInfo
+}
+
+// workloadOwners is refreshed from the cluster by RefreshWorkloadOwners, the webhook runs without it and
+// resolves the owners by their names.
+var workloadOwners = &workloadOwnerIndex{}
+
+// lookup returns the workload of an object by its workload key and whether the index was refreshed.
+func (i *workloadOwnerIndex) lookup(key string) (WorkloadInfo, bool, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	owner, ok := i.owners[key]
+	return owner, ok, i.loaded
+}
+
+func (i *workloadOwnerIndex) replace(owners map[string]WorkloadInfo) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.owners = owners
+	i.loaded = true
+}
+
+// RefreshWorkloadOwners lists the ReplicaSets, Jobs and workloads of the namespace and records for each of
+// them the workload its pods are counted for: the Deployment or Rollout of a ReplicaSet, the CronJob of a
+// Job, and the custom workload owning any of them, following their owner references.
+func RefreshWorkloadOwners(ctx context.Context, kubeClient *kubernetes.Clientset, dynamicClient dynamic.Interface, targetNamespace string) error {
+	parents := make(map[string]*WorkloadInfo)
+	add := func(kind, namespace, name string, ownerReferences []metav1.OwnerReference) {
+		parents[GetWorkloadKey(kind, namespace, name)] = workloadOwnerOf(namespace, ownerReferences)
+	}
+
+	replicaSets, err := kubeClient.AppsV1().ReplicaSets(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list replicasets: %w", err)
+	}
+	for _, replicaSet := range replicaSets.Items {
+		add(ReplicaSetKind, replicaSet.Namespace, replicaSet.Name, replicaSet.OwnerReferences)
+	}
+	deployments, err := kubeClient.AppsV1().Deployments(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for _, deployment := range deployments.Items {
+		add(DeploymentKind, deployment.Namespace, deployment.Name, deployment.OwnerReferences)
+	}
+	statefulSets, err := kubeClient.AppsV1().StatefulSets(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	for _, statefulSet := range statefulSets.Items {
+		add(StatefulSetKind, statefulSet.Namespace, statefulSet.Name, statefulSet.OwnerReferences)
+	}
+	daemonSets, err := kubeClient.AppsV1().DaemonSets(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list daemonsets: %w", err)
+	}
+	for _, daemonSet := range daemonSets.Items {
+		add(DaemonSetKind, daemonSet.Namespace, daemonSet.Name, daemonSet.OwnerReferences)
+	}
+	jobs, err := kubeClient.BatchV1().Jobs(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list jobs: %w", err)
+	}
+	for _, job := range jobs.Items {
+		add(JobKind, job.Namespace, job.Name, job.OwnerReferences)
+	}
+	cronJobs, err := kubeClient.BatchV1().CronJobs(targetNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list cronjobs: %w", err)
+	}
+	for _, cronJob := range cronJobs.Items {
+		add(CronJobKind, cronJob.Namespace, cronJob.Name, cronJob.OwnerReferences)
+	}
+	// the CRD is missing from clusters without Argo Rollouts
+	if dynamicClient != nil {
+		rollouts, err := dynamicClient.Resource(RolloutGVR).Namespace(targetNamespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			logging.Infof(ctx, "Could not list rollouts: %v", err)
+		} else {
+			for _, rollout := range rollouts.Items {
+				add(RolloutKind, rollout.GetNamespace(), rollout.GetName(), rollout.GetOwnerReferences())
+			}
+		}
+	}
+
+	owners := resolveWorkloadOwners(parents)
+	workloadOwners.replace(owners)
+	logging.Infof(ctx, "Refreshed the owners of %d workload objects", len(owners))
+	return nil
+}
+
+// resolveWorkloadOwners follows the parents of every object up to the workload without a parent.
+func resolveWorkloadOwners(parents map[string]*WorkloadInfo) map[string]WorkloadInfo {
+	owners := make(map[string]WorkloadInfo, len(parents))
+	for key := range parents {
+		kind, namespace, name, ok := ParseWorkloadKey(key)
+		if !ok {
+			continue
+		}
+		owner := WorkloadInfo{Kind: kind, Namespace: namespace, Name: name}
+		for range maxOwnerDepth {
+			parent := parents[GetWorkloadKey(owner.Kind, owner.Namespace, owner.Name)]
+			if parent == nil {
+				break
+			}
+			owner = *parent
+		}
+		owners[key] = owner
+	}
+	return owners
+}
+
+// workloadOwnerOf returns the owner an object is counted for, nil when it is not owned by a Deployment,
+// Rollout, CronJob or custom workload.
+func workloadOwnerOf(namespace string, ownerReferences []metav1.OwnerReference) *WorkloadInfo {
+	for _, ownerRef := range ownerReferences {
+		if ownerRef.Controller == nil || !*ownerRef.Controller {
+			continue
+		}
+		if _, ok := customWorkloadKindForOwner(ownerRef); ok {
+			return &WorkloadInfo{Kind: ownerRef.Kind, Namespace: namespace, Name: ownerRef.Name}
+		}
+		switch ownerRef.Kind {
+		case DeploymentKind, RolloutKind, CronJobKind:
+			return &WorkloadInfo{Kind: ownerRef.Kind, Namespace: namespace, Name: ownerRef.Name}
+		}
+	}
+	return nil
+}
+
+// ResolveWorkloadOwner maps the owner of a pod to the workload the stats are kept for, following the
+// owner references recorded by RefreshWorkloadOwners. An object missing from them, such as the ReplicaSet
+// of an old revision, is mapped by its name: a ReplicaSet to its Deployment and a Job to its CronJob, so
+// that repeated runs share their stats. The guess is dropped when the refreshed owners do not know it.
+func ResolveWorkloadOwner(kind, namespace, name string) (string, string) {
+	owner, ok, loaded := workloadOwners.lookup(GetWorkloadKey(kind, namespace, name))
+	if ok {
+		return owner.Kind, owner.Name
+	}
+
+	guessedKind, guessedName := kind, name
+	switch kind {
+	case ReplicaSetKind:
+		if deploymentName, ok := ExtractWorkloadFromReplicaSet(name); ok {
+			guessedKind, guessedName = DeploymentKind, deploymentName
+		}
+	case JobKind:
+		if cronJobName, ok := ExtractCronJobFromJob(name); ok {
+			guessedKind, guessedName = CronJobKind, cronJobName
+		}
+	}
+	if guessedKind == kind {
+		return kind, name
+	}
+	if !loaded {
+		return guessedKind, guessedName
+	}
+	if owner, ok, _ := workloadOwners.lookup(GetWorkloadKey(guessedKind, namespace, guessedName)); ok {
+		return owner.Kind, owner.Name
+	}
+	if guessedKind == DeploymentKind {
+		// the ReplicaSets of a Rollout are named like those of a Deployment
+		if owner, ok, _ := workloadOwners.lookup(GetWorkloadKey(RolloutKind, namespace, guessedName)); ok {
+			return owner.Kind, owner.Name
+		}
+	}
+	return kind, name
+}
+
+// OwnedWorkloadIDs returns the ids of the workloads counted for a custom workload, the webhook resolves
+// the pods of those to their own workload without the owner references.
+func OwnedWorkloadIDs(workloadInfo WorkloadInfo) []string {
+	workloadOwners.mu.RLock()
+	defer workloadOwners.mu.RUnlock()
+
+	var ids []string
+	for key, owner := range workloadOwners.owners {
+		kind, _, _, ok := ParseWorkloadKey(key)
+		if !ok || kind == ReplicaSetKind || kind == JobKind || owner != workloadInfo {
+			continue
+		}
+		if key != GetWorkloadKey(workloadInfo.Kind, workloadInfo.Namespace, workloadInfo.Name) {
+			ids = append(ids, key)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
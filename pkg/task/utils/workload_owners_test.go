@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/truefoundry/cruisekube/pkg/config"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func setWorkloadOwners(t *testing.T, parents map[string]*WorkloadInfo) {
+	t.Helper()
+	if err := RegisterCustomWorkloads([]config.CustomWorkloadConfig{
+		{Group: "acid.zalan.do", Version: "v1", Kind: "postgresql", Resource: "postgresqls", PodTemplatePath: "spec.template"},
+		{Group: "serving.kserve.io", Version: "v1beta1", Kind: "InferenceService", Resource: "inferenceservices", PodTemplatePath: "spec.predictor"},
+	}); err != nil {
+		t.Fatalf("Failed to register custom workloads: %v", err)
+	}
+	previous := workloadOwners
+	workloadOwners = &workloadOwnerIndex{}
+	if parents != nil {
+		workloadOwners.replace(resolveWorkloadOwners(parents))
+	}
+	t.Cleanup(func() {
+		workloadOwners = previous
+		customWorkloadKinds = map[string]CustomWorkloadKind{}
+	})
+}
+
+func testWorkloadOwnerParents() map[string]*WorkloadInfo {
+	return map[string]*WorkloadInfo{
+		// Postgres operator: postgresql -> StatefulSet -> Pod
+		"StatefulSet:db:orders": {Kind: "postgresql", Namespace: "db", Name: "orders"},
+		// KServe: InferenceService -> Deployment -> ReplicaSet -> Pod
+		"Deployment:ml:iris-predictor":             {Kind: "InferenceService", Namespace: "ml", Name: "iris"},
+		"ReplicaSet:ml:iris-predictor-5d8f9c7b6d":  {Kind: DeploymentKind, Namespace: "ml", Name: "iris-predictor"},
+		"Deployment:default:api":                   nil,
+		"ReplicaSet:default:api-7c9d8f6b5a":        {Kind: DeploymentKind, Namespace: "default", Name: "api"},
+		"CronJob:default:report":                   nil,
+		"Job:default:report-28000000":              {Kind: CronJobKind, Namespace: "default", Name: "report"},
+		"Job:default:backup-20240101":              nil,
+		"Rollout:default:web":                      nil,
+		"Deployment:default:web":                   nil,
+		"ReplicaSet:default:web-6b7c8d9f5e":        {Kind: RolloutKind, Namespace: "default", Name: "web"},
+		"ReplicaSet:default:standalone-4f5e6d7c8b": nil,
+	}
+}
+
+func TestResolveWorkloadOwner(t *testing.T) {
+	setWorkloadOwners(t, testWorkloadOwnerParents())
+
+	tests := []struct {
+		name     string
+		kind     string
+		ns       string
+		owner    string
+		wantKind string
+		wantName string
+	}{
+		{"statefulset of a custom workload", StatefulSetKind, "db", "orders", "postgresql", "orders"},
+		{"replicaset of a deployment of a custom workload", ReplicaSetKind, "ml", "iris-predictor-5d8f9c7b6d", "InferenceService", "iris"},
+		{"deleted replicaset of a deployment of a custom workload", ReplicaSetKind, "ml", "iris-predictor-8e7f6a5b4c", "InferenceService", "iris"},
+		{"replicaset of a deployment", ReplicaSetKind, "default", "api-7c9d8f6b5a", DeploymentKind, "api"},
+		{"replicaset of a rollout sharing the name of a deployment", ReplicaSetKind, "default", "web-6b7c8d9f5e", RolloutKind, "web"},
+		{"replicaset without owner", ReplicaSetKind, "default", "standalone-4f5e6d7c8b", ReplicaSetKind, "standalone-4f5e6d7c8b"},
+		{"job of a cronjob", JobKind, "default", "report-28000000", CronJobKind, "report"},
+		{"deleted job of a cronjob", JobKind, "default", "report-28000060", CronJobKind, "report"},
+		{"standalone job named like a cronjob job", JobKind, "default", "backup-20240101", JobKind, "backup-20240101"},
+		{"deleted standalone job named like a cronjob job", JobKind, "default", "backup-20240102", JobKind, "backup-20240102"},
+		{"deployment", DeploymentKind, "default", "api", DeploymentKind, "api"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, name := ResolveWorkloadOwner(tt.kind, tt.ns, tt.owner)
+			if kind != tt.wantKind || name != tt.wantName {
+				t.Errorf("Expected %s %s, got %s %s", tt.wantKind, tt.wantName, kind, name)
+			}
+		})
+	}
+}
+
+func TestResolveWorkloadOwnerWithoutRefresh(t *testing.T) {
+	setWorkloadOwners(t, nil)
+
+	if kind, name := ResolveWorkloadOwner(ReplicaSetKind, "default", "api-7c9d8f6b5a"); kind != DeploymentKind || name != "api" {
+		t.Errorf("Expected the replicaset to be guessed as Deployment api, got %s %s", kind, name)
+	}
+	if kind, name := ResolveWorkloadOwner(JobKind, "default", "report-28000000"); kind != CronJobKind || name != "report" {
+		t.Errorf("Expected the job to be guessed as CronJob report, got %s %s", kind, name)
+	}
+}
+
+func TestGetWorkloadInfoFromPod(t *testing.T) {
+	setWorkloadOwners(t, testWorkloadOwnerParents())
+
+	newPod := func(namespace, kind, name string, labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace:       namespace,
+			Labels:          labels,
+			OwnerReferences: []metav1.OwnerReference{{Kind: kind, Name: name, APIVersion: "apps/v1"}},
+		}}
+	}
+
+	tests := []struct {
+		name string
+		pod  *corev1.Pod
+		want WorkloadInfo
+	}{
+		{"postgres operator", newPod("db", StatefulSetKind, "orders", nil), WorkloadInfo{Kind: "postgresql", Namespace: "db", Name: "orders"}},
+		{"kserve", newPod("ml", ReplicaSetKind, "iris-predictor-5d8f9c7b6d", nil), WorkloadInfo{Kind: "InferenceService", Namespace: "ml", Name: "iris"}},
+		{"rollout", newPod("default", ReplicaSetKind, "web-6b7c8d9f5e", map[string]string{RolloutPodTemplateHashLabel: "6b7c8d9f5e"}), WorkloadInfo{Kind: RolloutKind, Namespace: "default", Name: "web"}},
+		{"standalone job", newPod("default", JobKind, "backup-20240101", nil), WorkloadInfo{Kind: JobKind, Namespace: "default", Name: "backup-20240101"}},
+		{"job of a cronjob", newPod("default", JobKind, "report-28000000", nil), WorkloadInfo{Kind: CronJobKind, Namespace: "default", Name: "report"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GetWorkloadInfoFromPod(tt.pod)
+			if got == nil || *got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+
+	owned := OwnedWorkloadIDs(WorkloadInfo{Kind: "InferenceService", Namespace: "ml", Name: "iris"})
+	if !slices.Equal(owned, []string{"Deployment:ml:iris-predictor"}) {
+		t.Errorf("Expected the inference service to own its deployment, got %v", owned)
+	}
+}
@@ -0,0 +1,71 @@
+package types
+
+import "time"
+
+type ApplyRunAction string
+
+const (
+	ApplyRunActionResized          ApplyRunAction = "resized"
+	ApplyRunActionSkipped          ApplyRunAction = "skipped"
+	ApplyRunActionFailed           ApplyRunAction = "failed"
+	ApplyRunActionEvicted          ApplyRunAction = "evicted"
+	ApplyRunActionEvictionDeferred ApplyRunAction = "eviction_deferred"
+	ApplyRunActionEvictionFailed   ApplyRunAction = "eviction_failed"
+	ApplyRunActionResizeRetried    ApplyRunAction = "resize_retried"
+	ApplyRunActionResizeReverted   ApplyRunAction = "resize_reverted"
+	ApplyRunActionResizeAbandoned  ApplyRunAction = "resize_abandoned"
+)
+
+type ApplyRunResource string
+
+const (
+	ApplyRunResourceCPU    ApplyRunResource = "cpu"
+	ApplyRunResourceMemory ApplyRunResource = "memory"
+	// ApplyRunResourcePod is used for decisions about the whole pod, such as evictions.
+	ApplyRunResourcePod ApplyRunResource = "pod"
+)
+
+// ApplyRun is the audit record of one run of the apply recommendation task.
+type ApplyRun struct {
+	ID         string    `json:"id"`
+	ClusterID  string    `json:"cluster_id"`
+	Strategy   string    `json:"strategy"`
+	DryRun     bool      `json:"dry_run"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// UnchangedCount is the number of pods and containers the run left as they were, they have no entry.
+	UnchangedCount int             `json:"unchanged_count"`
+	Entries        []ApplyRunEntry `json:"entries,omitempty"`
+}
+
+// ApplyRunEntry is a single decision taken for a pod or container during an apply run. CPU values
+// are in cores and memory values in MB.
+type ApplyRunEntry struct {
+	Namespace     string           `json:"namespace"`
+	PodName       string           `json:"pod_name"`
+	NodeName      string           `json:"node_name"`
+	WorkloadID    string           `json:"workload_id,omitempty"`
+	ContainerName string           `json:"container_name,omitempty"`
+	Resource      ApplyRunResource `json:"resource"`
+	Action        ApplyRunAction   `json:"action"`
+	OldRequest    float64          `json:"old_request,omitempty"`
+	NewRequest    float64          `json:"new_request,omitempty"`
+	OldLimit      float64          `json:"old_limit,omitempty"`
+	NewLimit      float64          `json:"new_limit,omitempty"`
+	Reason        string           `json:"reason,omitempty"`
+}
+
+// ApplyRunFilter selects apply runs. When any of Namespace, PodName or WorkloadID is set only the
+// runs with matching entries are returned, together with those entries.
+type ApplyRunFilter struct {
+	Since          time.Time
+	Limit          int
+	Namespace      string
+	PodName        string
+	WorkloadID     string
+	IncludeEntries bool
+}
+
+func (f ApplyRunFilter) HasEntryFilter() bool {
+	return f.Namespace != "" || f.PodName != "" || f.WorkloadID != ""
+}
@@ -0,0 +1,15 @@
+package types
+
+import "time"
+
+// RecommendationState is what the apply task remembers about the recommendations of a workload
+// container between runs to stabilize them. CPU values are in cores and memory values in MB.
+type RecommendationState struct {
+	WorkloadID    string           `json:"workload_id"`
+	ContainerName string           `json:"container_name"`
+	Resource      ApplyRunResource `json:"resource"`
+	// SmoothedValue is the moving average of the recommendations.
+	SmoothedValue float64   `json:"smoothed_value"`
+	LastResizedAt time.Time `json:"last_resized_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
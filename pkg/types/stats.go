@@ -2,6 +2,8 @@ package types
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -14,6 +16,17 @@ type WorkloadConstraints struct {
 	TopologySpreadConstraint bool `json:"topology_spread_constraint"`
 	PodAntiAffinity          bool `json:"pod_anti_affinity"`
 	ExcludedAnnotation       bool `json:"excluded_annotation"`
+	// ExtendedResources is set when a container requests a resource like nvidia.com/gpu, which is not
+	// optimized and which the pods may not get back on another node.
+	ExtendedResources     bool     `json:"extended_resources"`
+	ExtendedResourceNames []string `json:"extended_resource_names,omitempty"`
+}
+
+func (c *WorkloadConstraints) GetExtendedResourceNames() []string {
+	if c == nil {
+		return nil
+	}
+	return c.ExtendedResourceNames
 }
 
 type EvictionRanking int
@@ -26,8 +39,186 @@ const (
 )
 
 type Overrides struct {
-	EvictionRanking *EvictionRanking `json:"eviction_ranking"`
-	Enabled         *bool            `json:"enabled"`
+	EvictionRanking *EvictionRanking         `json:"eviction_ranking"`
+	Enabled         *bool                    `json:"enabled"`
+	Strategy        *string                  `json:"strategy,omitempty"`
+	Recommendation  *RecommendationOverrides `json:"recommendation,omitempty"`
+	// ResizeGuaranteed opts Guaranteed QoS pods into in-place resizes that keep requests equal to limits.
+	ResizeGuaranteed *bool `json:"resize_guaranteed,omitempty"`
+	// ResourceBounds clamps the recommendations of every container in the workload.
+	ResourceBounds *ResourceBounds `json:"resource_bounds,omitempty"`
+	// ContainerResourceBounds clamps the recommendations of a single container and takes precedence over ResourceBounds.
+	ContainerResourceBounds map[string]*ResourceBounds `json:"container_resource_bounds,omitempty"`
+}
+
+func (o *Overrides) Validate() error {
+	if o == nil {
+		return nil
+	}
+	if err := o.Recommendation.Validate(); err != nil {
+		return fmt.Errorf("invalid recommendation overrides: %w", err)
+	}
+	if err := o.ResourceBounds.Validate(); err != nil {
+		return fmt.Errorf("invalid resource bounds: %w", err)
+	}
+	for containerName, bounds := range o.ContainerResourceBounds {
+		if err := bounds.Validate(); err != nil {
+			return fmt.Errorf("invalid resource bounds for container %s: %w", containerName, err)
+		}
+	}
+	return nil
+}
+
+// ResourceBounds are the minimum and maximum allowed recommendations, similar to minAllowed and
+// maxAllowed of the VPA. CPU is in cores and memory in MB.
+type ResourceBounds struct {
+	MinCPU    *float64 `json:"min_cpu,omitempty"`
+	MaxCPU    *float64 `json:"max_cpu,omitempty"`
+	MinMemory *float64 `json:"min_memory,omitempty"`
+	MaxMemory *float64 `json:"max_memory,omitempty"`
+}
+
+func (b *ResourceBounds) Validate() error {
+	if b == nil {
+		return nil
+	}
+	for name, value := range map[string]*float64{"min_cpu": b.MinCPU, "max_cpu": b.MaxCPU, "min_memory": b.MinMemory, "max_memory": b.MaxMemory} {
+		if value != nil && *value < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	if b.MinCPU != nil && b.MaxCPU != nil && *b.MinCPU > *b.MaxCPU {
+		return fmt.Errorf("min_cpu %v is greater than max_cpu %v", *b.MinCPU, *b.MaxCPU)
+	}
+	if b.MinMemory != nil && b.MaxMemory != nil && *b.MinMemory > *b.MaxMemory {
+		return fmt.Errorf("min_memory %v is greater than max_memory %v", *b.MinMemory, *b.MaxMemory)
+	}
+	return nil
+}
+
+func (b *ResourceBounds) ClampCPU(cpu float64) float64 {
+	if b == nil {
+		return cpu
+	}
+	return clamp(cpu, b.MinCPU, b.MaxCPU)
+}
+
+func (b *ResourceBounds) ClampMemory(memory float64) float64 {
+	if b == nil {
+		return memory
+	}
+	return clamp(memory, b.MinMemory, b.MaxMemory)
+}
+
+func clamp(value float64, minValue *float64, maxValue *float64) float64 {
+	if maxValue != nil && value > *maxValue {
+		value = *maxValue
+	}
+	if minValue != nil && value < *minValue {
+		value = *minValue
+	}
+	return value
+}
+
+// MergeResourceBounds returns the bounds for a container, with the container bounds overriding the
+// workload bounds field by field. Returns nil when neither is set.
+func MergeResourceBounds(workloadBounds *ResourceBounds, containerBounds map[string]*ResourceBounds, containerName string) *ResourceBounds {
+	bounds := containerBounds[containerName]
+	if workloadBounds == nil {
+		return bounds
+	}
+	if bounds == nil {
+		return workloadBounds
+	}
+	merged := *workloadBounds
+	if bounds.MinCPU != nil {
+		merged.MinCPU = bounds.MinCPU
+	}
+	if bounds.MaxCPU != nil {
+		merged.MaxCPU = bounds.MaxCPU
+	}
+	if bounds.MinMemory != nil {
+		merged.MinMemory = bounds.MinMemory
+	}
+	if bounds.MaxMemory != nil {
+		merged.MaxMemory = bounds.MaxMemory
+	}
+	return &merged
+}
+
+type PmaxSource string
+
+const (
+	// PmaxSourcePrediction uses the max value of the time series prediction.
+	PmaxSourcePrediction PmaxSource = "prediction"
+	// PmaxSourceMax uses the max usage observed in the stats window.
+	PmaxSourceMax PmaxSource = "max"
+	// PmaxSource7DayMax uses the max usage observed over the last 7 days.
+	PmaxSource7DayMax PmaxSource = "7day_max"
+)
+
+type SafetyMarginType string
+
+const (
+	// SafetyMarginMultiplicative scales the request by (1 + margin).
+	SafetyMarginMultiplicative SafetyMarginType = "multiplicative"
+	// SafetyMarginAbsolute adds the margin to the request, in cores for CPU and MB for memory.
+	SafetyMarginAbsolute SafetyMarginType = "absolute"
+)
+
+type MemoryLimitMode string
+
+const (
+	// MemoryLimitModeKeep leaves the memory limit as it is, raising it only when the request would exceed it.
+	MemoryLimitModeKeep MemoryLimitMode = "keep"
+	// MemoryLimitModeRatioOfRequest sets the limit to a multiple of the recommended request.
+	MemoryLimitModeRatioOfRequest MemoryLimitMode = "ratio_of_request"
+	// MemoryLimitMode7DayMaxMultiple sets the limit to a multiple of the 7 day max usage, the OOM memory
+	// and the recommended request, whichever is largest.
+	MemoryLimitMode7DayMaxMultiple MemoryLimitMode = "7day_max_multiple"
+	// MemoryLimitModeRemove drops the memory limit.
+	MemoryLimitModeRemove MemoryLimitMode = "remove"
+)
+
+var SupportedRequestPercentiles = []float64{50, 75, 90, 95, 99, 100}
+
+// RecommendationOverrides overrides the cluster level recommendation settings for a workload.
+type RecommendationOverrides struct {
+	RequestPercentile  *float64          `json:"request_percentile,omitempty"`
+	PmaxSource         *PmaxSource       `json:"pmax_source,omitempty"`
+	SafetyMarginType   *SafetyMarginType `json:"safety_margin_type,omitempty"`
+	CPUSafetyMargin    *float64          `json:"cpu_safety_margin,omitempty"`
+	MemorySafetyMargin *float64          `json:"memory_safety_margin,omitempty"`
+}
+
+func (r *RecommendationOverrides) Validate() error {
+	if r == nil {
+		return nil
+	}
+	if r.RequestPercentile != nil && !slices.Contains(SupportedRequestPercentiles, *r.RequestPercentile) {
+		return fmt.Errorf("unsupported request percentile %v, supported percentiles: %v", *r.RequestPercentile, SupportedRequestPercentiles)
+	}
+	if r.PmaxSource != nil {
+		switch *r.PmaxSource {
+		case PmaxSourcePrediction, PmaxSourceMax, PmaxSource7DayMax:
+		default:
+			return fmt.Errorf("unsupported pmax source %q", *r.PmaxSource)
+		}
+	}
+	if r.SafetyMarginType != nil {
+		switch *r.SafetyMarginType {
+		case SafetyMarginMultiplicative, SafetyMarginAbsolute:
+		default:
+			return fmt.Errorf("unsupported safety margin type %q", *r.SafetyMarginType)
+		}
+	}
+	if r.CPUSafetyMargin != nil && *r.CPUSafetyMargin < 0 {
+		return fmt.Errorf("cpu safety margin must not be negative")
+	}
+	if r.MemorySafetyMargin != nil && *r.MemorySafetyMargin < 0 {
+		return fmt.Errorf("memory safety margin must not be negative")
+	}
+	return nil
 }
 
 type ContainerType int
@@ -45,11 +236,18 @@ type WorkloadStat struct {
 	Name                          string               `json:"name"`
 	CreationTime                  time.Time            `json:"creation_time"`
 	UpdatedAt                     time.Time            `json:"updated_at"`
+	GeneratedAt                   time.Time            `json:"generated_at"`
 	ContinuousOptimization        bool                 `json:"continuous_optimization"`
 	IsHorizontallyAutoscaledOnCPU bool                 `json:"is_horizontally_autoscaled_on_cpu"`
 	Constraints                   *WorkloadConstraints `json:"constraints,omitempty"`
 	EvictionRanking               EvictionRanking      `json:"eviction_ranking"`
 	Replicas                      int32                `json:"replicas"`
+	// StartupWindowMinutes is how long after a container starts its usage counts as startup usage, set
+	// when a container of the workload has a startup profile.
+	StartupWindowMinutes int `json:"startup_window_minutes,omitempty"`
+	// OwnedWorkloads are the ids of the workloads a custom workload manages its pods through, their pods
+	// share the stats of the custom workload.
+	OwnedWorkloads []string `json:"owned_workloads,omitempty"`
 
 	ContainerStats             []ContainerStats             `json:"container_stats"`
 	OriginalContainerResources []OriginalContainerResources `json:"original_container_resources"`
@@ -66,6 +264,9 @@ type ContainerStats struct {
 	Memory7Day  *Memory7DayStats `json:"memory_7day"`
 	CPU7Day     *CPU7DayStats    `json:"cpu_7day"`
 
+	EphemeralStorage *EphemeralStorageStats `json:"ephemeral_storage,omitempty"`
+	Startup          *StartupStats          `json:"startup,omitempty"`
+
 	MLPercentilesCPU            *MLPercentilesCPU            `json:"ml_percentiles_cpu,omitempty"`
 	MLPercentilesCPUPSIAdjusted *MLPercentilesCPUPSIAdjusted `json:"ml_percentiles_cpu_psi_adjusted,omitempty"`
 	SimplePredictionsCPU        *SimplePrediction            `json:"simple_predictions_cpu,omitempty"`
@@ -78,11 +279,18 @@ type CPUStats struct {
 	Max float64 `json:"max"`
 	P50 float64 `json:"p50"`
 	P75 float64 `json:"p75"`
+	P90 float64 `json:"p90,omitempty"`
+	P95 float64 `json:"p95,omitempty"`
+	P99 float64 `json:"p99,omitempty"`
 }
 
 type MemoryStats struct {
 	Max       float64 `json:"max"`
+	P50       float64 `json:"p50,omitempty"`
 	P75       float64 `json:"p75"`
+	P90       float64 `json:"p90,omitempty"`
+	P95       float64 `json:"p95,omitempty"`
+	P99       float64 `json:"p99,omitempty"`
 	OOMMemory float64 `json:"oom_memory,omitempty"`
 }
 
@@ -90,6 +298,20 @@ type Memory7DayStats struct {
 	Max float64 `json:"max"`
 }
 
+// EphemeralStorageStats is the ephemeral storage usage over 7 days in MB. Only the kubelet metrics
+// provider collects it.
+type EphemeralStorageStats struct {
+	Max float64 `json:"max"`
+	P75 float64 `json:"p75"`
+}
+
+// StartupStats is the cpu usage profile of a container during the startup window of its workload, over
+// 7 days, and the max usage of its containers past the window.
+type StartupStats struct {
+	CPUMax       float64 `json:"cpu_max"`
+	SteadyCPUMax float64 `json:"steady_cpu_max"`
+}
+
 type CPU7DayStats struct {
 	Max float64 `json:"max"`
 	P50 float64 `json:"p50"`
@@ -123,6 +345,9 @@ type PSIAdjustedUsageStats struct {
 	Max float64 `json:"max"`
 	P50 float64 `json:"p50"`
 	P75 float64 `json:"p75"`
+	P90 float64 `json:"p90,omitempty"`
+	P95 float64 `json:"p95,omitempty"`
+	P99 float64 `json:"p99,omitempty"`
 }
 
 type OriginalContainerResources struct {
@@ -132,17 +357,72 @@ type OriginalContainerResources struct {
 	CPULimit      float64       `json:"cpu_limit"`
 	MemoryRequest float64       `json:"memory_request,omitempty"`
 	MemoryLimit   float64       `json:"memory_limit,omitempty"`
+
+	EphemeralStorageRequest float64 `json:"ephemeral_storage_request,omitempty"`
+	EphemeralStorageLimit   float64 `json:"ephemeral_storage_limit,omitempty"`
 }
 
 type StatsResponse struct {
 	Stats []WorkloadStat `json:"stats"`
+	// Removed holds the ids of the workloads whose stats were deleted after the since of the request
+	Removed []string `json:"removed,omitempty"`
+}
+
+// StatsFilter selects the stats of a cluster, the zero value selects all of them. Since keeps the stats
+// updated after it, a client passes the newest updated_at it holds to only fetch what changed.
+type StatsFilter struct {
+	Namespace string
+	Kind      string
+	Since     time.Time
+}
+
+func (f StatsFilter) Matches(stat WorkloadStat) bool {
+	return (f.Namespace == "" || stat.Namespace == f.Namespace) &&
+		(f.Kind == "" || stat.Kind == f.Kind) &&
+		(f.Since.IsZero() || stat.UpdatedAt.After(f.Since))
+}
+
+// MatchesWorkloadID applies the namespace and kind of the filter to a workload id of the form
+// kind:namespace:name.
+func (f StatsFilter) MatchesWorkloadID(workloadID string) bool {
+	parts := strings.SplitN(workloadID, ":", 3)
+	if len(parts) != 3 {
+		return false
+	}
+	return (f.Namespace == "" || parts[1] == f.Namespace) && (f.Kind == "" || parts[0] == f.Kind)
+}
+
+// Content is the stat without UpdatedAt, when the stat last changed, and GeneratedAt, when a stats run
+// last produced it. Two runs producing the same stat have the same content.
+func (w WorkloadStat) Content() WorkloadStat {
+	w.UpdatedAt = time.Time{}
+	w.GeneratedAt = time.Time{}
+	return w
+}
+
+// WebhookSnapshot is everything the webhook needs to size the pods of a cluster, the overrides are keyed
+// by workload id.
+type WebhookSnapshot struct {
+	Stats     []WorkloadStat        `json:"stats"`
+	Overrides map[string]*Overrides `json:"overrides"`
 }
 
+// SimplePrediction is the usage forecast of a container. MaxValue is the peak expected until the next
+// stats run: the largest upper bound of the forecaster over the horizon, and at least the usage of the
+// last hour.
 type SimplePrediction struct {
 	WeeklyPrediction  float64 `json:"weekly_prediction"`
 	HourlyPrediction  float64 `json:"hourly_prediction"`
 	CurrentPrediction float64 `json:"current_prediction"`
 	MaxValue          float64 `json:"max_value"`
+	// Forecaster is the name of the forecaster that produced PointForecast and UpperBound.
+	Forecaster    string  `json:"forecaster,omitempty"`
+	PointForecast float64 `json:"point_forecast,omitempty"`
+	UpperBound    float64 `json:"upper_bound,omitempty"`
+}
+
+func (w *WorkloadStat) GetStartupWindow() time.Duration {
+	return time.Duration(w.StartupWindowMinutes) * time.Minute
 }
 
 func (w *WorkloadStat) CalculateTotalCPUStats(percentile float64) float64 {
@@ -221,6 +501,12 @@ func (c *CPUStats) GetPercentile(percentile float64) float64 {
 		return c.P50
 	case 75:
 		return c.P75
+	case 90:
+		return c.P90
+	case 95:
+		return c.P95
+	case 99:
+		return c.P99
 	case 100:
 		return c.Max
 	}
@@ -229,14 +515,40 @@ func (c *CPUStats) GetPercentile(percentile float64) float64 {
 
 func (m *MemoryStats) GetPercentile(percentile float64) float64 {
 	switch percentile {
+	case 50:
+		return m.P50
 	case 75:
 		return m.P75
+	case 90:
+		return m.P90
+	case 95:
+		return m.P95
+	case 99:
+		return m.P99
 	case 100:
 		return m.Max
 	}
 	return 0.0
 }
 
+func (p *PSIAdjustedUsageStats) GetPercentile(percentile float64) float64 {
+	switch percentile {
+	case 50:
+		return p.P50
+	case 75:
+		return p.P75
+	case 90:
+		return p.P90
+	case 95:
+		return p.P95
+	case 99:
+		return p.P99
+	case 100:
+		return p.Max
+	}
+	return 0.0
+}
+
 func (w *WorkloadStat) GetContainerStats(containerName string) (*ContainerStats, error) {
 	for _, containerStat := range w.ContainerStats {
 		if containerStat.ContainerName == containerName {
@@ -0,0 +1,10 @@
+package types
+
+import "time"
+
+// StatHistoryEntry is the stat of a workload as generated by one stats run.
+type StatHistoryEntry struct {
+	WorkloadID  string       `json:"workload_id"`
+	GeneratedAt time.Time    `json:"generated_at"`
+	Stat        WorkloadStat `json:"stat"`
+}
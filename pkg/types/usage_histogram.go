@@ -0,0 +1,30 @@
+package types
+
+import "time"
+
+type UsageResource string
+
+const (
+	UsageResourceCPU      UsageResource = "cpu"
+	UsageResourceMemory   UsageResource = "memory"
+	UsageResourceReplicas UsageResource = "replicas"
+	// UsageResourceEphemeralStorage is the writable layer and logs of a container, in MB.
+	UsageResourceEphemeralStorage UsageResource = "ephemeral_storage"
+)
+
+// UsageHistogram counts the usage samples of a workload container, or the replicas of a workload, seen
+// during the slot of Resolution starting at Start. Key is the workload container key for cpu and memory
+// and the workload key for replicas. CPU is in cores, memory and ephemeral storage in MB.
+type UsageHistogram struct {
+	Key        string        `json:"key"`
+	Namespace  string        `json:"namespace"`
+	Resource   UsageResource `json:"resource"`
+	Resolution time.Duration `json:"resolution"`
+	Start      time.Time     `json:"start"`
+	// Buckets maps the index of an exponential bucket to the number of samples in it.
+	Buckets map[int]float64 `json:"buckets"`
+	Count   float64         `json:"count"`
+	Max     float64         `json:"max"`
+	// LastSampleAt is the time of the latest sample added, where the next samples are fetched from.
+	LastSampleAt time.Time `json:"last_sample_at"`
+}
@@ -1,12 +1,26 @@
 package types
 
 type WorkloadOverrideInfo struct {
-	WorkloadID      string          `json:"workload_id"`
-	Name            string          `json:"name"`
-	Namespace       string          `json:"namespace"`
-	Kind            string          `json:"kind"`
-	EvictionRanking EvictionRanking `json:"eviction_ranking"`
-	Enabled         bool            `json:"enabled"`
+	WorkloadID       string                   `json:"workload_id"`
+	Name             string                   `json:"name"`
+	Namespace        string                   `json:"namespace"`
+	Kind             string                   `json:"kind"`
+	EvictionRanking  EvictionRanking          `json:"eviction_ranking"`
+	Enabled          bool                     `json:"enabled"`
+	Strategy         string                   `json:"strategy,omitempty"`
+	Recommendation   *RecommendationOverrides `json:"recommendation,omitempty"`
+	ResizeGuaranteed bool                     `json:"resize_guaranteed,omitempty"`
+
+	ResourceBounds          *ResourceBounds            `json:"resource_bounds,omitempty"`
+	ContainerResourceBounds map[string]*ResourceBounds `json:"container_resource_bounds,omitempty"`
+}
+
+// BoundsForContainer returns the effective resource bounds for a container of the workload.
+func (w *WorkloadOverrideInfo) BoundsForContainer(containerName string) *ResourceBounds {
+	if w == nil {
+		return nil
+	}
+	return MergeResourceBounds(w.ResourceBounds, w.ContainerResourceBounds, containerName)
 }
 
 type WorkloadAnalysisItem struct {
@@ -20,6 +34,9 @@ type WorkloadAnalysisItem struct {
 	RequestGap        float64       `json:"request_gap"`
 	AutoscalingOnCPU  string        `json:"autoscaling_on_cpu"`
 	BlockingKarpenter string        `json:"blocking_karpenter"`
+	// EphemeralStorageUsage7Days is the max / p75 ephemeral storage usage in MB.
+	EphemeralStorageUsage7Days string   `json:"ephemeral_storage_usage_7_days"`
+	ExtendedResources          []string `json:"extended_resources,omitempty"`
 }
 
 type KillswitchResponse struct {
@@ -31,6 +48,15 @@ type KillswitchResponse struct {
 	Errors                 []string `json:"errors,omitempty"`
 }
 
+type RollbackResponse struct {
+	Message      string          `json:"message"`
+	DryRun       bool            `json:"dry_run"`
+	PodsAnalyzed int             `json:"pods_analyzed"`
+	PodsResized  int             `json:"pods_resized"`
+	Changes      []ApplyRunEntry `json:"changes"`
+	Errors       []string        `json:"errors,omitempty"`
+}
+
 type RecommendationAnalysisItem struct {
 	WorkloadType           string  `json:"workload_type"`
 	WorkloadNamespace      string  `json:"workload_namespace"`
@@ -49,6 +75,9 @@ type RecommendationAnalysisItem struct {
 	CurrentRequestedMemory float64 `json:"current_requested_memory"`
 	RecommendedMemory      float64 `json:"recommended_memory"`
 	MemoryDifference       float64 `json:"memory_difference"`
+
+	EphemeralStorageUsage7Days string   `json:"ephemeral_storage_usage_7_days"`
+	ExtendedResources          []string `json:"extended_resources,omitempty"`
 }
 
 type RecommendationSummary struct {